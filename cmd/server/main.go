@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
@@ -9,6 +10,7 @@ import (
 	apiHandlers "datamiddleware/internal/api/handlers"
 	businessCommon "datamiddleware/internal/business/common"
 	errorCommon "datamiddleware/internal/common/errors"
+	"datamiddleware/internal/common/types"
 	"datamiddleware/internal/config"
 	dataPkg "datamiddleware/internal/data/dao"
 	asyncInfra "datamiddleware/internal/infrastructure/async"
@@ -54,13 +56,6 @@ func main() {
 	errorHandler := errorCommon.Init(log)
 	_ = errorHandler // TODO: 在后续阶段使用错误处理器
 
-	// 初始化TCP服务器
-	tcpServer := apiHandlers.NewTCPServer(cfg.Server, log)
-	if err := tcpServer.Start(); err != nil {
-		log.Error("TCP服务器启动失败", "error", err)
-		os.Exit(1)
-	}
-
 	// 初始化数据库
 	db := dataPkg.NewDatabase(cfg.Database, log)
 	if err := db.Connect(); err != nil {
@@ -79,7 +74,6 @@ func main() {
 
 	// 初始化JWT服务
 	jwtService := authInfra.NewJWTService(cfg.JWT, log)
-	_ = jwtService // TODO: 在后续功能中使用JWT服务
 
 	// 初始化业务服务
 	playerService := businessCommon.NewPlayerService(dao, log, jwtService)
@@ -92,6 +86,8 @@ func main() {
 		log.Error("缓存管理器初始化失败", "error", err)
 		os.Exit(1)
 	}
+	// 令牌黑名单保存在缓存中，各节点共享同一Redis时登出对所有节点生效
+	jwtService.SetRevocationStore(authInfra.NewCacheRevocationStore(cacheManager))
 
 	// 初始化异步任务调度器
 	queue := asyncInfra.NewPriorityQueue(1000, log)
@@ -104,21 +100,42 @@ func main() {
 	// 初始化消息路由器
 	messageRouter := router.NewMessageRouter(log)
 
-	// 注册游戏处理器
-	game1Handler := businessCommon.NewGameHandler("game1", playerService, itemService, orderService, log)
-	game2Handler := businessCommon.NewGameHandler("game2", playerService, itemService, orderService, log)
+	// 初始化TCP服务器（游戏消息经由消息路由器分发到各游戏处理器，启用的游戏另外监听各自的端口）
+	tcpServer := apiHandlers.NewTCPServer(cfg.Server, messageRouter, jwtService, dao, log)
 
-	if err := messageRouter.RegisterGameHandler("game1", game1Handler); err != nil {
-		log.Error("注册游戏处理器失败", "game_id", "game1", "error", err)
+	// 初始化游戏管理服务：游戏表是运行状态的来源，未下线的游戏注册处理器，停止开放的游戏断开已有连接
+	gameService := businessCommon.NewGameService(dao, messageRouter.GetGameRouter(), func(gameID string) router.GameHandler {
+		return businessCommon.NewGameHandler(gameID, playerService, itemService, orderService, log)
+	}, log)
+	gameService.OnStatusChange(tcpServer.HandleGameStatusChange)
+	if err := gameService.Sync(context.Background(), cfg.Games); err != nil {
+		log.Error("加载游戏失败", "error", err)
 		os.Exit(1)
 	}
-	if err := messageRouter.RegisterGameHandler("game2", game2Handler); err != nil {
-		log.Error("注册游戏处理器失败", "game_id", "game2", "error", err)
+
+	// 配置文件变化时补录新增的游戏并重新应用游戏表中的状态
+	config.OnChange(func(newCfg *types.Config, err error) {
+		if err != nil {
+			log.Error("配置文件变化后加载失败，保留原配置", "error", err)
+			return
+		}
+		if err := gameService.Sync(context.Background(), newCfg.Games); err != nil {
+			log.Error("配置文件变化后同步游戏失败", "error", err)
+		}
+	})
+
+	tcpServer.SetGames(cfg.Games)
+	tcpServer.SetGameStatusSource(gameService)
+	if err := tcpServer.Start(); err != nil {
+		log.Error("TCP服务器启动失败", "error", err)
 		os.Exit(1)
 	}
 
 	// 初始化HTTP服务器
 	httpServer := apiHandlers.NewHTTPServer(cfg.Server, log, errorHandler, dao, jwtService, playerService, itemService, orderService, cacheManager, taskScheduler)
+	httpServer.MountGames(cfg.Games, messageRouter)
+	httpServer.SetGameService(gameService)
+	httpServer.SetTCPServer(tcpServer)
 	if err := httpServer.Start(); err != nil {
 		log.Error("HTTP服务器启动失败", "error", err)
 		os.Exit(1)
@@ -129,7 +146,7 @@ func main() {
 
 	// 缓存预热
 	warmup := cacheInfra.NewDefaultWarmup(log)
-	if err := cacheManager.WarmupCache(warmup); err != nil {
+	if err := cacheManager.WarmupCache(context.Background(), warmup); err != nil {
 		log.Warn("缓存预热失败", "error", err)
 		// 预热失败不影响服务启动
 	}
@@ -143,16 +160,16 @@ func main() {
 
 	log.Info("数据中间件服务正在关闭...")
 
+	// 优雅关闭TCP服务器：先排空连接（包括WebSocket连接），期间HTTP接口照常服务
+	if err := tcpServer.Stop(); err != nil {
+		log.Error("TCP服务器停止失败", "error", err)
+	}
+
 	// 优雅关闭HTTP服务器
 	if err := httpServer.Stop(); err != nil {
 		log.Error("HTTP服务器停止失败", "error", err)
 	}
 
-	// 优雅关闭TCP服务器
-	if err := tcpServer.Stop(); err != nil {
-		log.Error("TCP服务器停止失败", "error", err)
-	}
-
 	// 关闭数据库连接
 	if err := db.Close(); err != nil {
 		log.Error("数据库关闭失败", "error", err)
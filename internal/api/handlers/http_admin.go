@@ -0,0 +1,275 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"datamiddleware/internal/business/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetGameService 设置游戏管理服务：游戏专属路由按运行状态放行，配置了管理令牌时挂载游戏管理接口，必须在Start之前调用
+func (s *HTTPServer) SetGameService(games *services.GameService) {
+	s.gameStatus = games
+	if s.config.HTTP.AdminToken == "" {
+		s.logger.Info("未配置管理令牌，不开放游戏管理接口")
+		return
+	}
+
+	admin := s.engine.Group("/api/v1/admin", s.adminMiddleware())
+	{
+		admin.GET("/games", s.listGames(games))
+		admin.POST("/games", s.createGame(games))
+		admin.PUT("/games/:id/status", s.setGameStatus(games))
+		admin.POST("/games/reload", s.reloadGames(games))
+	}
+}
+
+// registerDrainRoutes 配置了管理令牌时挂载TCP服务器排空连接接口
+func (s *HTTPServer) registerDrainRoutes(tcp *TCPServer) {
+	if s.config.HTTP.AdminToken == "" {
+		return
+	}
+
+	admin := s.engine.Group("/api/v1/admin", s.adminMiddleware())
+	{
+		admin.GET("/drain", s.drainStatus(tcp))
+		admin.POST("/drain", s.startDrain(tcp))
+	}
+}
+
+// adminMiddleware 校验管理令牌
+func (s *HTTPServer) adminMiddleware() gin.HandlerFunc {
+	token := []byte(s.config.HTTP.AdminToken)
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Token")), token) != 1 {
+			s.logger.Warn("管理令牌无效", "path", c.Request.URL.Path, "client_ip", c.ClientIP())
+			c.AbortWithStatusJSON(401, gin.H{
+				"code":    401,
+				"message": "管理令牌无效",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// listGames 获取游戏表中的游戏及其生效的状态
+func (s *HTTPServer) listGames(games *services.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		states, err := games.ListGames(c.Request.Context())
+		if err != nil {
+			s.logger.Error("获取游戏列表失败", "error", err)
+			c.JSON(500, gin.H{
+				"code":    500,
+				"message": "获取游戏列表失败",
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"code":    0,
+			"message": "获取成功",
+			"data": gin.H{
+				"games": states,
+				"total": len(states),
+			},
+		})
+	}
+}
+
+// createGame 新增游戏
+func (s *HTTPServer) createGame(games *services.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			GameID string `json:"game_id" binding:"required"`
+			Name   string `json:"name"`
+			Status string `json:"status"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{
+				"code":    400,
+				"message": "参数错误",
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		game, err := games.CreateGame(c.Request.Context(), req.GameID, req.Name, req.Status)
+		if err != nil {
+			s.respondGameError(c, req.GameID, "新增游戏失败", err)
+			return
+		}
+
+		s.logger.Info("管理接口新增游戏", "game_id", req.GameID, "status", game.Status, "client_ip", c.ClientIP())
+		c.JSON(200, gin.H{
+			"code":    0,
+			"message": "新增成功",
+			"data":    game,
+		})
+	}
+}
+
+// setGameStatus 修改游戏状态：active 开放，maintenance 维护，offline 下线
+func (s *HTTPServer) setGameStatus(games *services.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameID := c.Param("id")
+		var req struct {
+			Status string `json:"status" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{
+				"code":    400,
+				"message": "参数错误",
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		if err := games.SetStatus(c.Request.Context(), gameID, req.Status); err != nil {
+			s.respondGameError(c, gameID, "修改游戏状态失败", err)
+			return
+		}
+
+		s.logger.Info("管理接口修改游戏状态", "game_id", gameID, "status", req.Status, "client_ip", c.ClientIP())
+		c.JSON(200, gin.H{
+			"code":    0,
+			"message": "修改成功",
+			"data": gin.H{
+				"game_id": gameID,
+				"status":  req.Status,
+			},
+		})
+	}
+}
+
+// reloadGames 重新读取游戏表并应用状态
+func (s *HTTPServer) reloadGames(games *services.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := games.Reload(c.Request.Context()); err != nil {
+			s.logger.Error("重新加载游戏失败", "error", err)
+			c.JSON(500, gin.H{
+				"code":    500,
+				"message": "重新加载游戏失败",
+			})
+			return
+		}
+
+		s.logger.Info("管理接口重新加载游戏", "client_ip", c.ClientIP())
+		c.JSON(200, gin.H{
+			"code":    0,
+			"message": "重新加载成功",
+		})
+	}
+}
+
+// respondGameError 将游戏管理错误转换为HTTP响应
+func (s *HTTPServer) respondGameError(c *gin.Context, gameID, message string, err error) {
+	status := 500
+	switch {
+	case errors.Is(err, services.ErrGameNotFound):
+		status, message = 404, err.Error()
+	case errors.Is(err, services.ErrGameAlreadyExists):
+		status, message = 409, err.Error()
+	case errors.Is(err, services.ErrInvalidGameStatus):
+		status, message = 400, err.Error()
+	default:
+		s.logger.Error(message, "game_id", gameID, "error", err)
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": message,
+	})
+}
+
+// drainStatus 获取TCP服务器排空连接的进度
+func (s *HTTPServer) drainStatus(tcp *TCPServer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"code":    0,
+			"message": "获取成功",
+			"data":    tcp.DrainStatus(),
+		})
+	}
+}
+
+// startDrain 开始排空TCP服务器的连接，请求体中未指定的参数使用配置值，立即返回不等待排空完成
+func (s *HTTPServer) startDrain(tcp *TCPServer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Timeout         string  `json:"timeout"`          // 如 "30s"
+			Redirect        *string `json:"redirect"`         // 为空字符串时不建议重连地址
+			ReconnectSpread string  `json:"reconnect_spread"` // 如 "10s"
+			RequestGrace    string  `json:"request_grace"`    // 如 "5s"，超过timeout时按timeout处理
+		}
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(400, gin.H{
+					"code":    400,
+					"message": "参数错误",
+					"error":   err.Error(),
+				})
+				return
+			}
+		}
+
+		drain := s.config.TCP.Drain
+		options := DrainOptions{
+			Timeout:         drain.Timeout,
+			Redirect:        drain.Redirect,
+			ReconnectSpread: drain.ReconnectSpread,
+			RequestGrace:    drain.RequestGrace,
+		}
+		if req.Redirect != nil {
+			options.Redirect = *req.Redirect
+		}
+		for _, field := range []struct {
+			name  string
+			value string
+			dst   *time.Duration
+		}{
+			{"timeout", req.Timeout, &options.Timeout},
+			{"reconnect_spread", req.ReconnectSpread, &options.ReconnectSpread},
+			{"request_grace", req.RequestGrace, &options.RequestGrace},
+		} {
+			if field.value == "" {
+				continue
+			}
+			d, err := time.ParseDuration(field.value)
+			if err != nil || d < 0 {
+				c.JSON(400, gin.H{
+					"code":    400,
+					"message": "参数错误",
+					"error":   fmt.Sprintf("无效的%s: %s", field.name, field.value),
+				})
+				return
+			}
+			*field.dst = d
+		}
+
+		if _, err := tcp.Drain(options); err != nil {
+			status := 500
+			switch {
+			case errors.Is(err, ErrAlreadyDraining):
+				status = 409
+			case errors.Is(err, ErrServerNotRunning):
+				status = 503
+			}
+			c.JSON(status, gin.H{
+				"code":    status,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		s.logger.Info("管理接口开始排空TCP连接", "timeout", options.Timeout, "redirect", options.Redirect, "client_ip", c.ClientIP())
+		c.JSON(202, gin.H{
+			"code":    0,
+			"message": "开始排空连接",
+			"data":    tcp.DrainStatus(),
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	"datamiddleware/internal/router"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MountGames 在启用的游戏的HTTP前缀下挂载游戏专属路由，请求只会路由到该游戏的处理器，必须在Start之前调用
+func (s *HTTPServer) MountGames(games []types.GameConfig, messageRouter *router.MessageRouter) {
+	s.games = games
+	s.gameRouter = messageRouter.GetGameRouter()
+	for _, game := range games {
+		if !game.Enabled || game.HTTPPrefix == "" {
+			continue
+		}
+
+		prefix := strings.TrimSuffix(game.HTTPPrefix, "/")
+		group := s.engine.Group(prefix, s.gameScopeMiddleware(game.ID))
+		{
+			group.GET("/stats", s.getGameStats)
+			group.GET("/operations", s.getGameOperations)
+			group.POST("/messages/:type", s.routeGameMessage(messageRouter))
+		}
+		s.logger.Info("游戏HTTP路由已挂载", "game_id", game.ID, "prefix", prefix)
+	}
+}
+
+// gameScopeMiddleware 将请求限定在指定游戏，访问令牌必须属于该游戏，设置了游戏运行状态时只放行开放中的游戏
+func (s *HTTPServer) gameScopeMiddleware(gameID string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.gameStatus != nil {
+			status, ok := s.gameStatus.GameStatus(gameID)
+			if !ok || status == types.GameStatusOffline {
+				c.AbortWithStatusJSON(404, gin.H{
+					"code":    404,
+					"message": "游戏不存在或未启用",
+				})
+				return
+			}
+			if status == types.GameStatusMaintenance {
+				c.AbortWithStatusJSON(503, gin.H{
+					"code":    503,
+					"message": "游戏维护中",
+				})
+				return
+			}
+		}
+		if claimGame := c.GetString("game_id"); claimGame != "" && claimGame != gameID {
+			s.logger.Warn("访问令牌不属于该游戏", "game_id", gameID, "token_game_id", claimGame, "path", c.Request.URL.Path)
+			c.AbortWithStatusJSON(403, gin.H{
+				"code":    403,
+				"message": "访问令牌不属于该游戏",
+			})
+			return
+		}
+		c.Set("scope_game_id", gameID)
+		c.Next()
+	}
+}
+
+// routeGameMessage 将请求体作为指定类型的消息交给游戏处理器，用户ID取自访问令牌
+// 客户端断开时取消处理，请求头 X-Request-Timeout（毫秒）可以指定处理超时
+func (s *HTTPServer) routeGameMessage(messageRouter *router.MessageRouter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameID := c.GetString("scope_game_id")
+
+		// 消息类型支持十进制和0x开头的十六进制
+		msgType, err := strconv.ParseUint(c.Param("type"), 0, 16)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"code":    400,
+				"message": "无效的消息类型",
+			})
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"code":    400,
+				"message": "读取请求体失败",
+			})
+			return
+		}
+
+		var timeout time.Duration
+		if value := c.GetHeader("X-Request-Timeout"); value != "" {
+			ms, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || ms <= 0 {
+				c.JSON(400, gin.H{
+					"code":    400,
+					"message": "无效的请求超时",
+				})
+				return
+			}
+			timeout = time.Duration(ms) * time.Millisecond
+		}
+
+		req := &types.Request{
+			ID:        fmt.Sprintf("http_%s_%d", gameID, time.Now().UnixNano()),
+			Type:      types.MessageType(msgType),
+			GameID:    gameID,
+			UserID:    c.GetString("user_id"),
+			ClientIP:  c.ClientIP(),
+			Data:      body,
+			Timestamp: time.Now().Unix(),
+			Timeout:   timeout,
+		}
+		resp, err := messageRouter.GetGameRouter().Route(c.Request.Context(), req)
+		if err != nil {
+			s.logger.Error("处理游戏请求失败", "game_id", gameID, "type", req.Type, "user_id", req.UserID, "error", err)
+			c.JSON(500, gin.H{
+				"code":    500,
+				"message": "处理游戏请求失败",
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"code":    resp.Code,
+			"message": resp.Message,
+			"data":    resp.Data,
+		})
+	}
+}
+
+// getGameOperations 获取游戏支持的操作及请求体字段，游戏专属路由下使用前缀对应的游戏
+func (s *HTTPServer) getGameOperations(c *gin.Context) {
+	gameID := c.GetString("scope_game_id")
+	if gameID == "" {
+		gameID = c.Param("id")
+	}
+
+	var operations []types.OperationInfo
+	exists := false
+	if s.gameRouter != nil {
+		operations, exists = s.gameRouter.GetOperations(gameID)
+	}
+	if !exists {
+		c.JSON(404, gin.H{
+			"code":    404,
+			"message": "游戏不存在或未启用",
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code":    0,
+		"message": "获取成功",
+		"data": gin.H{
+			"game_id":    gameID,
+			"operations": operations,
+		},
+	})
+}
+
+// gameInfos 返回配置的游戏列表，设置了游戏运行状态时返回生效的状态
+func (s *HTTPServer) gameInfos() []gin.H {
+	games := make([]gin.H, 0, len(s.games))
+	for _, game := range s.games {
+		status := "active"
+		if !game.Enabled {
+			status = "disabled"
+		}
+		if s.gameStatus != nil {
+			if applied, ok := s.gameStatus.GameStatus(game.ID); ok {
+				status = applied
+			}
+		}
+		games = append(games, gin.H{
+			"game_id":     game.ID,
+			"name":        game.Name,
+			"status":      status,
+			"tcp_port":    game.TCPPort,
+			"http_prefix": game.HTTPPrefix,
+		})
+	}
+	return games
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/internal/router"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// echoGameHandler 返回收到的请求的游戏处理器
+type echoGameHandler struct{}
+
+func (echoGameHandler) Handle(ctx context.Context, gameID string, req *types.Request) (*types.Response, error) {
+	_, hasDeadline := ctx.Deadline()
+	return &types.Response{ID: req.ID, Code: 0, Message: "ok", Data: map[string]interface{}{
+		"game_id":      gameID,
+		"user_id":      req.UserID,
+		"client_ip":    req.ClientIP,
+		"type":         uint16(req.Type),
+		"body":         string(req.Data.([]byte)),
+		"has_deadline": hasDeadline,
+	}}, nil
+}
+
+func (echoGameHandler) GetSupportedMessageTypes() []types.MessageType {
+	return []types.MessageType{types.MessageTypePlayerData}
+}
+
+func (echoGameHandler) GetName() string { return "echo" }
+
+func TestMountGames(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+	messageRouter := router.NewMessageRouter(log)
+	messageRouter.RegisterGameHandler("game1", echoGameHandler{})
+
+	engine := gin.New()
+	// 模拟认证中间件写入的令牌声明
+	engine.Use(func(c *gin.Context) {
+		if gameID := c.GetHeader("X-Token-Game"); gameID != "" {
+			c.Set("game_id", gameID)
+			c.Set("user_id", "user1")
+		}
+		c.Next()
+	})
+	s := &HTTPServer{engine: engine, logger: log}
+
+	games := []types.GameConfig{
+		{ID: "game1", Name: "游戏1", Enabled: true, TCPPort: 9101, HTTPPrefix: "/api/game1/"},
+		{ID: "game2", Name: "游戏2", Enabled: false, TCPPort: 9102, HTTPPrefix: "/api/game2"},
+	}
+	s.MountGames(games, messageRouter)
+
+	request := func(path, tokenGame string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"level":3}`))
+		req.Header.Set("X-Token-Game", tokenGame)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("/api/game1/messages/0x1003", "game1")
+	var resp struct {
+		Code int                    `json:"code"`
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != 200 || resp.Code != 0 {
+		t.Fatalf("游戏请求失败: %d %s", w.Code, w.Body.String())
+	}
+	if resp.Data["game_id"] != "game1" || resp.Data["user_id"] != "user1" || resp.Data["body"] != `{"level":3}` || resp.Data["client_ip"] != "192.0.2.1" {
+		t.Errorf("游戏处理器收到的请求 = %v", resp.Data)
+	}
+	if resp.Data["has_deadline"] != false {
+		t.Errorf("未指定超时的请求不应有截止时间: %v", resp.Data)
+	}
+
+	// 请求头指定处理超时
+	timeoutRequest := func(timeout string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/game1/messages/0x1003", strings.NewReader(`{}`))
+		req.Header.Set("X-Token-Game", "game1")
+		req.Header.Set("X-Request-Timeout", timeout)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	w = timeoutRequest("500")
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data["has_deadline"] != true {
+		t.Errorf("指定超时的请求应有截止时间: %d %s", w.Code, w.Body.String())
+	}
+	if w := timeoutRequest("-1"); w.Code != 400 {
+		t.Errorf("无效超时状态码 = %d, 期望 400", w.Code)
+	}
+
+	if w := request("/api/game1/messages/0x1003", "game2"); w.Code != 403 {
+		t.Errorf("其他游戏的令牌状态码 = %d, 期望 403", w.Code)
+	}
+	if w := request("/api/game1/messages/abc", "game1"); w.Code != 400 {
+		t.Errorf("无效消息类型状态码 = %d, 期望 400", w.Code)
+	}
+	if w := request("/api/game2/messages/0x1003", "game2"); w.Code != 404 {
+		t.Errorf("禁用游戏的路由状态码 = %d, 期望 404", w.Code)
+	}
+
+	// 按游戏运行状态放行
+	statuses := fakeGameStatus{"game1": types.GameStatusMaintenance}
+	s.gameStatus = statuses
+	if w := request("/api/game1/messages/0x1003", "game1"); w.Code != 503 {
+		t.Errorf("维护中的游戏状态码 = %d, 期望 503", w.Code)
+	}
+	statuses["game1"] = types.GameStatusOffline
+	if w := request("/api/game1/messages/0x1003", "game1"); w.Code != 404 {
+		t.Errorf("下线的游戏状态码 = %d, 期望 404", w.Code)
+	}
+	statuses["game1"] = types.GameStatusActive
+	if w := request("/api/game1/messages/0x1003", "game1"); w.Code != 200 {
+		t.Errorf("开放的游戏状态码 = %d, 期望 200", w.Code)
+	}
+
+	// 未实现OperationLister的处理器按消息类型列出操作
+	req := httptest.NewRequest(http.MethodGet, "/api/game1/operations", nil)
+	req.Header.Set("X-Token-Game", "game1")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	var opsResp struct {
+		Data struct {
+			Operations []types.OperationInfo `json:"operations"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &opsResp); err != nil || w.Code != 200 {
+		t.Fatalf("获取操作失败: %d %s", w.Code, w.Body.String())
+	}
+	if ops := opsResp.Data.Operations; len(ops) != 1 || ops[0].MessageType != types.MessageTypePlayerData {
+		t.Errorf("操作列表 = %+v", ops)
+	}
+}
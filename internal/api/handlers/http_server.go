@@ -10,12 +10,14 @@ import (
 	"datamiddleware/internal/infrastructure/async"
 	"datamiddleware/internal/infrastructure/auth"
 	"datamiddleware/internal/infrastructure/cache"
+	"datamiddleware/internal/infrastructure/certs"
 	dataPkg "datamiddleware/internal/data/dao"
 	"datamiddleware/internal/common/errors"
 	"datamiddleware/internal/infrastructure/logging"
 	"datamiddleware/internal/infrastructure/monitor"
 	"datamiddleware/internal/business/common"
 	"datamiddleware/internal/common/types"
+	"datamiddleware/internal/router"
 
 	"github.com/gin-gonic/gin"
 )
@@ -35,6 +37,11 @@ type HTTPServer struct {
 	orderService  *services.OrderService  `json:"-"`  // 订单服务
 	cacheManager *cache.Manager          `json:"-"`  // 缓存管理器
 	taskScheduler *async.TaskScheduler   `json:"-"`  // 任务调度器
+	games         []types.GameConfig     `json:"-"`  // 游戏配置，由MountGames设置
+	gameStatus    GameStatusSource       `json:"-"`  // 游戏运行状态，nil表示不检查
+	gameRouter    *router.Router         `json:"-"`  // 游戏路由器，由MountGames设置
+	tcpServer     *TCPServer             `json:"-"`  // 处理WebSocket连接和排空连接的TCP服务器，nil表示不可用
+	tlsCerts      *certs.Reloader        `json:"-"`  // TLS证书，nil表示不启用HTTPS
 }
 
 // NewHTTPServer 创建HTTP服务器
@@ -51,6 +58,11 @@ func NewHTTPServer(config types.ServerConfig, log logger.Logger, errorHandler *e
 
 	engine := gin.New()
 
+	// 只信任配置的反向代理转发的客户端IP，未配置时c.ClientIP()为连接的远程地址（配置已在加载时校验）
+	if err := engine.SetTrustedProxies(config.HTTP.TrustedProxies); err != nil {
+		log.Error("设置可信代理失败", "trusted_proxies", config.HTTP.TrustedProxies, "error", err)
+	}
+
 	// 初始化监控器
 	monitor := monitor.NewMonitor(log)
 
@@ -90,10 +102,30 @@ func (s *HTTPServer) Start() error {
 		WriteTimeout: s.config.HTTP.WriteTimeout,
 	}
 
-	s.logger.Info("HTTP服务器启动", "address", s.server.Addr)
+	// 启用HTTPS时证书文件变化后自动重新加载
+	if s.config.HTTP.TLS.Enabled {
+		tlsCerts, err := certs.NewReloader(s.config.HTTP.TLS, s.logger)
+		if err != nil {
+			return fmt.Errorf("加载HTTP TLS证书失败: %w", err)
+		}
+		if err := tlsCerts.Start(); err != nil {
+			s.logger.Warn("监听TLS证书文件失败，证书更新后需要重启服务", "error", err)
+		}
+		s.tlsCerts = tlsCerts
+		s.server.TLSConfig = tlsCerts.TLSConfig()
+	}
+
+	s.logger.Info("HTTP服务器启动", "address", s.server.Addr, "tls", s.tlsCerts != nil)
 
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.tlsCerts != nil {
+			// 证书由TLSConfig提供
+			err = s.server.ListenAndServeTLS("", "")
+		} else {
+			err = s.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			s.logger.Error("HTTP服务器启动失败", "error", err)
 		}
 	}()
@@ -116,6 +148,9 @@ func (s *HTTPServer) Stop() error {
 			return err
 		}
 	}
+	if s.tlsCerts != nil {
+		s.tlsCerts.Stop()
+	}
 
 	s.logger.Info("HTTP服务器已停止")
 	return nil
@@ -202,6 +237,7 @@ func (s *HTTPServer) setupRoutes() {
 		{
 			games.GET("", s.getGames)
 			games.GET("/:id/stats", s.getGameStats)
+			games.GET("/:id/operations", s.getGameOperations)
 		}
 
 		// 缓存相关接口
@@ -235,7 +271,7 @@ func (s *HTTPServer) setupRoutes() {
 	// 监控接口
 	s.engine.GET("/metrics", s.metrics)
 
-	// WebSocket接口（预留）
+	// WebSocket接入，与TCP端口使用相同的消息协议
 	s.engine.GET("/ws", s.websocketHandler)
 
 	s.logger.Info("路由设置完成")
@@ -314,8 +350,9 @@ func (s *HTTPServer) corsMiddleware() gin.HandlerFunc {
 // authMiddleware 认证中间件
 func (s *HTTPServer) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 跳过健康检查、监控、认证、缓存和异步相关接口
+		// 跳过健康检查、监控、认证、缓存和异步相关接口，WebSocket连接通过握手消息认证
 		if c.Request.URL.Path == "/api/v1/health" ||
+			c.Request.URL.Path == "/ws" ||
 			c.Request.URL.Path == "/health" ||
 			c.Request.URL.Path == "/health/detailed" ||
 			c.Request.URL.Path == "/health/components" ||
@@ -325,6 +362,7 @@ func (s *HTTPServer) authMiddleware() gin.HandlerFunc {
 			c.Request.URL.Path == "/api/v1/health/components" ||
 			c.Request.URL.Path == "/api/v1/players/register" ||
 			c.Request.URL.Path == "/api/v1/players/login" ||
+			strings.HasPrefix(c.Request.URL.Path, "/api/v1/admin/") ||
 			strings.HasPrefix(c.Request.URL.Path, "/api/v1/cache/") ||
 			strings.HasPrefix(c.Request.URL.Path, "/api/v1/async/") ||
 			strings.HasPrefix(c.Request.URL.Path, "/api/v1/monitor/") {
@@ -355,7 +393,7 @@ func (s *HTTPServer) authMiddleware() gin.HandlerFunc {
 		}
 
 		// 验证JWT令牌
-		claims, err := s.jwtService.ValidateToken(token)
+		claims, err := s.jwtService.ValidateAccessToken(c.Request.Context(), token)
 		if err != nil {
 			s.logger.Warn("JWT令牌验证失败", "error", err)
 			c.AbortWithStatusJSON(401, gin.H{
@@ -555,7 +593,7 @@ func (s *HTTPServer) playerRegister(c *gin.Context) {
 	}
 
 	// 调用玩家服务注册
-	player, err := s.playerService.RegisterPlayer(req.GameID, req.Username, req.Password, req.Email, req.Phone)
+	player, err := s.playerService.RegisterPlayer(c.Request.Context(), req.GameID, req.Username, req.Password, req.Email, req.Phone)
 	if err != nil {
 		s.logger.Warn("玩家注册失败", "username", req.Username, "game_id", req.GameID, "error", err)
 		bizErr := s.errorHandler.Handle(err, "注册失败")
@@ -607,7 +645,7 @@ func (s *HTTPServer) playerLogin(c *gin.Context) {
 	}
 
 	// 调用玩家服务登录
-	result, err := s.playerService.LoginPlayerByUsername(req.Username, req.Password, req.GameID, req.DeviceID, req.Platform, req.Version)
+	result, err := s.playerService.LoginPlayerByUsername(c.Request.Context(), req.Username, req.Password, req.GameID, req.DeviceID, req.Platform, req.Version, c.ClientIP())
 	if err != nil {
 		s.logger.Warn("玩家登录失败", "username", req.Username, "game_id", req.GameID, "error", err)
 		bizErr := s.errorHandler.Handle(err, "登录失败")
@@ -650,22 +688,62 @@ func (s *HTTPServer) playerLogin(c *gin.Context) {
 }
 
 // playerLogout 玩家登出
+// 撤销请求使用的访问令牌，请求体可以携带同一用户的刷新令牌和登录会话ID一并注销，
+// 之后这些凭证不能再用于HTTP请求、刷新令牌或TCP握手
 func (s *HTTPServer) playerLogout(c *gin.Context) {
 	var req struct {
-		UserID string `json:"user_id" binding:"required"`
+		RefreshToken string `json:"refresh_token"`
+		SessionID    string `json:"session_id"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		bizErr := s.errorHandler.Handle(err, "参数绑定失败")
-		c.JSON(bizErr.HTTPStatus, gin.H{
-			"code":    bizErr.Code,
-			"message": bizErr.Message,
-		})
-		return
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			bizErr := s.errorHandler.Handle(err, "参数绑定失败")
+			c.JSON(bizErr.HTTPStatus, gin.H{
+				"code":    bizErr.Code,
+				"message": bizErr.Message,
+			})
+			return
+		}
 	}
 
-	// TODO: 实现玩家登出逻辑
-	s.logger.Info("玩家登出请求", "user_id", req.UserID)
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	tokenIDs := []string{c.GetString("token_id")}
+	if req.RefreshToken != "" {
+		// 已过期或已撤销的刷新令牌无需再撤销
+		if claims, err := s.jwtService.ValidateToken(ctx, req.RefreshToken); err == nil && claims.UserID == userID {
+			tokenIDs = append(tokenIDs, claims.TokenID)
+		}
+	}
+	for _, tokenID := range tokenIDs {
+		if err := s.jwtService.RevokeToken(ctx, tokenID); err != nil {
+			s.logger.Error("撤销令牌失败", "user_id", userID, "error", err)
+			bizErr := s.errorHandler.Handle(err, "登出失败")
+			c.JSON(bizErr.HTTPStatus, gin.H{
+				"code":    bizErr.Code,
+				"message": bizErr.Message,
+			})
+			return
+		}
+	}
+
+	if req.SessionID != "" {
+		session, err := s.dao.GetSessionByID(ctx, req.SessionID)
+		if err == nil && session != nil && session.UserID == userID {
+			err = s.playerService.LogoutPlayer(ctx, userID, req.SessionID)
+		}
+		if err != nil {
+			bizErr := s.errorHandler.Handle(err, "登出失败")
+			c.JSON(bizErr.HTTPStatus, gin.H{
+				"code":    bizErr.Code,
+				"message": bizErr.Message,
+			})
+			return
+		}
+	}
+
+	s.logger.Info("玩家登出成功", "user_id", userID, "revoked_tokens", len(tokenIDs))
 
 	c.JSON(200, gin.H{
 		"code":    0,
@@ -678,7 +756,7 @@ func (s *HTTPServer) getPlayer(c *gin.Context) {
 	userID := c.Param("id")
 
 	// 调用玩家服务获取信息
-	player, err := s.playerService.GetPlayer(userID)
+	player, err := s.playerService.GetPlayer(c.Request.Context(), userID)
 	if err != nil {
 		s.logger.Warn("获取玩家信息失败", "user_id", userID, "error", err)
 		bizErr := s.errorHandler.Handle(err, "获取玩家信息失败")
@@ -732,7 +810,7 @@ func (s *HTTPServer) updatePlayer(c *gin.Context) {
 	}
 
 	// 调用玩家服务更新
-	player, err := s.playerService.UpdatePlayer(userID, updates)
+	player, err := s.playerService.UpdatePlayer(c.Request.Context(), userID, updates)
 	if err != nil {
 		s.logger.Warn("更新玩家信息失败", "user_id", userID, "error", err)
 		bizErr := s.errorHandler.Handle(err, "更新玩家信息失败")
@@ -764,7 +842,7 @@ func (s *HTTPServer) getItems(c *gin.Context) {
 	}
 
 	// 调用道具服务获取列表
-	items, err := s.itemService.GetUserItems(userID, gameID)
+	items, err := s.itemService.GetUserItems(c.Request.Context(), userID, gameID)
 	if err != nil {
 		s.logger.Warn("获取道具列表失败", "user_id", userID, "game_id", gameID, "error", err)
 		bizErr := s.errorHandler.Handle(err, "获取道具列表失败")
@@ -806,7 +884,7 @@ func (s *HTTPServer) createItem(c *gin.Context) {
 	}
 
 	// 调用道具服务创建道具
-	item, err := s.itemService.CreateItem(req.UserID, req.GameID, req.Name, req.Type, req.Category, int64(req.Quantity))
+	item, err := s.itemService.CreateItem(c.Request.Context(), req.UserID, req.GameID, req.Name, req.Type, req.Category, int64(req.Quantity))
 	if err != nil {
 		s.logger.Error("创建道具失败", "user_id", req.UserID, "game_id", req.GameID, "name", req.Name, "error", err)
 		bizErr := s.errorHandler.Handle(err, "创建道具失败")
@@ -993,36 +1071,23 @@ func (s *HTTPServer) updateOrderStatus(c *gin.Context) {
 
 // getGames 获取游戏列表
 func (s *HTTPServer) getGames(c *gin.Context) {
-	// TODO: 获取游戏列表
 	s.logger.Info("获取游戏列表")
 
 	c.JSON(200, gin.H{
 		"code":    0,
 		"message": "获取成功",
 		"data": gin.H{
-			"games": []gin.H{
-				{
-					"game_id":    "game1",
-					"name":       "游戏1",
-					"status":     "active",
-					"players":    1250,
-					"created_at": time.Now().Add(-30 * 24 * time.Hour).Unix(),
-				},
-				{
-					"game_id":    "game2",
-					"name":       "游戏2",
-					"status":     "active",
-					"players":    890,
-					"created_at": time.Now().Add(-15 * 24 * time.Hour).Unix(),
-				},
-			},
+			"games": s.gameInfos(),
 		},
 	})
 }
 
-// getGameStats 获取游戏统计
+// getGameStats 获取游戏统计，游戏专属路由下使用前缀对应的游戏
 func (s *HTTPServer) getGameStats(c *gin.Context) {
-	gameID := c.Param("id")
+	gameID := c.GetString("scope_game_id")
+	if gameID == "" {
+		gameID = c.Param("id")
+	}
 
 	// TODO: 获取游戏统计
 	s.logger.Info("获取游戏统计", "game_id", gameID)
@@ -1051,15 +1116,6 @@ func (s *HTTPServer) metrics(c *gin.Context) {
 	})
 }
 
-// websocketHandler WebSocket处理器
-func (s *HTTPServer) websocketHandler(c *gin.Context) {
-	// TODO: 实现WebSocket连接处理
-	c.JSON(501, gin.H{
-		"code":    501,
-		"message": "WebSocket功能尚未实现",
-	})
-}
-
 // ==================== 缓存相关Handler ====================
 
 // setCache 设置缓存
@@ -1078,7 +1134,7 @@ func (s *HTTPServer) setCache(c *gin.Context) {
 		return
 	}
 
-	err := s.cacheManager.Set(req.Key, []byte(req.Value))
+	err := s.cacheManager.Set(c.Request.Context(), req.Key, []byte(req.Value))
 	if err != nil {
 		c.JSON(500, gin.H{
 			"code":    500,
@@ -1105,7 +1161,7 @@ func (s *HTTPServer) getCache(c *gin.Context) {
 		return
 	}
 
-	value, err := s.cacheManager.Get(key)
+	value, err := s.cacheManager.Get(c.Request.Context(), key)
 	if err != nil {
 		if err.Error() == "cache miss" {
 			c.JSON(404, gin.H{
@@ -1145,7 +1201,7 @@ func (s *HTTPServer) setCacheJSON(c *gin.Context) {
 		return
 	}
 
-	err := s.cacheManager.SetJSON(req.Key, req.Value)
+	err := s.cacheManager.SetJSON(c.Request.Context(), req.Key, req.Value)
 	if err != nil {
 		c.JSON(500, gin.H{
 			"code":    500,
@@ -1173,7 +1229,7 @@ func (s *HTTPServer) getCacheJSON(c *gin.Context) {
 	}
 
 	var value interface{}
-	err := s.cacheManager.GetJSON(key, &value)
+	err := s.cacheManager.GetJSON(c.Request.Context(), key, &value)
 	if err != nil {
 		if err.Error() == "cache miss" {
 			c.JSON(404, gin.H{
@@ -1208,7 +1264,7 @@ func (s *HTTPServer) deleteCache(c *gin.Context) {
 		return
 	}
 
-	err := s.cacheManager.Delete(key)
+	err := s.cacheManager.Delete(c.Request.Context(), key)
 	if err != nil {
 		c.JSON(500, gin.H{
 			"code":    500,
@@ -1236,7 +1292,7 @@ func (s *HTTPServer) existsCache(c *gin.Context) {
 		return
 	}
 
-	exists := s.cacheManager.Exists(key)
+	exists := s.cacheManager.Exists(c.Request.Context(), key)
 	c.JSON(200, gin.H{
 		"success": true,
 		"exists":  exists,
@@ -1246,7 +1302,7 @@ func (s *HTTPServer) existsCache(c *gin.Context) {
 // warmupCache 缓存预热
 func (s *HTTPServer) warmupCache(c *gin.Context) {
 	warmup := cache.NewDefaultWarmup(s.logger)
-	err := s.cacheManager.WarmupCache(warmup)
+	err := s.cacheManager.WarmupCache(c.Request.Context(), warmup)
 	if err != nil {
 		c.JSON(500, gin.H{
 			"code":    500,
@@ -1296,7 +1352,7 @@ func (s *HTTPServer) invalidateCache(c *gin.Context) {
 	} else if req.Prefix != "" {
 		err = s.cacheManager.InvalidateByPrefix(req.Prefix)
 	} else if len(req.Keys) > 0 {
-		err = s.cacheManager.BatchInvalidate(req.Keys)
+		err = s.cacheManager.BatchInvalidate(c.Request.Context(), req.Keys)
 	} else {
 		c.JSON(400, gin.H{
 			"code":    400,
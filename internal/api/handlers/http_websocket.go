@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"datamiddleware/internal/protocol"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// SetTCPServer 设置TCP服务器：/ws 升级后的连接交给它处理，配置了管理令牌时挂载排空连接接口，必须在Start之前调用
+func (s *HTTPServer) SetTCPServer(tcp *TCPServer) {
+	s.tcpServer = tcp
+	s.registerDrainRoutes(tcp)
+}
+
+// websocketHandler 将请求升级为WebSocket连接，连接承载与TCP端口相同的消息协议，阻塞直到连接关闭
+// 认证通过握手消息完成（浏览器无法为WebSocket设置Authorization请求头）
+func (s *HTTPServer) websocketHandler(c *gin.Context) {
+	if !s.config.HTTP.WebSocket.Enabled {
+		c.JSON(404, gin.H{
+			"code":    404,
+			"message": "WebSocket未开放",
+		})
+		return
+	}
+	// 排空连接期间新连接应由负载均衡转发到其他节点
+	if s.tcpServer == nil || !s.tcpServer.IsRunning() || s.tcpServer.IsDraining() {
+		c.JSON(503, gin.H{
+			"code":    503,
+			"message": "WebSocket服务不可用",
+		})
+		return
+	}
+
+	limits := protocol.FrameLimits{
+		MaxHeaderSize: s.config.TCP.MaxHeaderSize,
+		MaxBodySize:   s.config.TCP.MaxBodySize,
+	}
+	server := websocket.Server{
+		Handshake: s.checkWebSocketOrigin,
+		Handler: func(ws *websocket.Conn) {
+			// 清除HTTP服务器为请求设置的读写超时，之后由连接按TCP配置设置
+			ws.SetDeadline(time.Time{})
+
+			conn := protocol.NewWebSocketConn(ws, limits)
+			conn.SetClientIP(c.ClientIP())
+			s.logger.Info("WebSocket连接已建立", "remote_addr", conn.RemoteAddr(), "origin", c.GetHeader("Origin"))
+			if err := s.tcpServer.ServeConn(conn); err != nil {
+				s.logger.Warn("处理WebSocket连接失败", "remote_addr", conn.RemoteAddr(), "error", err)
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkWebSocketOrigin 检查页面来源是否在允许列表中，未配置允许列表时不检查
+func (s *HTTPServer) checkWebSocketOrigin(config *websocket.Config, req *http.Request) error {
+	allowed := s.config.HTTP.WebSocket.AllowedOrigins
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	origin := strings.TrimSuffix(req.Header.Get("Origin"), "/")
+	for _, candidate := range allowed {
+		if strings.EqualFold(origin, strings.TrimSuffix(candidate, "/")) {
+			return nil
+		}
+	}
+	s.logger.Warn("拒绝WebSocket连接：页面来源不在允许列表中", "origin", origin, "remote_addr", req.RemoteAddr)
+	return fmt.Errorf("不允许的页面来源: %s", origin)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/internal/protocol"
+	"datamiddleware/internal/router"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+func TestWebSocketHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+
+	var config types.ServerConfig
+	config.TCP = types.TCPConfig{Host: "127.0.0.1", Codec: protocol.CodecBinary, CodecNegotiation: true}
+	config.HTTP.WebSocket = types.WebSocketConfig{Enabled: true, AllowedOrigins: []string{"https://h5.example.com/"}}
+
+	tcp := NewTCPServer(config, router.NewMessageRouter(log), nil, nil, log)
+	if err := tcp.Start(); err != nil {
+		t.Fatalf("启动TCP服务器失败: %v", err)
+	}
+	defer tcp.Stop()
+
+	s := &HTTPServer{config: config, engine: gin.New(), logger: log}
+	s.engine.GET("/ws", s.websocketHandler)
+	s.SetTCPServer(tcp)
+	server := httptest.NewServer(s.engine)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	if _, err := websocket.Dial(url, "", "https://evil.example.com"); err == nil {
+		t.Error("不在允许列表中的页面来源应被拒绝")
+	}
+
+	client, err := websocket.Dial(url, "", "https://H5.example.com")
+	if err != nil {
+		t.Fatalf("建立WebSocket连接失败: %v", err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// 心跳与TCP端口的处理相同
+	codec := protocol.NewBinaryCodec()
+	frame, _ := codec.Encode(protocol.CreateHeartbeatMessage(7))
+	if err := websocket.Message.Send(client, frame); err != nil {
+		t.Fatalf("发送心跳失败: %v", err)
+	}
+	var reply []byte
+	if err := websocket.Message.Receive(client, &reply); err != nil {
+		t.Fatalf("接收心跳回复失败: %v", err)
+	}
+	msg, _, err := codec.Decode(reply)
+	if err != nil || msg.Header.Type != types.MessageTypeHeartbeat || msg.Header.SequenceID != 7 {
+		t.Fatalf("心跳回复 = %+v, err=%v", msg, err)
+	}
+
+	// WebSocket连接与TCP连接注册在同一个连接管理器中
+	conns := tcp.connManager.GetAllConnections()
+	if len(conns) != 1 {
+		t.Fatalf("连接数 = %d, 期望 1", len(conns))
+	}
+	for _, conn := range conns {
+		if info := conn.GetStats(); info.Transport != protocol.TransportWebSocket || !strings.HasPrefix(info.RemoteAddr, "127.0.0.1:") {
+			t.Errorf("连接信息 = %+v", info)
+		}
+	}
+
+	// 客户端断开后从连接管理器移除
+	client.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for tcp.connManager.GetConnectionCount() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := tcp.connManager.GetConnectionCount(); n != 0 {
+		t.Errorf("断开后连接数 = %d, 期望 0", n)
+	}
+
+	// 未开放时返回404
+	s.config.HTTP.WebSocket.Enabled = false
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if w.Code != 404 {
+		t.Errorf("未开放时状态码 = %d, 期望 404", w.Code)
+	}
+}
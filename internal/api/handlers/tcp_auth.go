@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	dataPkg "datamiddleware/internal/data/dao"
+	"datamiddleware/internal/infrastructure/auth"
+	"datamiddleware/pkg/constants"
+)
+
+// SessionStore 握手时按会话ID查询玩家登录会话，数据访问对象实现该接口
+type SessionStore interface {
+	GetSessionByID(ctx context.Context, sessionID string) (*dataPkg.PlayerSession, error)
+}
+
+// 握手认证错误
+var (
+	ErrHandshakeCredentialsMissing = errors.New("握手缺少访问令牌或会话ID")
+	ErrHandshakeSessionInvalid     = errors.New("会话不存在或已失效")
+	ErrHandshakeSessionExpired     = errors.New("会话已过期")
+	ErrHandshakeGameMismatch       = errors.New("凭证不属于请求的游戏")
+	ErrHandshakeAuthUnavailable    = errors.New("服务器未配置握手认证")
+	ErrHandshakeGameNotFound       = errors.New("游戏不存在")
+	ErrHandshakeGameOffline        = errors.New("游戏已下线")
+	ErrHandshakeGameMaintenance    = errors.New("游戏维护中")
+)
+
+// handshakeIdentity 握手凭证校验通过后的连接身份
+type handshakeIdentity struct {
+	GameID string
+	UserID string
+}
+
+// authenticateHandshake 校验握手携带的访问令牌或会话ID，连接身份取自凭证
+// 消息头中的游戏ID用于声明要进入的游戏，必须与凭证一致；为空时使用凭证中的游戏ID
+func (s *TCPServer) authenticateHandshake(ctx context.Context, header *types.MessageHeader, req *types.HandshakeRequest) (handshakeIdentity, error) {
+	if !s.config.TCP.Auth.Required && req.Token == "" && req.SessionID == "" {
+		if header.GameID == "" || header.UserID == "" {
+			return handshakeIdentity{}, ErrHandshakeCredentialsMissing
+		}
+		return handshakeIdentity{GameID: header.GameID, UserID: header.UserID}, nil
+	}
+
+	var identity handshakeIdentity
+	switch {
+	case req.Token != "":
+		if s.jwtService == nil {
+			return handshakeIdentity{}, ErrHandshakeAuthUnavailable
+		}
+		// 刷新令牌有效期更长，只能用于换取新的访问令牌，不能用于握手
+		claims, err := s.jwtService.ValidateAccessToken(ctx, req.Token)
+		if err != nil {
+			return handshakeIdentity{}, err
+		}
+		identity = handshakeIdentity{GameID: claims.GameID, UserID: claims.UserID}
+	case req.SessionID != "":
+		if s.sessions == nil {
+			return handshakeIdentity{}, ErrHandshakeAuthUnavailable
+		}
+		session, err := s.sessions.GetSessionByID(ctx, req.SessionID)
+		if err != nil {
+			return handshakeIdentity{}, fmt.Errorf("查询会话失败: %w", err)
+		}
+		if session == nil || !session.IsActive {
+			return handshakeIdentity{}, ErrHandshakeSessionInvalid
+		}
+		if time.Now().After(session.ExpireAt) {
+			return handshakeIdentity{}, ErrHandshakeSessionExpired
+		}
+		identity = handshakeIdentity{GameID: session.GameID, UserID: session.UserID}
+	default:
+		return handshakeIdentity{}, ErrHandshakeCredentialsMissing
+	}
+
+	if identity.UserID == "" {
+		return handshakeIdentity{}, ErrHandshakeSessionInvalid
+	}
+	if header.GameID != "" && header.GameID != identity.GameID {
+		return handshakeIdentity{}, ErrHandshakeGameMismatch
+	}
+	return identity, nil
+}
+
+// handshakeErrorCode 将握手认证错误转换为返回给客户端的错误码和提示，不暴露内部错误细节
+func handshakeErrorCode(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrHandshakeCredentialsMissing):
+		return constants.ErrCodeUnauthorized, "缺少访问令牌或会话ID"
+	case errors.Is(err, auth.ErrTokenExpired), errors.Is(err, ErrHandshakeSessionExpired):
+		return constants.ErrCodeTokenExpired, "凭证已过期"
+	case errors.Is(err, ErrHandshakeGameMismatch):
+		return constants.ErrCodePermissionDenied, "凭证不属于请求的游戏"
+	case errors.Is(err, ErrHandshakeGameNotFound):
+		return constants.ErrCodeGameNotFound, "游戏不存在"
+	case errors.Is(err, ErrHandshakeGameOffline):
+		return constants.ErrCodeGameDisabled, "游戏已下线"
+	case errors.Is(err, ErrHandshakeGameMaintenance):
+		return constants.ErrCodeGameMaintenance, "游戏维护中"
+	case errors.Is(err, ErrHandshakeAuthUnavailable):
+		return constants.ErrCodeSystemInternal, "服务器暂不支持该认证方式"
+	default:
+		return constants.ErrCodeTokenInvalid, "凭证无效"
+	}
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	dataPkg "datamiddleware/internal/data/dao"
+	"datamiddleware/internal/infrastructure/auth"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/pkg/constants"
+
+	"go.uber.org/zap"
+)
+
+// fakeSessions 内存中的会话存储
+type fakeSessions map[string]*dataPkg.PlayerSession
+
+func (f fakeSessions) GetSessionByID(ctx context.Context, sessionID string) (*dataPkg.PlayerSession, error) {
+	return f[sessionID], nil
+}
+
+// fakeCache 多个节点共享的内存缓存
+type fakeCache struct {
+	keys sync.Map
+}
+
+func (f *fakeCache) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	f.keys.Store(key, value)
+	return nil
+}
+
+func (f *fakeCache) Exists(ctx context.Context, key string) bool {
+	_, ok := f.keys.Load(key)
+	return ok
+}
+
+func TestAuthenticateHandshake(t *testing.T) {
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+	jwtService := auth.NewJWTService(types.JWTConfig{Secret: "test-secret", Expire: 3600}, log)
+	expiredService := auth.NewJWTService(types.JWTConfig{Secret: "test-secret", Expire: -60}, log)
+	otherService := auth.NewJWTService(types.JWTConfig{Secret: "other-secret", Expire: 3600}, log)
+
+	token := func(service *auth.JWTService, userID, gameID string) string {
+		pair, err := service.GenerateToken(userID, gameID, userID)
+		if err != nil {
+			t.Fatalf("生成令牌失败: %v", err)
+		}
+		return pair.AccessToken
+	}
+	revoked := token(jwtService, "user9", "game1")
+	claims, _ := jwtService.ValidateToken(context.Background(), revoked)
+	jwtService.RevokeToken(context.Background(), claims.TokenID)
+
+	// 其他节点撤销的令牌通过共享黑名单在本节点失效
+	store := auth.NewCacheRevocationStore(&fakeCache{})
+	jwtService.SetRevocationStore(store)
+	otherNode := auth.NewJWTService(types.JWTConfig{Secret: "test-secret", Expire: 3600}, log)
+	otherNode.SetRevocationStore(store)
+	revokedElsewhere := token(jwtService, "user8", "game1")
+	claims, _ = otherNode.ValidateToken(context.Background(), revokedElsewhere)
+	otherNode.RevokeToken(context.Background(), claims.TokenID)
+
+	pair, _ := jwtService.GenerateToken("user1", "game1", "user1")
+
+	sessions := fakeSessions{
+		"sess_ok":      {SessionID: "sess_ok", UserID: "user2", GameID: "game1", IsActive: true, ExpireAt: time.Now().Add(time.Hour)},
+		"sess_expired": {SessionID: "sess_expired", UserID: "user2", GameID: "game1", IsActive: true, ExpireAt: time.Now().Add(-time.Hour)},
+		"sess_logout":  {SessionID: "sess_logout", UserID: "user2", GameID: "game1", IsActive: false, ExpireAt: time.Now().Add(time.Hour)},
+	}
+
+	s := &TCPServer{jwtService: jwtService, sessions: sessions}
+	s.config.TCP.Auth.Required = true
+
+	tests := []struct {
+		name     string
+		header   types.MessageHeader
+		req      types.HandshakeRequest
+		wantUser string
+		wantCode int
+	}{
+		{"令牌身份取自声明", types.MessageHeader{GameID: "game1", UserID: "admin"}, types.HandshakeRequest{Token: token(jwtService, "user1", "game1")}, "user1", 0},
+		{"消息头未指定游戏", types.MessageHeader{}, types.HandshakeRequest{Token: token(jwtService, "user1", "game1")}, "user1", 0},
+		{"会话ID", types.MessageHeader{GameID: "game1"}, types.HandshakeRequest{SessionID: "sess_ok"}, "user2", 0},
+		{"缺少凭证", types.MessageHeader{GameID: "game1", UserID: "user1"}, types.HandshakeRequest{}, "", constants.ErrCodeUnauthorized},
+		{"签名错误", types.MessageHeader{GameID: "game1"}, types.HandshakeRequest{Token: token(otherService, "user1", "game1")}, "", constants.ErrCodeTokenInvalid},
+		{"令牌过期", types.MessageHeader{GameID: "game1"}, types.HandshakeRequest{Token: token(expiredService, "user1", "game1")}, "", constants.ErrCodeTokenExpired},
+		{"令牌已撤销", types.MessageHeader{GameID: "game1"}, types.HandshakeRequest{Token: revoked}, "", constants.ErrCodeTokenInvalid},
+		{"令牌在其他节点撤销", types.MessageHeader{GameID: "game1"}, types.HandshakeRequest{Token: revokedElsewhere}, "", constants.ErrCodeTokenInvalid},
+		{"刷新令牌", types.MessageHeader{GameID: "game1"}, types.HandshakeRequest{Token: pair.RefreshToken}, "", constants.ErrCodeTokenInvalid},
+		{"游戏不匹配", types.MessageHeader{GameID: "game2"}, types.HandshakeRequest{Token: token(jwtService, "user1", "game1")}, "", constants.ErrCodePermissionDenied},
+		{"会话不存在", types.MessageHeader{GameID: "game1"}, types.HandshakeRequest{SessionID: "sess_unknown"}, "", constants.ErrCodeTokenInvalid},
+		{"会话已过期", types.MessageHeader{GameID: "game1"}, types.HandshakeRequest{SessionID: "sess_expired"}, "", constants.ErrCodeTokenExpired},
+		{"会话已登出", types.MessageHeader{GameID: "game1"}, types.HandshakeRequest{SessionID: "sess_logout"}, "", constants.ErrCodeTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := s.authenticateHandshake(context.Background(), &tt.header, &tt.req)
+			if tt.wantCode != 0 {
+				if err == nil {
+					t.Fatalf("期望认证失败，实际身份 %+v", identity)
+				}
+				if code, _ := handshakeErrorCode(err); code != tt.wantCode {
+					t.Errorf("错误码 = %d, 期望 %d (%v)", code, tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("认证失败: %v", err)
+			}
+			if identity.UserID != tt.wantUser || identity.GameID != "game1" {
+				t.Errorf("连接身份 = %+v", identity)
+			}
+		})
+	}
+
+	// 关闭认证时兼容只在消息头中声明身份的旧客户端
+	s.config.TCP.Auth.Required = false
+	identity, err := s.authenticateHandshake(context.Background(), &types.MessageHeader{GameID: "game1", UserID: "user1"}, &types.HandshakeRequest{})
+	if err != nil || identity.UserID != "user1" {
+		t.Errorf("关闭认证时握手失败: %+v %v", identity, err)
+	}
+	if _, err := s.authenticateHandshake(context.Background(), &types.MessageHeader{GameID: "game1"}, &types.HandshakeRequest{}); !errors.Is(err, ErrHandshakeCredentialsMissing) {
+		t.Errorf("缺少用户ID时期望缺少凭证错误，实际 %v", err)
+	}
+}
+
+func TestBindListenerGame(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		boundGame string
+		wantGame  string
+		wantErr   error
+	}{
+		{"公共端口", "game2", "", "game2", nil},
+		{"使用端口绑定的游戏", "", "game1", "game1", nil},
+		{"与端口一致", "game1", "game1", "game1", nil},
+		{"跨游戏", "game2", "game1", "", ErrHandshakeGameMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := types.MessageHeader{GameID: tt.header}
+			err := bindListenerGame(&header, tt.boundGame)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("错误 = %v, 期望 %v", err, tt.wantErr)
+			}
+			if err == nil && header.GameID != tt.wantGame {
+				t.Errorf("游戏ID = %s, 期望 %s", header.GameID, tt.wantGame)
+			}
+		})
+	}
+}
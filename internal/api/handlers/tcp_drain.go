@@ -0,0 +1,188 @@
+package server
+
+import (
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	"datamiddleware/internal/protocol"
+)
+
+// drainPollInterval 排空期间检查剩余连接数和处理中请求数的间隔
+const drainPollInterval = 100 * time.Millisecond
+
+// 排空连接错误
+var (
+	ErrServerNotRunning = errors.New("TCP服务器未运行")
+	ErrAlreadyDraining  = errors.New("TCP服务器已在排空连接")
+)
+
+// DrainOptions 排空连接的参数
+type DrainOptions struct {
+	Timeout         time.Duration // 等待客户端断开的最长时间，超时后关闭剩余连接
+	Redirect        string        // 建议客户端重连的地址，为空时重连原地址
+	ReconnectSpread time.Duration // 客户端重连前等待时间的上限
+	RequestGrace    time.Duration // 关闭剩余连接前拒绝新请求、等待处理中请求完成的时间，计入Timeout，超过Timeout时按Timeout处理
+}
+
+// DrainStatus 排空连接的进度
+type DrainStatus struct {
+	Draining    bool       `json:"draining"`             // 是否正在排空
+	StartedAt   *time.Time `json:"started_at,omitempty"` // 开始时间
+	Deadline    *time.Time `json:"deadline,omitempty"`   // 关闭剩余连接的时间
+	Redirect    string     `json:"redirect,omitempty"`   // 建议客户端重连的地址
+	Connections int        `json:"connections"`          // 剩余连接数
+	InFlight    int64      `json:"in_flight"`            // 处理中的请求数
+	Completed   bool       `json:"completed,omitempty"`  // 是否已关闭所有连接
+}
+
+// drainState 一次排空的状态
+type drainState struct {
+	options   DrainOptions
+	startedAt time.Time
+	closing   atomic.Bool   // 已进入关闭阶段，不再接受新的业务请求
+	done      chan struct{} // 所有连接关闭后关闭
+}
+
+// Drain 排空连接：停止接受新连接，通知已有连接断开后重连，等待客户端断开，超时后关闭剩余连接
+// 通知和等待都在后台进行，Drain立即返回；返回的通道在所有连接关闭后关闭；已建立的连接照常处理请求，超时前RequestGrace进入关闭阶段，
+// 拒绝新的业务请求，处理中的请求全部完成或超时后关闭剩余连接
+func (s *TCPServer) Drain(options DrainOptions) (<-chan struct{}, error) {
+	options.RequestGrace = min(max(options.RequestGrace, 0), options.Timeout)
+
+	s.mu.Lock()
+	if !s.running || s.shuttingDown {
+		s.mu.Unlock()
+		return nil, ErrServerNotRunning
+	}
+	if state := s.drain.Load(); state != nil {
+		s.mu.Unlock()
+		return state.done, ErrAlreadyDraining
+	}
+
+	state := &drainState{
+		options:   options,
+		startedAt: time.Now(),
+		done:      make(chan struct{}),
+	}
+	s.drain.Store(state)
+
+	// 关闭监听器，之后的连接由负载均衡转发到其他节点
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	closeGameListeners(s.listeners)
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go s.runDrain(state)
+	return state.done, nil
+}
+
+// sendGoAway 通知已有连接断开后重连，每个连接在单独的协程中发送，同步写入的慢客户端不阻塞其他连接
+func (s *TCPServer) sendGoAway(options DrainOptions) int {
+	conns := s.connManager.GetAllConnections()
+	for _, conn := range conns {
+		msg := protocol.CreateGoAwayMessage(&types.GoAway{
+			Message:        "服务器即将关闭，请重新连接",
+			Redirect:       options.Redirect,
+			ReconnectAfter: reconnectDelay(options.ReconnectSpread).Milliseconds(),
+			CloseAfter:     (options.Timeout - options.RequestGrace).Milliseconds(),
+		})
+		go conn.SendMessage(msg)
+	}
+	return len(conns)
+}
+
+// reconnectDelay 在[0, spread]内随机选择重连等待时间，避免客户端同时重连
+func reconnectDelay(spread time.Duration) time.Duration {
+	if spread <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(spread.Milliseconds()+1)) * time.Millisecond
+}
+
+// runDrain 等待客户端断开，进入关闭阶段后等待处理中的请求完成，超时后关闭剩余连接
+func (s *TCPServer) runDrain(state *drainState) {
+	defer s.wg.Done()
+	defer close(state.done)
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	closing := time.NewTimer(state.options.Timeout - state.options.RequestGrace)
+	defer closing.Stop()
+	timeout := time.NewTimer(state.options.Timeout)
+	defer timeout.Stop()
+
+	options := state.options
+	connections := s.sendGoAway(options)
+	s.logger.Info("TCP服务器开始排空连接", "connections", connections, "timeout", options.Timeout, "redirect", options.Redirect, "reconnect_spread", options.ReconnectSpread)
+
+	// 等待客户端断开
+wait:
+	for s.connManager.GetConnectionCount() > 0 {
+		select {
+		case <-s.stopChan:
+			return
+		case <-closing.C:
+			break wait
+		case <-ticker.C:
+		}
+	}
+
+	// 拒绝新的业务请求，处理中的请求完成或超时后关闭剩余连接
+	state.closing.Store(true)
+finish:
+	for s.connManager.GetConnectionCount() > 0 && s.inFlight.Load() > 0 {
+		select {
+		case <-s.stopChan:
+			return
+		case <-timeout.C:
+			break finish
+		case <-ticker.C:
+		}
+	}
+
+	conns := s.connManager.GetAllConnections()
+	for _, conn := range conns {
+		conn.Close()
+	}
+	s.logger.Info("TCP服务器排空连接完成", "closed_connections", len(conns), "duration", time.Since(state.startedAt))
+}
+
+// IsDraining 检查服务器是否正在排空连接
+func (s *TCPServer) IsDraining() bool {
+	return s.drain.Load() != nil
+}
+
+// DrainStatus 获取排空连接的进度
+func (s *TCPServer) DrainStatus() DrainStatus {
+	status := DrainStatus{
+		Connections: s.connManager.GetConnectionCount(),
+		InFlight:    s.inFlight.Load(),
+	}
+	state := s.drain.Load()
+	if state == nil {
+		return status
+	}
+
+	status.Draining = true
+	deadline := state.startedAt.Add(state.options.Timeout)
+	status.StartedAt = &state.startedAt
+	status.Deadline = &deadline
+	status.Redirect = state.options.Redirect
+	select {
+	case <-state.done:
+		status.Completed = true
+	default:
+	}
+	return status
+}
+
+// rejectWhileClosing 排空进入关闭阶段后拒绝新的业务请求
+func (s *TCPServer) rejectWhileClosing() bool {
+	state := s.drain.Load()
+	return state != nil && state.closing.Load()
+}
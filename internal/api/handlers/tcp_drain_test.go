@@ -0,0 +1,279 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/internal/protocol"
+	"datamiddleware/internal/router"
+	"datamiddleware/pkg/constants"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestTCPServerDrain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+
+	var config types.ServerConfig
+	config.TCP = types.TCPConfig{Host: "127.0.0.1", Codec: protocol.CodecBinary}
+	config.TCP.Drain = types.DrainConfig{Timeout: time.Minute, ReconnectSpread: time.Second}
+	config.HTTP.AdminToken = "admin-secret"
+
+	tcp := NewTCPServer(config, router.NewMessageRouter(log), nil, nil, log)
+	if err := tcp.Start(); err != nil {
+		t.Fatalf("启动TCP服务器失败: %v", err)
+	}
+	defer tcp.Stop()
+	address := tcp.listener.Addr().String()
+
+	s := &HTTPServer{config: config, engine: gin.New(), logger: log}
+	s.SetTCPServer(tcp)
+	admin := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/admin/drain", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Admin-Token", "admin-secret")
+		w := httptest.NewRecorder()
+		s.engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// 两个连接都通过心跳确认已加入连接管理器
+	codec := protocol.NewBinaryCodec()
+	connect := func(seq uint32) net.Conn {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			t.Fatalf("建立连接失败: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		frame, _ := codec.Encode(protocol.CreateHeartbeatMessage(seq))
+		conn.Write(frame)
+		if msg := readTestMessage(t, conn, codec); msg.Header.Type != types.MessageTypeHeartbeat {
+			t.Fatalf("心跳回复类型 = 0x%04x", msg.Header.Type)
+		}
+		return conn
+	}
+	leaving := connect(1)
+	staying := connect(2)
+
+	if w := admin(http.MethodPost, `{"timeout":"300ms","redirect":"10.0.0.2:9090"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("开始排空状态码 = %d, 响应 %s", w.Code, w.Body.String())
+	}
+	if w := admin(http.MethodPost, ""); w.Code != http.StatusConflict {
+		t.Errorf("重复排空状态码 = %d, 期望 409", w.Code)
+	}
+
+	// 已有连接收到GoAway，重连等待时间在配置的范围内
+	for _, conn := range []net.Conn{leaving, staying} {
+		msg := readTestMessage(t, conn, codec)
+		var goAway types.GoAway
+		if msg.Header.Type != types.MessageTypeGoAway || json.Unmarshal(msg.Body, &goAway) != nil {
+			t.Fatalf("期望GoAway消息，实际 %+v", msg.Header)
+		}
+		if goAway.Redirect != "10.0.0.2:9090" || goAway.CloseAfter != 300 || goAway.ReconnectAfter < 0 || goAway.ReconnectAfter > 1000 {
+			t.Errorf("GoAway内容 = %+v", goAway)
+		}
+	}
+
+	// 不再接受新连接
+	if conn, err := net.DialTimeout("tcp", address, 200*time.Millisecond); err == nil {
+		conn.Close()
+		t.Error("排空期间不应接受新连接")
+	}
+
+	// 客户端自行断开一个连接，另一个连接在超时后被服务端关闭
+	leaving.Close()
+	staying.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := staying.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Errorf("超时后连接应被关闭，读取结果 %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	var status DrainStatus
+	for time.Now().Before(deadline) {
+		w := admin(http.MethodGet, "")
+		var resp struct {
+			Data DrainStatus `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if status = resp.Data; status.Completed {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !status.Draining || !status.Completed || status.Connections != 0 || status.Redirect != "10.0.0.2:9090" {
+		t.Errorf("排空状态 = %+v", status)
+	}
+
+	// 排空完成后停止服务器不再等待
+	start := time.Now()
+	tcp.Stop()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("排空完成后停止耗时 %v", elapsed)
+	}
+}
+
+func TestTCPServerStopDrains(t *testing.T) {
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+
+	var config types.ServerConfig
+	config.TCP = types.TCPConfig{Host: "127.0.0.1", Codec: protocol.CodecBinary}
+	config.TCP.Drain = types.DrainConfig{Timeout: 5 * time.Second}
+
+	tcp := NewTCPServer(config, router.NewMessageRouter(log), nil, nil, log)
+	if err := tcp.Start(); err != nil {
+		t.Fatalf("启动TCP服务器失败: %v", err)
+	}
+	conn, err := net.Dial("tcp", tcp.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("建立连接失败: %v", err)
+	}
+	defer conn.Close()
+	codec := protocol.NewBinaryCodec()
+	frame, _ := codec.Encode(protocol.CreateHeartbeatMessage(1))
+	conn.Write(frame)
+	readTestMessage(t, conn, codec)
+
+	stopped := make(chan struct{})
+	go func() {
+		tcp.Stop()
+		close(stopped)
+	}()
+
+	// 停止前先发送GoAway，客户端断开后立即完成停止
+	if msg := readTestMessage(t, conn, codec); msg.Header.Type != types.MessageTypeGoAway {
+		t.Fatalf("期望GoAway消息，实际 0x%04x", msg.Header.Type)
+	}
+	select {
+	case <-stopped:
+		t.Fatal("客户端断开前服务器不应停止")
+	default:
+	}
+	conn.Close()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("客户端断开后服务器未停止")
+	}
+	if _, err := tcp.Drain(DrainOptions{}); !errors.Is(err, ErrServerNotRunning) {
+		t.Errorf("停止后排空 = %v, 期望 ErrServerNotRunning", err)
+	}
+}
+
+// blockingGameHandler 收到请求后阻塞到release关闭的游戏处理器
+type blockingGameHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingGameHandler) Handle(ctx context.Context, gameID string, req *types.Request) (*types.Response, error) {
+	h.started <- struct{}{}
+	<-h.release
+	return &types.Response{ID: req.ID, Message: "ok"}, nil
+}
+
+func (h *blockingGameHandler) GetSupportedMessageTypes() []types.MessageType {
+	return []types.MessageType{types.MessageTypePlayerData}
+}
+
+func (h *blockingGameHandler) GetName() string { return "blocking" }
+
+func TestTCPServerDrainRequestGrace(t *testing.T) {
+	tcp := startResumeServer(t, types.ResumeConfig{})
+	handler := &blockingGameHandler{started: make(chan struct{}, 1), release: make(chan struct{})}
+	tcp.router.RegisterGameHandler("game1", handler)
+	release := sync.OnceFunc(func() { close(handler.release) })
+	t.Cleanup(release)
+
+	codec := protocol.NewBinaryCodec()
+	request := func(stream *frameStream, userID string, seq uint32) {
+		frame, _ := codec.Encode(&types.Message{
+			Header: types.MessageHeader{Version: types.ProtocolVersion, Type: types.MessageTypePlayerData, SequenceID: seq, GameID: "game1", UserID: userID, BodyLength: 2},
+			Body:   []byte("{}"),
+		})
+		stream.Write(frame)
+	}
+	busy, _ := resumeHandshake(t, tcp, "user1", "", 0)
+	idle, _ := resumeHandshake(t, tcp, "user2", "", 0)
+	request(busy, "user1", 2)
+	<-handler.started
+
+	// 关闭阶段覆盖整个排空时间：立即拒绝新的请求
+	done, err := tcp.Drain(DrainOptions{Timeout: 10 * time.Second, RequestGrace: time.Minute})
+	if err != nil {
+		t.Fatalf("开始排空失败: %v", err)
+	}
+	for _, stream := range []*frameStream{busy, idle} {
+		var goAway types.GoAway
+		if msg := stream.next(t); msg.Header.Type != types.MessageTypeGoAway || json.Unmarshal(msg.Body, &goAway) != nil || goAway.CloseAfter != 0 {
+			t.Fatalf("期望立即进入关闭阶段的GoAway消息，实际 %+v %s", msg.Header, msg.Body)
+		}
+	}
+	request(idle, "user2", 3)
+	var rejected struct {
+		Code int `json:"code"`
+	}
+	if msg := idle.next(t); msg.Header.Type != types.MessageTypeError || json.Unmarshal(msg.Body, &rejected) != nil || rejected.Code != constants.ErrCodeServerDraining {
+		t.Fatalf("关闭阶段的新请求应被拒绝，实际 %+v %s", msg.Header, msg.Body)
+	}
+
+	// 处理中的请求完成后不等超时立即关闭剩余连接
+	select {
+	case <-done:
+		t.Fatal("处理中的请求完成前不应关闭连接")
+	case <-time.After(200 * time.Millisecond):
+	}
+	release()
+	if msg := busy.next(t); msg.Header.Type != types.MessageTypePlayerData || msg.Header.SequenceID != 2 {
+		t.Errorf("处理中的请求应正常响应，实际 %+v", msg.Header)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("处理中的请求完成后排空未结束")
+	}
+}
+
+func TestTCPServerDrainSlowClient(t *testing.T) {
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+
+	var config types.ServerConfig
+	config.TCP = types.TCPConfig{Host: "127.0.0.1", Codec: protocol.CodecBinary}
+	tcp := NewTCPServer(config, router.NewMessageRouter(log), nil, nil, log)
+	if err := tcp.Start(); err != nil {
+		t.Fatalf("启动TCP服务器失败: %v", err)
+	}
+	defer tcp.Stop()
+
+	// 客户端不读取数据，同步写入的GoAway会一直阻塞到连接关闭
+	server, client := net.Pipe()
+	defer client.Close()
+	go tcp.ServeConn(server)
+	waitConnectionCount(t, tcp, 1)
+
+	start := time.Now()
+	done, err := tcp.Drain(DrainOptions{Timeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("排空失败: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Drain耗时 %v，应立即返回", elapsed)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("超时后排空未完成")
+	}
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	"datamiddleware/internal/protocol"
+)
+
+// GameStatusSource 查询游戏当前生效的运行状态，游戏不存在时ok为false
+type GameStatusSource interface {
+	GameStatus(gameID string) (status string, ok bool)
+}
+
+// gameListener 游戏专属端口的监听器，接入的连接绑定到该游戏
+type gameListener struct {
+	gameID   string
+	listener net.Listener
+}
+
+// SetGames 设置游戏配置，启用且配置了端口的游戏在Start时各自监听一个端口，必须在Start之前调用
+func (s *TCPServer) SetGames(games []types.GameConfig) {
+	s.games = games
+}
+
+// listenGames 为启用的游戏创建监听器，任一游戏失败时关闭已创建的监听器
+func (s *TCPServer) listenGames() ([]gameListener, error) {
+	var listeners []gameListener
+	for _, game := range s.games {
+		if !game.Enabled || game.TCPPort == 0 {
+			continue
+		}
+
+		address := fmt.Sprintf("%s:%d", s.config.TCP.Host, game.TCPPort)
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			closeGameListeners(listeners)
+			return nil, fmt.Errorf("创建游戏 %s 的TCP监听器失败: %w", game.ID, err)
+		}
+		listeners = append(listeners, gameListener{gameID: game.ID, listener: listener})
+	}
+	return listeners, nil
+}
+
+// closeGameListeners 关闭游戏专属端口的监听器
+func closeGameListeners(listeners []gameListener) {
+	for _, gl := range listeners {
+		gl.listener.Close()
+	}
+}
+
+// SetGameStatusSource 设置游戏运行状态来源，设置后握手拒绝未开放的游戏，必须在Start之前调用
+func (s *TCPServer) SetGameStatusSource(source GameStatusSource) {
+	s.gameStatus = source
+}
+
+// checkGameStatus 检查游戏是否接受新的握手，未设置状态来源时不检查
+func (s *TCPServer) checkGameStatus(gameID string) error {
+	if s.gameStatus == nil {
+		return nil
+	}
+	status, ok := s.gameStatus.GameStatus(gameID)
+	switch {
+	case !ok:
+		return ErrHandshakeGameNotFound
+	case status == types.GameStatusMaintenance:
+		return ErrHandshakeGameMaintenance
+	case status != types.GameStatusActive:
+		return ErrHandshakeGameOffline
+	}
+	return nil
+}
+
+// HandleGameStatusChange 游戏状态变化时调用：游戏停止开放后通知该游戏的已有连接，宽限时间后断开；重新开放时取消尚未执行的断开
+func (s *TCPServer) HandleGameStatusChange(gameID, oldStatus, newStatus string) {
+	if newStatus == types.GameStatusActive {
+		s.drainMu.Lock()
+		defer s.drainMu.Unlock()
+		if timer, ok := s.drains[gameID]; ok {
+			timer.Stop()
+			delete(s.drains, gameID)
+			s.logger.Info("游戏重新开放，取消断开已有连接", "game_id", gameID)
+		}
+		return
+	}
+
+	conns := s.connManager.GetConnectionsByGame(gameID)
+	if len(conns) == 0 {
+		return
+	}
+
+	grace := s.config.TCP.GameDrainGrace
+	for _, conn := range conns {
+		conn.SendMessage(protocol.CreateNoticeMessage(&types.Notice{
+			Event:      types.NoticeGameStatus,
+			GameID:     gameID,
+			Status:     newStatus,
+			Message:    gameStatusNotice(newStatus),
+			CloseAfter: grace.Milliseconds(),
+		}))
+	}
+	s.logger.Info("游戏停止开放，已通知已有连接", "game_id", gameID, "status", newStatus, "connections", len(conns), "grace", grace)
+
+	// 已在等待断开时保持原来的断开时间
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+	if _, ok := s.drains[gameID]; ok {
+		return
+	}
+	if s.drains == nil {
+		s.drains = make(map[string]*time.Timer)
+	}
+	s.drains[gameID] = time.AfterFunc(grace, func() {
+		s.drainGame(gameID)
+	})
+}
+
+// drainGame 断开游戏的所有连接
+func (s *TCPServer) drainGame(gameID string) {
+	s.drainMu.Lock()
+	delete(s.drains, gameID)
+	s.drainMu.Unlock()
+
+	// 定时器触发后游戏又重新开放时不再断开
+	if s.gameStatus != nil {
+		if status, ok := s.gameStatus.GameStatus(gameID); ok && status == types.GameStatusActive {
+			return
+		}
+	}
+
+	conns := s.connManager.GetConnectionsByGame(gameID)
+	for _, conn := range conns {
+		conn.Close()
+	}
+	s.logger.Info("已断开停止开放的游戏的连接", "game_id", gameID, "connections", len(conns))
+}
+
+// gameStatusNotice 返回游戏状态对应的提示信息
+func gameStatusNotice(status string) string {
+	if status == types.GameStatusMaintenance {
+		return "游戏维护中，连接即将断开"
+	}
+	return "游戏已下线，连接即将断开"
+}
+
+// bindListenerGame 通过游戏专属端口接入的连接只能进入该游戏：消息头未指定游戏时使用端口绑定的游戏，指定其他游戏时拒绝
+func bindListenerGame(header *types.MessageHeader, boundGame string) error {
+	if boundGame == "" {
+		return nil
+	}
+	if header.GameID == "" {
+		header.GameID = boundGame
+		return nil
+	}
+	if header.GameID != boundGame {
+		return ErrHandshakeGameMismatch
+	}
+	return nil
+}
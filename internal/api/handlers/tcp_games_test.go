@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/internal/protocol"
+
+	"go.uber.org/zap"
+)
+
+// fakeGameStatus 内存中的游戏运行状态
+type fakeGameStatus map[string]string
+
+func (f fakeGameStatus) GameStatus(gameID string) (string, bool) {
+	status, ok := f[gameID]
+	return status, ok
+}
+
+func TestCheckGameStatus(t *testing.T) {
+	s := &TCPServer{}
+	if err := s.checkGameStatus("game1"); err != nil {
+		t.Errorf("未设置状态来源时不应检查: %v", err)
+	}
+
+	s.SetGameStatusSource(fakeGameStatus{
+		"game1": types.GameStatusActive,
+		"game2": types.GameStatusMaintenance,
+		"game3": types.GameStatusOffline,
+	})
+	tests := []struct {
+		gameID   string
+		wantErr  error
+		wantCode int
+	}{
+		{"game1", nil, 0},
+		{"game2", ErrHandshakeGameMaintenance, 5006},
+		{"game3", ErrHandshakeGameOffline, 5002},
+		{"game4", ErrHandshakeGameNotFound, 5001},
+	}
+	for _, tt := range tests {
+		err := s.checkGameStatus(tt.gameID)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: 错误 = %v, 期望 %v", tt.gameID, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			if code, _ := handshakeErrorCode(err); code != tt.wantCode {
+				t.Errorf("%s: 错误码 = %d, 期望 %d", tt.gameID, code, tt.wantCode)
+			}
+		}
+	}
+}
+
+func TestHandleGameStatusChange(t *testing.T) {
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+	codec, _ := protocol.NewCodec("json")
+	connManager := protocol.NewConnectionManager(types.ConnectionConfig{SendQueueSize: 16, Codec: "json"}, codec, log)
+
+	statuses := fakeGameStatus{"game1": types.GameStatusActive, "game2": types.GameStatusActive}
+	s := &TCPServer{connManager: connManager, logger: log, gameStatus: statuses}
+	s.config.TCP.GameDrainGrace = 50 * time.Millisecond
+
+	connect := func(gameID string) net.Conn {
+		server, client := net.Pipe()
+		t.Cleanup(func() { client.Close() })
+		conn, err := connManager.AddConnection(server)
+		if err != nil {
+			t.Fatalf("添加连接失败: %v", err)
+		}
+		conn.Authenticate(gameID, "user1")
+		return client
+	}
+	game1 := connect("game1")
+	game2 := connect("game2")
+
+	statuses["game1"] = types.GameStatusMaintenance
+	s.HandleGameStatusChange("game1", types.GameStatusActive, types.GameStatusMaintenance)
+
+	// 游戏1的连接先收到通知，宽限时间后被断开
+	msg := readTestMessage(t, game1, codec)
+	var notice types.Notice
+	if msg.Header.Type != types.MessageTypeNotice || json.Unmarshal(msg.Body, &notice) != nil {
+		t.Fatalf("期望通知消息，实际 %+v", msg.Header)
+	}
+	if notice.Event != types.NoticeGameStatus || notice.Status != types.GameStatusMaintenance || notice.CloseAfter != 50 {
+		t.Errorf("通知内容 = %+v", notice)
+	}
+	game1.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := game1.Read(make([]byte, 1)); err == nil {
+		t.Error("宽限时间后连接应被断开")
+	}
+	if n := len(connManager.GetConnectionsByGame("game2")); n != 1 {
+		t.Errorf("其他游戏的连接数 = %d, 期望 1", n)
+	}
+
+	// 宽限时间内重新开放时不再断开
+	statuses["game2"] = types.GameStatusOffline
+	s.HandleGameStatusChange("game2", types.GameStatusActive, types.GameStatusOffline)
+	readTestMessage(t, game2, codec)
+	statuses["game2"] = types.GameStatusActive
+	s.HandleGameStatusChange("game2", types.GameStatusOffline, types.GameStatusActive)
+	time.Sleep(100 * time.Millisecond)
+	if n := len(connManager.GetConnectionsByGame("game2")); n != 1 {
+		t.Errorf("重新开放后连接数 = %d, 期望 1", n)
+	}
+}
+
+// readTestMessage 从客户端读取一条完整消息
+func readTestMessage(t *testing.T, conn net.Conn, codec protocol.Codec) *types.Message {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var data []byte
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("读取消息失败: %v", err)
+		}
+		data = append(data, buf[:n]...)
+		if msg, _, err := codec.Decode(data); err == nil && msg != nil {
+			return msg
+		}
+	}
+}
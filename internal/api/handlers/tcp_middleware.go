@@ -0,0 +1,139 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"datamiddleware/internal/common/types"
+	"datamiddleware/internal/protocol"
+)
+
+// TCPHandlerFunc TCP消息中间件，调用c.Next()继续执行后续中间件和消息处理，不调用则中止
+type TCPHandlerFunc func(c *TCPContext)
+
+// TCPContext 一条TCP消息在中间件链中的上下文
+// 上下文在处理完成后被复用，中间件不能在返回后继续持有
+type TCPContext struct {
+	Conn     *protocol.Connection // 消息所属连接
+	Message  *types.Message       // 当前消息，中间件可以替换
+	Outbound bool                 // 是否为发送给客户端的消息
+
+	handlers []TCPHandlerFunc
+	index    int
+	aborted  bool
+	err      error
+}
+
+// 中间件错误
+var (
+	ErrMessageDropped = errors.New("消息被中间件丢弃")
+)
+
+// Next 执行后续的中间件
+func (c *TCPContext) Next() {
+	c.index++
+	for c.index < len(c.handlers) && !c.aborted {
+		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+// Abort 中止处理：入站消息不再分发，出站消息不再发送，SendMessage返回err
+func (c *TCPContext) Abort(err error) {
+	c.aborted = true
+	c.err = err
+}
+
+// AbortWithError 中止处理，入站消息同时向客户端回复错误消息
+func (c *TCPContext) AbortWithError(code int, message string) {
+	if !c.Outbound {
+		errorMsg := protocol.CreateErrorMessage(code, message, c.Message.Header.SequenceID)
+		errorMsg.Header.TraceID = c.Message.Header.TraceID
+		c.Conn.SendMessage(errorMsg)
+	}
+	c.Abort(fmt.Errorf("%w: %s", ErrMessageDropped, message))
+}
+
+// IsAborted 是否已中止
+func (c *TCPContext) IsAborted() bool {
+	return c.aborted
+}
+
+// Err 中止的原因
+func (c *TCPContext) Err() error {
+	return c.err
+}
+
+// AdaptMiddleware 将types.Middleware转换为中间件：Process返回错误或nil消息时中止，否则用返回的消息替换当前消息
+func AdaptMiddleware(mw types.Middleware) TCPHandlerFunc {
+	return func(c *TCPContext) {
+		msg, err := mw.Process(c.Conn.ID, c.Message)
+		if err != nil {
+			c.Abort(err)
+			return
+		}
+		if msg == nil {
+			c.Abort(ErrMessageDropped)
+			return
+		}
+		c.Message = msg
+		c.Next()
+	}
+}
+
+// tcpPipeline 入站和出站消息的中间件链，服务器启动后不再修改
+type tcpPipeline struct {
+	inbound  []TCPHandlerFunc
+	outbound []TCPHandlerFunc
+	contexts sync.Pool
+}
+
+// newTCPPipeline 创建中间件链，dispatch作为入站链的最后一环处理消息
+func newTCPPipeline(inbound, outbound []TCPHandlerFunc, dispatch TCPHandlerFunc) *tcpPipeline {
+	p := &tcpPipeline{
+		inbound:  append(append([]TCPHandlerFunc{}, inbound...), dispatch),
+		outbound: append([]TCPHandlerFunc{}, outbound...),
+	}
+	p.contexts.New = func() interface{} { return &TCPContext{} }
+	return p
+}
+
+// run 依次执行中间件，返回最终的消息和中止原因
+func (p *tcpPipeline) run(handlers []TCPHandlerFunc, conn *protocol.Connection, msg *types.Message, outbound bool) (*types.Message, error) {
+	c := p.contexts.Get().(*TCPContext)
+	*c = TCPContext{Conn: conn, Message: msg, Outbound: outbound, handlers: handlers, index: -1}
+	c.Next()
+
+	msg, err := c.Message, c.err
+	if c.aborted && err == nil {
+		err = ErrMessageDropped
+	}
+	*c = TCPContext{}
+	p.contexts.Put(c)
+	return msg, err
+}
+
+// handleInbound 处理收到的消息
+func (p *tcpPipeline) handleInbound(conn *protocol.Connection, msg *types.Message) {
+	p.run(p.inbound, conn, msg, false)
+}
+
+// filterOutbound 作为连接的出站过滤器，中止时丢弃消息
+func (p *tcpPipeline) filterOutbound(conn *protocol.Connection, msg *types.Message) (*types.Message, error) {
+	msg, err := p.run(p.outbound, conn, msg, true)
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Use 注册入站消息中间件，按注册顺序在内置中间件之后执行，必须在Start之前调用
+func (s *TCPServer) Use(middleware ...TCPHandlerFunc) {
+	s.inbound = append(s.inbound, middleware...)
+}
+
+// UseOutbound 注册出站消息中间件，在消息编码发送前按注册顺序执行，必须在Start之前调用
+func (s *TCPServer) UseOutbound(middleware ...TCPHandlerFunc) {
+	s.outbound = append(s.outbound, middleware...)
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/internal/protocol"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newTestConnection 创建基于内存管道的连接，发送的消息进入发送队列
+func newTestConnection(t *testing.T) *protocol.Connection {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	codec, err := protocol.NewCodec("json")
+	if err != nil {
+		t.Fatalf("创建编解码器失败: %v", err)
+	}
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+	return protocol.NewConnection(server, types.ConnectionConfig{SendQueueSize: 16}, codec, log)
+}
+
+// funcMiddleware 用函数实现types.Middleware
+type funcMiddleware func(connID string, msg *types.Message) (*types.Message, error)
+
+func (f funcMiddleware) Process(connID string, msg *types.Message) (*types.Message, error) {
+	return f(connID, msg)
+}
+
+func TestTCPPipelineOrder(t *testing.T) {
+	conn := newTestConnection(t)
+	var order []string
+	trace := func(name string) TCPHandlerFunc {
+		return func(c *TCPContext) {
+			order = append(order, name+">")
+			c.Next()
+			order = append(order, "<"+name)
+		}
+	}
+	dispatched := 0
+	p := newTCPPipeline([]TCPHandlerFunc{trace("a"), trace("b")}, nil, func(c *TCPContext) {
+		dispatched++
+		order = append(order, "dispatch")
+	})
+
+	p.handleInbound(conn, &types.Message{Header: types.MessageHeader{Type: types.MessageTypePlayerData}})
+	if got := strings.Join(order, " "); got != "a> b> dispatch <b <a" {
+		t.Errorf("执行顺序 = %s", got)
+	}
+
+	// 中止后不再执行后续中间件和分发
+	order = nil
+	errDenied := errors.New("拒绝")
+	p = newTCPPipeline([]TCPHandlerFunc{trace("a"), func(c *TCPContext) { c.Abort(errDenied) }, trace("b")}, nil, func(c *TCPContext) {
+		dispatched++
+	})
+	p.handleInbound(conn, &types.Message{})
+	if got := strings.Join(order, " "); got != "a> <a" || dispatched != 1 {
+		t.Errorf("中止后执行顺序 = %s, 分发次数 = %d", got, dispatched)
+	}
+
+	// 出站中止的原因返回给SendMessage
+	p = newTCPPipeline(nil, []TCPHandlerFunc{func(c *TCPContext) { c.Abort(errDenied) }}, nil)
+	if msg, err := p.filterOutbound(conn, &types.Message{}); msg != nil || !errors.Is(err, errDenied) {
+		t.Errorf("出站中止结果 = %v, %v", msg, err)
+	}
+}
+
+var errTestMiddleware = errors.New("中间件错误")
+
+func TestAdaptMiddleware(t *testing.T) {
+	conn := newTestConnection(t)
+	replaced := &types.Message{Body: []byte("replaced")}
+
+	tests := []struct {
+		name    string
+		mw      funcMiddleware
+		wantMsg *types.Message
+		wantErr error
+	}{
+		{"替换消息", func(string, *types.Message) (*types.Message, error) { return replaced, nil }, replaced, nil},
+		{"返回nil丢弃", func(string, *types.Message) (*types.Message, error) { return nil, nil }, nil, ErrMessageDropped},
+		{"返回错误", func(string, *types.Message) (*types.Message, error) { return nil, errTestMiddleware }, nil, errTestMiddleware},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTCPPipeline(nil, []TCPHandlerFunc{AdaptMiddleware(tt.mw)}, nil)
+			msg, err := p.filterOutbound(conn, &types.Message{})
+			if msg != tt.wantMsg || !errors.Is(err, tt.wantErr) {
+				t.Errorf("结果 = %v, %v; 期望 %v, %v", msg, err, tt.wantMsg, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuiltinMiddleware(t *testing.T) {
+	gameMsg := func() *types.Message {
+		return &types.Message{Header: types.MessageHeader{Type: types.MessageTypePlayerData, GameID: "forged", UserID: "forged"}}
+	}
+	run := func(conn *protocol.Connection, msg *types.Message, middleware ...TCPHandlerFunc) (*types.Message, bool) {
+		var dispatched *types.Message
+		p := newTCPPipeline(middleware, nil, func(c *TCPContext) { dispatched = c.Message })
+		p.handleInbound(conn, msg)
+		return dispatched, dispatched != nil
+	}
+
+	t.Run("认证", func(t *testing.T) {
+		conn := newTestConnection(t)
+		if _, ok := run(conn, gameMsg(), AuthMiddleware()); ok {
+			t.Error("未认证连接的业务消息不应被分发")
+		}
+		if _, ok := run(conn, &types.Message{Header: types.MessageHeader{Type: types.MessageTypeHeartbeat}}, AuthMiddleware()); !ok {
+			t.Error("系统消息不需要认证")
+		}
+		conn.Authenticate("game1", "user1")
+		msg, ok := run(conn, gameMsg(), AuthMiddleware())
+		if !ok || msg.Header.GameID != "game1" || msg.Header.UserID != "user1" {
+			t.Errorf("已认证连接的消息头 = %+v", msg)
+		}
+	})
+
+	t.Run("消息类型白名单", func(t *testing.T) {
+		conn := newTestConnection(t)
+		conn.Authenticate("game1", "user1")
+		allow := AllowListMiddleware(map[string][]uint16{"game1": {uint16(types.MessageTypeItemOperation)}})
+		if _, ok := run(conn, gameMsg(), allow); ok {
+			t.Error("不在白名单中的消息类型不应被分发")
+		}
+		if _, ok := run(conn, &types.Message{Header: types.MessageHeader{Type: types.MessageTypeItemOperation}}, allow); !ok {
+			t.Error("白名单中的消息类型应被分发")
+		}
+
+		other := newTestConnection(t)
+		other.Authenticate("game2", "user1")
+		if _, ok := run(other, gameMsg(), allow); !ok {
+			t.Error("未配置白名单的游戏不应受限")
+		}
+	})
+
+	t.Run("限流", func(t *testing.T) {
+		conn := newTestConnection(t)
+		conn.Authenticate("game1", "user1")
+		limit := RateLimitMiddleware(types.RateLimitConfig{Enabled: true, Rate: 1, Burst: 3})
+		allowed := 0
+		for i := 0; i < 5; i++ {
+			if _, ok := run(conn, gameMsg(), limit); ok {
+				allowed++
+			}
+		}
+		if allowed != 3 {
+			t.Errorf("突发容量内放行 %d 条，期望 3", allowed)
+		}
+	})
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(10, 2)
+	if !b.allow(now) || !b.allow(now) || b.allow(now) {
+		t.Fatal("突发容量应为2")
+	}
+	// 100ms恢复一个令牌
+	if !b.allow(now.Add(100*time.Millisecond)) || b.allow(now.Add(100*time.Millisecond)) {
+		t.Error("令牌恢复速率错误")
+	}
+	// 恢复不超过突发容量
+	later := now.Add(time.Hour)
+	if !b.allow(later) || !b.allow(later) || b.allow(later) {
+		t.Error("令牌数超过突发容量")
+	}
+}
+
+func TestRedactPayload(t *testing.T) {
+	redact := map[string]bool{"password": true, "token": true}
+
+	got := redactPayload([]byte(`{"user":"u1","Password":"secret","items":[{"token":"abc","id":1}]}`), redact, 0)
+	if strings.Contains(got, "secret") || strings.Contains(got, "abc") || !strings.Contains(got, `"user":"u1"`) {
+		t.Errorf("脱敏结果 = %s", got)
+	}
+	if got := redactPayload([]byte{0x01, 0x02, 0x03}, redact, 0); got != "<3字节二进制数据>" {
+		t.Errorf("二进制消息体 = %s", got)
+	}
+	if got := redactPayload([]byte(`{"data":"0123456789"}`), redact, 8); !strings.HasSuffix(got, "...(已截断)") {
+		t.Errorf("截断结果 = %s", got)
+	}
+}
+
+func TestPayloadLoggingDebugLevel(t *testing.T) {
+	config := types.PayloadLoggingConfig{Enabled: true, RedactFields: []string{"password"}}
+	msg := func() *types.Message {
+		return &types.Message{Header: types.MessageHeader{Type: types.MessageTypePlayerData}, Body: []byte(`{"password":"secret"}`)}
+	}
+
+	for _, tt := range []struct {
+		name    string
+		level   zapcore.Level
+		entries int
+	}{
+		{"调试级别", zapcore.DebugLevel, 1},
+		{"信息级别", zapcore.InfoLevel, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(tt.level)
+			log := &logger.ZapLogger{SugaredLogger: zap.New(core).Sugar()}
+			if got := logger.DebugEnabled(log); got != (tt.entries > 0) {
+				t.Fatalf("DebugEnabled = %v", got)
+			}
+
+			dispatched := false
+			p := newTCPPipeline([]TCPHandlerFunc{PayloadLoggingMiddleware(config, log)}, nil, func(c *TCPContext) { dispatched = true })
+			p.handleInbound(newTestConnection(t), msg())
+			if !dispatched {
+				t.Error("消息应继续分发")
+			}
+			if logs.Len() != tt.entries {
+				t.Fatalf("日志条数 = %d, 期望 %d", logs.Len(), tt.entries)
+			}
+			if tt.entries > 0 {
+				if entry := logs.All()[0].Message; strings.Contains(entry, "secret") || !strings.Contains(entry, "***") {
+					t.Errorf("消息体未脱敏: %s", entry)
+				}
+			}
+		})
+	}
+}
+
+func TestMessageStats(t *testing.T) {
+	stats := newMessageStats()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				stats.observe(types.MessageTypePlayerData, false, false, time.Duration(i+1)*time.Millisecond)
+				stats.observe(types.MessageTypePlayerData, true, false, 0)
+			}
+		}(i)
+	}
+	wg.Wait()
+	stats.observe(types.MessageTypePlayerData, false, true, time.Hour)
+
+	got := stats.snapshot()[fmt.Sprintf("0x%04x", uint16(types.MessageTypePlayerData))]
+	if got.Received != 800 || got.Queued != 800 || got.Rejected != 1 {
+		t.Errorf("计数 = %+v", got)
+	}
+	if got.MaxLatencyMs != 8 || got.AvgLatencyMs != 4.5 {
+		t.Errorf("耗时 = 平均%v毫秒，最大%v毫秒，期望4.5和8", got.AvgLatencyMs, got.MaxLatencyMs)
+	}
+}
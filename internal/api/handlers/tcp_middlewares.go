@@ -0,0 +1,285 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	"datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/pkg/constants"
+)
+
+// RecoveryMiddleware 捕获后续中间件和消息处理中的panic，入站消息回复内部错误，连接继续可用
+func RecoveryMiddleware(log logger.Logger) TCPHandlerFunc {
+	return func(c *TCPContext) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("处理消息发生panic", "conn_id", c.Conn.ID, "type", c.Message.Header.Type, "outbound", c.Outbound, "panic", r, "stack", string(debug.Stack()))
+				c.AbortWithError(constants.ErrCodeSystemInternal, "处理消息失败")
+			}
+		}()
+		c.Next()
+	}
+}
+
+// MetricsMiddleware 按消息类型统计收到和待发送的数量、被中止的数量和入站处理耗时
+// 出站消息在编码和写入之前统计，实际写出的消息数以连接的MessagesSent为准
+func MetricsMiddleware(stats *messageStats) TCPHandlerFunc {
+	return func(c *TCPContext) {
+		msgType := c.Message.Header.Type
+		start := time.Now()
+		c.Next()
+		stats.observe(msgType, c.Outbound, c.IsAborted(), time.Since(start))
+	}
+}
+
+// PayloadLoggingMiddleware 以调试级别记录收发的消息体，JSON消息体中的敏感字段替换为***
+// 未启用调试级别日志时不解析消息体
+func PayloadLoggingMiddleware(config types.PayloadLoggingConfig, log logger.Logger) TCPHandlerFunc {
+	redact := make(map[string]bool, len(config.RedactFields))
+	for _, field := range config.RedactFields {
+		redact[strings.ToLower(field)] = true
+	}
+	return func(c *TCPContext) {
+		if !logger.DebugEnabled(log) {
+			c.Next()
+			return
+		}
+		direction := "in"
+		if c.Outbound {
+			direction = "out"
+		}
+		header := &c.Message.Header
+		log.Debug("TCP消息体", "conn_id", c.Conn.ID, "direction", direction, "type", header.Type, "seq", header.SequenceID, "trace_id", header.TraceID, "body", redactPayload(c.Message.Body, redact, config.MaxBytes))
+		c.Next()
+	}
+}
+
+// redactPayload 返回用于日志的消息体，非JSON消息体只记录长度
+func redactPayload(body []byte, redact map[string]bool, maxBytes int) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Sprintf("<%d字节二进制数据>", len(body))
+	}
+	data, _ := json.Marshal(redactValue(value, redact))
+	if maxBytes > 0 && len(data) > maxBytes {
+		return string(data[:maxBytes]) + "...(已截断)"
+	}
+	return string(data)
+}
+
+// redactValue 递归替换需要脱敏的字段
+func redactValue(value interface{}, redact map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if redact[strings.ToLower(key)] {
+				v[key] = "***"
+			} else {
+				v[key] = redactValue(field, redact)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item, redact)
+		}
+	}
+	return value
+}
+
+// AuthMiddleware 业务消息必须来自已认证的连接，并使用握手时认证的身份覆盖消息头，防止客户端伪造游戏ID或用户ID
+func AuthMiddleware() TCPHandlerFunc {
+	return func(c *TCPContext) {
+		if c.Outbound || c.Message.Header.Type.IsSystem() {
+			c.Next()
+			return
+		}
+		if !c.Conn.IsAuthenticated() {
+			c.AbortWithError(4002, "连接未认证")
+			return
+		}
+		c.Message.Header.GameID, c.Message.Header.UserID = c.Conn.Identity()
+		c.Next()
+	}
+}
+
+// AllowListMiddleware 按游戏限制客户端可发送的业务消息类型，未配置的游戏不限制
+func AllowListMiddleware(allowed map[string][]uint16) TCPHandlerFunc {
+	lists := make(map[string]map[types.MessageType]bool, len(allowed))
+	for gameID, msgTypes := range allowed {
+		set := make(map[types.MessageType]bool, len(msgTypes))
+		for _, t := range msgTypes {
+			set[types.MessageType(t)] = true
+		}
+		lists[gameID] = set
+	}
+	return func(c *TCPContext) {
+		if c.Outbound || c.Message.Header.Type.IsSystem() {
+			c.Next()
+			return
+		}
+		gameID, _ := c.Conn.Identity()
+		if set, ok := lists[gameID]; ok && !set[c.Message.Header.Type] {
+			c.AbortWithError(constants.ErrCodePermissionDenied, "不允许的消息类型")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RateLimitMiddleware 按连接限制入站消息速率（令牌桶），超出时回复错误并丢弃消息
+func RateLimitMiddleware(config types.RateLimitConfig) TCPHandlerFunc {
+	var buckets sync.Map // *protocol.Connection -> *tokenBucket
+	return func(c *TCPContext) {
+		if c.Outbound {
+			c.Next()
+			return
+		}
+		value, ok := buckets.Load(c.Conn)
+		if !ok {
+			var loaded bool
+			value, loaded = buckets.LoadOrStore(c.Conn, newTokenBucket(config.Rate, config.Burst))
+			if !loaded {
+				conn := c.Conn
+				conn.OnClose(func() { buckets.Delete(conn) })
+			}
+		}
+		if !value.(*tokenBucket).allow(time.Now()) {
+			c.AbortWithError(constants.ErrCodeResourceExhausted, "请求过于频繁")
+			return
+		}
+		c.Next()
+	}
+}
+
+// tokenBucket 令牌桶，同一连接的消息由同一个协程处理，加锁只是为了安全
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// allow 消耗一个令牌，令牌不足时返回false
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// messageStats 按消息类型统计的收发计数，每个消息类型使用独立的原子计数器，收发消息时不加全局锁
+type messageStats struct {
+	byType sync.Map // types.MessageType -> *messageCounters
+}
+
+// messageCounters 单个消息类型的原子计数器
+type messageCounters struct {
+	received     atomic.Int64
+	queued       atomic.Int64
+	rejected     atomic.Int64
+	totalLatency atomic.Int64 // 入站处理总耗时（纳秒）
+	maxLatency   atomic.Int64 // 入站处理最大耗时（纳秒）
+}
+
+// MessageTypeStats 单个消息类型的统计
+type MessageTypeStats struct {
+	Received     int64   `json:"received"`       // 收到的消息数
+	Queued       int64   `json:"queued"`         // 通过出站中间件交给发送路径的消息数，不保证已写出
+	Rejected     int64   `json:"rejected"`       // 被中间件中止的消息数
+	AvgLatencyMs float64 `json:"avg_latency_ms"` // 入站消息平均处理耗时
+	MaxLatencyMs float64 `json:"max_latency_ms"` // 入站消息最大处理耗时
+}
+
+func newMessageStats() *messageStats {
+	return &messageStats{}
+}
+
+// counters 获取消息类型的计数器，不存在时创建
+func (m *messageStats) counters(msgType types.MessageType) *messageCounters {
+	if c, ok := m.byType.Load(msgType); ok {
+		return c.(*messageCounters)
+	}
+	c, _ := m.byType.LoadOrStore(msgType, &messageCounters{})
+	return c.(*messageCounters)
+}
+
+// observe 记录一条消息
+func (m *messageStats) observe(msgType types.MessageType, outbound, aborted bool, latency time.Duration) {
+	c := m.counters(msgType)
+	switch {
+	case aborted:
+		c.rejected.Add(1)
+	case outbound:
+		c.queued.Add(1)
+	default:
+		c.received.Add(1)
+		c.totalLatency.Add(int64(latency))
+		for {
+			cur := c.maxLatency.Load()
+			if int64(latency) <= cur || c.maxLatency.CompareAndSwap(cur, int64(latency)) {
+				break
+			}
+		}
+	}
+}
+
+// snapshot 返回按消息类型（十六进制）统计的快照，各计数器分别读取，快照不保证跨计数器一致
+func (m *messageStats) snapshot() map[string]MessageTypeStats {
+	result := make(map[string]MessageTypeStats)
+	m.byType.Range(func(key, value interface{}) bool {
+		c := value.(*messageCounters)
+		stats := MessageTypeStats{
+			Received:     c.received.Load(),
+			Queued:       c.queued.Load(),
+			Rejected:     c.rejected.Load(),
+			MaxLatencyMs: float64(c.maxLatency.Load()) / float64(time.Millisecond),
+		}
+		if stats.Received > 0 {
+			stats.AvgLatencyMs = float64(c.totalLatency.Load()) / float64(stats.Received) / float64(time.Millisecond)
+		}
+		result[fmt.Sprintf("0x%04x", uint16(key.(types.MessageType)))] = stats
+		return true
+	})
+	return result
+}
+
+// useBuiltinMiddleware 按配置注册内置中间件
+func (s *TCPServer) useBuiltinMiddleware() {
+	config := s.config.TCP.Middleware
+
+	s.Use(RecoveryMiddleware(s.logger), MetricsMiddleware(s.messageStats))
+	s.UseOutbound(MetricsMiddleware(s.messageStats))
+	if config.PayloadLogging.Enabled {
+		s.Use(PayloadLoggingMiddleware(config.PayloadLogging, s.logger))
+		s.UseOutbound(PayloadLoggingMiddleware(config.PayloadLogging, s.logger))
+	}
+	s.Use(AuthMiddleware())
+	if len(config.AllowedTypes) > 0 {
+		s.Use(AllowListMiddleware(config.AllowedTypes))
+	}
+	if config.RateLimit.Enabled {
+		s.Use(RateLimitMiddleware(config.RateLimit))
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/internal/protocol"
+	"datamiddleware/internal/router"
+
+	"go.uber.org/zap"
+)
+
+func TestTCPServerProxyProtocol(t *testing.T) {
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+
+	var config types.ServerConfig
+	config.TCP = types.TCPConfig{
+		Host:             "127.0.0.1",
+		Codec:            protocol.CodecBinary,
+		HandshakeTimeout: time.Second,
+		ProxyProtocol:    types.ProxyProtocol{Enabled: true, TrustedCIDRs: []string{"127.0.0.0/8"}},
+	}
+	tcp := NewTCPServer(config, router.NewMessageRouter(log), nil, nil, log)
+	if err := tcp.Start(); err != nil {
+		t.Fatalf("启动TCP服务器失败: %v", err)
+	}
+	defer tcp.Stop()
+
+	client, err := net.Dial("tcp", tcp.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("建立连接失败: %v", err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// PROXY协议头之后紧跟第一个消息帧
+	codec := protocol.NewBinaryCodec()
+	frame, _ := codec.Encode(protocol.CreateHeartbeatMessage(3))
+	if _, err := client.Write(append([]byte("PROXY TCP4 203.0.113.7 127.0.0.1 5555 9090\r\n"), frame...)); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	reply := make([]byte, len(frame))
+	if _, err := io.ReadFull(client, reply); err != nil {
+		t.Fatalf("接收心跳回复失败: %v", err)
+	}
+
+	conns := tcp.connManager.GetAllConnections()
+	if len(conns) != 1 {
+		t.Fatalf("连接数 = %d, 期望 1", len(conns))
+	}
+	for _, conn := range conns {
+		if conn.GetStats().RemoteAddr != "203.0.113.7:5555" || conn.ClientIP() != "203.0.113.7" {
+			t.Errorf("远程地址 = %s, 客户端IP = %s, 期望 203.0.113.7", conn.GetStats().RemoteAddr, conn.ClientIP())
+		}
+	}
+
+	// 可信上游未发送PROXY协议头的连接被关闭，不加入连接管理器
+	direct, err := net.Dial("tcp", tcp.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("建立连接失败: %v", err)
+	}
+	defer direct.Close()
+	direct.SetDeadline(time.Now().Add(5 * time.Second))
+	direct.Write(frame)
+	if _, err := direct.Read(make([]byte, 1)); err == nil {
+		t.Error("缺少PROXY协议头的连接应被关闭")
+	}
+	if n := tcp.connManager.GetConnectionCount(); n != 1 {
+		t.Errorf("连接数 = %d, 期望 1", n)
+	}
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	"datamiddleware/internal/protocol"
+)
+
+// resumableSession 可在断线重连后恢复的逻辑会话
+// 推送消息按会话内递增的序列号编号并保留最近的若干条，新连接恢复会话后补发客户端尚未收到的推送
+// 发送推送时不持有mu，慢连接只阻塞向同一会话发送推送的调用方
+type resumableSession struct {
+	token  string
+	gameID string
+	userID string
+
+	mu         sync.Mutex
+	conn       *protocol.Connection // 当前连接，nil表示已断开等待恢复
+	pushSeq    uint32               // 最近分配的推送序列号
+	pushes     []*types.Message     // 最近的推送消息，按序列号递增
+	expiry     *time.Timer          // 断开后到期删除会话
+	generation uint64               // 每次恢复时递增，使之前的到期定时器失效
+
+	sendMu sync.Mutex // 保证推送按序列号顺序发送，需要同时持有mu时先取得sendMu
+	sent   uint32     // 已发送到当前连接的最大推送序列号，由sendMu保护
+}
+
+// generateResumeToken 生成恢复令牌
+func generateResumeToken() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return "resume_" + hex.EncodeToString(bytes)
+}
+
+// beginSession 为握手成功的连接分配会话
+// 恢复令牌有效、身份一致且缺失的推送仍在保留范围内时恢复之前的会话（仍在使用该会话的旧连接被关闭），否则创建新会话
+// 返回的会话在attachSession之前不向连接发送推送，期间的推送保留到attachSession时补发
+func (s *TCPServer) beginSession(req *types.HandshakeRequest, gameID, userID string) (*resumableSession, bool) {
+	s.resumeMu.Lock()
+	defer s.resumeMu.Unlock()
+
+	if session, ok := s.resumes[req.ResumeToken]; ok && session.gameID == gameID && session.userID == userID {
+		session.mu.Lock()
+		if session.canReplay(req.LastPushSeq) {
+			if session.expiry != nil {
+				session.expiry.Stop()
+				session.expiry = nil
+			}
+			session.generation++
+			old := session.conn
+			session.conn = nil
+			session.mu.Unlock()
+
+			if old != nil {
+				s.logger.Info("会话在新连接上恢复，关闭旧连接", "old_conn_id", old.ID, "game_id", gameID, "user_id", userID)
+				old.Close()
+			}
+			return session, true
+		}
+		session.mu.Unlock()
+		s.logger.Info("缺失的推送已不在保留范围内，无法恢复会话", "game_id", gameID, "user_id", userID, "last_push_seq", req.LastPushSeq)
+	}
+
+	session := &resumableSession{
+		token:  generateResumeToken(),
+		gameID: gameID,
+		userID: userID,
+	}
+	if s.resumes == nil {
+		s.resumes = make(map[string]*resumableSession)
+		s.gameSessions = make(map[string]map[string]*resumableSession)
+		s.userSessions = make(map[string]map[string]*resumableSession)
+	}
+	s.resumes[session.token] = session
+	addSessionIndex(s.gameSessions, gameID, session)
+	addSessionIndex(s.userSessions, userID, session)
+	return session, false
+}
+
+// addSessionIndex 将会话加入按游戏或用户的索引
+func addSessionIndex(index map[string]map[string]*resumableSession, key string, session *resumableSession) {
+	if index[key] == nil {
+		index[key] = make(map[string]*resumableSession)
+	}
+	index[key][session.token] = session
+}
+
+// removeSessionIndex 从按游戏或用户的索引中删除会话
+func removeSessionIndex(index map[string]map[string]*resumableSession, key string, session *resumableSession) {
+	delete(index[key], session.token)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
+
+// canReplay 检查客户端已收到lastPushSeq之前的推送时，之后的推送是否都还保留，调用方需持有mu
+func (rs *resumableSession) canReplay(lastPushSeq uint32) bool {
+	if lastPushSeq > rs.pushSeq {
+		return false
+	}
+	if lastPushSeq == rs.pushSeq {
+		return true
+	}
+	return len(rs.pushes) > 0 && rs.pushes[0].Header.SequenceID <= lastPushSeq+1
+}
+
+// attachSession 将会话绑定到连接，按顺序补发序列号大于lastPushSeq的推送，连接关闭后会话在恢复窗口内保留
+// 必须在握手响应发送并启用压缩和加密之后调用
+func (s *TCPServer) attachSession(session *resumableSession, conn *protocol.Connection, lastPushSeq uint32) {
+	session.sendMu.Lock()
+	session.mu.Lock()
+	session.conn = conn
+	session.sent = lastPushSeq
+	session.mu.Unlock()
+	session.sendMu.Unlock()
+
+	if replayed := session.flush(); replayed > 0 {
+		s.logger.Info("会话已恢复，补发断开期间的推送", "conn_id", conn.ID, "game_id", session.gameID, "user_id", session.userID, "replayed", replayed)
+	}
+	conn.OnClose(func() {
+		s.detachSession(session, conn)
+	})
+}
+
+// detachSession 连接关闭后保留会话，超过恢复窗口仍未恢复时删除
+func (s *TCPServer) detachSession(session *resumableSession, conn *protocol.Connection) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	// 会话已在其他连接上恢复
+	if session.conn != conn {
+		return
+	}
+	session.conn = nil
+	generation := session.generation
+	session.expiry = time.AfterFunc(s.config.TCP.Resume.Window, func() {
+		s.expireSession(session, generation)
+	})
+}
+
+// expireSession 删除超过恢复窗口的会话，期间已恢复时不删除
+func (s *TCPServer) expireSession(session *resumableSession, generation uint64) {
+	s.resumeMu.Lock()
+	defer s.resumeMu.Unlock()
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.generation != generation || session.conn != nil {
+		return
+	}
+	s.removeSession(session)
+}
+
+// removeSession 从索引中删除会话，调用方需持有resumeMu
+func (s *TCPServer) removeSession(session *resumableSession) {
+	delete(s.resumes, session.token)
+	removeSessionIndex(s.gameSessions, session.gameID, session)
+	removeSessionIndex(s.userSessions, session.userID, session)
+}
+
+// clearSessions 停止服务器时删除所有会话
+func (s *TCPServer) clearSessions() {
+	s.resumeMu.Lock()
+	defer s.resumeMu.Unlock()
+	for _, session := range s.resumes {
+		session.mu.Lock()
+		if session.expiry != nil {
+			session.expiry.Stop()
+		}
+		session.mu.Unlock()
+	}
+	s.resumes = nil
+	s.gameSessions = nil
+	s.userSessions = nil
+}
+
+// push 为消息分配推送序列号并保留，连接在线时发送
+func (rs *resumableSession) push(msg *types.Message, bufferSize int) {
+	rs.mu.Lock()
+	rs.pushSeq++
+	pushed := &types.Message{Header: msg.Header, Body: msg.Body}
+	pushed.Header.Flags |= types.FlagAsync
+	pushed.Header.SequenceID = rs.pushSeq
+	pushed.Header.GameID = rs.gameID
+	pushed.Header.UserID = rs.userID
+
+	rs.pushes = append(rs.pushes, pushed)
+	if len(rs.pushes) > bufferSize {
+		rs.pushes = rs.pushes[len(rs.pushes)-bufferSize:]
+	}
+	rs.mu.Unlock()
+
+	rs.flush()
+}
+
+// flush 按序列号顺序向当前连接发送尚未发送的推送，返回发送的条数
+// 在mu下取得连接和待发送推送的快照，在mu之外发送；并发调用时先取得sendMu的调用方一并发送之后的推送
+func (rs *resumableSession) flush() int {
+	rs.sendMu.Lock()
+	defer rs.sendMu.Unlock()
+
+	rs.mu.Lock()
+	conn := rs.conn
+	var pending []*types.Message
+	if conn != nil {
+		for _, msg := range rs.pushes {
+			if msg.Header.SequenceID > rs.sent {
+				pending = append(pending, msg)
+			}
+		}
+	}
+	rs.mu.Unlock()
+
+	sent := 0
+	for _, msg := range pending {
+		// 连接已关闭，恢复会话时按客户端确认的序列号补发
+		if conn.SendMessage(msg) != nil {
+			break
+		}
+		rs.sent = msg.Header.SequenceID
+		sent++
+	}
+	return sent
+}
+
+// PushToUser 向用户在游戏中的连接推送消息，返回接收推送的会话（未启用会话恢复时为连接）数
+// 推送消息带有FlagAsync标志，序列号为会话内的推送序列号；启用会话恢复时连接断开期间的推送保留到恢复后补发
+// 消息之后可能被补发，调用方不能再修改消息
+func (s *TCPServer) PushToUser(gameID, userID string, msg *types.Message) int {
+	return s.pushSessions(gameID, userID, msg)
+}
+
+// PushToGame 向游戏的所有连接推送消息，其他同PushToUser
+func (s *TCPServer) PushToGame(gameID string, msg *types.Message) int {
+	return s.pushSessions(gameID, "", msg)
+}
+
+// pushSessions 向匹配的会话投递推送，gameID或userID为空时不按其筛选
+// 启用会话恢复时也是连接管理器BroadcastToGame和BroadcastToUser的投递函数
+func (s *TCPServer) pushSessions(gameID, userID string, msg *types.Message) int {
+	if !s.config.TCP.Resume.Enabled {
+		return s.pushConnections(gameID, userID, msg)
+	}
+
+	s.resumeMu.Lock()
+	index := s.gameSessions[gameID]
+	if userID != "" {
+		index = s.userSessions[userID]
+	}
+	sessions := make([]*resumableSession, 0, len(index))
+	for _, session := range index {
+		if gameID == "" || session.gameID == gameID {
+			sessions = append(sessions, session)
+		}
+	}
+	s.resumeMu.Unlock()
+
+	for _, session := range sessions {
+		session.push(msg, s.config.TCP.Resume.BufferSize)
+	}
+	return len(sessions)
+}
+
+// pushConnections 未启用会话恢复时PushToUser和PushToGame直接向已认证的连接发送推送，序列号为0
+func (s *TCPServer) pushConnections(gameID, userID string, msg *types.Message) int {
+	conns := s.connManager.GetConnectionsByGame(gameID)
+	if userID != "" {
+		conns = s.connManager.GetConnectionsByUser(userID)
+	}
+
+	sent := 0
+	for _, conn := range conns {
+		connGameID, connUserID := conn.Identity()
+		if gameID != "" && connGameID != gameID {
+			continue
+		}
+		pushed := &types.Message{Header: msg.Header, Body: msg.Body}
+		pushed.Header.Flags |= types.FlagAsync
+		pushed.Header.SequenceID = 0
+		pushed.Header.GameID = connGameID
+		pushed.Header.UserID = connUserID
+		if err := conn.SendMessage(pushed); err != nil {
+			s.logger.Warn("推送消息失败", "conn_id", conn.ID, "error", err)
+			continue
+		}
+		sent++
+	}
+	return sent
+}
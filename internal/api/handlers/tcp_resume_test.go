@@ -0,0 +1,223 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/internal/protocol"
+	"datamiddleware/internal/router"
+
+	"go.uber.org/zap"
+)
+
+// startResumeServer 启动不校验握手凭证、启用会话恢复的TCP服务器
+func startResumeServer(t *testing.T, resume types.ResumeConfig) *TCPServer {
+	t.Helper()
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+
+	var config types.ServerConfig
+	config.TCP = types.TCPConfig{Host: "127.0.0.1", Codec: protocol.CodecBinary, Resume: resume}
+	tcp := NewTCPServer(config, router.NewMessageRouter(log), nil, nil, log)
+	if err := tcp.Start(); err != nil {
+		t.Fatalf("启动TCP服务器失败: %v", err)
+	}
+	t.Cleanup(func() { tcp.Stop() })
+	return tcp
+}
+
+// frameStream 从连接中逐条读取消息，保留一次读取中多余的数据（握手响应和补发的推送可能同时到达）
+type frameStream struct {
+	net.Conn
+	data []byte
+}
+
+func (f *frameStream) next(t *testing.T) *types.Message {
+	t.Helper()
+	f.SetReadDeadline(time.Now().Add(time.Second))
+	defer f.SetReadDeadline(time.Time{})
+
+	codec := protocol.NewBinaryCodec()
+	buf := make([]byte, 4096)
+	for {
+		if msg, consumed, err := codec.Decode(f.data); err == nil {
+			f.data = f.data[consumed:]
+			return msg
+		}
+		n, err := f.Read(buf)
+		if err != nil {
+			t.Fatalf("读取消息失败: %v", err)
+		}
+		f.data = append(f.data, buf[:n]...)
+	}
+}
+
+// resumeHandshake 建立连接并握手，token非空时请求恢复会话
+func resumeHandshake(t *testing.T, tcp *TCPServer, userID, token string, lastPushSeq uint32) (*frameStream, types.HandshakeResponse) {
+	t.Helper()
+	conn, err := net.Dial("tcp", tcp.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("建立连接失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	codec := protocol.NewBinaryCodec()
+	body, _ := json.Marshal(types.HandshakeRequest{ResumeToken: token, LastPushSeq: lastPushSeq})
+	frame, _ := codec.Encode(&types.Message{
+		Header: types.MessageHeader{Version: types.ProtocolVersion, Type: types.MessageTypeHandshake, SequenceID: 1, GameID: "game1", UserID: userID, BodyLength: uint32(len(body))},
+		Body:   body,
+	})
+	conn.Write(frame)
+
+	stream := &frameStream{Conn: conn}
+	msg := stream.next(t)
+	var resp types.HandshakeResponse
+	if msg.Header.Type != types.MessageTypeHandshake || json.Unmarshal(msg.Body, &resp) != nil {
+		t.Fatalf("握手响应 = %+v %s", msg.Header, msg.Body)
+	}
+	return stream, resp
+}
+
+// waitConnectionCount 等待连接管理器中的连接数变为n
+func waitConnectionCount(t *testing.T, tcp *TCPServer, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for tcp.connManager.GetConnectionCount() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("连接数 = %d, 期望 %d", tcp.connManager.GetConnectionCount(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// pushTest 向user1推送一条消息体为body的消息
+func pushTest(tcp *TCPServer, body string) int {
+	return tcp.PushToUser("game1", "user1", &types.Message{
+		Header: types.MessageHeader{Version: types.ProtocolVersion, Type: types.MessageTypePlayerData, BodyLength: uint32(len(body))},
+		Body:   []byte(body),
+	})
+}
+
+// readPushes 读取n条推送，返回序列号和消息体
+func readPushes(t *testing.T, stream *frameStream, n int) (seqs []uint32, bodies []string) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		msg := stream.next(t)
+		if msg.Header.Flags&types.FlagAsync == 0 {
+			t.Fatalf("推送消息缺少FlagAsync标志: %+v", msg.Header)
+		}
+		seqs = append(seqs, msg.Header.SequenceID)
+		bodies = append(bodies, string(msg.Body))
+	}
+	return seqs, bodies
+}
+
+func TestTCPServerResumeSession(t *testing.T) {
+	tcp := startResumeServer(t, types.ResumeConfig{Enabled: true, Window: time.Minute, BufferSize: 3})
+
+	conn, resp := resumeHandshake(t, tcp, "user1", "", 0)
+	if resp.ResumeToken == "" || resp.Resumed || resp.ResumeWindow != 60000 {
+		t.Fatalf("首次握手响应 = %+v", resp)
+	}
+	pushTest(tcp, "p1")
+	pushTest(tcp, "p2")
+	if seqs, _ := readPushes(t, conn, 2); seqs[0] != 1 || seqs[1] != 2 {
+		t.Fatalf("推送序列号 = %v, 期望 [1 2]", seqs)
+	}
+
+	// 断开期间的推送保留到恢复后按顺序补发
+	conn.Close()
+	waitConnectionCount(t, tcp, 0)
+	if n := pushTest(tcp, "p3"); n != 1 {
+		t.Errorf("断开期间接收推送的会话数 = %d, 期望 1", n)
+	}
+	// 连接管理器的广播同样经由会话投递
+	tcp.connManager.BroadcastToGame("game1", &types.Message{
+		Header: types.MessageHeader{Version: types.ProtocolVersion, Type: types.MessageTypePlayerData, BodyLength: 2},
+		Body:   []byte("p4"),
+	})
+
+	// 其他用户不能恢复该会话
+	if _, other := resumeHandshake(t, tcp, "user2", resp.ResumeToken, 2); other.Resumed || other.ResumeToken == resp.ResumeToken {
+		t.Errorf("其他用户的握手响应 = %+v", other)
+	}
+
+	conn, resumed := resumeHandshake(t, tcp, "user1", resp.ResumeToken, 2)
+	if !resumed.Resumed || resumed.ResumeToken != resp.ResumeToken {
+		t.Fatalf("恢复握手响应 = %+v", resumed)
+	}
+	seqs, bodies := readPushes(t, conn, 2)
+	if seqs[0] != 3 || seqs[1] != 4 || bodies[0] != "p3" || bodies[1] != "p4" {
+		t.Errorf("补发的推送 = %v %v, 期望 [3 4] [p3 p4]", seqs, bodies)
+	}
+
+	// 新连接恢复会话时关闭仍在使用该会话的旧连接
+	pushTest(tcp, "p5")
+	readPushes(t, conn, 1)
+	takeover, _ := resumeHandshake(t, tcp, "user1", resp.ResumeToken, 5)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("会话被新连接恢复后旧连接应被关闭")
+	}
+	pushTest(tcp, "p6")
+	if seqs, _ := readPushes(t, takeover, 1); seqs[0] != 6 {
+		t.Errorf("恢复后的推送序列号 = %d, 期望 6", seqs[0])
+	}
+
+	// 缺失的推送超出保留范围时不能恢复
+	takeover.Close()
+	waitConnectionCount(t, tcp, 1) // user2的连接
+	for _, body := range []string{"p7", "p8", "p9", "p10"} {
+		pushTest(tcp, body)
+	}
+	if _, fresh := resumeHandshake(t, tcp, "user1", resp.ResumeToken, 6); fresh.Resumed || fresh.ResumeToken == resp.ResumeToken {
+		t.Errorf("推送已被丢弃时的握手响应 = %+v", fresh)
+	}
+}
+
+func TestTCPServerResumeWindow(t *testing.T) {
+	tcp := startResumeServer(t, types.ResumeConfig{Enabled: true, Window: 50 * time.Millisecond, BufferSize: 16})
+
+	conn, resp := resumeHandshake(t, tcp, "user1", "", 0)
+	conn.Close()
+	waitConnectionCount(t, tcp, 0)
+	time.Sleep(200 * time.Millisecond)
+
+	if n := pushTest(tcp, "expired"); n != 0 {
+		t.Errorf("会话过期后接收推送的会话数 = %d, 期望 0", n)
+	}
+	if _, fresh := resumeHandshake(t, tcp, "user1", resp.ResumeToken, 0); fresh.Resumed {
+		t.Error("超过恢复窗口后不应恢复会话")
+	}
+}
+
+func TestTCPServerPushWithoutResume(t *testing.T) {
+	tcp := startResumeServer(t, types.ResumeConfig{})
+
+	conn, resp := resumeHandshake(t, tcp, "user1", "", 0)
+	if resp.ResumeToken != "" {
+		t.Errorf("未启用会话恢复时不应返回恢复令牌: %+v", resp)
+	}
+	resumeHandshake(t, tcp, "user2", "", 0)
+
+	if n := pushTest(tcp, "p1"); n != 1 {
+		t.Errorf("接收推送的连接数 = %d, 期望 1", n)
+	}
+	seqs, bodies := readPushes(t, conn, 1)
+	if seqs[0] != 0 || bodies[0] != "p1" {
+		t.Errorf("推送 = %v %v, 期望 [0] [p1]", seqs, bodies)
+	}
+
+	// 未启用会话恢复时广播按调用方的消息原样发送
+	tcp.connManager.BroadcastToUser("user1", &types.Message{
+		Header: types.MessageHeader{Version: types.ProtocolVersion, Type: types.MessageTypePlayerData, SequenceID: 7, GameID: "other", BodyLength: 2},
+		Body:   []byte("p2"),
+	})
+	msg := conn.next(t)
+	if msg.Header.SequenceID != 7 || msg.Header.Flags&types.FlagAsync != 0 || msg.Header.GameID != "other" || string(msg.Body) != "p2" {
+		t.Errorf("广播消息被改写: %+v %s", msg.Header, msg.Body)
+	}
+}
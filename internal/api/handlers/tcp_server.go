@@ -1,23 +1,59 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"datamiddleware/internal/infrastructure/auth"
+	"datamiddleware/internal/infrastructure/certs"
 	"datamiddleware/internal/infrastructure/logging"
 	"datamiddleware/internal/protocol"
+	"datamiddleware/internal/router"
 	"datamiddleware/internal/common/types"
+	"datamiddleware/pkg/constants"
 )
 
+// defaultConnectionSetupTimeout 未配置握手超时时读取PROXY协议头和TLS握手的超时时间
+const defaultConnectionSetupTimeout = 10 * time.Second
+
 // TCPServer TCP服务器
 type TCPServer struct {
 	config       types.ServerConfig          `json:"config"`        // 服务器配置（包含环境信息）
 	connManager  *protocol.ConnectionManager `json:"-"`             // 连接管理器
+	router       *router.MessageRouter       `json:"-"`             // 消息路由器
+	identity     *protocol.ServerIdentity    `json:"-"`             // 会话加密身份密钥
+	jwtService   *auth.JWTService            `json:"-"`             // 校验握手携带的访问令牌
+	sessions     SessionStore                `json:"-"`             // 校验握手携带的会话ID
+	frameRejects *frameRejectStats           `json:"-"`             // 按原因统计被拒绝的消息帧
+	messageStats *messageStats               `json:"-"`             // 按消息类型统计的收发计数
+	inbound      []TCPHandlerFunc            `json:"-"`             // 入站消息中间件
+	outbound     []TCPHandlerFunc            `json:"-"`             // 出站消息中间件
+	pipeline     *tcpPipeline                `json:"-"`             // 启动时由中间件组装的处理链
+	engine       *protocol.EventEngine       `json:"-"`             // epoll事件循环引擎，nil表示每个连接一个读协程
+	tlsCerts     *certs.Reloader             `json:"-"`             // TLS证书，nil表示不启用TLS
+	tlsConfig    *tls.Config                 `json:"-"`             // 接入连接的TLS配置
+	proxyTrust   protocol.ProxyTrust         `json:"-"`             // 发送PROXY协议头的可信上游，nil表示不解析
 	logger       logger.Logger               `json:"-"`             // 日志器
-	listener     net.Listener                `json:"-"`             // 监听器
+	listener     net.Listener                `json:"-"`             // 公共端口监听器，连接的游戏由握手凭证决定
+	games        []types.GameConfig          `json:"-"`             // 游戏配置
+	listeners    []gameListener              `json:"-"`             // 游戏专属端口的监听器
+	gameStatus   GameStatusSource            `json:"-"`             // 游戏运行状态，nil表示不检查
+	drains       map[string]*time.Timer      `json:"-"`             // 等待断开连接的游戏
+	drainMu      sync.Mutex                  `json:"-"`             // 保护drains
+	drain        atomic.Pointer[drainState]  `json:"-"`             // 服务器排空连接的状态，nil表示未在排空
+	inFlight     atomic.Int64                `json:"-"`             // 处理中的游戏业务请求数
+	resumes      map[string]*resumableSession `json:"-"`            // 按恢复令牌索引的可恢复会话
+	gameSessions map[string]map[string]*resumableSession `json:"-"` // 按游戏索引的可恢复会话
+	userSessions map[string]map[string]*resumableSession `json:"-"` // 按用户索引的可恢复会话
+	resumeMu     sync.Mutex                  `json:"-"`             // 保护resumes、gameSessions和userSessions
 	stopChan     chan struct{}               `json:"-"`             // 停止通道
 	wg           sync.WaitGroup              `json:"-"`             // 等待组
 	running      bool                        `json:"running"`       // 运行状态
@@ -25,36 +61,83 @@ type TCPServer struct {
 	mu           sync.RWMutex                `json:"-"`             // 保护并发访问
 }
 
-// NewTCPServer 创建TCP服务器
-func NewTCPServer(config types.ServerConfig, log logger.Logger) *TCPServer {
+// NewTCPServer 创建TCP服务器，jwtService和sessions用于校验握手凭证，为nil时不支持对应的认证方式
+func NewTCPServer(config types.ServerConfig, messageRouter *router.MessageRouter, jwtService *auth.JWTService, sessions SessionStore, log logger.Logger) *TCPServer {
 	// 创建连接配置
 	connConfig := types.ConnectionConfig{
-		MaxConnections: config.TCP.MaxConnections,
-		ReadTimeout:    config.TCP.ReadTimeout,
-		WriteTimeout:   config.TCP.WriteTimeout,
-		BufferSize:     8192, // 8KB缓冲区
+		MaxConnections:     config.TCP.MaxConnections,
+		ReadTimeout:        config.TCP.ReadTimeout,
+		WriteTimeout:       config.TCP.WriteTimeout,
+		BufferSize:         8192, // 8KB缓冲区
+		Codec:              config.TCP.Codec,
+		CodecNegotiation:   config.TCP.CodecNegotiation,
+		MaxHeaderSize:      config.TCP.MaxHeaderSize,
+		MaxBodySize:        config.TCP.MaxBodySize,
+		SendQueueSize:      config.TCP.SendQueueSize,
+		SlowConsumerPolicy: config.TCP.SlowConsumerPolicy,
+		WriteBatchSize:     config.TCP.WriteBatchSize,
 		Heartbeat: types.HeartbeatConfig{
 			Enabled:   true,
-			Interval:  30 * time.Second, // 30秒心跳间隔
-			Timeout:   90 * time.Second, // 90秒超时
+			Interval:  30 * time.Second, // 空闲30秒后发送Ping
+			Timeout:   10 * time.Second, // 10秒内未收到Pong计为丢失一次
 			MaxMissed: 3,                // 最多丢失3次
 		},
-		IdleTimeout:     300 * time.Second, // 5分钟空闲超时
-		CleanupInterval: 60 * time.Second,  // 60秒清理间隔
+		IdleTimeout:      300 * time.Second, // 5分钟空闲超时
+		HandshakeTimeout: config.TCP.HandshakeTimeout,
+		CleanupInterval:  60 * time.Second, // 60秒清理间隔
 	}
 
-	// 创建编解码器
-	codec := protocol.NewBinaryCodec() // 使用二进制编解码器获得更好性能
+	// 创建编解码器（默认使用二进制编解码器获得更好性能）
+	codec, err := protocol.NewCodec(config.TCP.Codec)
+	if err != nil {
+		log.Error("创建编解码器失败，使用二进制编解码器", "codec", config.TCP.Codec, "error", err)
+		codec = protocol.NewBinaryCodec()
+	}
 
 	// 创建连接管理器
 	connManager := protocol.NewConnectionManager(connConfig, codec, log)
 
-	return &TCPServer{
+	// 加载会话加密身份密钥（配置已在加载时校验）
+	var identity *protocol.ServerIdentity
+	if config.TCP.Encryption.Enabled {
+		identity, err = protocol.NewServerIdentity(config.TCP.Encryption.IdentityKey)
+		if err != nil {
+			log.Error("加载TCP加密身份密钥失败，使用临时密钥", "error", err)
+			identity, _ = protocol.NewServerIdentity("")
+		}
+		if config.TCP.Encryption.IdentityKey == "" {
+			log.Warn("未配置TCP加密身份密钥，已生成临时密钥，客户端需在每次重启后更新预置公钥", "identity_key", identity.PublicKey())
+		}
+	}
+
+	// 解析PROXY协议的可信上游（配置已在加载时校验）
+	var proxyTrust protocol.ProxyTrust
+	if config.TCP.ProxyProtocol.Enabled {
+		proxyTrust, err = protocol.ParseProxyTrust(config.TCP.ProxyProtocol.TrustedCIDRs)
+		if err != nil {
+			log.Error("解析PROXY协议可信上游失败，不解析PROXY协议头", "error", err)
+		}
+	}
+
+	s := &TCPServer{
 		config:      config,
 		connManager: connManager,
-		logger:      log,
+		router:      messageRouter,
+		identity:     identity,
+		jwtService:   jwtService,
+		sessions:     sessions,
+		frameRejects: newFrameRejectStats(),
+		messageStats: newMessageStats(),
+		proxyTrust:   proxyTrust,
+		logger:       log,
 		stopChan:    make(chan struct{}),
 	}
+	s.useBuiltinMiddleware()
+	// 启用会话恢复时广播经由会话投递，连接断开期间的推送保留到恢复后补发；未启用时广播按原样发送
+	if config.TCP.Resume.Enabled {
+		connManager.SetPushFunc(s.pushSessions)
+	}
+	return s
 }
 
 // Start 启动TCP服务器
@@ -66,6 +149,15 @@ func (s *TCPServer) Start() error {
 		return fmt.Errorf("TCP服务器已在运行")
 	}
 
+	// 加载TLS证书，所有端口接入的连接都在握手后才加入连接管理器
+	var tlsCerts *certs.Reloader
+	if s.config.TCP.TLS.Enabled {
+		var err error
+		if tlsCerts, err = certs.NewReloader(s.config.TCP.TLS, s.logger); err != nil {
+			return fmt.Errorf("加载TCP TLS证书失败: %w", err)
+		}
+	}
+
 	// 创建监听器
 	address := fmt.Sprintf("%s:%d", s.config.TCP.Host, s.config.TCP.Port)
 	listener, err := net.Listen("tcp", address)
@@ -73,23 +165,81 @@ func (s *TCPServer) Start() error {
 		return fmt.Errorf("创建TCP监听器失败: %w", err)
 	}
 
+	// 启用的游戏在各自的端口上监听
+	listeners, err := s.listenGames()
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	if tlsCerts != nil {
+		if err := tlsCerts.Start(); err != nil {
+			s.logger.Warn("监听TLS证书文件失败，证书更新后需要重启服务", "error", err)
+		}
+		s.tlsCerts = tlsCerts
+		s.tlsConfig = tlsCerts.TLSConfig()
+	}
+
 	s.listener = listener
+	s.listeners = listeners
 	s.running = true
 
+	// 组装中间件链，之后建立的连接发送消息前都经过出站中间件
+	s.pipeline = newTCPPipeline(s.inbound, s.outbound, func(c *TCPContext) {
+		s.handleMessage(c.Conn, c.Message)
+	})
+	if len(s.outbound) > 0 {
+		s.connManager.SetOutboundFilter(s.pipeline.filterOutbound)
+	}
+
 	// 启动连接管理器
 	s.connManager.Start()
 
+	// 按配置启动事件循环引擎，当前平台不支持时回退到每个连接一个读协程
+	// 事件循环直接读取套接字，无法处理TLS记录，启用TLS时使用协程引擎
+	if s.config.TCP.Engine == protocol.EngineEpoll && s.tlsConfig != nil {
+		s.logger.Warn("启用TLS时不支持事件循环引擎，使用协程引擎")
+	} else if s.config.TCP.Engine == protocol.EngineEpoll {
+		engine, err := protocol.NewEventEngine(protocol.EventEngineConfig{
+			Loops:           s.config.TCP.EventLoops,
+			Workers:         s.config.TCP.Workers,
+			WorkerQueueSize: s.config.TCP.WorkerQueueSize,
+		}, &engineHandler{server: s}, s.logger)
+		if err != nil {
+			s.logger.Warn("启动事件循环引擎失败，使用协程引擎", "error", err)
+		} else {
+			s.engine = engine
+		}
+	}
+
 	s.logger.Info("TCP服务器启动", "address", address)
 
 	// 启动接受连接的协程
 	s.wg.Add(1)
-	go s.acceptLoop()
+	go s.acceptLoop(listener, "")
+	for _, gl := range listeners {
+		s.logger.Info("游戏TCP端口启动", "game_id", gl.gameID, "address", gl.listener.Addr().String())
+		s.wg.Add(1)
+		go s.acceptLoop(gl.listener, gl.gameID)
+	}
 
 	return nil
 }
 
-// Stop 停止TCP服务器
+// Stop 停止TCP服务器，配置了排空超时时先排空连接（或等待进行中的排空完成）
 func (s *TCPServer) Stop() error {
+	if drain := s.config.TCP.Drain; drain.Timeout > 0 || s.IsDraining() {
+		done, err := s.Drain(DrainOptions{
+			Timeout:         drain.Timeout,
+			Redirect:        drain.Redirect,
+			ReconnectSpread: drain.ReconnectSpread,
+			RequestGrace:    drain.RequestGrace,
+		})
+		if err == nil || errors.Is(err, ErrAlreadyDraining) {
+			<-done
+		}
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -110,10 +260,34 @@ func (s *TCPServer) Stop() error {
 	if s.listener != nil {
 		s.listener.Close()
 	}
+	closeGameListeners(s.listeners)
 
 	// 停止连接管理器
 	s.connManager.Stop()
 
+	// 取消等待中的游戏连接断开
+	s.drainMu.Lock()
+	for gameID, timer := range s.drains {
+		timer.Stop()
+		delete(s.drains, gameID)
+	}
+	s.drainMu.Unlock()
+
+	// 停止事件循环引擎
+	if s.engine != nil {
+		s.engine.Stop()
+		s.engine = nil
+	}
+
+	// 删除等待恢复的会话
+	s.clearSessions()
+
+	// 停止监听证书文件
+	if s.tlsCerts != nil {
+		s.tlsCerts.Stop()
+		s.tlsCerts = nil
+	}
+
 	// 等待所有协程退出
 	s.wg.Wait()
 
@@ -138,6 +312,9 @@ func (s *TCPServer) GetStats() ServerStats {
 		GameConnections:  connStats.GameStats,
 		UserConnections:  connStats.UserStats,
 		StateConnections: connStats.StateStats,
+		GameRTT:          connStats.GameRTT,
+		Messages:         s.messageStats.snapshot(),
+		FrameRejects:     s.frameRejects.snapshot(),
 	}
 }
 
@@ -148,10 +325,42 @@ type ServerStats struct {
 	GameConnections  map[string]int                `json:"game_connections"`  // 按游戏分组的连接数
 	UserConnections  map[string]int                `json:"user_connections"`  // 按用户分组的连接数
 	StateConnections map[types.ConnectionState]int `json:"state_connections"` // 按状态分组的连接数
+	GameRTT          map[string]protocol.RTTStats  `json:"game_rtt"`          // 按游戏统计的往返时延分布
+	Messages         map[string]MessageTypeStats   `json:"messages"`          // 按消息类型统计的收发计数和处理耗时
+	FrameRejects     map[string]int64              `json:"frame_rejects"`     // 按原因统计被拒绝的消息帧数
+}
+
+// frameRejectStats 被拒绝消息帧的计数器
+type frameRejectStats struct {
+	counts map[string]int64
+	mu     sync.Mutex
+}
+
+func newFrameRejectStats() *frameRejectStats {
+	return &frameRejectStats{counts: make(map[string]int64)}
 }
 
-// acceptLoop 接受连接循环
-func (s *TCPServer) acceptLoop() {
+// inc 增加指定原因的计数
+func (f *frameRejectStats) inc(reason string) {
+	f.mu.Lock()
+	f.counts[reason]++
+	f.mu.Unlock()
+}
+
+// snapshot 获取计数快照
+func (f *frameRejectStats) snapshot() map[string]int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	counts := make(map[string]int64, len(f.counts))
+	for reason, n := range f.counts {
+		counts[reason] = n
+	}
+	return counts
+}
+
+// acceptLoop 接受连接循环，gameID非空时接入的连接绑定到该游戏
+func (s *TCPServer) acceptLoop(listener net.Listener, gameID string) {
 	defer func() {
 		if r := recover(); r != nil {
 			s.logger.Error("acceptLoop发生panic", "panic", r)
@@ -166,17 +375,17 @@ func (s *TCPServer) acceptLoop() {
 		case <-s.stopChan:
 			// 服务器正在停止，设置很短的超时以便快速退出
 			timeout = 10 * time.Millisecond
-			s.listener.(*net.TCPListener).SetDeadline(time.Now().Add(timeout))
+			listener.(*net.TCPListener).SetDeadline(time.Now().Add(timeout))
 		default:
 			if s.config.Env == "dev" {
 				timeout = 10 * time.Second // 开发模式10秒超时，减少日志噪音
 			} else {
 				timeout = 30 * time.Second // 生产模式30秒超时
 			}
-			s.listener.(*net.TCPListener).SetDeadline(time.Now().Add(timeout))
+			listener.(*net.TCPListener).SetDeadline(time.Now().Add(timeout))
 		}
 
-		conn, err := s.listener.Accept()
+		conn, err := listener.Accept()
 
 		// 检查是否收到停止信号
 		select {
@@ -238,12 +447,84 @@ func (s *TCPServer) acceptLoop() {
 		}
 
 		// 处理新连接
-		s.handleConnection(conn)
+		s.handleConnection(conn, gameID)
 	}
 }
 
 // handleConnection 处理新连接
-func (s *TCPServer) handleConnection(conn net.Conn) {
+func (s *TCPServer) handleConnection(conn net.Conn, gameID string) {
+	// PROXY协议头和TLS握手在单独的协程中完成，避免阻塞接受连接
+	if s.tlsConfig != nil || s.proxyTrust.Contains(conn.RemoteAddr()) {
+		s.wg.Add(1)
+		go s.prepareConnection(conn, gameID)
+		return
+	}
+	s.addConnection(conn, gameID)
+}
+
+// prepareConnection 读取可信上游发送的PROXY协议头并完成TLS握手后添加连接
+// 失败的连接不计入连接管理器，两者需在握手超时时间内完成
+func (s *TCPServer) prepareConnection(conn net.Conn, gameID string) {
+	defer s.wg.Done()
+
+	timeout := s.config.TCP.HandshakeTimeout
+	if timeout <= 0 {
+		timeout = defaultConnectionSetupTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if s.proxyTrust.Contains(conn.RemoteAddr()) {
+		proxyAddr := conn.RemoteAddr()
+		deadline, _ := ctx.Deadline()
+		conn.SetReadDeadline(deadline)
+		proxied, err := protocol.ReadProxyHeader(conn)
+		if err != nil {
+			s.logger.Warn("读取PROXY协议头失败", "proxy_addr", proxyAddr, "error", err)
+			conn.Close()
+			return
+		}
+		conn.SetReadDeadline(time.Time{})
+		conn = proxied
+		s.logger.Debug("PROXY协议头已解析", "remote_addr", conn.RemoteAddr(), "proxy_addr", proxyAddr)
+	}
+
+	if s.tlsConfig != nil {
+		tlsConn := tls.Server(conn, s.tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			s.logger.Debug("TLS握手失败", "remote_addr", conn.RemoteAddr(), "error", err)
+			conn.Close()
+			return
+		}
+		state := tlsConn.ConnectionState()
+		if len(state.PeerCertificates) > 0 {
+			s.logger.Info("TLS客户端证书认证通过",
+				"remote_addr", conn.RemoteAddr(),
+				"subject", state.PeerCertificates[0].Subject.String(),
+				"verified", len(state.VerifiedChains) > 0)
+		}
+		conn = tlsConn
+	}
+
+	s.mu.RLock()
+	stopping := !s.running || s.shuttingDown || s.IsDraining()
+	s.mu.RUnlock()
+	if stopping {
+		conn.Close()
+		return
+	}
+	s.addConnection(conn, gameID)
+}
+
+// addConnection 将连接添加到管理器并开始读取消息
+func (s *TCPServer) addConnection(conn net.Conn, gameID string) {
 	// 添加连接到管理器
 	connection, err := s.connManager.AddConnection(conn)
 	if err != nil {
@@ -251,12 +532,77 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 		conn.Close()
 		return
 	}
+	if gameID != "" {
+		connection.BindGame(gameID)
+	}
+
+	// 由事件循环引擎读取消息
+	if s.engine != nil {
+		if err := s.engine.Register(connection); err != nil {
+			s.logger.Error("注册连接到事件循环失败", "conn_id", connection.ID, "error", err)
+			s.connManager.RemoveConnection(connection.ID)
+			connection.Close()
+		}
+		return
+	}
 
 	// 启动连接处理协程
 	s.wg.Add(1)
 	go s.handleConnectionLoop(connection)
 }
 
+// ServeConn 处理由其他传输层（如WebSocket）接入的连接，阻塞直到连接关闭
+// 连接与TCP端口接入的连接注册在同一个连接管理器中，握手认证、心跳、中间件和游戏路由完全相同
+func (s *TCPServer) ServeConn(conn net.Conn) error {
+	s.mu.RLock()
+	if !s.running || s.shuttingDown {
+		s.mu.RUnlock()
+		conn.Close()
+		return ErrServerNotRunning
+	}
+	if s.IsDraining() {
+		s.mu.RUnlock()
+		conn.Close()
+		return ErrAlreadyDraining
+	}
+	s.wg.Add(1)
+	s.mu.RUnlock()
+
+	connection, err := s.connManager.AddConnection(conn)
+	if err != nil {
+		s.wg.Done()
+		s.logger.Error("添加连接失败", "remote_addr", conn.RemoteAddr(), "error", err)
+		conn.Close()
+		return err
+	}
+
+	// 事件循环引擎只能监听TCP套接字，其他传输层的连接在调用方协程中读取
+	s.handleConnectionLoop(connection)
+	return nil
+}
+
+// engineHandler 将事件循环引擎的回调转发给TCP服务器
+type engineHandler struct {
+	server *TCPServer
+}
+
+// HandleMessage 在工作协程中处理消息
+func (h *engineHandler) HandleMessage(conn *protocol.Connection, msg *types.Message) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.server.logger.Error("处理消息发生panic", "conn_id", conn.ID, "panic", r)
+		}
+	}()
+	h.server.pipeline.handleInbound(conn, msg)
+}
+
+// HandleClose 连接断开时清理
+func (h *engineHandler) HandleClose(conn *protocol.Connection, err error) {
+	h.server.handleReadError(conn, err)
+	h.server.connManager.RemoveConnection(conn.ID)
+	conn.Close()
+}
+
 // isConnectionClosedError 检查是否是连接关闭相关的错误
 func isConnectionClosedError(err error) bool {
 	if err == nil {
@@ -291,23 +637,45 @@ func (s *TCPServer) handleConnectionLoop(conn *protocol.Connection) {
 			// 读取消息
 			msg, err := conn.ReadMessage()
 			if err != nil {
-				if err == protocol.ErrConnectionClosed {
-					s.logger.Info("连接已关闭", "conn_id", conn.ID)
-				} else if isConnectionClosedError(err) {
-					s.logger.Debug("连接被客户端关闭", "conn_id", conn.ID, "error", err)
-				} else {
-					s.logger.Error("读取消息失败", "conn_id", conn.ID, "error", err)
-				}
+				s.handleReadError(conn, err)
 				return
 			}
 
 			// 处理消息
 			s.logger.Debug("处理消息", "conn_id", conn.ID, "type", msg.Header.Type, "seq", msg.Header.SequenceID)
-			s.handleMessage(conn, msg)
+			s.pipeline.handleInbound(conn, msg)
+
+			// 消息体借用连接的读缓冲区，处理完成后归还
+			msg.Release()
 		}
 	}
 }
 
+// handleReadError 记录读取消息失败的原因，帧被拒绝时通知客户端，之后连接会被关闭
+func (s *TCPServer) handleReadError(conn *protocol.Connection, err error) {
+	if reason := protocol.FrameRejectReason(err); reason != "" {
+		s.rejectFrame(conn, reason, err)
+	} else if err == protocol.ErrConnectionClosed {
+		s.logger.Info("连接已关闭", "conn_id", conn.ID)
+	} else if isConnectionClosedError(err) {
+		s.logger.Debug("连接被客户端关闭", "conn_id", conn.ID, "error", err)
+	} else {
+		s.logger.Error("读取消息失败", "conn_id", conn.ID, "error", err)
+	}
+}
+
+// rejectFrame 记录被拒绝的消息帧并通知客户端，之后连接会被关闭
+func (s *TCPServer) rejectFrame(conn *protocol.Connection, reason string, err error) {
+	s.frameRejects.inc(reason)
+	s.logger.Warn("消息帧被拒绝，关闭连接", "conn_id", conn.ID, "remote_addr", conn.Info.RemoteAddr, "reason", reason, "error", err)
+
+	code, message := constants.ErrCodeProtocolError, "消息格式错误"
+	if errors.Is(err, protocol.ErrFrameTooLarge) {
+		code, message = constants.ErrCodeMessageTooLarge, "消息过大"
+	}
+	conn.SendMessage(protocol.CreateErrorMessage(code, message, 0))
+}
+
 // handleMessage 处理消息
 func (s *TCPServer) handleMessage(conn *protocol.Connection, msg *types.Message) {
 	s.logger.Debug("收到消息", "conn_id", conn.ID, "type", msg.Header.Type, "seq", msg.Header.SequenceID)
@@ -317,12 +685,18 @@ func (s *TCPServer) handleMessage(conn *protocol.Connection, msg *types.Message)
 		s.handleHeartbeat(conn, msg)
 	case types.MessageTypeHandshake:
 		s.handleHandshake(conn, msg)
-	case types.MessageTypePlayerLogin:
-		s.handlePlayerLogin(conn, msg)
-	case types.MessageTypePlayerLogout:
-		s.handlePlayerLogout(conn, msg)
+	case types.MessageTypePing:
+		s.handlePing(conn, msg)
+	case types.MessageTypePong:
+		if !conn.HandlePong(msg.Header.SequenceID) {
+			s.logger.Debug("忽略不匹配的Pong", "conn_id", conn.ID, "seq", msg.Header.SequenceID)
+		}
 	default:
-		s.handleUnknownMessage(conn, msg)
+		if msg.Header.Type.IsSystem() {
+			s.handleUnknownMessage(conn, msg)
+			return
+		}
+		s.handleGameMessage(conn, msg)
 	}
 }
 
@@ -342,55 +716,181 @@ func (s *TCPServer) handleHeartbeat(conn *protocol.Connection, msg *types.Messag
 	}
 }
 
+// handlePing 回复客户端发起的Ping
+func (s *TCPServer) handlePing(conn *protocol.Connection, msg *types.Message) {
+	if err := conn.SendMessage(protocol.CreatePongMessage(msg.Header.SequenceID)); err != nil && !isConnectionClosedError(err) {
+		s.logger.Error("发送Pong失败", "conn_id", conn.ID, "error", err)
+	}
+}
+
 // handleHandshake 处理握手消息
 func (s *TCPServer) handleHandshake(conn *protocol.Connection, msg *types.Message) {
-	// 解析握手数据
-	gameID := msg.Header.GameID
-	userID := msg.Header.UserID
+	// 重复握手会改变连接身份，只允许一次
+	if conn.IsAuthenticated() {
+		s.logger.Warn("握手失败：连接已认证", "conn_id", conn.ID)
+		errorMsg := protocol.CreateErrorMessage(4001, "连接已认证", msg.Header.SequenceID)
+		conn.SendMessage(errorMsg)
+		return
+	}
+
+	// 解析握手请求体（未启用认证时旧客户端可能不携带消息体）
+	var req types.HandshakeRequest
+	if len(msg.Body) > 0 {
+		if err := json.Unmarshal(msg.Body, &req); err != nil {
+			s.logger.Warn("握手失败：握手数据格式错误", "conn_id", conn.ID, "error", err)
+			errorMsg := protocol.CreateErrorMessage(4001, "握手数据格式错误", msg.Header.SequenceID)
+			conn.SendMessage(errorMsg)
+			return
+		}
+	}
+
+	// 校验访问令牌或会话ID，连接身份以凭证为准；失败时连接保持未认证，超过握手超时后关闭
+	err := bindListenerGame(&msg.Header, conn.BoundGame())
+	var identity handshakeIdentity
+	if err == nil {
+		identity, err = s.authenticateHandshake(conn.Context(), &msg.Header, &req)
+	}
+	if err == nil {
+		err = s.checkGameStatus(identity.GameID)
+	}
+	if err != nil {
+		code, message := handshakeErrorCode(err)
+		s.logger.Warn("握手失败：认证未通过", "conn_id", conn.ID, "remote_addr", conn.Info.RemoteAddr, "game_id", msg.Header.GameID, "error", err)
+		conn.SendMessage(protocol.CreateErrorMessage(code, message, msg.Header.SequenceID))
+		return
+	}
+	gameID, userID := identity.GameID, identity.UserID
+
+	if req.Codec != "" {
+		if _, ok := protocol.DefaultCodecRegistry.ID(req.Codec); !ok {
+			s.logger.Warn("握手失败：不支持的编解码器", "conn_id", conn.ID, "codec", req.Codec)
+			errorMsg := protocol.CreateErrorMessage(4001, "不支持的编解码器", msg.Header.SequenceID)
+			conn.SendMessage(errorMsg)
+			return
+		}
+	}
 
-	if gameID == "" || userID == "" {
-		s.logger.Warn("握手失败：缺少游戏ID或用户ID", "conn_id", conn.ID)
-		errorMsg := protocol.CreateErrorMessage(4001, "缺少游戏ID或用户ID", msg.Header.SequenceID)
+	if s.config.TCP.Encryption.Required && req.KeyExchange == nil {
+		s.logger.Warn("握手失败：服务器要求加密连接", "conn_id", conn.ID)
+		errorMsg := protocol.CreateErrorMessage(4001, "服务器要求加密连接", msg.Header.SequenceID)
 		conn.SendMessage(errorMsg)
 		return
 	}
 
+	resp := &types.HandshakeResponse{
+		GameID: gameID,
+		UserID: userID,
+		Codec:  req.Codec,
+	}
+
+	// 密钥交换
+	var sessionCipher *protocol.SessionCipher
+	if req.KeyExchange != nil && s.identity != nil {
+		keyExchange, sc, err := s.identity.KeyExchange(req.KeyExchange, gameID, userID)
+		if err != nil {
+			s.logger.Warn("握手失败：密钥交换失败", "conn_id", conn.ID, "error", err)
+			errorMsg := protocol.CreateErrorMessage(4001, "密钥交换失败", msg.Header.SequenceID)
+			conn.SendMessage(errorMsg)
+			return
+		}
+		resp.KeyExchange = keyExchange
+		sessionCipher = sc
+	}
+
 	// 认证连接
 	conn.Authenticate(gameID, userID)
 
-	s.logger.Info("握手成功", "conn_id", conn.ID, "game_id", gameID, "user_id", userID)
+	// 协商压缩算法
+	compressor := protocol.NegotiateCompression(s.config.TCP.Compression, req.Compression)
+	if compressor != nil {
+		resp.Compression = compressor.Name()
+		resp.CompressionThreshold = s.config.TCP.Compression.Threshold
+	}
+
+	// 分配可恢复的会话，恢复之前的会话时在启用加密后补发断开期间的推送
+	var session *resumableSession
+	var resumed bool
+	if s.config.TCP.Resume.Enabled {
+		session, resumed = s.beginSession(&req, gameID, userID)
+		resp.ResumeToken = session.token
+		resp.ResumeWindow = s.config.TCP.Resume.Window.Milliseconds()
+		resp.Resumed = resumed
+	}
+
+	s.logger.Info("握手成功", "conn_id", conn.ID, "game_id", gameID, "user_id", userID, "compression", resp.Compression, "encrypted", sessionCipher != nil, "resumed", resumed)
 
-	// 回复握手成功
-	response := protocol.CreateHandshakeMessage(gameID, userID, msg.Header.SequenceID)
+	// 回复握手成功（握手响应本身不压缩、不加密）
+	response := protocol.CreateHandshakeMessage(resp, msg.Header.SequenceID)
 	conn.SendMessage(response)
+
+	if req.Codec != "" {
+		conn.SwitchCodec(req.Codec)
+	}
+	if compressor != nil {
+		conn.EnableCompression(compressor, resp.CompressionThreshold)
+	}
+	if sessionCipher != nil {
+		conn.EnableEncryption(sessionCipher)
+	}
+	if session != nil {
+		var lastPushSeq uint32
+		if resumed {
+			lastPushSeq = req.LastPushSeq
+		}
+		s.attachSession(session, conn, lastPushSeq)
+	}
 }
 
-// handlePlayerLogin 处理玩家登录
-func (s *TCPServer) handlePlayerLogin(conn *protocol.Connection, msg *types.Message) {
-	if !conn.IsAuthenticated() {
-		errorMsg := protocol.CreateErrorMessage(4002, "连接未认证", msg.Header.SequenceID)
-		conn.SendMessage(errorMsg)
+// handleGameMessage 处理游戏业务消息，通过消息路由器分发到对应游戏的处理器
+// 认证检查和消息头身份覆盖由AuthMiddleware完成
+func (s *TCPServer) handleGameMessage(conn *protocol.Connection, msg *types.Message) {
+	// 已超过客户端指定的截止时间，客户端不再等待结果
+	if msg.Header.DeadlineExceeded(time.Now()) {
+		s.logger.Warn("请求已超过截止时间", "conn_id", conn.ID, "type", msg.Header.Type, "trace_id", msg.Header.TraceID, "deadline", msg.Header.Deadline)
+		s.sendGameError(conn, msg, constants.ErrCodeTimeout, "请求已超时")
 		return
 	}
 
-	s.logger.Info("玩家登录", "conn_id", conn.ID, "game_id", conn.Info.GameID, "user_id", conn.Info.UserID)
+	// 排空进入关闭阶段后不再接受新的请求，先计数再检查，保证关闭连接前等待已计数的请求完成
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	if s.rejectWhileClosing() {
+		s.sendGameError(conn, msg, constants.ErrCodeServerDraining, "服务器正在关闭，请重新连接")
+		return
+	}
 
-	// TODO: 调用业务逻辑处理玩家登录
-	// 这里暂时只记录日志，实际实现会调用业务服务
-}
+	// 连接关闭时取消处理中的请求，客户端指定了截止时间时同时受其约束
+	ctx := router.WithClientIP(conn.Context(), conn.ClientIP())
+	if msg.Header.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(msg.Header.Deadline))
+		defer cancel()
+	}
 
-// handlePlayerLogout 处理玩家登出
-func (s *TCPServer) handlePlayerLogout(conn *protocol.Connection, msg *types.Message) {
-	if !conn.IsAuthenticated() {
-		errorMsg := protocol.CreateErrorMessage(4002, "连接未认证", msg.Header.SequenceID)
-		conn.SendMessage(errorMsg)
+	response, err := s.router.RouteTCPMessage(ctx, conn.ID, msg)
+	if err != nil {
+		s.logger.Error("处理游戏消息失败", "conn_id", conn.ID, "client_ip", conn.ClientIP(), "game_id", msg.Header.GameID, "type", msg.Header.Type, "trace_id", msg.Header.TraceID, "error", err)
+		s.sendGameError(conn, msg, constants.ErrCodeSystemInternal, "处理消息失败")
+		return
+	}
+	if response == nil {
 		return
 	}
 
-	s.logger.Info("玩家登出", "conn_id", conn.ID, "game_id", conn.Info.GameID, "user_id", conn.Info.UserID)
+	if err := conn.SendMessage(response); err != nil {
+		if isConnectionClosedError(err) {
+			s.logger.Debug("客户端已断开，跳过响应", "conn_id", conn.ID)
+		} else {
+			s.logger.Error("发送响应失败", "conn_id", conn.ID, "type", msg.Header.Type, "trace_id", msg.Header.TraceID, "error", err)
+		}
+	}
+}
 
-	// TODO: 调用业务逻辑处理玩家登出
-	// 这里暂时只记录日志，实际实现会调用业务服务
+// sendGameError 回复游戏消息处理错误，携带请求的链路追踪ID
+func (s *TCPServer) sendGameError(conn *protocol.Connection, msg *types.Message, code int, message string) {
+	errorMsg := protocol.CreateErrorMessage(code, message, msg.Header.SequenceID)
+	errorMsg.Header.TraceID = msg.Header.TraceID
+	conn.SendMessage(errorMsg)
 }
 
 // handleUnknownMessage 处理未知消息
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/internal/protocol"
+	"datamiddleware/internal/router"
+
+	"go.uber.org/zap"
+)
+
+// writeTestCertificate 生成localhost的自签名证书，返回证书和私钥文件路径
+func writeTestCertificate(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("生成私钥失败: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("生成证书失败: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func TestTCPServerTLS(t *testing.T) {
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+	certFile, keyFile := writeTestCertificate(t)
+
+	var config types.ServerConfig
+	config.TCP = types.TCPConfig{
+		Host:             "127.0.0.1",
+		Codec:            protocol.CodecBinary,
+		HandshakeTimeout: time.Second,
+		Engine:           protocol.EngineEpoll,
+		TLS:              types.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile},
+	}
+	tcp := NewTCPServer(config, router.NewMessageRouter(log), nil, nil, log)
+	if err := tcp.Start(); err != nil {
+		t.Fatalf("启动TCP服务器失败: %v", err)
+	}
+	defer tcp.Stop()
+	address := tcp.listener.Addr().String()
+
+	// 未完成TLS握手的连接不加入连接管理器
+	plain, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("建立连接失败: %v", err)
+	}
+	defer plain.Close()
+	plain.Write([]byte("not tls"))
+
+	roots := x509.NewCertPool()
+	pemData, _ := os.ReadFile(certFile)
+	roots.AppendCertsFromPEM(pemData)
+	client, err := tls.Dial("tcp", address, &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatalf("建立TLS连接失败: %v", err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+
+	codec := protocol.NewBinaryCodec()
+	frame, _ := codec.Encode(protocol.CreateHeartbeatMessage(9))
+	if _, err := client.Write(frame); err != nil {
+		t.Fatalf("发送心跳失败: %v", err)
+	}
+	reply := make([]byte, len(frame))
+	if _, err := io.ReadFull(client, reply); err != nil {
+		t.Fatalf("接收心跳回复失败: %v", err)
+	}
+	msg, _, err := codec.Decode(reply)
+	if err != nil || msg.Header.Type != types.MessageTypeHeartbeat || msg.Header.SequenceID != 9 {
+		t.Fatalf("心跳回复 = %+v, err=%v", msg, err)
+	}
+
+	// 启用TLS时回退到协程引擎
+	if tcp.engine != nil {
+		t.Error("启用TLS时不应使用事件循环引擎")
+	}
+	conns := tcp.connManager.GetAllConnections()
+	if len(conns) != 1 {
+		t.Fatalf("连接数 = %d, 期望 1", len(conns))
+	}
+	for _, conn := range conns {
+		if transport := conn.GetStats().Transport; transport != protocol.TransportTLS {
+			t.Errorf("传输方式 = %s, 期望 %s", transport, protocol.TransportTLS)
+		}
+	}
+}
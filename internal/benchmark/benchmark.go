@@ -219,10 +219,10 @@ func (br *BenchmarkRunner) cacheWorker(ctx context.Context, cache types.Cache, r
 			var err error
 			if atomic.LoadInt64(&result.TotalRequests)%2 == 0 {
 				// 写操作
-				err = cache.Set(key, value)
+				err = cache.Set(ctx, key, value)
 			} else {
 				// 读操作
-				_, err = cache.Get(key)
+				_, err = cache.Get(ctx, key)
 			}
 
 			responseTime := time.Since(start)
@@ -311,7 +311,7 @@ func (br *BenchmarkRunner) warmupCache(cache types.Cache, duration time.Duration
 				case <-ticker.C:
 					key := fmt.Sprintf("warmup_key_%d_%d", workerID, time.Now().UnixNano())
 					value := []byte(fmt.Sprintf("warmup_value_%d", time.Now().UnixNano()))
-					cache.Set(key, value)
+					cache.Set(ctx, key, value)
 				}
 			}
 		}(i)
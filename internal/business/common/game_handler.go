@@ -1,7 +1,7 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 
 	"datamiddleware/internal/infrastructure/logging"
@@ -13,52 +13,40 @@ type GameHandler struct {
 	playerService *PlayerService
 	itemService   *ItemService
 	orderService  *OrderService
+	operations    *OperationRegistry
 	logger        logger.Logger
 	gameID        string
 }
 
 // NewGameHandler 创建游戏处理器
 func NewGameHandler(gameID string, playerService *PlayerService, itemService *ItemService, orderService *OrderService, log logger.Logger) *GameHandler {
-	return &GameHandler{
+	h := &GameHandler{
 		playerService: playerService,
 		itemService:   itemService,
 		orderService:  orderService,
+		operations:    NewOperationRegistry(log),
 		logger:        log,
 		gameID:        gameID,
 	}
+	h.registerOperations()
+	return h
 }
 
 // Handle 处理游戏请求
-func (h *GameHandler) Handle(gameID string, req *types.Request) (*types.Response, error) {
+func (h *GameHandler) Handle(ctx context.Context, gameID string, req *types.Request) (*types.Response, error) {
 	h.logger.Debug("处理游戏请求", "game_id", gameID, "type", req.Type, "user_id", req.UserID)
 
-	switch req.Type {
-	case types.MessageTypePlayerLogin:
-		return h.handlePlayerLogin(req)
-	case types.MessageTypePlayerLogout:
-		return h.handlePlayerLogout(req)
-	case types.MessageTypeItemOperation:
-		return h.handleItemOperation(req)
-	case types.MessageTypeOrderOperation:
-		return h.handleOrderOperation(req)
-	default:
-		return &types.Response{
-			ID:        req.ID,
-			Code:      4001,
-			Message:   "不支持的消息类型",
-			Timestamp: req.Timestamp,
-		}, nil
-	}
+	return h.operations.Dispatch(ctx, req), nil
 }
 
 // GetSupportedMessageTypes 获取支持的消息类型
 func (h *GameHandler) GetSupportedMessageTypes() []types.MessageType {
-	return []types.MessageType{
-		types.MessageTypePlayerLogin,
-		types.MessageTypePlayerLogout,
-		types.MessageTypeItemOperation,
-		types.MessageTypeOrderOperation,
-	}
+	return h.operations.MessageTypes()
+}
+
+// GetOperations 获取支持的操作
+func (h *GameHandler) GetOperations() []types.OperationInfo {
+	return h.operations.Operations()
 }
 
 // GetName 获取处理器名称
@@ -66,298 +54,137 @@ func (h *GameHandler) GetName() string {
 	return fmt.Sprintf("GameHandler-%s", h.gameID)
 }
 
-// handlePlayerLogin 处理玩家登录
-func (h *GameHandler) handlePlayerLogin(req *types.Request) (*types.Response, error) {
-	var loginReq struct {
-		UserID   string `json:"user_id"`
-		DeviceID string `json:"device_id"`
-		Platform string `json:"platform"`
-		Version  string `json:"version"`
-	}
-
-	data, ok := req.Data.([]byte)
-	if !ok {
-		return &types.Response{
-			ID:        req.ID,
-			Code:      4002,
-			Message:   "请求数据格式错误",
-			Timestamp: req.Timestamp,
-		}, nil
-	}
-	if err := json.Unmarshal(data, &loginReq); err != nil {
-		h.logger.Error("解析登录请求失败", "error", err)
-		return &types.Response{
-			ID:        req.ID,
-			Code:      4002,
-			Message:   "请求数据格式错误",
-			Timestamp: req.Timestamp,
-		}, nil
-	}
-
-	// 调用玩家服务登录
-	result, err := h.playerService.LoginPlayer(loginReq.UserID, req.GameID, loginReq.DeviceID, loginReq.Platform, loginReq.Version)
-	if err != nil {
-		h.logger.Error("玩家登录失败", "user_id", loginReq.UserID, "error", err)
-		return &types.Response{
-			ID:        req.ID,
-			Code:      5001,
-			Message:   fmt.Sprintf("登录失败: %v", err),
-			Timestamp: req.Timestamp,
-		}, nil
-	}
-
-	return &types.Response{
-		ID:        req.ID,
-		Code:      0,
-		Message:   "登录成功",
-		Data:      result,
-		Timestamp: req.Timestamp,
-	}, nil
+// registerOperations 注册游戏支持的操作，新增操作时在这里注册请求体和处理函数
+func (h *GameHandler) registerOperations() {
+	r := h.operations
+
+	RegisterOperation(r, types.MessageTypePlayerLogin, "", OperationSpec{
+		Description: "玩家登录", SuccessMessage: "登录成功", FailureCode: 5001, FailureMessage: "登录失败",
+	}, h.playerLogin)
+	RegisterOperation(r, types.MessageTypePlayerLogout, "", OperationSpec{
+		Description: "玩家登出", SuccessMessage: "登出成功", FailureCode: 5002, FailureMessage: "登出失败",
+	}, h.playerLogout)
+
+	RegisterOperation(r, types.MessageTypeItemOperation, "create", OperationSpec{
+		Description: "创建道具", SuccessMessage: "创建道具成功", FailureCode: 5003, FailureMessage: "创建道具失败",
+	}, h.createItem)
+	RegisterOperation(r, types.MessageTypeItemOperation, "consume", OperationSpec{
+		Description: "消耗道具", SuccessMessage: "消耗道具成功", FailureCode: 5004, FailureMessage: "消耗道具失败",
+	}, h.consumeItem)
+	RegisterOperation(r, types.MessageTypeItemOperation, "transfer", OperationSpec{
+		Description: "转移道具", SuccessMessage: "转移道具成功", FailureCode: 5005, FailureMessage: "转移道具失败",
+	}, h.transferItem)
+
+	RegisterOperation(r, types.MessageTypeOrderOperation, "create", OperationSpec{
+		Description: "创建订单", SuccessMessage: "创建订单成功", FailureCode: 5006, FailureMessage: "创建订单失败",
+	}, h.createOrder)
+	RegisterOperation(r, types.MessageTypeOrderOperation, "pay", OperationSpec{
+		Description: "处理支付", SuccessMessage: "支付成功", FailureCode: 5007, FailureMessage: "处理支付失败",
+	}, h.payOrder)
+	RegisterOperation(r, types.MessageTypeOrderOperation, "cancel", OperationSpec{
+		Description: "取消订单", SuccessMessage: "取消订单成功", FailureCode: 5008, FailureMessage: "取消订单失败",
+	}, h.cancelOrder)
 }
 
-// handlePlayerLogout 处理玩家登出
-func (h *GameHandler) handlePlayerLogout(req *types.Request) (*types.Response, error) {
-	var logoutReq struct {
-		UserID    string `json:"user_id"`
-		SessionID string `json:"session_id"`
-	}
+// playerLoginRequest 玩家登录请求
+type playerLoginRequest struct {
+	UserID   string `json:"user_id" validate:"required"`
+	DeviceID string `json:"device_id"`
+	Platform string `json:"platform"`
+	Version  string `json:"version"`
+}
 
-	data, ok := req.Data.([]byte)
-	if !ok {
-		return &types.Response{
-			ID:        req.ID,
-			Code:      4002,
-			Message:   "请求数据格式错误",
-			Timestamp: req.Timestamp,
-		}, nil
-	}
-	if err := json.Unmarshal(data, &logoutReq); err != nil {
-		h.logger.Error("解析登出请求失败", "error", err)
-		return &types.Response{
-			ID:        req.ID,
-			Code:      4002,
-			Message:   "请求数据格式错误",
-			Timestamp: req.Timestamp,
-		}, nil
-	}
+// playerLogin 处理玩家登录
+func (h *GameHandler) playerLogin(ctx context.Context, req *types.Request, p *playerLoginRequest) (interface{}, error) {
+	return h.playerService.LoginPlayer(ctx, p.UserID, req.GameID, p.DeviceID, p.Platform, p.Version, req.ClientIP)
+}
 
-	// 调用玩家服务登出
-	if err := h.playerService.LogoutPlayer(logoutReq.UserID, logoutReq.SessionID); err != nil {
-		h.logger.Error("玩家登出失败", "user_id", logoutReq.UserID, "error", err)
-		return &types.Response{
-			ID:        req.ID,
-			Code:      5002,
-			Message:   fmt.Sprintf("登出失败: %v", err),
-			Timestamp: req.Timestamp,
-		}, nil
-	}
+// playerLogoutRequest 玩家登出请求
+type playerLogoutRequest struct {
+	UserID    string `json:"user_id" validate:"required"`
+	SessionID string `json:"session_id" validate:"required"`
+}
 
-	return &types.Response{
-		ID:        req.ID,
-		Code:      0,
-		Message:   "登出成功",
-		Timestamp: req.Timestamp,
-	}, nil
+// playerLogout 处理玩家登出
+func (h *GameHandler) playerLogout(ctx context.Context, req *types.Request, p *playerLogoutRequest) (interface{}, error) {
+	return nil, h.playerService.LogoutPlayer(ctx, p.UserID, p.SessionID)
 }
 
-// handleItemOperation 处理道具操作
-func (h *GameHandler) handleItemOperation(req *types.Request) (*types.Response, error) {
-	var itemReq struct {
-		UserID     string `json:"user_id"`
-		Operation  string `json:"operation"` // create, consume, transfer
-		ItemID     string `json:"item_id,omitempty"`
-		Name       string `json:"name,omitempty"`
-		Type       string `json:"type,omitempty"`
-		Category   string `json:"category,omitempty"`
-		Quantity   int64  `json:"quantity,omitempty"`
-		ToUserID   string `json:"to_user_id,omitempty"`
-	}
+// createItemRequest 创建道具请求
+type createItemRequest struct {
+	UserID   string `json:"user_id" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	Type     string `json:"type"`
+	Category string `json:"category"`
+	Quantity int64  `json:"quantity" validate:"gt=0"`
+}
 
-	data, ok := req.Data.([]byte)
-	if !ok {
-		return &types.Response{
-			ID:        req.ID,
-			Code:      4002,
-			Message:   "请求数据格式错误",
-			Timestamp: req.Timestamp,
-		}, nil
-	}
-	if err := json.Unmarshal(data, &itemReq); err != nil {
-		h.logger.Error("解析道具操作请求失败", "error", err)
-		return &types.Response{
-			ID:        req.ID,
-			Code:      4002,
-			Message:   "请求数据格式错误",
-			Timestamp: req.Timestamp,
-		}, nil
-	}
+// createItem 创建道具
+func (h *GameHandler) createItem(ctx context.Context, req *types.Request, p *createItemRequest) (interface{}, error) {
+	return h.itemService.CreateItem(ctx, p.UserID, req.GameID, p.Name, p.Type, p.Category, p.Quantity)
+}
 
-	switch itemReq.Operation {
-	case "create":
-		item, err := h.itemService.CreateItem(itemReq.UserID, req.GameID, itemReq.Name, itemReq.Type, itemReq.Category, itemReq.Quantity)
-		if err != nil {
-			h.logger.Error("创建道具失败", "user_id", itemReq.UserID, "error", err)
-			return &types.Response{
-				ID:        req.ID,
-				Code:      5003,
-				Message:   fmt.Sprintf("创建道具失败: %v", err),
-				Timestamp: req.Timestamp,
-			}, nil
-		}
-		return &types.Response{
-			ID:        req.ID,
-			Code:      0,
-			Message:   "创建道具成功",
-			Data:      item,
-			Timestamp: req.Timestamp,
-		}, nil
+// consumeItemRequest 消耗道具请求
+type consumeItemRequest struct {
+	ItemID   string `json:"item_id" validate:"required"`
+	Quantity int64  `json:"quantity" validate:"gt=0"`
+}
 
-	case "consume":
-		if err := h.itemService.ConsumeItem(itemReq.ItemID, itemReq.Quantity); err != nil {
-			h.logger.Error("消耗道具失败", "item_id", itemReq.ItemID, "error", err)
-			return &types.Response{
-				ID:        req.ID,
-				Code:      5004,
-				Message:   fmt.Sprintf("消耗道具失败: %v", err),
-				Timestamp: req.Timestamp,
-			}, nil
-		}
-		return &types.Response{
-			ID:        req.ID,
-			Code:      0,
-			Message:   "消耗道具成功",
-			Timestamp: req.Timestamp,
-		}, nil
+// consumeItem 消耗道具
+func (h *GameHandler) consumeItem(ctx context.Context, req *types.Request, p *consumeItemRequest) (interface{}, error) {
+	return nil, h.itemService.ConsumeItem(ctx, p.ItemID, p.Quantity)
+}
 
-	case "transfer":
-		if err := h.itemService.TransferItem(itemReq.ItemID, itemReq.UserID, itemReq.ToUserID, itemReq.Quantity); err != nil {
-			h.logger.Error("转移道具失败", "item_id", itemReq.ItemID, "error", err)
-			return &types.Response{
-				ID:        req.ID,
-				Code:      5005,
-				Message:   fmt.Sprintf("转移道具失败: %v", err),
-				Timestamp: req.Timestamp,
-			}, nil
-		}
-		return &types.Response{
-			ID:        req.ID,
-			Code:      0,
-			Message:   "转移道具成功",
-			Timestamp: req.Timestamp,
-		}, nil
+// transferItemRequest 转移道具请求
+type transferItemRequest struct {
+	UserID   string `json:"user_id" validate:"required"`
+	ItemID   string `json:"item_id" validate:"required"`
+	ToUserID string `json:"to_user_id" validate:"required,nefield=UserID"`
+	Quantity int64  `json:"quantity" validate:"gt=0"`
+}
 
-	default:
-		return &types.Response{
-			ID:        req.ID,
-			Code:      4003,
-			Message:   "不支持的道具操作",
-			Timestamp: req.Timestamp,
-		}, nil
-	}
+// transferItem 转移道具
+func (h *GameHandler) transferItem(ctx context.Context, req *types.Request, p *transferItemRequest) (interface{}, error) {
+	return nil, h.itemService.TransferItem(ctx, p.ItemID, p.UserID, p.ToUserID, p.Quantity)
 }
 
-// handleOrderOperation 处理订单操作
-func (h *GameHandler) handleOrderOperation(req *types.Request) (*types.Response, error) {
-	var orderReq struct {
-		UserID        string `json:"user_id"`
-		Operation     string `json:"operation"` // create, pay, cancel
-		OrderID       string `json:"order_id,omitempty"`
-		ProductID     string `json:"product_id,omitempty"`
-		ProductName   string `json:"product_name,omitempty"`
-		Amount        int64  `json:"amount,omitempty"`
-		Currency      string `json:"currency,omitempty"`
-		PaymentMethod string `json:"payment_method,omitempty"`
-		Channel       string `json:"channel,omitempty"`
-		TransactionID string `json:"transaction_id,omitempty"`
-	}
+// createOrderRequest 创建订单请求
+type createOrderRequest struct {
+	UserID        string `json:"user_id" validate:"required"`
+	ProductID     string `json:"product_id" validate:"required"`
+	ProductName   string `json:"product_name"`
+	Amount        int64  `json:"amount" validate:"gt=0"`
+	Currency      string `json:"currency"`
+	PaymentMethod string `json:"payment_method"`
+	Channel       string `json:"channel"`
+}
 
-	data, ok := req.Data.([]byte)
-	if !ok {
-		return &types.Response{
-			ID:        req.ID,
-			Code:      4002,
-			Message:   "请求数据格式错误",
-			Timestamp: req.Timestamp,
-		}, nil
-	}
-	if err := json.Unmarshal(data, &orderReq); err != nil {
-		h.logger.Error("解析订单操作请求失败", "error", err)
-		return &types.Response{
-			ID:        req.ID,
-			Code:      4002,
-			Message:   "请求数据格式错误",
-			Timestamp: req.Timestamp,
-		}, nil
-	}
+// createOrder 创建订单
+func (h *GameHandler) createOrder(ctx context.Context, req *types.Request, p *createOrderRequest) (interface{}, error) {
+	return h.orderService.CreateOrder(ctx,
+		p.UserID, req.GameID, p.ProductID, p.ProductName,
+		p.Amount, p.Currency, p.PaymentMethod,
+		p.Channel, req.ClientIP, "", // DeviceID暂时为空
+	)
+}
 
-	switch orderReq.Operation {
-	case "create":
-		order, err := h.orderService.CreateOrder(
-			orderReq.UserID, req.GameID, orderReq.ProductID, orderReq.ProductName,
-			orderReq.Amount, orderReq.Currency, orderReq.PaymentMethod,
-			orderReq.Channel, "", "", // IP和DeviceID暂时为空
-		)
-		if err != nil {
-			h.logger.Error("创建订单失败", "user_id", orderReq.UserID, "error", err)
-			return &types.Response{
-				ID:        req.ID,
-				Code:      5006,
-				Message:   fmt.Sprintf("创建订单失败: %v", err),
-				Timestamp: req.Timestamp,
-			}, nil
-		}
-		return &types.Response{
-			ID:        req.ID,
-			Code:      0,
-			Message:   "创建订单成功",
-			Data:      order,
-			Timestamp: req.Timestamp,
-		}, nil
+// payOrderRequest 处理支付请求
+type payOrderRequest struct {
+	OrderID       string `json:"order_id" validate:"required"`
+	TransactionID string `json:"transaction_id"`
+}
 
-	case "pay":
-		order, err := h.orderService.ProcessPayment(orderReq.OrderID, orderReq.TransactionID)
-		if err != nil {
-			h.logger.Error("处理支付失败", "order_id", orderReq.OrderID, "error", err)
-			return &types.Response{
-				ID:        req.ID,
-				Code:      5007,
-				Message:   fmt.Sprintf("处理支付失败: %v", err),
-				Timestamp: req.Timestamp,
-			}, nil
-		}
-		return &types.Response{
-			ID:        req.ID,
-			Code:      0,
-			Message:   "支付成功",
-			Data:      order,
-			Timestamp: req.Timestamp,
-		}, nil
+// payOrder 处理支付
+func (h *GameHandler) payOrder(ctx context.Context, req *types.Request, p *payOrderRequest) (interface{}, error) {
+	return h.orderService.ProcessPayment(ctx, p.OrderID, p.TransactionID)
+}
 
-	case "cancel":
-		order, err := h.orderService.CancelOrder(orderReq.OrderID)
-		if err != nil {
-			h.logger.Error("取消订单失败", "order_id", orderReq.OrderID, "error", err)
-			return &types.Response{
-				ID:        req.ID,
-				Code:      5008,
-				Message:   fmt.Sprintf("取消订单失败: %v", err),
-				Timestamp: req.Timestamp,
-			}, nil
-		}
-		return &types.Response{
-			ID:        req.ID,
-			Code:      0,
-			Message:   "取消订单成功",
-			Data:      order,
-			Timestamp: req.Timestamp,
-		}, nil
+// cancelOrderRequest 取消订单请求
+type cancelOrderRequest struct {
+	OrderID string `json:"order_id" validate:"required"`
+}
 
-	default:
-		return &types.Response{
-			ID:        req.ID,
-			Code:      4003,
-			Message:   "不支持的订单操作",
-			Timestamp: req.Timestamp,
-		}, nil
-	}
+// cancelOrder 取消订单
+func (h *GameHandler) cancelOrder(ctx context.Context, req *types.Request, p *cancelOrderRequest) (interface{}, error) {
+	return h.orderService.CancelOrder(ctx, p.OrderID)
 }
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"datamiddleware/internal/common/types"
+	daoPkg "datamiddleware/internal/data/dao"
+	loggingInfra "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/internal/router"
+)
+
+// 游戏管理错误
+var (
+	ErrGameNotFound      = errors.New("游戏不存在")
+	ErrGameAlreadyExists = errors.New("游戏已存在")
+	ErrInvalidGameStatus = errors.New("无效的游戏状态")
+)
+
+// GameHandlerFactory 为游戏创建处理器，游戏开放时注册到路由器
+type GameHandlerFactory func(gameID string) router.GameHandler
+
+// GameStatusListener 游戏生效的运行状态变化时调用，首次加载时oldStatus为空
+type GameStatusListener func(gameID, oldStatus, newStatus string)
+
+// GameState 游戏表记录及当前生效的运行状态
+type GameState struct {
+	*daoPkg.Game
+	AppliedStatus string `json:"applied_status"` // 当前生效的状态，游戏表被外部修改后需要重新加载才会生效
+}
+
+// GameService 游戏运行状态管理服务
+// 游戏表是状态的唯一来源：未下线的游戏注册处理器，下线的游戏注销处理器，状态变化通知订阅者（如TCP服务器断开该游戏的连接）
+type GameService struct {
+	dao        daoPkg.DAO
+	router     *router.Router
+	factory    GameHandlerFactory
+	logger     loggingInfra.Logger
+	configured []types.GameConfig // 最近一次同步的游戏配置
+	statuses   map[string]string  // 游戏ID -> 生效的状态
+	listeners  []GameStatusListener
+	mu         sync.RWMutex
+	changeMu   sync.Mutex // 串行化加载和状态修改
+}
+
+// NewGameService 创建游戏管理服务
+func NewGameService(dao daoPkg.DAO, gameRouter *router.Router, factory GameHandlerFactory, log loggingInfra.Logger) *GameService {
+	return &GameService{
+		dao:      dao,
+		router:   gameRouter,
+		factory:  factory,
+		logger:   log,
+		statuses: make(map[string]string),
+	}
+}
+
+// OnStatusChange 订阅游戏状态变化，必须在Sync之前调用
+func (s *GameService) OnStatusChange(listener GameStatusListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// Sync 将配置中游戏表尚未记录的游戏写入游戏表（启用的为active，否则为offline），然后按游戏表应用所有游戏的状态
+// 启动和配置文件变化时调用，已记录的游戏以游戏表为准
+func (s *GameService) Sync(ctx context.Context, games []types.GameConfig) error {
+	s.changeMu.Lock()
+	defer s.changeMu.Unlock()
+
+	s.configured = games
+	return s.load(ctx)
+}
+
+// Reload 重新读取游戏表并应用状态，游戏表被其他实例或手工修改后调用
+func (s *GameService) Reload(ctx context.Context) error {
+	s.changeMu.Lock()
+	defer s.changeMu.Unlock()
+
+	return s.load(ctx)
+}
+
+// SetStatus 修改游戏状态并立即生效
+func (s *GameService) SetStatus(ctx context.Context, gameID, status string) error {
+	if !isValidGameStatus(status) {
+		return fmt.Errorf("%w: %s", ErrInvalidGameStatus, status)
+	}
+
+	s.changeMu.Lock()
+	defer s.changeMu.Unlock()
+
+	game, err := s.dao.GetGameByID(ctx, gameID)
+	if err != nil {
+		return fmt.Errorf("查询游戏失败: %w", err)
+	}
+	if game == nil {
+		return ErrGameNotFound
+	}
+	if game.Status != status {
+		game.Status = status
+		if err := s.dao.UpdateGame(ctx, game); err != nil {
+			return fmt.Errorf("更新游戏状态失败: %w", err)
+		}
+	}
+
+	s.apply(gameID, status)
+	return nil
+}
+
+// CreateGame 在游戏表中新增游戏并立即生效
+func (s *GameService) CreateGame(ctx context.Context, gameID, name, status string) (*daoPkg.Game, error) {
+	if status == "" {
+		status = types.GameStatusActive
+	}
+	if !isValidGameStatus(status) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidGameStatus, status)
+	}
+
+	s.changeMu.Lock()
+	defer s.changeMu.Unlock()
+
+	existing, err := s.dao.GetGameByID(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("查询游戏失败: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrGameAlreadyExists
+	}
+
+	game := &daoPkg.Game{GameID: gameID, Name: name, Status: status, IsVisible: true}
+	if err := s.dao.CreateGame(ctx, game); err != nil {
+		return nil, fmt.Errorf("创建游戏失败: %w", err)
+	}
+
+	s.apply(gameID, status)
+	return game, nil
+}
+
+// GameStatus 返回游戏当前生效的状态，游戏不存在时ok为false
+func (s *GameService) GameStatus(gameID string) (status string, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status, ok = s.statuses[gameID]
+	return status, ok
+}
+
+// ListGames 返回游戏表中的所有游戏及其生效的状态
+func (s *GameService) ListGames(ctx context.Context) ([]GameState, error) {
+	games, err := s.listAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	states := make([]GameState, 0, len(games))
+	for _, game := range games {
+		states = append(states, GameState{Game: game, AppliedStatus: s.statuses[game.GameID]})
+	}
+	return states, nil
+}
+
+// load 补录配置中新增的游戏，然后按游戏表应用状态，游戏表中已删除的游戏按下线处理
+func (s *GameService) load(ctx context.Context) error {
+	for _, game := range s.configured {
+		existing, err := s.dao.GetGameByID(ctx, game.ID)
+		if err != nil {
+			return fmt.Errorf("查询游戏 %s 失败: %w", game.ID, err)
+		}
+		if existing != nil {
+			continue
+		}
+
+		status := types.GameStatusOffline
+		if game.Enabled {
+			status = types.GameStatusActive
+		}
+		if err := s.dao.CreateGame(ctx, &daoPkg.Game{GameID: game.ID, Name: game.Name, Status: status, IsVisible: true}); err != nil {
+			return fmt.Errorf("写入游戏 %s 失败: %w", game.ID, err)
+		}
+		s.logger.Info("配置中的游戏已写入游戏表", "game_id", game.ID, "status", status)
+	}
+
+	games, err := s.listAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(games))
+	for _, game := range games {
+		seen[game.GameID] = true
+		status := game.Status
+		if !isValidGameStatus(status) {
+			s.logger.Warn("游戏表中的状态无效，按下线处理", "game_id", game.GameID, "status", status)
+			status = types.GameStatusOffline
+		}
+		s.apply(game.GameID, status)
+	}
+
+	s.mu.RLock()
+	var removed []string
+	for gameID := range s.statuses {
+		if !seen[gameID] {
+			removed = append(removed, gameID)
+		}
+	}
+	s.mu.RUnlock()
+	for _, gameID := range removed {
+		s.apply(gameID, types.GameStatusOffline)
+	}
+	return nil
+}
+
+// listAll 分页读取游戏表中的所有游戏
+func (s *GameService) listAll(ctx context.Context) ([]*daoPkg.Game, error) {
+	const pageSize = 100
+
+	var games []*daoPkg.Game
+	for offset := 0; ; offset += pageSize {
+		page, _, err := s.dao.ListGames(ctx, offset, pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("读取游戏表失败: %w", err)
+		}
+		games = append(games, page...)
+		if len(page) < pageSize {
+			return games, nil
+		}
+	}
+}
+
+// apply 使游戏状态生效：按状态注册或注销处理器，状态变化时通知订阅者
+func (s *GameService) apply(gameID, status string) {
+	s.mu.Lock()
+	oldStatus, known := s.statuses[gameID]
+	if known && oldStatus == status {
+		s.mu.Unlock()
+		return
+	}
+	s.statuses[gameID] = status
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	_, registered := s.router.GetHandler(gameID)
+	switch {
+	case status == types.GameStatusOffline && registered:
+		s.router.UnregisterHandler(gameID)
+	case status != types.GameStatusOffline && !registered:
+		if err := s.router.RegisterHandler(gameID, s.factory(gameID)); err != nil {
+			s.logger.Error("注册游戏处理器失败", "game_id", gameID, "error", err)
+		}
+	}
+
+	s.logger.Info("游戏状态已生效", "game_id", gameID, "old_status", oldStatus, "status", status)
+	for _, listener := range listeners {
+		listener(gameID, oldStatus, status)
+	}
+}
+
+// isValidGameStatus 检查是否为有效的游戏状态
+func isValidGameStatus(status string) bool {
+	switch status {
+	case types.GameStatusActive, types.GameStatusMaintenance, types.GameStatusOffline:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"datamiddleware/internal/common/types"
+	daoPkg "datamiddleware/internal/data/dao"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/internal/router"
+
+	"go.uber.org/zap"
+)
+
+// fakeGameDAO 内存中的游戏表，只实现游戏相关的方法
+type fakeGameDAO struct {
+	daoPkg.DAO
+	games map[string]*daoPkg.Game
+}
+
+func (f *fakeGameDAO) CreateGame(ctx context.Context, game *daoPkg.Game) error {
+	copied := *game
+	f.games[game.GameID] = &copied
+	return nil
+}
+
+func (f *fakeGameDAO) GetGameByID(ctx context.Context, gameID string) (*daoPkg.Game, error) {
+	game, ok := f.games[gameID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *game
+	return &copied, nil
+}
+
+func (f *fakeGameDAO) UpdateGame(ctx context.Context, game *daoPkg.Game) error {
+	copied := *game
+	f.games[game.GameID] = &copied
+	return nil
+}
+
+func (f *fakeGameDAO) ListGames(ctx context.Context, offset, limit int) ([]*daoPkg.Game, int64, error) {
+	var games []*daoPkg.Game
+	for _, game := range f.games {
+		copied := *game
+		games = append(games, &copied)
+	}
+	if offset >= len(games) {
+		return nil, int64(len(games)), nil
+	}
+	return games[offset:min(offset+limit, len(games))], int64(len(games)), nil
+}
+
+// stubHandler 不处理任何消息的游戏处理器
+type stubHandler struct{ gameID string }
+
+func (h stubHandler) Handle(context.Context, string, *types.Request) (*types.Response, error) {
+	return nil, nil
+}
+func (h stubHandler) GetSupportedMessageTypes() []types.MessageType { return nil }
+func (h stubHandler) GetName() string                               { return h.gameID }
+
+func TestGameService(t *testing.T) {
+	ctx := context.Background()
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+	dao := &fakeGameDAO{games: map[string]*daoPkg.Game{
+		// 游戏表中已有的游戏以游戏表为准
+		"game2": {GameID: "game2", Status: types.GameStatusMaintenance},
+	}}
+	gameRouter := router.NewRouter(log)
+	service := NewGameService(dao, gameRouter, func(gameID string) router.GameHandler {
+		return stubHandler{gameID: gameID}
+	}, log)
+
+	type change struct{ gameID, oldStatus, newStatus string }
+	var changes []change
+	service.OnStatusChange(func(gameID, oldStatus, newStatus string) {
+		changes = append(changes, change{gameID, oldStatus, newStatus})
+	})
+
+	err := service.Sync(ctx, []types.GameConfig{
+		{ID: "game1", Enabled: true},
+		{ID: "game2", Enabled: true},
+		{ID: "game3", Enabled: false},
+	})
+	if err != nil {
+		t.Fatalf("同步游戏失败: %v", err)
+	}
+
+	wantStatus := map[string]string{
+		"game1": types.GameStatusActive,
+		"game2": types.GameStatusMaintenance,
+		"game3": types.GameStatusOffline,
+	}
+	for gameID, want := range wantStatus {
+		if status, ok := service.GameStatus(gameID); !ok || status != want {
+			t.Errorf("%s 状态 = %s, 期望 %s", gameID, status, want)
+		}
+		if dao.games[gameID].Status != want {
+			t.Errorf("%s 游戏表状态 = %s, 期望 %s", gameID, dao.games[gameID].Status, want)
+		}
+	}
+	assertRegistered := func(gameID string, want bool) {
+		t.Helper()
+		if _, ok := gameRouter.GetHandler(gameID); ok != want {
+			t.Errorf("%s 处理器已注册 = %v, 期望 %v", gameID, ok, want)
+		}
+	}
+	assertRegistered("game1", true)
+	assertRegistered("game2", true)
+	assertRegistered("game3", false)
+	if len(changes) != 3 {
+		t.Errorf("首次加载的状态变化 = %v", changes)
+	}
+
+	// 修改状态写入游戏表，下线时注销处理器
+	changes = nil
+	if err := service.SetStatus(ctx, "game1", types.GameStatusOffline); err != nil {
+		t.Fatalf("修改状态失败: %v", err)
+	}
+	assertRegistered("game1", false)
+	if dao.games["game1"].Status != types.GameStatusOffline {
+		t.Errorf("游戏表状态 = %s", dao.games["game1"].Status)
+	}
+	if len(changes) != 1 || changes[0] != (change{"game1", types.GameStatusActive, types.GameStatusOffline}) {
+		t.Errorf("状态变化 = %v", changes)
+	}
+
+	// 状态未变化时不通知
+	changes = nil
+	service.SetStatus(ctx, "game1", types.GameStatusOffline)
+	if len(changes) != 0 {
+		t.Errorf("状态未变化时不应通知: %v", changes)
+	}
+
+	if err := service.SetStatus(ctx, "game1", "closed"); !errors.Is(err, ErrInvalidGameStatus) {
+		t.Errorf("无效状态错误 = %v", err)
+	}
+	if err := service.SetStatus(ctx, "game9", types.GameStatusActive); !errors.Is(err, ErrGameNotFound) {
+		t.Errorf("游戏不存在错误 = %v", err)
+	}
+
+	// 新增游戏立即生效
+	if _, err := service.CreateGame(ctx, "game4", "游戏4", ""); err != nil {
+		t.Fatalf("新增游戏失败: %v", err)
+	}
+	assertRegistered("game4", true)
+	if _, err := service.CreateGame(ctx, "game4", "游戏4", ""); !errors.Is(err, ErrGameAlreadyExists) {
+		t.Errorf("重复新增错误 = %v", err)
+	}
+
+	// 游戏表被外部修改后重新加载生效
+	dao.games["game3"].Status = types.GameStatusActive
+	delete(dao.games, "game4")
+	if err := service.Reload(ctx); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	assertRegistered("game3", true)
+	assertRegistered("game4", false)
+	if status, _ := service.GameStatus("game4"); status != types.GameStatusOffline {
+		t.Errorf("已删除的游戏状态 = %s, 期望 offline", status)
+	}
+}
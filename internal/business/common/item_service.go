@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -24,7 +25,7 @@ func NewItemService(dao daoPkg.DAO, log loggingInfra.Logger) *ItemService {
 }
 
 // CreateItem 创建道具
-func (s *ItemService) CreateItem(userID, gameID, name, itemType, category string, quantity int64) (*types.Item, error) {
+func (s *ItemService) CreateItem(ctx context.Context, userID, gameID, name, itemType, category string, quantity int64) (*types.Item, error) {
 	// 生成道具ID
 	itemID := s.generateItemID()
 
@@ -43,7 +44,7 @@ func (s *ItemService) CreateItem(userID, gameID, name, itemType, category string
 		Description: fmt.Sprintf("%s 道具", name),
 	}
 
-	if err := s.dao.CreateItem(item); err != nil {
+	if err := s.dao.CreateItem(ctx, item); err != nil {
 		s.logger.Error("创建道具失败", "item_id", itemID, "user_id", userID, "error", err)
 		return nil, fmt.Errorf("创建道具失败: %w", err)
 	}
@@ -53,8 +54,8 @@ func (s *ItemService) CreateItem(userID, gameID, name, itemType, category string
 }
 
 // GetItem 获取道具信息
-func (s *ItemService) GetItem(itemID string) (*types.Item, error) {
-	item, err := s.dao.GetItemByID(itemID)
+func (s *ItemService) GetItem(ctx context.Context, itemID string) (*types.Item, error) {
+	item, err := s.dao.GetItemByID(ctx, itemID)
 	if err != nil {
 		s.logger.Error("获取道具信息失败", "item_id", itemID, "error", err)
 		return nil, fmt.Errorf("获取道具信息失败: %w", err)
@@ -67,8 +68,8 @@ func (s *ItemService) GetItem(itemID string) (*types.Item, error) {
 }
 
 // GetUserItems 获取用户道具列表
-func (s *ItemService) GetUserItems(userID, gameID string) ([]*types.Item, error) {
-	items, err := s.dao.GetUserItems(userID, gameID)
+func (s *ItemService) GetUserItems(ctx context.Context, userID, gameID string) ([]*types.Item, error) {
+	items, err := s.dao.GetUserItems(ctx, userID, gameID)
 	if err != nil {
 		s.logger.Error("获取用户道具失败", "user_id", userID, "game_id", gameID, "error", err)
 		return nil, fmt.Errorf("获取用户道具失败: %w", err)
@@ -84,8 +85,8 @@ func (s *ItemService) GetUserItems(userID, gameID string) ([]*types.Item, error)
 }
 
 // UpdateItem 更新道具信息
-func (s *ItemService) UpdateItem(itemID string, updates map[string]interface{}) (*types.Item, error) {
-	item, err := s.dao.GetItemByID(itemID)
+func (s *ItemService) UpdateItem(ctx context.Context, itemID string, updates map[string]interface{}) (*types.Item, error) {
+	item, err := s.dao.GetItemByID(ctx, itemID)
 	if err != nil {
 		s.logger.Error("获取道具信息失败", "item_id", itemID, "error", err)
 		return nil, fmt.Errorf("获取道具信息失败: %w", err)
@@ -105,7 +106,7 @@ func (s *ItemService) UpdateItem(itemID string, updates map[string]interface{})
 		item.IconURL = iconURL
 	}
 
-	if err := s.dao.UpdateItem(item); err != nil {
+	if err := s.dao.UpdateItem(ctx, item); err != nil {
 		s.logger.Error("更新道具信息失败", "item_id", itemID, "error", err)
 		return nil, fmt.Errorf("更新道具信息失败: %w", err)
 	}
@@ -115,12 +116,12 @@ func (s *ItemService) UpdateItem(itemID string, updates map[string]interface{})
 }
 
 // AddItemQuantity 增加道具数量
-func (s *ItemService) AddItemQuantity(itemID string, quantity int64) error {
+func (s *ItemService) AddItemQuantity(ctx context.Context, itemID string, quantity int64) error {
 	if quantity <= 0 {
 		return fmt.Errorf("增加数量必须大于0: %d", quantity)
 	}
 
-	if err := s.dao.AddItemQuantity(itemID, quantity); err != nil {
+	if err := s.dao.AddItemQuantity(ctx, itemID, quantity); err != nil {
 		s.logger.Error("增加道具数量失败", "item_id", itemID, "quantity", quantity, "error", err)
 		return fmt.Errorf("增加道具数量失败: %w", err)
 	}
@@ -130,12 +131,12 @@ func (s *ItemService) AddItemQuantity(itemID string, quantity int64) error {
 }
 
 // ConsumeItem 消耗道具
-func (s *ItemService) ConsumeItem(itemID string, quantity int64) error {
+func (s *ItemService) ConsumeItem(ctx context.Context, itemID string, quantity int64) error {
 	if quantity <= 0 {
 		return fmt.Errorf("消耗数量必须大于0: %d", quantity)
 	}
 
-	if err := s.dao.ConsumeItem(itemID, quantity); err != nil {
+	if err := s.dao.ConsumeItem(ctx, itemID, quantity); err != nil {
 		s.logger.Error("消耗道具失败", "item_id", itemID, "quantity", quantity, "error", err)
 		return fmt.Errorf("消耗道具失败: %w", err)
 	}
@@ -145,13 +146,13 @@ func (s *ItemService) ConsumeItem(itemID string, quantity int64) error {
 }
 
 // TransferItem 道具转移（交易）
-func (s *ItemService) TransferItem(itemID, fromUserID, toUserID string, quantity int64) error {
+func (s *ItemService) TransferItem(ctx context.Context, itemID, fromUserID, toUserID string, quantity int64) error {
 	if quantity <= 0 {
 		return fmt.Errorf("转移数量必须大于0: %d", quantity)
 	}
 
 	// 检查道具是否存在且属于fromUser
-	item, err := s.dao.GetItemByID(itemID)
+	item, err := s.dao.GetItemByID(ctx, itemID)
 	if err != nil {
 		s.logger.Error("获取道具信息失败", "item_id", itemID, "error", err)
 		return fmt.Errorf("获取道具信息失败: %w", err)
@@ -170,7 +171,7 @@ func (s *ItemService) TransferItem(itemID, fromUserID, toUserID string, quantity
 	}
 
 	// 检查接收方是否已有此道具
-	toUserItems, err := s.dao.GetUserItems(toUserID, item.GameID)
+	toUserItems, err := s.dao.GetUserItems(ctx, toUserID, item.GameID)
 	if err != nil {
 		s.logger.Error("获取接收方道具失败", "to_user_id", toUserID, "error", err)
 		return fmt.Errorf("获取接收方道具失败: %w", err)
@@ -187,7 +188,7 @@ func (s *ItemService) TransferItem(itemID, fromUserID, toUserID string, quantity
 
 	// 开始事务处理
 	// 1. 减少发送方道具数量
-	if err := s.dao.ConsumeItem(itemID, quantity); err != nil {
+	if err := s.dao.ConsumeItem(ctx, itemID, quantity); err != nil {
 		s.logger.Error("减少发送方道具数量失败", "item_id", itemID, "quantity", quantity, "error", err)
 		return fmt.Errorf("减少发送方道具数量失败: %w", err)
 	}
@@ -195,10 +196,10 @@ func (s *ItemService) TransferItem(itemID, fromUserID, toUserID string, quantity
 	// 2. 增加接收方道具数量
 	if existingItem != nil {
 		// 增加现有道具数量
-		if err := s.dao.AddItemQuantity(existingItem.ItemID, quantity); err != nil {
+		if err := s.dao.AddItemQuantity(ctx, existingItem.ItemID, quantity); err != nil {
 			s.logger.Error("增加接收方道具数量失败", "item_id", existingItem.ItemID, "quantity", quantity, "error", err)
-			// 回滚：恢复发送方道具数量
-			s.dao.AddItemQuantity(itemID, quantity)
+			// 回滚：恢复发送方道具数量，请求取消或超时后仍需执行
+			s.dao.AddItemQuantity(context.WithoutCancel(ctx), itemID, quantity)
 			return fmt.Errorf("增加接收方道具数量失败: %w", err)
 		}
 	} else {
@@ -221,10 +222,10 @@ func (s *ItemService) TransferItem(itemID, fromUserID, toUserID string, quantity
 			IconURL:     item.IconURL,
 		}
 
-		if err := s.dao.CreateItem(newItem); err != nil {
+		if err := s.dao.CreateItem(ctx, newItem); err != nil {
 			s.logger.Error("创建接收方道具失败", "new_item_id", newItemID, "error", err)
-			// 回滚：恢复发送方道具数量
-			s.dao.AddItemQuantity(itemID, quantity)
+			// 回滚：恢复发送方道具数量，请求取消或超时后仍需执行
+			s.dao.AddItemQuantity(context.WithoutCancel(ctx), itemID, quantity)
 			return fmt.Errorf("创建接收方道具失败: %w", err)
 		}
 	}
@@ -234,8 +235,8 @@ func (s *ItemService) TransferItem(itemID, fromUserID, toUserID string, quantity
 }
 
 // DeleteItem 删除道具
-func (s *ItemService) DeleteItem(itemID string) error {
-	if err := s.dao.DeleteItem(itemID); err != nil {
+func (s *ItemService) DeleteItem(ctx context.Context, itemID string) error {
+	if err := s.dao.DeleteItem(ctx, itemID); err != nil {
 		s.logger.Error("删除道具失败", "item_id", itemID, "error", err)
 		return fmt.Errorf("删除道具失败: %w", err)
 	}
@@ -245,7 +246,7 @@ func (s *ItemService) DeleteItem(itemID string) error {
 }
 
 // BatchCreateItems 批量创建道具
-func (s *ItemService) BatchCreateItems(userID, gameID string, itemRequests []types.ItemRequest) ([]*types.Item, error) {
+func (s *ItemService) BatchCreateItems(ctx context.Context, userID, gameID string, itemRequests []types.ItemRequest) ([]*types.Item, error) {
 	if len(itemRequests) == 0 {
 		return []*types.Item{}, nil
 	}
@@ -253,7 +254,7 @@ func (s *ItemService) BatchCreateItems(userID, gameID string, itemRequests []typ
 	items := make([]*types.Item, 0, len(itemRequests))
 
 	for _, req := range itemRequests {
-		item, err := s.CreateItem(userID, gameID, req.Name, req.Type, req.Category, req.Quantity)
+		item, err := s.CreateItem(ctx, userID, gameID, req.Name, req.Type, req.Category, req.Quantity)
 		if err != nil {
 			s.logger.Error("批量创建道具失败", "user_id", userID, "item_name", req.Name, "error", err)
 			return nil, fmt.Errorf("创建道具 %s 失败: %w", req.Name, err)
@@ -266,8 +267,8 @@ func (s *ItemService) BatchCreateItems(userID, gameID string, itemRequests []typ
 }
 
 // ValidateItemOwnership 验证道具所有权
-func (s *ItemService) ValidateItemOwnership(itemID, userID string) error {
-	item, err := s.dao.GetItemByID(itemID)
+func (s *ItemService) ValidateItemOwnership(ctx context.Context, itemID, userID string) error {
+	item, err := s.dao.GetItemByID(ctx, itemID)
 	if err != nil {
 		return fmt.Errorf("验证道具所有权失败: %w", err)
 	}
@@ -281,7 +282,7 @@ func (s *ItemService) ValidateItemOwnership(itemID, userID string) error {
 }
 
 // CheckItemExpiration 检查道具过期
-func (s *ItemService) CheckItemExpiration() ([]*types.Item, error) {
+func (s *ItemService) CheckItemExpiration(ctx context.Context) ([]*types.Item, error) {
 	// 这里需要DAO层支持按过期时间查询
 	// 暂时返回空切片
 	s.logger.Debug("检查道具过期")
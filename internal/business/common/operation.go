@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/pkg/constants"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// 操作分发的响应码
+const (
+	codeUnsupportedMessageType = 4001 // 不支持的消息类型
+	codeInvalidPayload         = 4002 // 请求体无法解码或未通过校验
+	codeUnsupportedOperation   = 4003 // 不支持的操作
+)
+
+// OperationSpec 操作的说明和响应
+type OperationSpec struct {
+	Description    string // 操作说明，用于查询支持的操作
+	SuccessMessage string // 成功时的响应消息
+	FailureCode    int    // 处理失败时的响应码
+	FailureMessage string // 处理失败时的响应消息前缀
+}
+
+// OperationFunc 处理已解码并通过校验的请求体，返回值作为响应数据
+// ctx 在请求超过截止时间、HTTP客户端断开或TCP连接关闭时取消，需要传给服务层和数据访问层
+type OperationFunc[T any] func(ctx context.Context, req *types.Request, payload *T) (interface{}, error)
+
+// operation 已注册的操作
+type operation struct {
+	info       types.OperationInfo
+	spec       OperationSpec
+	newPayload func() interface{}
+	handle     func(ctx context.Context, req *types.Request, payload interface{}) (interface{}, error)
+}
+
+// OperationRegistry 按消息类型和操作名注册的操作表
+// 负责解码请求体、校验字段、分发到操作并生成响应，注册必须在处理请求之前完成
+type OperationRegistry struct {
+	operations map[types.MessageType]map[string]*operation
+	ordered    []*operation // 注册顺序，用于列出操作
+	validate   *validator.Validate
+	logger     logger.Logger
+}
+
+// NewOperationRegistry 创建操作表
+func NewOperationRegistry(log logger.Logger) *OperationRegistry {
+	validate := validator.New()
+	// 校验错误中使用JSON字段名
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return jsonFieldName(field)
+	})
+
+	return &OperationRegistry{
+		operations: make(map[types.MessageType]map[string]*operation),
+		validate:   validate,
+		logger:     log,
+	}
+}
+
+// RegisterOperation 注册操作，T为请求体结构体，字段通过validate标签声明校验规则
+// name为空时该消息类型不区分操作，否则按请求体的operation字段分发；重复注册或T不是结构体时panic
+func RegisterOperation[T any](r *OperationRegistry, msgType types.MessageType, name string, spec OperationSpec, fn OperationFunc[T]) {
+	payloadType := reflect.TypeOf((*T)(nil)).Elem()
+	if payloadType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("操作 0x%04X %q 的请求体必须是结构体: %s", uint16(msgType), name, payloadType))
+	}
+
+	ops := r.operations[msgType]
+	if ops == nil {
+		ops = make(map[string]*operation)
+		r.operations[msgType] = ops
+	}
+	if _, exists := ops[name]; exists {
+		panic(fmt.Sprintf("操作已注册: 0x%04X %q", uint16(msgType), name))
+	}
+	// 同一消息类型不能既不区分操作又注册具名操作
+	if _, whole := ops[""]; whole || (name == "" && len(ops) > 0) {
+		panic(fmt.Sprintf("消息类型 0x%04X 不能同时注册不区分操作的处理和具名操作", uint16(msgType)))
+	}
+
+	op := &operation{
+		info: types.OperationInfo{
+			MessageType: msgType,
+			Operation:   name,
+			Description: spec.Description,
+			Fields:      describeFields(payloadType),
+		},
+		spec:       spec,
+		newPayload: func() interface{} { return new(T) },
+		handle: func(ctx context.Context, req *types.Request, payload interface{}) (interface{}, error) {
+			return fn(ctx, req, payload.(*T))
+		},
+	}
+	ops[name] = op
+	r.ordered = append(r.ordered, op)
+}
+
+// Dispatch 解码并校验请求体后调用对应的操作，所有结果都转换为业务响应
+// 操作因ctx超时或取消而失败时返回超时响应码
+func (r *OperationRegistry) Dispatch(ctx context.Context, req *types.Request) *types.Response {
+	ops, ok := r.operations[req.Type]
+	if !ok {
+		return failureResponse(req, codeUnsupportedMessageType, "不支持的消息类型")
+	}
+	data, ok := req.Data.([]byte)
+	if !ok {
+		return failureResponse(req, codeInvalidPayload, "请求数据格式错误")
+	}
+
+	op, ok := ops[""]
+	if !ok {
+		var envelope struct {
+			Operation string `json:"operation"`
+		}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			r.logger.Error("解析请求失败", "game_id", req.GameID, "type", req.Type, "error", err)
+			return failureResponse(req, codeInvalidPayload, "请求数据格式错误")
+		}
+		if op, ok = ops[envelope.Operation]; !ok {
+			return failureResponse(req, codeUnsupportedOperation, fmt.Sprintf("不支持的操作: %s", envelope.Operation))
+		}
+	}
+
+	payload := op.newPayload()
+	if err := json.Unmarshal(data, payload); err != nil {
+		r.logger.Error("解析请求失败", "game_id", req.GameID, "type", req.Type, "operation", op.info.Operation, "error", err)
+		return failureResponse(req, codeInvalidPayload, "请求数据格式错误")
+	}
+	if err := r.validate.Struct(payload); err != nil {
+		return failureResponse(req, codeInvalidPayload, fmt.Sprintf("请求参数无效: %s", validationMessage(err)))
+	}
+
+	result, err := op.handle(ctx, req, payload)
+	if err != nil && ctx.Err() != nil {
+		r.logger.Warn("请求已取消或超时",
+			"game_id", req.GameID,
+			"type", req.Type,
+			"operation", op.info.Operation,
+			"user_id", req.UserID,
+			"client_ip", req.ClientIP,
+			"error", err)
+		return failureResponse(req, constants.ErrCodeTimeout, "请求已超时或已取消")
+	}
+	if err != nil {
+		r.logger.Error(op.spec.FailureMessage,
+			"game_id", req.GameID,
+			"type", req.Type,
+			"operation", op.info.Operation,
+			"user_id", req.UserID,
+			"client_ip", req.ClientIP,
+			"error", err)
+		return failureResponse(req, op.spec.FailureCode, fmt.Sprintf("%s: %v", op.spec.FailureMessage, err))
+	}
+
+	return &types.Response{
+		ID:        req.ID,
+		Code:      0,
+		Message:   op.spec.SuccessMessage,
+		Data:      result,
+		Timestamp: req.Timestamp,
+	}
+}
+
+// MessageTypes 返回注册了操作的消息类型，按首次注册的顺序
+func (r *OperationRegistry) MessageTypes() []types.MessageType {
+	msgTypes := make([]types.MessageType, 0, len(r.operations))
+	seen := make(map[types.MessageType]bool, len(r.operations))
+	for _, op := range r.ordered {
+		if !seen[op.info.MessageType] {
+			seen[op.info.MessageType] = true
+			msgTypes = append(msgTypes, op.info.MessageType)
+		}
+	}
+	return msgTypes
+}
+
+// Operations 返回所有已注册的操作，按注册顺序
+func (r *OperationRegistry) Operations() []types.OperationInfo {
+	infos := make([]types.OperationInfo, 0, len(r.ordered))
+	for _, op := range r.ordered {
+		infos = append(infos, op.info)
+	}
+	return infos
+}
+
+// failureResponse 创建失败响应
+func failureResponse(req *types.Request, code int, message string) *types.Response {
+	return &types.Response{
+		ID:        req.ID,
+		Code:      code,
+		Message:   message,
+		Timestamp: req.Timestamp,
+	}
+}
+
+// validationMessage 将校验错误转换为字段和规则的列表，如 "user_id(required), quantity(gt=0)"
+func validationMessage(err error) string {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err.Error()
+	}
+
+	parts := make([]string, 0, len(fieldErrors))
+	for _, fe := range fieldErrors {
+		rule := fe.Tag()
+		if fe.Param() != "" {
+			rule += "=" + fe.Param()
+		}
+		parts = append(parts, fmt.Sprintf("%s(%s)", fe.Field(), rule))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeFields 列出请求体结构体的JSON字段
+func describeFields(payloadType reflect.Type) []types.OperationField {
+	var fields []types.OperationField
+	for i := 0; i < payloadType.NumField(); i++ {
+		field := payloadType.Field(i)
+		name := jsonFieldName(field)
+		if !field.IsExported() || name == "" {
+			continue
+		}
+		fields = append(fields, types.OperationField{
+			Name:  name,
+			Type:  field.Type.String(),
+			Rules: field.Tag.Get("validate"),
+		})
+	}
+	return fields
+}
+
+// jsonFieldName 返回字段的JSON名称，忽略的字段返回空字符串
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/pkg/constants"
+
+	"go.uber.org/zap"
+)
+
+type testGrantRequest struct {
+	UserID   string `json:"user_id" validate:"required"`
+	Quantity int64  `json:"quantity" validate:"gt=0"`
+	Note     string `json:"note,omitempty"`
+	internal string
+}
+
+type testPingRequest struct{}
+
+func newTestRegistry() *OperationRegistry {
+	r := NewOperationRegistry(&logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()})
+	RegisterOperation(r, types.MessageTypeItemOperation, "grant", OperationSpec{
+		Description: "发放道具", SuccessMessage: "发放成功", FailureCode: 5009, FailureMessage: "发放失败",
+	}, func(ctx context.Context, req *types.Request, p *testGrantRequest) (interface{}, error) {
+		if p.UserID == "banned" {
+			return nil, errors.New("玩家已封禁")
+		}
+		return map[string]interface{}{"user_id": p.UserID, "quantity": p.Quantity}, nil
+	})
+	RegisterOperation(r, types.MessageTypePlayerData, "", OperationSpec{
+		Description: "测试", SuccessMessage: "ok",
+	}, func(ctx context.Context, req *types.Request, p *testPingRequest) (interface{}, error) {
+		return req.GameID, nil
+	})
+	return r
+}
+
+func TestOperationRegistryDispatch(t *testing.T) {
+	r := newTestRegistry()
+
+	tests := []struct {
+		name        string
+		msgType     types.MessageType
+		data        interface{}
+		wantCode    int
+		wantMessage string
+	}{
+		{"成功", types.MessageTypeItemOperation, []byte(`{"operation":"grant","user_id":"u1","quantity":2}`), 0, "发放成功"},
+		{"不区分操作", types.MessageTypePlayerData, []byte(`{}`), 0, "ok"},
+		{"不支持的消息类型", types.MessageTypeOrderOperation, []byte(`{}`), 4001, "不支持的消息类型"},
+		{"请求体不是字节", types.MessageTypeItemOperation, "{}", 4002, "请求数据格式错误"},
+		{"无效JSON", types.MessageTypeItemOperation, []byte(`{`), 4002, "请求数据格式错误"},
+		{"字段类型错误", types.MessageTypeItemOperation, []byte(`{"operation":"grant","user_id":"u1","quantity":"2"}`), 4002, "请求数据格式错误"},
+		{"不支持的操作", types.MessageTypeItemOperation, []byte(`{"operation":"melt"}`), 4003, "不支持的操作: melt"},
+		{"校验失败", types.MessageTypeItemOperation, []byte(`{"operation":"grant","quantity":0}`), 4002, "请求参数无效: user_id(required), quantity(gt=0)"},
+		{"处理失败", types.MessageTypeItemOperation, []byte(`{"operation":"grant","user_id":"banned","quantity":1}`), 5009, "发放失败: 玩家已封禁"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := r.Dispatch(context.Background(), &types.Request{ID: "req1", Type: tt.msgType, GameID: "game1", Data: tt.data, Timestamp: 100})
+			if resp.Code != tt.wantCode || resp.Message != tt.wantMessage {
+				t.Errorf("响应 = (%d, %s), 期望 (%d, %s)", resp.Code, resp.Message, tt.wantCode, tt.wantMessage)
+			}
+			if resp.ID != "req1" || resp.Timestamp != 100 {
+				t.Errorf("响应ID和时间戳应与请求一致: %+v", resp)
+			}
+		})
+	}
+
+	resp := r.Dispatch(context.Background(), &types.Request{Type: types.MessageTypePlayerData, GameID: "game1", Data: []byte(`{}`)})
+	if resp.Data != "game1" {
+		t.Errorf("响应数据 = %v", resp.Data)
+	}
+}
+
+func TestOperationRegistryCancelled(t *testing.T) {
+	r := newTestRegistry()
+	RegisterOperation(r, types.MessageTypeOrderOperation, "", OperationSpec{
+		Description: "等待", SuccessMessage: "ok", FailureCode: 5009, FailureMessage: "处理失败",
+	}, func(ctx context.Context, req *types.Request, p *testPingRequest) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	resp := r.Dispatch(ctx, &types.Request{ID: "req1", Type: types.MessageTypeOrderOperation, Data: []byte(`{}`)})
+	if resp.Code != constants.ErrCodeTimeout {
+		t.Errorf("超时响应码 = %d, 期望 %d", resp.Code, constants.ErrCodeTimeout)
+	}
+
+	// 未超时的失败仍使用操作的失败码
+	resp = r.Dispatch(context.Background(), &types.Request{Type: types.MessageTypeItemOperation, Data: []byte(`{"operation":"grant","user_id":"banned","quantity":1}`)})
+	if resp.Code != 5009 {
+		t.Errorf("失败响应码 = %d, 期望 5009", resp.Code)
+	}
+}
+
+func TestOperationRegistryIntrospection(t *testing.T) {
+	r := newTestRegistry()
+
+	msgTypes := r.MessageTypes()
+	if len(msgTypes) != 2 || msgTypes[0] != types.MessageTypeItemOperation || msgTypes[1] != types.MessageTypePlayerData {
+		t.Errorf("消息类型 = %v", msgTypes)
+	}
+
+	ops := r.Operations()
+	if len(ops) != 2 {
+		t.Fatalf("操作数 = %d, 期望 2", len(ops))
+	}
+	grant := ops[0]
+	if grant.Operation != "grant" || grant.Description != "发放道具" {
+		t.Errorf("操作信息 = %+v", grant)
+	}
+	wantFields := []types.OperationField{
+		{Name: "user_id", Type: "string", Rules: "required"},
+		{Name: "quantity", Type: "int64", Rules: "gt=0"},
+		{Name: "note", Type: "string"},
+	}
+	if len(grant.Fields) != len(wantFields) {
+		t.Fatalf("字段 = %+v", grant.Fields)
+	}
+	for i, want := range wantFields {
+		if grant.Fields[i] != want {
+			t.Errorf("字段[%d] = %+v, 期望 %+v", i, grant.Fields[i], want)
+		}
+	}
+}
+
+func TestRegisterOperationConflicts(t *testing.T) {
+	noop := func(ctx context.Context, req *types.Request, p *testPingRequest) (interface{}, error) {
+		return nil, nil
+	}
+
+	tests := []struct {
+		name     string
+		register func(r *OperationRegistry)
+		wantMsg  string
+	}{
+		{"重复注册", func(r *OperationRegistry) {
+			RegisterOperation(r, types.MessageTypeItemOperation, "grant", OperationSpec{}, noop)
+		}, "操作已注册"},
+		{"具名操作与不区分操作混用", func(r *OperationRegistry) {
+			RegisterOperation(r, types.MessageTypePlayerData, "sync", OperationSpec{}, noop)
+		}, "不能同时注册"},
+		{"请求体不是结构体", func(r *OperationRegistry) {
+			RegisterOperation(r, types.MessageTypeOrderOperation, "", OperationSpec{}, func(ctx context.Context, req *types.Request, p *string) (interface{}, error) {
+				return nil, nil
+			})
+		}, "必须是结构体"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, tt.wantMsg) {
+					t.Errorf("panic = %q, 期望包含 %q", msg, tt.wantMsg)
+				}
+			}()
+			tt.register(newTestRegistry())
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -24,7 +25,7 @@ func NewOrderService(dao daoPkg.DAO, log loggingInfra.Logger) *OrderService {
 }
 
 // CreateOrder 创建订单
-func (s *OrderService) CreateOrder(userID, gameID, productID, productName string, amount int64, currency, paymentMethod, channel, ip, deviceID string) (*types.Order, error) {
+func (s *OrderService) CreateOrder(ctx context.Context, userID, gameID, productID, productName string, amount int64, currency, paymentMethod, channel, ip, deviceID string) (*types.Order, error) {
 	// 生成订单ID
 	orderID := s.generateOrderID()
 
@@ -43,18 +44,18 @@ func (s *OrderService) CreateOrder(userID, gameID, productID, productName string
 		DeviceID:      deviceID,
 	}
 
-	if err := s.dao.CreateOrder(order); err != nil {
+	if err := s.dao.CreateOrder(ctx, order); err != nil {
 		s.logger.Error("创建订单失败", "order_id", orderID, "user_id", userID, "error", err)
 		return nil, fmt.Errorf("创建订单失败: %w", err)
 	}
 
-	s.logger.Info("订单创建成功", "order_id", orderID, "user_id", userID, "amount", amount, "currency", currency)
+	s.logger.Info("订单创建成功", "order_id", orderID, "user_id", userID, "amount", amount, "currency", currency, "client_ip", ip)
 	return s.convertToAPITypes(order), nil
 }
 
 // GetOrder 获取订单信息
-func (s *OrderService) GetOrder(orderID string) (*types.Order, error) {
-	order, err := s.dao.GetOrderByID(orderID)
+func (s *OrderService) GetOrder(ctx context.Context, orderID string) (*types.Order, error) {
+	order, err := s.dao.GetOrderByID(ctx, orderID)
 	if err != nil {
 		s.logger.Error("获取订单信息失败", "order_id", orderID, "error", err)
 		return nil, fmt.Errorf("获取订单信息失败: %w", err)
@@ -67,8 +68,8 @@ func (s *OrderService) GetOrder(orderID string) (*types.Order, error) {
 }
 
 // GetUserOrders 获取用户订单列表
-func (s *OrderService) GetUserOrders(userID, status string, offset, limit int) ([]*types.Order, int64, error) {
-	orders, total, err := s.dao.GetUserOrders(userID, status, offset, limit)
+func (s *OrderService) GetUserOrders(ctx context.Context, userID, status string, offset, limit int) ([]*types.Order, int64, error) {
+	orders, total, err := s.dao.GetUserOrders(ctx, userID, status, offset, limit)
 	if err != nil {
 		s.logger.Error("获取用户订单失败", "user_id", userID, "status", status, "error", err)
 		return nil, 0, fmt.Errorf("获取用户订单失败: %w", err)
@@ -84,8 +85,8 @@ func (s *OrderService) GetUserOrders(userID, status string, offset, limit int) (
 }
 
 // ProcessPayment 处理支付
-func (s *OrderService) ProcessPayment(orderID, transactionID string) (*types.Order, error) {
-	order, err := s.dao.GetOrderByID(orderID)
+func (s *OrderService) ProcessPayment(ctx context.Context, orderID, transactionID string) (*types.Order, error) {
+	order, err := s.dao.GetOrderByID(ctx, orderID)
 	if err != nil {
 		s.logger.Error("获取订单信息失败", "order_id", orderID, "error", err)
 		return nil, fmt.Errorf("获取订单信息失败: %w", err)
@@ -104,7 +105,7 @@ func (s *OrderService) ProcessPayment(orderID, transactionID string) (*types.Ord
 	order.PaymentAt = &now
 	order.TransactionID = transactionID
 
-	if err := s.dao.UpdateOrderStatus(orderID, "paid"); err != nil {
+	if err := s.dao.UpdateOrderStatus(ctx, orderID, "paid"); err != nil {
 		s.logger.Error("更新订单状态失败", "order_id", orderID, "error", err)
 		return nil, fmt.Errorf("更新订单状态失败: %w", err)
 	}
@@ -117,8 +118,8 @@ func (s *OrderService) ProcessPayment(orderID, transactionID string) (*types.Ord
 }
 
 // CancelOrder 取消订单
-func (s *OrderService) CancelOrder(orderID string) (*types.Order, error) {
-	order, err := s.dao.GetOrderByID(orderID)
+func (s *OrderService) CancelOrder(ctx context.Context, orderID string) (*types.Order, error) {
+	order, err := s.dao.GetOrderByID(ctx, orderID)
 	if err != nil {
 		s.logger.Error("获取订单信息失败", "order_id", orderID, "error", err)
 		return nil, fmt.Errorf("获取订单信息失败: %w", err)
@@ -132,7 +133,7 @@ func (s *OrderService) CancelOrder(orderID string) (*types.Order, error) {
 	}
 
 	// 更新订单状态
-	if err := s.dao.UpdateOrderStatus(orderID, "cancelled"); err != nil {
+	if err := s.dao.UpdateOrderStatus(ctx, orderID, "cancelled"); err != nil {
 		s.logger.Error("取消订单失败", "order_id", orderID, "error", err)
 		return nil, fmt.Errorf("取消订单失败: %w", err)
 	}
@@ -143,8 +144,8 @@ func (s *OrderService) CancelOrder(orderID string) (*types.Order, error) {
 }
 
 // RefundOrder 退款订单
-func (s *OrderService) RefundOrder(orderID string, refundAmount int64) (*types.Order, error) {
-	order, err := s.dao.GetOrderByID(orderID)
+func (s *OrderService) RefundOrder(ctx context.Context, orderID string, refundAmount int64) (*types.Order, error) {
+	order, err := s.dao.GetOrderByID(ctx, orderID)
 	if err != nil {
 		s.logger.Error("获取订单信息失败", "order_id", orderID, "error", err)
 		return nil, fmt.Errorf("获取订单信息失败: %w", err)
@@ -169,7 +170,7 @@ func (s *OrderService) RefundOrder(orderID string, refundAmount int64) (*types.O
 
 	// 这里需要更新数据库中的退款信息
 	// 由于DAO层没有专门的退款更新方法，这里暂时使用UpdateOrderStatus
-	if err := s.dao.UpdateOrderStatus(orderID, "refunded"); err != nil {
+	if err := s.dao.UpdateOrderStatus(ctx, orderID, "refunded"); err != nil {
 		s.logger.Error("退款订单失败", "order_id", orderID, "error", err)
 		return nil, fmt.Errorf("退款订单失败: %w", err)
 	}
@@ -181,8 +182,8 @@ func (s *OrderService) RefundOrder(orderID string, refundAmount int64) (*types.O
 }
 
 // GetOrdersByStatus 根据状态获取订单
-func (s *OrderService) GetOrdersByStatus(status string, offset, limit int) ([]*types.Order, int64, error) {
-	orders, total, err := s.dao.GetOrdersByStatus(status, offset, limit)
+func (s *OrderService) GetOrdersByStatus(ctx context.Context, status string, offset, limit int) ([]*types.Order, int64, error) {
+	orders, total, err := s.dao.GetOrdersByStatus(ctx, status, offset, limit)
 	if err != nil {
 		s.logger.Error("获取订单列表失败", "status", status, "error", err)
 		return nil, 0, fmt.Errorf("获取订单列表失败: %w", err)
@@ -198,8 +199,8 @@ func (s *OrderService) GetOrdersByStatus(status string, offset, limit int) ([]*t
 }
 
 // ValidateOrderOwnership 验证订单所有权
-func (s *OrderService) ValidateOrderOwnership(orderID, userID string) error {
-	order, err := s.dao.GetOrderByID(orderID)
+func (s *OrderService) ValidateOrderOwnership(ctx context.Context, orderID, userID string) error {
+	order, err := s.dao.GetOrderByID(ctx, orderID)
 	if err != nil {
 		return fmt.Errorf("验证订单所有权失败: %w", err)
 	}
@@ -213,7 +214,7 @@ func (s *OrderService) ValidateOrderOwnership(orderID, userID string) error {
 }
 
 // CalculateTotalRevenue 计算总营收
-func (s *OrderService) CalculateTotalRevenue(gameID string, startDate, endDate time.Time) (int64, error) {
+func (s *OrderService) CalculateTotalRevenue(ctx context.Context, gameID string, startDate, endDate time.Time) (int64, error) {
 	// 这里需要DAO层支持按时间和游戏ID查询订单统计
 	// 暂时返回0
 	s.logger.Debug("计算营收", "game_id", gameID, "start", startDate, "end", endDate)
@@ -221,7 +222,7 @@ func (s *OrderService) CalculateTotalRevenue(gameID string, startDate, endDate t
 }
 
 // GetOrderStatistics 获取订单统计
-func (s *OrderService) GetOrderStatistics(gameID string, startDate, endDate time.Time) (*types.OrderStatistics, error) {
+func (s *OrderService) GetOrderStatistics(ctx context.Context, gameID string, startDate, endDate time.Time) (*types.OrderStatistics, error) {
 	// 这里需要DAO层支持订单统计查询
 	// 暂时返回空统计
 	s.logger.Debug("获取订单统计", "game_id", gameID, "start", startDate, "end", endDate)
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
@@ -30,9 +31,9 @@ func NewPlayerService(dao daoPkg.DAO, log loggingInfra.Logger, authService *auth
 }
 
 // RegisterPlayer 注册玩家
-func (s *PlayerService) RegisterPlayer(gameID, username, password, email, phone string) (*types.Player, error) {
+func (s *PlayerService) RegisterPlayer(ctx context.Context, gameID, username, password, email, phone string) (*types.Player, error) {
 	// 检查用户名是否已存在
-	existing, err := s.dao.GetPlayerByUsername(username)
+	existing, err := s.dao.GetPlayerByUsername(ctx, username)
 	if err != nil {
 		s.logger.Error("检查用户名是否存在失败", "username", username, "error", err)
 		return nil, fmt.Errorf("检查用户名失败: %w", err)
@@ -71,7 +72,7 @@ func (s *PlayerService) RegisterPlayer(gameID, username, password, email, phone
 		Status:      "active",
 	}
 
-	if err := s.dao.CreatePlayer(player); err != nil {
+	if err := s.dao.CreatePlayer(ctx, player); err != nil {
 		s.logger.Error("创建玩家失败", "user_id", userID, "username", username, "error", err)
 		return nil, fmt.Errorf("创建玩家失败: %w", err)
 	}
@@ -82,10 +83,10 @@ func (s *PlayerService) RegisterPlayer(gameID, username, password, email, phone
 	return s.convertToAPITypes(player), nil
 }
 
-// LoginPlayerByUsername 通过用户名密码登录
-func (s *PlayerService) LoginPlayerByUsername(username, password, gameID, deviceID, platform, version string) (*types.LoginResult, error) {
+// LoginPlayerByUsername 通过用户名密码登录，clientIP为客户端真实IP
+func (s *PlayerService) LoginPlayerByUsername(ctx context.Context, username, password, gameID, deviceID, platform, version, clientIP string) (*types.LoginResult, error) {
 	// 获取玩家信息
-	player, err := s.dao.GetPlayerByUsername(username)
+	player, err := s.dao.GetPlayerByUsername(ctx, username)
 	if err != nil {
 		s.logger.Error("获取玩家信息失败", "username", username, "error", err)
 		return nil, fmt.Errorf("获取玩家信息失败: %w", err)
@@ -96,7 +97,7 @@ func (s *PlayerService) LoginPlayerByUsername(username, password, gameID, device
 
 	// 验证密码
 	if err := s.verifyPassword(password, player.Password); err != nil {
-		s.logger.Warn("密码验证失败", "username", username)
+		s.logger.Warn("密码验证失败", "username", username, "client_ip", clientIP)
 		return nil, fmt.Errorf("密码错误")
 	}
 
@@ -106,13 +107,13 @@ func (s *PlayerService) LoginPlayerByUsername(username, password, gameID, device
 	}
 
 	// 调用现有的登录逻辑
-	return s.LoginPlayer(player.UserID, gameID, deviceID, platform, version)
+	return s.LoginPlayer(ctx, player.UserID, gameID, deviceID, platform, version, clientIP)
 }
 
-// LoginPlayer 玩家登录
-func (s *PlayerService) LoginPlayer(userID, gameID, deviceID, platform, version string) (*types.LoginResult, error) {
+// LoginPlayer 玩家登录，clientIP为客户端真实IP，记录到玩家的最后登录IP和会话中
+func (s *PlayerService) LoginPlayer(ctx context.Context, userID, gameID, deviceID, platform, version, clientIP string) (*types.LoginResult, error) {
 	// 获取玩家信息
-	player, err := s.dao.GetPlayerByID(userID)
+	player, err := s.dao.GetPlayerByID(ctx, userID)
 	if err != nil {
 		s.logger.Error("获取玩家信息失败", "user_id", userID, "error", err)
 		return nil, fmt.Errorf("获取玩家信息失败: %w", err)
@@ -129,12 +130,12 @@ func (s *PlayerService) LoginPlayer(userID, gameID, deviceID, platform, version
 	// 更新登录信息
 	now := time.Now()
 	player.LastLoginAt = &now
-	player.LastLoginIP = "" // TODO: 从请求中获取IP
+	player.LastLoginIP = clientIP
 	player.DeviceID = deviceID
 	player.Platform = platform
 	player.Version = version
 
-	if err := s.dao.UpdatePlayer(player); err != nil {
+	if err := s.dao.UpdatePlayer(ctx, player); err != nil {
 		s.logger.Error("更新玩家登录信息失败", "user_id", userID, "error", err)
 		return nil, fmt.Errorf("更新登录信息失败: %w", err)
 	}
@@ -154,7 +155,7 @@ func (s *PlayerService) LoginPlayer(userID, gameID, deviceID, platform, version
 		UserID:    userID,
 		GameID:    gameID,
 		Token:     token,
-		IPAddress: "", // TODO: 从请求中获取
+		IPAddress: clientIP,
 		UserAgent: "",
 		LoginAt:   time.Now(),
 		ExpireAt:  time.Now().Add(24 * time.Hour), // 24小时过期
@@ -162,12 +163,12 @@ func (s *PlayerService) LoginPlayer(userID, gameID, deviceID, platform, version
 		DeviceID:  deviceID,
 	}
 
-	if err := s.dao.CreateSession(session); err != nil {
+	if err := s.dao.CreateSession(ctx, session); err != nil {
 		s.logger.Error("创建会话失败", "session_id", sessionID, "error", err)
 		return nil, fmt.Errorf("创建会话失败: %w", err)
 	}
 
-	s.logger.Info("玩家登录成功", "user_id", userID, "session_id", sessionID, "game_id", gameID)
+	s.logger.Info("玩家登录成功", "user_id", userID, "session_id", sessionID, "game_id", gameID, "client_ip", clientIP)
 
 	return &types.LoginResult{
 		User:      s.convertToAPITypes(player),
@@ -178,9 +179,9 @@ func (s *PlayerService) LoginPlayer(userID, gameID, deviceID, platform, version
 }
 
 // LogoutPlayer 玩家登出
-func (s *PlayerService) LogoutPlayer(userID, sessionID string) error {
+func (s *PlayerService) LogoutPlayer(ctx context.Context, userID, sessionID string) error {
 	// 使会话失效
-	if err := s.dao.InvalidateSession(sessionID); err != nil {
+	if err := s.dao.InvalidateSession(ctx, sessionID); err != nil {
 		s.logger.Error("使会话失效失败", "session_id", sessionID, "error", err)
 		return fmt.Errorf("登出失败: %w", err)
 	}
@@ -190,8 +191,8 @@ func (s *PlayerService) LogoutPlayer(userID, sessionID string) error {
 }
 
 // GetPlayer 获取玩家信息
-func (s *PlayerService) GetPlayer(userID string) (*types.Player, error) {
-	player, err := s.dao.GetPlayerByID(userID)
+func (s *PlayerService) GetPlayer(ctx context.Context, userID string) (*types.Player, error) {
+	player, err := s.dao.GetPlayerByID(ctx, userID)
 	if err != nil {
 		s.logger.Error("获取玩家信息失败", "user_id", userID, "error", err)
 		return nil, fmt.Errorf("获取玩家信息失败: %w", err)
@@ -204,8 +205,8 @@ func (s *PlayerService) GetPlayer(userID string) (*types.Player, error) {
 }
 
 // UpdatePlayer 更新玩家信息
-func (s *PlayerService) UpdatePlayer(userID string, updates map[string]interface{}) (*types.Player, error) {
-	player, err := s.dao.GetPlayerByID(userID)
+func (s *PlayerService) UpdatePlayer(ctx context.Context, userID string, updates map[string]interface{}) (*types.Player, error) {
+	player, err := s.dao.GetPlayerByID(ctx, userID)
 	if err != nil {
 		s.logger.Error("获取玩家信息失败", "user_id", userID, "error", err)
 		return nil, fmt.Errorf("获取玩家信息失败: %w", err)
@@ -222,7 +223,7 @@ func (s *PlayerService) UpdatePlayer(userID string, updates map[string]interface
 		player.Avatar = avatar
 	}
 
-	if err := s.dao.UpdatePlayer(player); err != nil {
+	if err := s.dao.UpdatePlayer(ctx, player); err != nil {
 		s.logger.Error("更新玩家信息失败", "user_id", userID, "error", err)
 		return nil, fmt.Errorf("更新玩家信息失败: %w", err)
 	}
@@ -232,8 +233,8 @@ func (s *PlayerService) UpdatePlayer(userID string, updates map[string]interface
 }
 
 // UpdatePlayerStats 更新玩家统计数据
-func (s *PlayerService) UpdatePlayerStats(userID string, experience int64, coins int64, diamonds int64) (*types.Player, error) {
-	player, err := s.dao.GetPlayerByID(userID)
+func (s *PlayerService) UpdatePlayerStats(ctx context.Context, userID string, experience int64, coins int64, diamonds int64) (*types.Player, error) {
+	player, err := s.dao.GetPlayerByID(ctx, userID)
 	if err != nil {
 		s.logger.Error("获取玩家信息失败", "user_id", userID, "error", err)
 		return nil, fmt.Errorf("获取玩家信息失败: %w", err)
@@ -262,7 +263,7 @@ func (s *PlayerService) UpdatePlayerStats(userID string, experience int64, coins
 		player.Diamonds = 0
 	}
 
-	if err := s.dao.UpdatePlayer(player); err != nil {
+	if err := s.dao.UpdatePlayer(ctx, player); err != nil {
 		s.logger.Error("更新玩家统计失败", "user_id", userID, "error", err)
 		return nil, fmt.Errorf("更新玩家统计失败: %w", err)
 	}
@@ -272,8 +273,8 @@ func (s *PlayerService) UpdatePlayerStats(userID string, experience int64, coins
 }
 
 // ListPlayers 列出玩家
-func (s *PlayerService) ListPlayers(gameID string, offset, limit int) ([]*types.Player, int64, error) {
-	players, total, err := s.dao.ListPlayers(gameID, offset, limit)
+func (s *PlayerService) ListPlayers(ctx context.Context, gameID string, offset, limit int) ([]*types.Player, int64, error) {
+	players, total, err := s.dao.ListPlayers(ctx, gameID, offset, limit)
 	if err != nil {
 		s.logger.Error("获取玩家列表失败", "game_id", gameID, "error", err)
 		return nil, 0, fmt.Errorf("获取玩家列表失败: %w", err)
@@ -289,8 +290,8 @@ func (s *PlayerService) ListPlayers(gameID string, offset, limit int) ([]*types.
 }
 
 // ValidateSession 验证会话
-func (s *PlayerService) ValidateSession(sessionID string) (*types.Player, error) {
-	session, err := s.dao.GetSessionByID(sessionID)
+func (s *PlayerService) ValidateSession(ctx context.Context, sessionID string) (*types.Player, error) {
+	session, err := s.dao.GetSessionByID(ctx, sessionID)
 	if err != nil {
 		s.logger.Error("获取会话失败", "session_id", sessionID, "error", err)
 		return nil, fmt.Errorf("获取会话失败: %w", err)
@@ -307,7 +308,7 @@ func (s *PlayerService) ValidateSession(sessionID string) (*types.Player, error)
 		return nil, fmt.Errorf("会话已过期: %s", sessionID)
 	}
 
-	player, err := s.dao.GetPlayerByID(session.UserID)
+	player, err := s.dao.GetPlayerByID(ctx, session.UserID)
 	if err != nil {
 		s.logger.Error("获取玩家信息失败", "user_id", session.UserID, "error", err)
 		return nil, fmt.Errorf("获取玩家信息失败: %w", err)
@@ -320,8 +321,8 @@ func (s *PlayerService) ValidateSession(sessionID string) (*types.Player, error)
 }
 
 // CleanupExpiredSessions 清理过期会话
-func (s *PlayerService) CleanupExpiredSessions() error {
-	if err := s.dao.CleanupExpiredSessions(); err != nil {
+func (s *PlayerService) CleanupExpiredSessions(ctx context.Context) error {
+	if err := s.dao.CleanupExpiredSessions(ctx); err != nil {
 		s.logger.Error("清理过期会话失败", "error", err)
 		return fmt.Errorf("清理过期会话失败: %w", err)
 	}
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"container/heap"
+	"container/list"
+	"math"
 	"sync"
 	"time"
 )
@@ -318,14 +320,44 @@ func (pq *PriorityQueue[T]) Remove(item *PriorityQueueItem[T]) {
 	heap.Remove(pq, item.Index)
 }
 
-// TimeWheel 时间轮（用于定时任务）
+// Clock 时钟接口，测试时可注入手动推进的时钟
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock 系统时钟
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock 返回系统时钟
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// TimeWheel 分层时间轮（用于大量定时任务，如连接超时检测）
+// 第i层每个槽位覆盖 wheelSize^i 个刻度，超出当前层范围的任务放入上层，到期前逐层下移
+// 到期任务在推进时间轮的协程中同步执行，任务不能阻塞，耗时操作应另起协程
 type TimeWheel struct {
-	tickMs    int64         // 每个刻度的毫秒数
-	wheelSize int           // 时间轮大小
-	current   int           // 当前位置
-	wheel     [][]func()    // 时间轮槽位，每个槽位包含多个任务
-	ticker    *time.Ticker
-	stopChan  chan struct{}
+	tick        time.Duration // 每个刻度的时长
+	wheelSize   int64         // 每层槽位数
+	levels      [][]*list.List
+	currentTick int64     // 已推进的刻度数
+	start       time.Time // 第0个刻度对应的时间
+	clock       Clock
+	ticker      *time.Ticker
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+	mu          sync.Mutex
+}
+
+// Timer 时间轮定时器，可取消和重置
+type Timer struct {
+	tw     *TimeWheel
+	task   func()
+	expire int64         // 到期刻度
+	slot   *list.List    // 所在槽位，nil表示未启动或已到期
+	elem   *list.Element // 在槽位中的位置
 }
 
 // NewTimeWheel 创建时间轮
@@ -333,52 +365,119 @@ func NewTimeWheel(tickMs int64, wheelSize int) *TimeWheel {
 	if tickMs <= 0 {
 		tickMs = 1000 // 默认1秒
 	}
-	if wheelSize <= 0 {
+	return NewTimeWheelWithClock(time.Duration(tickMs)*time.Millisecond, wheelSize, SystemClock())
+}
+
+// NewTimeWheelWithClock 使用指定时钟创建时间轮，测试时配合Advance手动推进
+func NewTimeWheelWithClock(tick time.Duration, wheelSize int, clock Clock) *TimeWheel {
+	if tick <= 0 {
+		tick = time.Second
+	}
+	if wheelSize <= 1 {
 		wheelSize = 60 // 默认60个槽位
 	}
 
-	tw := &TimeWheel{
-		tickMs:   tickMs,
-		wheelSize: wheelSize,
-		wheel:    make([][]func(), wheelSize),
-		stopChan: make(chan struct{}),
+	return &TimeWheel{
+		tick:      tick,
+		wheelSize: int64(wheelSize),
+		start:     clock.Now(),
+		clock:     clock,
+		stopChan:  make(chan struct{}),
 	}
-
-	return tw
 }
 
-// Start 启动时间轮
+// Start 启动时间轮，按刻度自动推进
 func (tw *TimeWheel) Start() {
-	tw.ticker = time.NewTicker(time.Duration(tw.tickMs) * time.Millisecond)
+	tw.ticker = time.NewTicker(tw.tick)
 	go tw.run()
 }
 
-// Stop 停止时间轮
+// Stop 停止时间轮，未到期的任务不再执行
 func (tw *TimeWheel) Stop() {
-	if tw.ticker != nil {
-		tw.ticker.Stop()
-	}
-	close(tw.stopChan)
+	tw.stopOnce.Do(func() {
+		if tw.ticker != nil {
+			tw.ticker.Stop()
+		}
+		close(tw.stopChan)
+	})
 }
 
-// AddTask 添加任务
-func (tw *TimeWheel) AddTask(delayMs int64, task func()) {
-	if delayMs <= 0 {
-		// 立即执行
-		go task()
-		return
-	}
+// AddTask 添加延迟执行的任务
+func (tw *TimeWheel) AddTask(delayMs int64, task func()) *Timer {
+	return tw.AfterFunc(time.Duration(delayMs)*time.Millisecond, task)
+}
+
+// AfterFunc 在delay之后执行任务，返回可取消的定时器
+func (tw *TimeWheel) AfterFunc(delay time.Duration, task func()) *Timer {
+	t := tw.NewTimer(task)
+	t.Reset(delay)
+	return t
+}
+
+// NewTimer 创建未启动的定时器，调用Reset后开始计时
+// 任务中需要引用定时器自身（如周期任务）时先创建再启动，避免并发访问未赋值的变量
+func (tw *TimeWheel) NewTimer(task func()) *Timer {
+	return &Timer{tw: tw, task: task}
+}
+
+// Stop 取消定时器，返回定时器在取消前是否处于等待状态
+func (t *Timer) Stop() bool {
+	t.tw.mu.Lock()
+	defer t.tw.mu.Unlock()
+	return t.tw.remove(t)
+}
+
+// Reset 重新设置定时器在delay之后到期，返回定时器在重置前是否处于等待状态
+func (t *Timer) Reset(delay time.Duration) bool {
+	tw := t.tw
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
 
-	ticks := delayMs / tw.tickMs
-	if ticks <= 0 {
+	active := tw.remove(t)
+
+	// 不足一个刻度的延迟向上取整，保证不会提前执行
+	ticks := int64((delay + tw.tick - 1) / tw.tick)
+	if ticks < 1 {
 		ticks = 1
 	}
+	t.expire = tw.currentTick + ticks
+	tw.place(t)
+	return active
+}
+
+// Advance 将时间轮推进到时钟的当前时间，执行期间到期的任务
+func (tw *TimeWheel) Advance() {
+	target := int64(tw.clock.Now().Sub(tw.start) / tw.tick)
 
-	// 计算槽位位置
-	pos := (tw.current + int(ticks)) % tw.wheelSize
+	tw.mu.Lock()
+	for tw.currentTick < target {
+		expired := tw.step()
+		if len(expired) == 0 {
+			continue
+		}
 
-	// 添加到对应槽位
-	tw.wheel[pos] = append(tw.wheel[pos], task)
+		// 释放锁后执行任务，任务中可以重置定时器
+		tw.mu.Unlock()
+		for _, task := range expired {
+			task()
+		}
+		tw.mu.Lock()
+	}
+	tw.mu.Unlock()
+}
+
+// Len 获取等待中的定时器数量
+func (tw *TimeWheel) Len() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	n := 0
+	for _, level := range tw.levels {
+		for _, slot := range level {
+			n += slot.Len()
+		}
+	}
+	return n
 }
 
 // run 运行时间轮
@@ -386,24 +485,66 @@ func (tw *TimeWheel) run() {
 	for {
 		select {
 		case <-tw.ticker.C:
-			tw.tick()
+			tw.Advance()
 		case <-tw.stopChan:
 			return
 		}
 	}
 }
 
-// tick 执行一个tick
-func (tw *TimeWheel) tick() {
-	// 获取当前槽位的任务
-	tasks := tw.wheel[tw.current]
-	tw.wheel[tw.current] = nil // 清空槽位
+// step 推进一个刻度：先将上层到达的槽位下移，再取出第0层当前槽位的到期任务
+func (tw *TimeWheel) step() []func() {
+	tw.currentTick++
 
-	// 执行任务
-	for _, task := range tasks {
-		go task()
+	var expired []func()
+	span := int64(1)
+	for i := range tw.levels {
+		if i > 0 {
+			span *= tw.wheelSize
+			if tw.currentTick%span != 0 {
+				break
+			}
+		}
+		slot := tw.levels[i][(tw.currentTick/span)%tw.wheelSize]
+		for slot.Len() > 0 {
+			t := slot.Remove(slot.Front()).(*Timer)
+			t.slot, t.elem = nil, nil
+			if t.expire <= tw.currentTick {
+				expired = append(expired, t.task)
+			} else {
+				tw.place(t)
+			}
+		}
+	}
+	return expired
+}
+
+// place 按剩余刻度数将定时器放入对应层的槽位，调用方需持有锁
+func (tw *TimeWheel) place(t *Timer) {
+	delta := t.expire - tw.currentTick
+	level, span := 0, int64(1)
+	for delta >= span*tw.wheelSize && span <= math.MaxInt64/(tw.wheelSize*tw.wheelSize) {
+		level++
+		span *= tw.wheelSize
+	}
+	for len(tw.levels) <= level {
+		slots := make([]*list.List, tw.wheelSize)
+		for i := range slots {
+			slots[i] = list.New()
+		}
+		tw.levels = append(tw.levels, slots)
 	}
 
-	// 移动到下一个槽位
-	tw.current = (tw.current + 1) % tw.wheelSize
+	t.slot = tw.levels[level][(t.expire/span)%tw.wheelSize]
+	t.elem = t.slot.PushBack(t)
+}
+
+// remove 从槽位中移除定时器，调用方需持有锁
+func (tw *TimeWheel) remove(t *Timer) bool {
+	if t.slot == nil {
+		return false
+	}
+	t.slot.Remove(t.elem)
+	t.slot, t.elem = nil, nil
+	return true
 }
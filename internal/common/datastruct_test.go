@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock 手动推进的时钟
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestTimeWheel(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1700000000, 0)}
+	tw := NewTimeWheelWithClock(time.Second, 4, clock)
+
+	var fired []string
+	record := func(name string) func() {
+		return func() { fired = append(fired, name) }
+	}
+
+	// 超过一圈（4秒）和超过两层（16秒）的任务也按时执行
+	tw.AfterFunc(2*time.Second, record("2s"))
+	tw.AfterFunc(7*time.Second, record("7s"))
+	tw.AfterFunc(21*time.Second, record("21s"))
+	canceled := tw.AfterFunc(3*time.Second, record("canceled"))
+	reset := tw.AfterFunc(time.Second, record("reset"))
+
+	if !canceled.Stop() {
+		t.Error("等待中的定时器Stop应返回true")
+	}
+	if !reset.Reset(10 * time.Second) {
+		t.Error("等待中的定时器Reset应返回true")
+	}
+
+	expect := map[int][]string{2: {"2s"}, 7: {"7s"}, 10: {"reset"}, 21: {"21s"}}
+	for second := 1; second <= 25; second++ {
+		clock.Add(time.Second)
+		fired = nil
+		tw.Advance()
+		if len(fired) != len(expect[second]) || (len(fired) > 0 && fired[0] != expect[second][0]) {
+			t.Fatalf("第%d秒执行的任务 = %v, 期望 %v", second, fired, expect[second])
+		}
+	}
+
+	if canceled.Stop() {
+		t.Error("已取消的定时器Stop应返回false")
+	}
+	if tw.Len() != 0 {
+		t.Errorf("剩余定时器数 = %d, 期望 0", tw.Len())
+	}
+}
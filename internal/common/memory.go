@@ -11,21 +11,33 @@ import (
 // BufferPool 字节缓冲区对象池
 type BufferPool struct {
 	pool sync.Pool
+	size int
 }
 
 // NewBufferPool 创建字节缓冲区池
 func NewBufferPool() *BufferPool {
+	// 默认创建4KB缓冲区
+	return NewSizedBufferPool(4096)
+}
+
+// NewSizedBufferPool 创建指定缓冲区大小的字节缓冲区池
+func NewSizedBufferPool(size int) *BufferPool {
 	return &BufferPool{
 		pool: sync.Pool{
 			New: func() interface{} {
-				// 默认创建4KB缓冲区
-				buf := make([]byte, 4096)
+				buf := make([]byte, size)
 				return &buf
 			},
 		},
+		size: size,
 	}
 }
 
+// Size 返回池中缓冲区的大小
+func (p *BufferPool) Size() int {
+	return p.size
+}
+
 // Get 获取缓冲区
 func (p *BufferPool) Get() []byte {
 	buf := p.pool.Get().(*[]byte)
@@ -34,7 +46,7 @@ func (p *BufferPool) Get() []byte {
 
 // Put 归还缓冲区
 func (p *BufferPool) Put(buf []byte) {
-	if cap(buf) >= 4096 { // 只回收足够大的缓冲区
+	if cap(buf) >= p.size { // 只回收足够大的缓冲区
 		p.pool.Put(&buf)
 	}
 }
@@ -1,6 +1,9 @@
 package types
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // Player 玩家信息
 type Player struct {
@@ -169,6 +172,7 @@ type TokenClaims struct {
 	UserID    string `json:"user_id"`
 	GameID    string `json:"game_id"`
 	Username  string `json:"username"`
+	TokenType string `json:"token_type"`
 	ExpiresAt int64  `json:"expires_at"`
 	IssuedAt  int64  `json:"issued_at"`
 	TokenID   string `json:"token_id"`
@@ -184,18 +188,18 @@ type APIKey struct {
 	IsActive  bool      `json:"is_active"`
 }
 
-// Cache 缓存接口
+// Cache 缓存接口，ctx 用于远程缓存的超时和取消
 type Cache interface {
 	// Get 获取缓存值
-	Get(key string) ([]byte, error)
+	Get(ctx context.Context, key string) ([]byte, error)
 	// Set 设置缓存值
-	Set(key string, value []byte) error
+	Set(ctx context.Context, key string, value []byte) error
 	// SetWithTTL 设置缓存值并指定TTL
-	SetWithTTL(key string, value []byte, ttl time.Duration) error
+	SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error
 	// Delete 删除缓存值
-	Delete(key string) error
+	Delete(ctx context.Context, key string) error
 	// Exists 检查键是否存在
-	Exists(key string) bool
+	Exists(ctx context.Context, key string) bool
 	// Clear 清空缓存
 	Clear() error
 	// Close 关闭缓存
@@ -26,21 +26,123 @@ type ServerConfig struct {
 
 // HTTPConfig HTTP服务器配置
 type HTTPConfig struct {
-	Host           string        `mapstructure:"host" yaml:"host"`
-	Port           int           `mapstructure:"port" yaml:"port"`
-	ReadTimeout    time.Duration `mapstructure:"read_timeout" yaml:"read_timeout"`
-	WriteTimeout   time.Duration `mapstructure:"write_timeout" yaml:"write_timeout"`
-	MaxHeaderBytes int           `mapstructure:"max_header_bytes" yaml:"max_header_bytes"`
+	Host           string          `mapstructure:"host" yaml:"host"`
+	Port           int             `mapstructure:"port" yaml:"port"`
+	ReadTimeout    time.Duration   `mapstructure:"read_timeout" yaml:"read_timeout"`
+	WriteTimeout   time.Duration   `mapstructure:"write_timeout" yaml:"write_timeout"`
+	MaxHeaderBytes int             `mapstructure:"max_header_bytes" yaml:"max_header_bytes"`
+	AdminToken     string          `mapstructure:"admin_token" yaml:"admin_token"` // 管理接口令牌（X-Admin-Token请求头），为空时不开放管理接口
+	WebSocket      WebSocketConfig `mapstructure:"websocket" yaml:"websocket"`
+	TLS            TLSConfig       `mapstructure:"tls" yaml:"tls"`
+	TrustedProxies []string        `mapstructure:"trusted_proxies" yaml:"trusted_proxies"` // 可信反向代理的地址段，只有来自这些地址的请求才使用X-Forwarded-For/X-Real-IP中的客户端IP
+}
+
+// WebSocketConfig WebSocket接入配置，/ws 承载与TCP端口相同的消息协议
+type WebSocketConfig struct {
+	Enabled        bool     `mapstructure:"enabled" yaml:"enabled"`                 // 是否开放 /ws
+	AllowedOrigins []string `mapstructure:"allowed_origins" yaml:"allowed_origins"` // 允许的页面来源（Origin请求头），为空时不检查
 }
 
 // TCPConfig TCP服务器配置
 type TCPConfig struct {
-	Host           string        `mapstructure:"host" yaml:"host"`
-	Port           int           `mapstructure:"port" yaml:"port"`
-	MaxConnections int           `mapstructure:"max_connections" yaml:"max_connections"`
-	ReadTimeout    time.Duration `mapstructure:"read_timeout" yaml:"read_timeout"`
-	WriteTimeout   time.Duration `mapstructure:"write_timeout" yaml:"write_timeout"`
-	Debug          bool          `mapstructure:"debug" yaml:"debug"` // 是否显示调试信息
+	Host               string            `mapstructure:"host" yaml:"host"`
+	Port               int               `mapstructure:"port" yaml:"port"`
+	MaxConnections     int               `mapstructure:"max_connections" yaml:"max_connections"`
+	ReadTimeout        time.Duration     `mapstructure:"read_timeout" yaml:"read_timeout"`
+	WriteTimeout       time.Duration     `mapstructure:"write_timeout" yaml:"write_timeout"`
+	HandshakeTimeout   time.Duration     `mapstructure:"handshake_timeout" yaml:"handshake_timeout"`       // 连接建立后完成握手认证的超时时间，0表示不限制
+	GameDrainGrace     time.Duration     `mapstructure:"game_drain_grace" yaml:"game_drain_grace"`         // 游戏进入维护或下线后，已有连接收到通知到被断开的宽限时间
+	Debug              bool              `mapstructure:"debug" yaml:"debug"`                               // 是否显示调试信息
+	Codec              string            `mapstructure:"codec" yaml:"codec"`                               // 默认编解码器: binary, json, protobuf
+	CodecNegotiation   bool              `mapstructure:"codec_negotiation" yaml:"codec_negotiation"`       // 是否按连接前导字节或帧格式选择编解码器
+	MaxHeaderSize      int               `mapstructure:"max_header_size" yaml:"max_header_size"`           // 消息头最大字节数
+	MaxBodySize        int               `mapstructure:"max_body_size" yaml:"max_body_size"`               // 消息体最大字节数
+	SendQueueSize      int               `mapstructure:"send_queue_size" yaml:"send_queue_size"`           // 每个连接的发送队列容量，0表示同步写入
+	SlowConsumerPolicy string            `mapstructure:"slow_consumer_policy" yaml:"slow_consumer_policy"` // 发送队列已满时的处理策略: disconnect, drop_oldest
+	WriteBatchSize     int               `mapstructure:"write_batch_size" yaml:"write_batch_size"`         // 单次向量写入合并的最大帧数
+	Engine             string            `mapstructure:"engine" yaml:"engine"`                             // 连接引擎: goroutine 每个连接一个读协程, epoll 事件循环（仅Linux）
+	EventLoops         int               `mapstructure:"event_loops" yaml:"event_loops"`                   // epoll引擎的事件循环协程数，0表示CPU核数
+	Workers            int               `mapstructure:"workers" yaml:"workers"`                           // epoll引擎处理消息的工作协程数，0表示CPU核数的8倍
+	WorkerQueueSize    int               `mapstructure:"worker_queue_size" yaml:"worker_queue_size"`       // epoll引擎每个工作协程的消息队列容量
+	Compression        CompressionConfig `mapstructure:"compression" yaml:"compression"`
+	Encryption         EncryptionConfig  `mapstructure:"encryption" yaml:"encryption"`
+	TLS                TLSConfig         `mapstructure:"tls" yaml:"tls"`
+	ProxyProtocol      ProxyProtocol     `mapstructure:"proxy_protocol" yaml:"proxy_protocol"`
+	Drain              DrainConfig       `mapstructure:"drain" yaml:"drain"`
+	Resume             ResumeConfig      `mapstructure:"resume" yaml:"resume"`
+	Auth               TCPAuthConfig     `mapstructure:"auth" yaml:"auth"`
+	Middleware         TCPMiddleware     `mapstructure:"middleware" yaml:"middleware"`
+}
+
+// TCPMiddleware TCP消息内置中间件配置
+type TCPMiddleware struct {
+	RateLimit      RateLimitConfig      `mapstructure:"rate_limit" yaml:"rate_limit"`
+	PayloadLogging PayloadLoggingConfig `mapstructure:"payload_logging" yaml:"payload_logging"`
+	AllowedTypes   map[string][]uint16  `mapstructure:"allowed_types" yaml:"allowed_types"` // 按游戏限制客户端可发送的业务消息类型，未配置的游戏不限制
+}
+
+// RateLimitConfig 每个连接的消息限流配置（令牌桶）
+type RateLimitConfig struct {
+	Enabled bool    `mapstructure:"enabled" yaml:"enabled"` // 是否启用限流
+	Rate    float64 `mapstructure:"rate" yaml:"rate"`       // 每秒允许的消息数
+	Burst   int     `mapstructure:"burst" yaml:"burst"`     // 允许的突发消息数
+}
+
+// PayloadLoggingConfig 消息体日志配置
+type PayloadLoggingConfig struct {
+	Enabled      bool     `mapstructure:"enabled" yaml:"enabled"`             // 是否以调试级别记录收发的消息体
+	RedactFields []string `mapstructure:"redact_fields" yaml:"redact_fields"` // 需要脱敏的JSON字段名（不区分大小写）
+	MaxBytes     int      `mapstructure:"max_bytes" yaml:"max_bytes"`         // 记录的消息体最大字节数，超出部分截断
+}
+
+// TCPAuthConfig TCP握手认证配置
+type TCPAuthConfig struct {
+	Required bool `mapstructure:"required" yaml:"required"` // 握手是否必须携带访问令牌或会话ID，关闭时信任消息头中的游戏ID和用户ID，仅用于开发调试
+}
+
+// CompressionConfig 消息体压缩配置
+type CompressionConfig struct {
+	Enabled    bool     `mapstructure:"enabled" yaml:"enabled"`       // 是否启用压缩
+	Threshold  int      `mapstructure:"threshold" yaml:"threshold"`   // 压缩阈值（字节），小于该长度的消息体不压缩
+	Algorithms []string `mapstructure:"algorithms" yaml:"algorithms"` // 服务端支持的压缩算法，按优先级排序
+}
+
+// EncryptionConfig 会话加密配置
+type EncryptionConfig struct {
+	Enabled     bool   `mapstructure:"enabled" yaml:"enabled"`           // 是否支持会话加密
+	Required    bool   `mapstructure:"required" yaml:"required"`         // 是否要求所有连接加密
+	IdentityKey string `mapstructure:"identity_key" yaml:"identity_key"` // 服务端Ed25519身份密钥种子（base64），为空时启动时随机生成
+}
+
+// DrainConfig 关闭服务器时排空连接的配置
+type DrainConfig struct {
+	Timeout         time.Duration `mapstructure:"timeout" yaml:"timeout"`                   // 等待客户端断开的最长时间，超时后关闭剩余连接，0表示不排空直接关闭
+	RequestGrace    time.Duration `mapstructure:"request_grace" yaml:"request_grace"`       // 关闭剩余连接前拒绝新的业务请求、等待处理中请求完成的时间，计入timeout
+	ReconnectSpread time.Duration `mapstructure:"reconnect_spread" yaml:"reconnect_spread"` // 客户端重连前等待时间的上限，每个连接在此范围内随机分配
+	Redirect        string        `mapstructure:"redirect" yaml:"redirect"`                 // 建议客户端重连的地址，为空时重连原地址
+}
+
+// ResumeConfig 断线重连后恢复会话的配置
+type ResumeConfig struct {
+	Enabled    bool          `mapstructure:"enabled" yaml:"enabled"`         // 是否在握手时下发恢复令牌
+	Window     time.Duration `mapstructure:"window" yaml:"window"`           // 连接断开后保留会话的时间，超过后客户端需要重新开始会话
+	BufferSize int           `mapstructure:"buffer_size" yaml:"buffer_size"` // 每个会话保留的最近推送消息数，用于重连后补发
+}
+
+// ProxyProtocol TCP端口的PROXY协议配置，用于在负载均衡之后获取客户端真实地址
+type ProxyProtocol struct {
+	Enabled      bool     `mapstructure:"enabled" yaml:"enabled"`             // 是否解析PROXY协议头（v1和v2）
+	TrustedCIDRs []string `mapstructure:"trusted_cidrs" yaml:"trusted_cidrs"` // 可信上游的地址段，来自这些地址的连接必须以PROXY协议头开始，其他连接不解析
+}
+
+// TLSConfig 监听端口的TLS配置，证书文件变化后自动重新加载，已建立的连接不受影响
+type TLSConfig struct {
+	Enabled      bool   `mapstructure:"enabled" yaml:"enabled"`               // 是否启用TLS
+	CertFile     string `mapstructure:"cert_file" yaml:"cert_file"`           // 证书文件路径（PEM，可包含中间证书）
+	KeyFile      string `mapstructure:"key_file" yaml:"key_file"`             // 私钥文件路径（PEM）
+	MinVersion   string `mapstructure:"min_version" yaml:"min_version"`       // 最低TLS版本: 1.2, 1.3
+	ClientAuth   string `mapstructure:"client_auth" yaml:"client_auth"`       // 客户端证书认证: none, request, require, verify_if_given, require_and_verify
+	ClientCAFile string `mapstructure:"client_ca_file" yaml:"client_ca_file"` // 校验客户端证书的CA证书文件路径（PEM）
 }
 
 // LoggerConfig 日志配置
@@ -62,8 +164,9 @@ type LogFileConfig struct {
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	Primary DBConfig   `mapstructure:"primary" yaml:"primary"`
-	Replica []DBConfig `mapstructure:"replica" yaml:"replica"`
+	Primary      DBConfig      `mapstructure:"primary" yaml:"primary"`
+	Replica      []DBConfig    `mapstructure:"replica" yaml:"replica"`
+	QueryTimeout time.Duration `mapstructure:"query_timeout" yaml:"query_timeout"` // 调用方未设置截止时间时单次查询的超时时间，0表示不限制
 }
 
 // DBConfig 数据库连接配置
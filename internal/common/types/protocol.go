@@ -5,8 +5,16 @@ import (
 	"time"
 )
 
-// ProtocolVersion 协议版本
-const ProtocolVersion = 1
+// 协议版本
+const (
+	ProtocolVersionV1 = 1 // 固定消息头
+	ProtocolVersionV2 = 2 // 固定消息头 + TLV扩展字段
+
+	// ProtocolVersion 服务端主动创建消息使用的默认版本，发送时会升级为连接协商出的版本
+	ProtocolVersion = ProtocolVersionV1
+	// MaxProtocolVersion 服务端支持的最高协议版本
+	MaxProtocolVersion = ProtocolVersionV2
+)
 
 // MessageType 消息类型
 type MessageType uint16
@@ -24,11 +32,18 @@ const (
 	MessageTypeOrderOperation MessageType = 0x1005 // 订单操作
 
 	// 系统消息类型
-	MessageTypeError MessageType = 0x2001 // 错误消息
-	MessageTypePing  MessageType = 0x2002 // ping
-	MessageTypePong  MessageType = 0x2003 // pong
+	MessageTypeError  MessageType = 0x2001 // 错误消息
+	MessageTypePing   MessageType = 0x2002 // ping
+	MessageTypePong   MessageType = 0x2003 // pong
+	MessageTypeNotice MessageType = 0x2004 // 服务端通知
+	MessageTypeGoAway MessageType = 0x2005 // 服务器即将关闭，客户端应断开后重连
 )
 
+// IsSystem 是否为系统消息（基础消息和系统消息由服务器自身处理，其余消息路由到游戏处理器）
+func (t MessageType) IsSystem() bool {
+	return t < 0x1000 || t >= 0x2000
+}
+
 // MessageFlag 消息标志
 type MessageFlag uint8
 
@@ -37,7 +52,7 @@ const (
 	FlagCompressed   MessageFlag = 0x01 // 压缩
 	FlagEncrypted    MessageFlag = 0x02 // 加密
 	FlagNeedResponse MessageFlag = 0x04 // 需要响应
-	FlagAsync        MessageFlag = 0x08 // 异步消息
+	FlagAsync        MessageFlag = 0x08 // 异步消息（服务端推送），序列号为会话内的推送序列号
 )
 
 // MessageHeader 消息头
@@ -51,12 +66,118 @@ type MessageHeader struct {
 	Timestamp  int64       `json:"timestamp"`   // 时间戳
 	BodyLength uint32      `json:"body_length"` // 消息体长度
 	Checksum   uint32      `json:"checksum"`    // 校验和
+
+	// v2扩展字段，v1编码时丢弃
+	TraceID     string `json:"trace_id,omitempty"`     // 链路追踪ID
+	Deadline    int64  `json:"deadline,omitempty"`     // 请求截止时间（Unix毫秒），0表示不限
+	ClientBuild string `json:"client_build,omitempty"` // 客户端构建版本
+	ErrorCode   int32  `json:"error_code,omitempty"`   // 错误码，仅错误和响应消息使用
+}
+
+// HeaderExtensionType 消息头扩展字段类型（v2 TLV中的T）
+type HeaderExtensionType uint8
+
+const (
+	ExtTraceID     HeaderExtensionType = 0x01 // 链路追踪ID，字符串
+	ExtDeadline    HeaderExtensionType = 0x02 // 请求截止时间，int64 Unix毫秒
+	ExtClientBuild HeaderExtensionType = 0x03 // 客户端构建版本，字符串
+	ExtErrorCode   HeaderExtensionType = 0x04 // 错误码，int32
+)
+
+// DeadlineExceeded 检查请求是否已超过截止时间
+func (h *MessageHeader) DeadlineExceeded(now time.Time) bool {
+	return h.Deadline > 0 && now.UnixMilli() > h.Deadline
 }
 
 // Message 完整消息
 type Message struct {
 	Header MessageHeader `json:"header"`
 	Body   []byte        `json:"body"`
+
+	release func(*Message) // 归还池化资源的函数，nil表示消息不属于任何池
+}
+
+// SetRelease 设置消息的释放函数，由复用消息对象和缓冲区的连接调用
+func (m *Message) SetRelease(fn func(*Message)) {
+	m.release = fn
+}
+
+// Release 释放消息占用的池化对象和缓冲区，之后不能再访问消息及其消息体
+// 需要在处理完成后继续持有消息体的调用方应先复制；未设置释放函数时为空操作
+func (m *Message) Release() {
+	if fn := m.release; fn != nil {
+		m.release = nil
+		fn(m)
+	}
+}
+
+// HandshakeRequest 握手请求消息体
+type HandshakeRequest struct {
+	Token       string              `json:"token,omitempty"`         // 访问令牌（JWT），与SessionID二选一，连接身份以令牌声明为准
+	SessionID   string              `json:"session_id,omitempty"`    // 玩家登录返回的会话ID
+	Codec       string              `json:"codec,omitempty"`         // 握手后切换到的编解码器
+	Compression []string            `json:"compression,omitempty"`   // 客户端支持的压缩算法
+	KeyExchange *KeyExchangeRequest `json:"key_exchange,omitempty"`  // 会话加密密钥交换
+	ResumeToken string              `json:"resume_token,omitempty"`  // 上一次握手返回的恢复令牌，用于断线重连后恢复会话
+	LastPushSeq uint32              `json:"last_push_seq,omitempty"` // 已收到的最后一条推送的序列号，之后的推送在恢复后补发
+}
+
+// HandshakeResponse 握手响应消息体
+type HandshakeResponse struct {
+	GameID               string `json:"game_id"`                         // 游戏ID
+	UserID               string `json:"user_id"`                         // 用户ID
+	Codec                string `json:"codec,omitempty"`                 // 握手后使用的编解码器
+	Compression          string `json:"compression,omitempty"`           // 协商出的压缩算法，为空表示不压缩
+	CompressionThreshold int    `json:"compression_threshold,omitempty"` // 压缩阈值
+
+	KeyExchange *KeyExchangeResponse `json:"key_exchange,omitempty"` // 会话加密密钥交换，为空表示不加密
+
+	ResumeToken  string `json:"resume_token,omitempty"`  // 恢复令牌，断线重连时在握手请求中携带，为空表示服务器未启用会话恢复
+	ResumeWindow int64  `json:"resume_window,omitempty"` // 断开后可以恢复会话的毫秒数
+	Resumed      bool   `json:"resumed,omitempty"`       // 是否恢复了之前的会话，为false时客户端应重新同步状态
+}
+
+// 游戏运行状态，与游戏表的status字段一致
+const (
+	GameStatusActive      = "active"      // 正常开放
+	GameStatusMaintenance = "maintenance" // 维护中：拒绝新的握手，已有连接收到通知后在宽限时间内断开
+	GameStatusOffline     = "offline"     // 已下线：在维护的基础上注销游戏处理器
+)
+
+// 通知事件
+const (
+	NoticeGameStatus = "game_status" // 游戏运行状态变化
+)
+
+// Notice 服务端通知消息体
+type Notice struct {
+	Event      string `json:"event"`                 // 通知事件
+	GameID     string `json:"game_id,omitempty"`     // 相关的游戏
+	Status     string `json:"status,omitempty"`      // 游戏运行状态
+	Message    string `json:"message,omitempty"`     // 提示信息
+	CloseAfter int64  `json:"close_after,omitempty"` // 连接将在多少毫秒后被关闭，0表示不关闭
+}
+
+// GoAway 服务器排空连接时发送给客户端的消息体
+type GoAway struct {
+	Message        string `json:"message,omitempty"`  // 提示信息
+	Redirect       string `json:"redirect,omitempty"` // 建议重连的地址（host:port），为空时重连原地址（由负载均衡选择其他节点）
+	ReconnectAfter int64  `json:"reconnect_after"`    // 客户端断开并重连前等待的毫秒数，服务端为每个连接随机分配，避免同时重连
+	CloseAfter     int64  `json:"close_after"`        // 服务端在多少毫秒后拒绝新的请求，并在处理中的请求完成后关闭仍未断开的连接
+}
+
+// KeyExchangeRequest 客户端密钥交换数据
+type KeyExchangeRequest struct {
+	Scheme    string `json:"scheme"`     // 密钥交换方案
+	PublicKey string `json:"public_key"` // 客户端临时公钥（base64）
+}
+
+// KeyExchangeResponse 服务端密钥交换数据
+type KeyExchangeResponse struct {
+	Scheme      string `json:"scheme"`       // 密钥交换方案
+	PublicKey   string `json:"public_key"`   // 服务端临时公钥（base64）
+	IdentityKey string `json:"identity_key"` // 服务端身份公钥（base64）
+	Signature   string `json:"signature"`    // 身份密钥对握手记录的签名（base64）
 }
 
 // ConnectionState 连接状态
@@ -80,29 +201,46 @@ type ConnectionInfo struct {
 	LastActivity     time.Time       `json:"last_activity"`     // 最后活动时间
 	GameID           string          `json:"game_id"`           // 游戏ID
 	UserID           string          `json:"user_id"`           // 用户ID
+	Codec            string          `json:"codec"`             // 编解码器名称
+	Transport        string          `json:"transport"`         // 传输方式: tcp, tls, websocket
+	ProtocolVersion  uint8           `json:"protocol_version"`  // 客户端使用的协议版本
+	ClientBuild      string          `json:"client_build"`      // 客户端构建版本
 	BytesReceived    int64           `json:"bytes_received"`    // 接收字节数
 	BytesSent        int64           `json:"bytes_sent"`        // 发送字节数
 	MessagesReceived int64           `json:"messages_received"` // 接收消息数
 	MessagesSent     int64           `json:"messages_sent"`     // 发送消息数
+	MessagesDropped  int64           `json:"messages_dropped"`  // 因发送队列已满丢弃的消息数
+	SendQueueLength  int             `json:"send_queue_length"` // 发送队列中等待的帧数
+	RTT              time.Duration   `json:"rtt"`               // 服务端Ping测得的平滑往返时延，0表示尚未测量
+	RTTJitter        time.Duration   `json:"rtt_jitter"`        // 往返时延抖动（平滑平均偏差）
+	MissedPings      int64           `json:"missed_pings"`      // 连续未响应的Ping次数
 }
 
 // HeartbeatConfig 心跳配置
 type HeartbeatConfig struct {
 	Enabled   bool          `json:"enabled"`    // 是否启用心跳
-	Interval  time.Duration `json:"interval"`   // 心跳间隔
-	Timeout   time.Duration `json:"timeout"`    // 心跳超时时间
-	MaxMissed int           `json:"max_missed"` // 最大连续丢失次数
+	Interval  time.Duration `json:"interval"`   // 心跳检测间隔，连接空闲超过该时间时服务端主动发送Ping
+	Timeout   time.Duration `json:"timeout"`    // 等待Pong的超时时间，期间未收到任何数据计为一次丢失
+	MaxMissed int           `json:"max_missed"` // 最大连续丢失次数，达到后关闭连接
 }
 
 // ConnectionConfig 连接配置
 type ConnectionConfig struct {
-	MaxConnections  int             `json:"max_connections"`  // 最大连接数
-	ReadTimeout     time.Duration   `json:"read_timeout"`     // 读取超时
-	WriteTimeout    time.Duration   `json:"write_timeout"`    // 写入超时
-	BufferSize      int             `json:"buffer_size"`      // 缓冲区大小
-	Heartbeat       HeartbeatConfig `json:"heartbeat"`        // 心跳配置
-	IdleTimeout     time.Duration   `json:"idle_timeout"`     // 空闲超时
-	CleanupInterval time.Duration   `json:"cleanup_interval"` // 清理间隔
+	MaxConnections     int             `json:"max_connections"`      // 最大连接数
+	ReadTimeout        time.Duration   `json:"read_timeout"`         // 读取超时
+	WriteTimeout       time.Duration   `json:"write_timeout"`        // 写入超时
+	BufferSize         int             `json:"buffer_size"`          // 缓冲区大小
+	Codec              string          `json:"codec"`                // 默认编解码器名称
+	CodecNegotiation   bool            `json:"codec_negotiation"`    // 是否根据连接首部字节选择编解码器
+	MaxHeaderSize      int             `json:"max_header_size"`      // 消息头最大字节数，0表示不限制
+	MaxBodySize        int             `json:"max_body_size"`        // 消息体最大字节数，0表示不限制
+	SendQueueSize      int             `json:"send_queue_size"`      // 发送队列容量，0表示在调用方协程同步写入
+	SlowConsumerPolicy string          `json:"slow_consumer_policy"` // 发送队列已满时的处理策略: disconnect, drop_oldest
+	WriteBatchSize     int             `json:"write_batch_size"`     // 单次向量写入合并的最大帧数
+	Heartbeat          HeartbeatConfig `json:"heartbeat"`            // 心跳配置
+	IdleTimeout        time.Duration   `json:"idle_timeout"`         // 空闲超时
+	HandshakeTimeout   time.Duration   `json:"handshake_timeout"`    // 握手超时，连接建立后需在此时间内完成认证，0表示不限制
+	CleanupInterval    time.Duration   `json:"cleanup_interval"`     // 清理间隔
 }
 
 // Request 业务请求
@@ -111,7 +249,8 @@ type Request struct {
 	Type      MessageType   `json:"type"`      // 请求类型
 	GameID    string        `json:"game_id"`   // 游戏ID
 	UserID    string        `json:"user_id"`   // 用户ID
-	Data      interface{}   `json:"data"`      // 请求数据
+	ClientIP  string        `json:"client_ip"` // 客户端真实IP
+	Data      interface{}   `json:"data"`      // 请求数据（TCP请求的[]byte引用连接读缓冲区，处理器返回后失效，需要保留时应复制）
 	Timestamp int64         `json:"timestamp"` // 时间戳
 	Timeout   time.Duration `json:"-"`         // 超时时间
 }
@@ -125,6 +264,21 @@ type Response struct {
 	Timestamp int64       `json:"timestamp"` // 时间戳
 }
 
+// OperationInfo 游戏处理器支持的操作
+type OperationInfo struct {
+	MessageType MessageType      `json:"message_type"`        // 消息类型
+	Operation   string           `json:"operation,omitempty"` // 请求体operation字段的值，为空表示该消息类型不区分操作
+	Description string           `json:"description,omitempty"`
+	Fields      []OperationField `json:"fields,omitempty"` // 请求体字段
+}
+
+// OperationField 操作请求体字段
+type OperationField struct {
+	Name  string `json:"name"`            // JSON字段名
+	Type  string `json:"type"`            // Go类型
+	Rules string `json:"rules,omitempty"` // 校验规则
+}
+
 // Handler 消息处理器接口
 type Handler interface {
 	Handle(connID string, req *Request) (*Response, error)
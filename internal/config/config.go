@@ -1,13 +1,18 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"datamiddleware/internal/common/types"
+	"datamiddleware/internal/infrastructure/certs"
+	"datamiddleware/internal/protocol"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
 )
 
@@ -72,12 +77,51 @@ func setDefaults() {
 	viper.SetDefault("server.http.read_timeout", "30s")
 	viper.SetDefault("server.http.write_timeout", "30s")
 	viper.SetDefault("server.http.max_header_bytes", 1048576)
+	viper.SetDefault("server.http.websocket.enabled", true)
+	viper.SetDefault("server.http.tls.enabled", false)
+	viper.SetDefault("server.http.tls.min_version", "1.2")
+	viper.SetDefault("server.http.tls.client_auth", "none")
 
 	viper.SetDefault("server.tcp.host", "0.0.0.0")
 	viper.SetDefault("server.tcp.port", 9090)
 	viper.SetDefault("server.tcp.max_connections", 10000)
 	viper.SetDefault("server.tcp.read_timeout", "30s")
 	viper.SetDefault("server.tcp.write_timeout", "30s")
+	viper.SetDefault("server.tcp.handshake_timeout", "10s")
+	viper.SetDefault("server.tcp.game_drain_grace", "30s")
+	viper.SetDefault("server.tcp.codec", "binary")
+	viper.SetDefault("server.tcp.codec_negotiation", true)
+	viper.SetDefault("server.tcp.max_header_size", 16384)
+	viper.SetDefault("server.tcp.max_body_size", 4194304)
+	viper.SetDefault("server.tcp.send_queue_size", 1024)
+	viper.SetDefault("server.tcp.slow_consumer_policy", "disconnect")
+	viper.SetDefault("server.tcp.write_batch_size", 64)
+	viper.SetDefault("server.tcp.engine", "goroutine")
+	viper.SetDefault("server.tcp.event_loops", 0)
+	viper.SetDefault("server.tcp.workers", 0)
+	viper.SetDefault("server.tcp.worker_queue_size", 256)
+	viper.SetDefault("server.tcp.compression.enabled", true)
+	viper.SetDefault("server.tcp.compression.threshold", 1024)
+	viper.SetDefault("server.tcp.compression.algorithms", []string{"deflate", "gzip"})
+	viper.SetDefault("server.tcp.encryption.enabled", true)
+	viper.SetDefault("server.tcp.encryption.required", false)
+	viper.SetDefault("server.tcp.auth.required", true)
+	viper.SetDefault("server.tcp.tls.enabled", false)
+	viper.SetDefault("server.tcp.tls.min_version", "1.2")
+	viper.SetDefault("server.tcp.tls.client_auth", "none")
+	viper.SetDefault("server.tcp.proxy_protocol.enabled", false)
+	viper.SetDefault("server.tcp.drain.timeout", "20s")
+	viper.SetDefault("server.tcp.drain.reconnect_spread", "5s")
+	viper.SetDefault("server.tcp.drain.request_grace", "5s")
+	viper.SetDefault("server.tcp.resume.enabled", true)
+	viper.SetDefault("server.tcp.resume.window", "60s")
+	viper.SetDefault("server.tcp.resume.buffer_size", 256)
+	viper.SetDefault("server.tcp.middleware.rate_limit.enabled", true)
+	viper.SetDefault("server.tcp.middleware.rate_limit.rate", 100)
+	viper.SetDefault("server.tcp.middleware.rate_limit.burst", 200)
+	viper.SetDefault("server.tcp.middleware.payload_logging.enabled", false)
+	viper.SetDefault("server.tcp.middleware.payload_logging.redact_fields", []string{"password", "token", "access_token", "refresh_token", "session_id", "key_exchange"})
+	viper.SetDefault("server.tcp.middleware.payload_logging.max_bytes", 1024)
 
 	// 日志默认配置
 	viper.SetDefault("logger.level", "info")
@@ -100,6 +144,7 @@ func setDefaults() {
 	viper.SetDefault("database.primary.max_open_conns", 100)
 	viper.SetDefault("database.primary.max_idle_conns", 10)
 	viper.SetDefault("database.primary.conn_max_lifetime", "300s")
+	viper.SetDefault("database.query_timeout", "5s")
 
 	// Redis默认配置
 	viper.SetDefault("redis.host", "localhost")
@@ -144,6 +189,86 @@ func validateConfig(cfg *types.Config) error {
 		return fmt.Errorf("无效的TCP端口: %d", cfg.Server.TCP.Port)
 	}
 
+	// 验证TCP编解码器（为空时使用二进制编解码器）
+	validCodecs := []string{"binary", "json", "protobuf"}
+	if cfg.Server.TCP.Codec != "" && !contains(validCodecs, cfg.Server.TCP.Codec) {
+		return fmt.Errorf("无效的TCP编解码器: %s", cfg.Server.TCP.Codec)
+	}
+
+	// 验证帧大小限制（0表示不限制）
+	if cfg.Server.TCP.MaxHeaderSize < 0 || cfg.Server.TCP.MaxBodySize < 0 {
+		return fmt.Errorf("无效的TCP帧大小限制: max_header_size=%d, max_body_size=%d", cfg.Server.TCP.MaxHeaderSize, cfg.Server.TCP.MaxBodySize)
+	}
+
+	// 验证慢消费者策略
+	validPolicies := []string{"disconnect", "drop_oldest"}
+	if cfg.Server.TCP.SlowConsumerPolicy != "" && !contains(validPolicies, cfg.Server.TCP.SlowConsumerPolicy) {
+		return fmt.Errorf("无效的慢消费者策略: %s", cfg.Server.TCP.SlowConsumerPolicy)
+	}
+
+	// 验证连接引擎
+	validEngines := []string{"goroutine", "epoll"}
+	if cfg.Server.TCP.Engine != "" && !contains(validEngines, cfg.Server.TCP.Engine) {
+		return fmt.Errorf("无效的TCP连接引擎: %s", cfg.Server.TCP.Engine)
+	}
+	if cfg.Server.TCP.EventLoops < 0 || cfg.Server.TCP.Workers < 0 || cfg.Server.TCP.WorkerQueueSize < 0 {
+		return fmt.Errorf("无效的TCP事件循环配置: event_loops=%d, workers=%d, worker_queue_size=%d", cfg.Server.TCP.EventLoops, cfg.Server.TCP.Workers, cfg.Server.TCP.WorkerQueueSize)
+	}
+
+	// 验证会话加密身份密钥
+	if key := cfg.Server.TCP.Encryption.IdentityKey; key != "" {
+		seed, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(seed) != 32 {
+			return fmt.Errorf("无效的TCP加密身份密钥，应为base64编码的32字节种子")
+		}
+	}
+
+	// 验证TLS配置
+	if err := certs.Validate(cfg.Server.HTTP.TLS); err != nil {
+		return fmt.Errorf("HTTP服务器: %w", err)
+	}
+	if err := certs.Validate(cfg.Server.TCP.TLS); err != nil {
+		return fmt.Errorf("TCP服务器: %w", err)
+	}
+
+	// 验证可信代理地址
+	if _, err := protocol.ParseProxyTrust(cfg.Server.HTTP.TrustedProxies); err != nil {
+		return fmt.Errorf("无效的HTTP可信代理: %w", err)
+	}
+	if pp := cfg.Server.TCP.ProxyProtocol; pp.Enabled {
+		if len(pp.TrustedCIDRs) == 0 {
+			return fmt.Errorf("启用PROXY协议必须配置可信上游(server.tcp.proxy_protocol.trusted_cidrs)")
+		}
+		if _, err := protocol.ParseProxyTrust(pp.TrustedCIDRs); err != nil {
+			return fmt.Errorf("无效的PROXY协议可信上游: %w", err)
+		}
+	}
+
+	// 验证排空连接配置
+	if drain := cfg.Server.TCP.Drain; drain.Timeout < 0 || drain.ReconnectSpread < 0 || drain.RequestGrace < 0 || drain.RequestGrace > drain.Timeout {
+		return fmt.Errorf("无效的排空连接配置: timeout=%s, reconnect_spread=%s, request_grace=%s", drain.Timeout, drain.ReconnectSpread, drain.RequestGrace)
+	}
+	if redirect := cfg.Server.TCP.Drain.Redirect; redirect != "" {
+		if _, _, err := net.SplitHostPort(redirect); err != nil {
+			return fmt.Errorf("无效的排空连接重连地址 %s: %w", redirect, err)
+		}
+	}
+
+	// 验证会话恢复配置
+	if resume := cfg.Server.TCP.Resume; resume.Enabled && (resume.Window <= 0 || resume.BufferSize <= 0) {
+		return fmt.Errorf("无效的会话恢复配置: window=%s, buffer_size=%d", resume.Window, resume.BufferSize)
+	}
+
+	// 验证TCP限流配置
+	if rl := cfg.Server.TCP.Middleware.RateLimit; rl.Enabled && (rl.Rate <= 0 || rl.Burst < 1) {
+		return fmt.Errorf("无效的TCP限流配置: rate=%v, burst=%d", rl.Rate, rl.Burst)
+	}
+
+	// 生产环境必须校验握手凭证
+	if cfg.Server.Env == "prod" && !cfg.Server.TCP.Auth.Required {
+		return fmt.Errorf("生产环境必须启用TCP握手认证(server.tcp.auth.required)")
+	}
+
 	// 验证日志级别
 	validLevels := []string{"debug", "info", "warn", "error"}
 	if !contains(validLevels, cfg.Logger.Level) {
@@ -156,8 +281,10 @@ func validateConfig(cfg *types.Config) error {
 		return fmt.Errorf("无效的数据库驱动: %s", cfg.Database.Primary.Driver)
 	}
 
-	// 验证游戏配置
+	// 验证游戏配置，启用的游戏的端口和HTTP前缀不能与其他游戏或服务器冲突
 	gameIDs := make(map[string]bool)
+	ports := map[int]string{cfg.Server.HTTP.Port: "HTTP服务器", cfg.Server.TCP.Port: "TCP服务器"}
+	prefixes := make(map[string]string)
 	for _, game := range cfg.Games {
 		if game.ID == "" {
 			return fmt.Errorf("游戏ID不能为空")
@@ -170,6 +297,24 @@ func validateConfig(cfg *types.Config) error {
 		if game.TCPPort < 1 || game.TCPPort > 65535 {
 			return fmt.Errorf("游戏 %s 的TCP端口无效: %d", game.ID, game.TCPPort)
 		}
+		if !game.Enabled {
+			continue
+		}
+		if owner, ok := ports[game.TCPPort]; ok {
+			return fmt.Errorf("游戏 %s 的TCP端口 %d 与%s冲突", game.ID, game.TCPPort, owner)
+		}
+		ports[game.TCPPort] = "游戏" + game.ID
+
+		if game.HTTPPrefix != "" {
+			prefix := strings.TrimSuffix(game.HTTPPrefix, "/")
+			if !strings.HasPrefix(prefix, "/") || prefix == "/api/v1" || strings.HasPrefix(prefix, "/api/v1/") {
+				return fmt.Errorf("游戏 %s 的HTTP前缀无效: %s", game.ID, game.HTTPPrefix)
+			}
+			if owner, ok := prefixes[prefix]; ok {
+				return fmt.Errorf("游戏 %s 的HTTP前缀 %s 与游戏 %s 冲突", game.ID, game.HTTPPrefix, owner)
+			}
+			prefixes[prefix] = game.ID
+		}
 	}
 
 	return nil
@@ -185,6 +330,19 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
+// OnChange 注册配置文件变化的回调，配置重新解析并通过校验后调用fn，失败时cfg为nil、err说明原因
+func OnChange(fn func(cfg *types.Config, err error)) {
+	viper.OnConfigChange(func(fsnotify.Event) {
+		cfg, err := GetConfig()
+		if err == nil {
+			if err = validateConfig(cfg); err != nil {
+				cfg, err = nil, fmt.Errorf("配置验证失败: %w", err)
+			}
+		}
+		fn(cfg, err)
+	})
+}
+
 // GetConfig 获取当前配置（用于热更新后的重新加载）
 func GetConfig() (*types.Config, error) {
 	var cfg types.Config
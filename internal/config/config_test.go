@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"datamiddleware/internal/common/types"
 
@@ -143,6 +144,116 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "游戏端口冲突",
+			config: validGamesConfig(
+				types.GameConfig{ID: "game1", Enabled: true, TCPPort: 9101},
+				types.GameConfig{ID: "game2", Enabled: true, TCPPort: 9101},
+			),
+			wantErr: true,
+		},
+		{
+			name: "游戏端口与服务器冲突",
+			config: validGamesConfig(
+				types.GameConfig{ID: "game1", Enabled: true, TCPPort: 9090},
+			),
+			wantErr: true,
+		},
+		{
+			name: "重复HTTP前缀",
+			config: validGamesConfig(
+				types.GameConfig{ID: "game1", Enabled: true, TCPPort: 9101, HTTPPrefix: "/api/game"},
+				types.GameConfig{ID: "game2", Enabled: true, TCPPort: 9102, HTTPPrefix: "/api/game/"},
+			),
+			wantErr: true,
+		},
+		{
+			name: "无效HTTP前缀",
+			config: validGamesConfig(
+				types.GameConfig{ID: "game1", Enabled: true, TCPPort: 9101, HTTPPrefix: "/api/v1/game1"},
+			),
+			wantErr: true,
+		},
+		{
+			name: "TLS缺少证书文件",
+			config: func() *types.Config {
+				cfg := validGamesConfig()
+				cfg.Server.TCP.TLS = types.TLSConfig{Enabled: true, KeyFile: "server.key"}
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "TLS校验客户端证书缺少CA",
+			config: func() *types.Config {
+				cfg := validGamesConfig()
+				cfg.Server.HTTP.TLS = types.TLSConfig{Enabled: true, CertFile: "server.crt", KeyFile: "server.key", ClientAuth: "require_and_verify"}
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "TLS不支持的最低版本",
+			config: func() *types.Config {
+				cfg := validGamesConfig()
+				cfg.Server.TCP.TLS = types.TLSConfig{Enabled: true, CertFile: "server.crt", KeyFile: "server.key", MinVersion: "1.0"}
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "PROXY协议缺少可信上游",
+			config: func() *types.Config {
+				cfg := validGamesConfig()
+				cfg.Server.TCP.ProxyProtocol = types.ProxyProtocol{Enabled: true}
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "无效的HTTP可信代理",
+			config: func() *types.Config {
+				cfg := validGamesConfig()
+				cfg.Server.HTTP.TrustedProxies = []string{"10.0.0.0/33"}
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "排空连接重连地址缺少端口",
+			config: func() *types.Config {
+				cfg := validGamesConfig()
+				cfg.Server.TCP.Drain = types.DrainConfig{Timeout: 30 * time.Second, Redirect: "backup.example.com"}
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "排空连接关闭阶段超过超时时间",
+			config: func() *types.Config {
+				cfg := validGamesConfig()
+				cfg.Server.TCP.Drain = types.DrainConfig{Timeout: 10 * time.Second, RequestGrace: 20 * time.Second}
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "会话恢复缺少保留时间",
+			config: func() *types.Config {
+				cfg := validGamesConfig()
+				cfg.Server.TCP.Resume = types.ResumeConfig{Enabled: true, BufferSize: 256}
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "禁用的游戏不检查冲突",
+			config: validGamesConfig(
+				types.GameConfig{ID: "game1", Enabled: true, TCPPort: 9101, HTTPPrefix: "/api/game1"},
+				types.GameConfig{ID: "game2", Enabled: false, TCPPort: 9101, HTTPPrefix: "/api/game1"},
+			),
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -155,6 +266,20 @@ func TestValidateConfig(t *testing.T) {
 	}
 }
 
+// validGamesConfig 返回使用指定游戏配置的有效配置
+func validGamesConfig(games ...types.GameConfig) *types.Config {
+	return &types.Config{
+		Server: types.ServerConfig{
+			Env:  "dev",
+			HTTP: types.HTTPConfig{Port: 8080},
+			TCP:  types.TCPConfig{Port: 9090},
+		},
+		Logger:   types.LoggerConfig{Level: "info"},
+		Database: types.DatabaseConfig{Primary: types.DBConfig{Driver: "mysql"}},
+		Games:    games,
+	}
+}
+
 func TestSetDefaults(t *testing.T) {
 	// 清除所有设置
 	// 注意：这里只是测试默认值设置，不进行实际验证
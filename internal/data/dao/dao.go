@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -11,52 +12,52 @@ import (
 // DAO 数据访问对象接口
 type DAO interface {
 	// 玩家相关
-	CreatePlayer(player *Player) error
-	GetPlayerByID(userID string) (*Player, error)
-	GetPlayerByUsername(username string) (*Player, error)
-	UpdatePlayer(player *Player) error
-	DeletePlayer(userID string) error
-	ListPlayers(gameID string, offset, limit int) ([]*Player, int64, error)
+	CreatePlayer(ctx context.Context, player *Player) error
+	GetPlayerByID(ctx context.Context, userID string) (*Player, error)
+	GetPlayerByUsername(ctx context.Context, username string) (*Player, error)
+	UpdatePlayer(ctx context.Context, player *Player) error
+	DeletePlayer(ctx context.Context, userID string) error
+	ListPlayers(ctx context.Context, gameID string, offset, limit int) ([]*Player, int64, error)
 
 	// 会话相关
-	CreateSession(session *PlayerSession) error
-	GetSessionByID(sessionID string) (*PlayerSession, error)
-	GetActiveSessions(userID string) ([]*PlayerSession, error)
-	UpdateSession(session *PlayerSession) error
-	InvalidateSession(sessionID string) error
-	CleanupExpiredSessions() error
+	CreateSession(ctx context.Context, session *PlayerSession) error
+	GetSessionByID(ctx context.Context, sessionID string) (*PlayerSession, error)
+	GetActiveSessions(ctx context.Context, userID string) ([]*PlayerSession, error)
+	UpdateSession(ctx context.Context, session *PlayerSession) error
+	InvalidateSession(ctx context.Context, sessionID string) error
+	CleanupExpiredSessions(ctx context.Context) error
 
 	// 道具相关
-	CreateItem(item *Item) error
-	GetItemByID(itemID string) (*Item, error)
-	GetUserItems(userID string, gameID string) ([]*Item, error)
-	UpdateItem(item *Item) error
-	DeleteItem(itemID string) error
-	ConsumeItem(itemID string, quantity int64) error
-	AddItemQuantity(itemID string, quantity int64) error
+	CreateItem(ctx context.Context, item *Item) error
+	GetItemByID(ctx context.Context, itemID string) (*Item, error)
+	GetUserItems(ctx context.Context, userID string, gameID string) ([]*Item, error)
+	UpdateItem(ctx context.Context, item *Item) error
+	DeleteItem(ctx context.Context, itemID string) error
+	ConsumeItem(ctx context.Context, itemID string, quantity int64) error
+	AddItemQuantity(ctx context.Context, itemID string, quantity int64) error
 
 	// 订单相关
-	CreateOrder(order *Order) error
-	GetOrderByID(orderID string) (*Order, error)
-	GetUserOrders(userID string, status string, offset, limit int) ([]*Order, int64, error)
-	UpdateOrderStatus(orderID string, status string) error
-	GetOrdersByStatus(status string, offset, limit int) ([]*Order, int64, error)
+	CreateOrder(ctx context.Context, order *Order) error
+	GetOrderByID(ctx context.Context, orderID string) (*Order, error)
+	GetUserOrders(ctx context.Context, userID string, status string, offset, limit int) ([]*Order, int64, error)
+	UpdateOrderStatus(ctx context.Context, orderID string, status string) error
+	GetOrdersByStatus(ctx context.Context, status string, offset, limit int) ([]*Order, int64, error)
 
 	// 游戏相关
-	CreateGame(game *Game) error
-	GetGameByID(gameID string) (*Game, error)
-	ListGames(offset, limit int) ([]*Game, int64, error)
-	UpdateGame(game *Game) error
-	DeleteGame(gameID string) error
+	CreateGame(ctx context.Context, game *Game) error
+	GetGameByID(ctx context.Context, gameID string) (*Game, error)
+	ListGames(ctx context.Context, offset, limit int) ([]*Game, int64, error)
+	UpdateGame(ctx context.Context, game *Game) error
+	DeleteGame(ctx context.Context, gameID string) error
 
 	// 统计相关
-	CreateGameStats(stats *GameStats) error
-	GetGameStats(gameID string, date time.Time) (*GameStats, error)
-	UpdateGameStats(gameID string, date time.Time, updates map[string]interface{}) error
+	CreateGameStats(ctx context.Context, stats *GameStats) error
+	GetGameStats(ctx context.Context, gameID string, date time.Time) (*GameStats, error)
+	UpdateGameStats(ctx context.Context, gameID string, date time.Time, updates map[string]interface{}) error
 
 	// 日志相关
-	CreateSystemLog(logEntry *SystemLog) error
-	ListSystemLogs(filters map[string]interface{}, offset, limit int) ([]*SystemLog, int64, error)
+	CreateSystemLog(ctx context.Context, logEntry *SystemLog) error
+	ListSystemLogs(ctx context.Context, filters map[string]interface{}, offset, limit int) ([]*SystemLog, int64, error)
 }
 
 // daoImpl DAO实现
@@ -73,9 +74,32 @@ func NewDAO(db *Database, logger logger.Logger) DAO {
 	}
 }
 
+// master 返回绑定上下文的主库会话，上下文没有截止时间时使用默认的查询超时
+func (d *daoImpl) master(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	ctx, cancel := d.withQueryTimeout(ctx)
+	return d.db.Master().WithContext(ctx), cancel
+}
+
+// slave 返回绑定上下文的从库会话，上下文没有截止时间时使用默认的查询超时
+func (d *daoImpl) slave(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	ctx, cancel := d.withQueryTimeout(ctx)
+	return d.db.Slave().WithContext(ctx), cancel
+}
+
+// withQueryTimeout 为没有截止时间的上下文设置默认的查询超时，调用方的截止时间和取消优先
+func (d *daoImpl) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || d.db.config.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d.db.config.QueryTimeout)
+}
+
 // CreatePlayer 创建玩家
-func (d *daoImpl) CreatePlayer(player *Player) error {
-	result := d.db.Master().Create(player)
+func (d *daoImpl) CreatePlayer(ctx context.Context, player *Player) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Create(player)
 	if result.Error != nil {
 		d.logger.Error("创建玩家失败", "user_id", player.UserID, "error", result.Error)
 		return result.Error
@@ -85,9 +109,12 @@ func (d *daoImpl) CreatePlayer(player *Player) error {
 }
 
 // GetPlayerByID 根据ID获取玩家
-func (d *daoImpl) GetPlayerByID(userID string) (*Player, error) {
+func (d *daoImpl) GetPlayerByID(ctx context.Context, userID string) (*Player, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var player Player
-	result := d.db.Slave().Where("user_id = ?", userID).First(&player)
+	result := db.Where("user_id = ?", userID).First(&player)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -99,9 +126,12 @@ func (d *daoImpl) GetPlayerByID(userID string) (*Player, error) {
 }
 
 // GetPlayerByUsername 根据用户名获取玩家
-func (d *daoImpl) GetPlayerByUsername(username string) (*Player, error) {
+func (d *daoImpl) GetPlayerByUsername(ctx context.Context, username string) (*Player, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var player Player
-	result := d.db.Slave().Where("username = ?", username).First(&player)
+	result := db.Where("username = ?", username).First(&player)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -113,8 +143,11 @@ func (d *daoImpl) GetPlayerByUsername(username string) (*Player, error) {
 }
 
 // UpdatePlayer 更新玩家
-func (d *daoImpl) UpdatePlayer(player *Player) error {
-	result := d.db.Master().Save(player)
+func (d *daoImpl) UpdatePlayer(ctx context.Context, player *Player) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Save(player)
 	if result.Error != nil {
 		d.logger.Error("更新玩家失败", "user_id", player.UserID, "error", result.Error)
 		return result.Error
@@ -124,8 +157,11 @@ func (d *daoImpl) UpdatePlayer(player *Player) error {
 }
 
 // DeletePlayer 删除玩家
-func (d *daoImpl) DeletePlayer(userID string) error {
-	result := d.db.Master().Where("user_id = ?", userID).Delete(&Player{})
+func (d *daoImpl) DeletePlayer(ctx context.Context, userID string) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Where("user_id = ?", userID).Delete(&Player{})
 	if result.Error != nil {
 		d.logger.Error("删除玩家失败", "user_id", userID, "error", result.Error)
 		return result.Error
@@ -135,11 +171,14 @@ func (d *daoImpl) DeletePlayer(userID string) error {
 }
 
 // ListPlayers 列出玩家
-func (d *daoImpl) ListPlayers(gameID string, offset, limit int) ([]*Player, int64, error) {
+func (d *daoImpl) ListPlayers(ctx context.Context, gameID string, offset, limit int) ([]*Player, int64, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var players []*Player
 	var total int64
 
-	query := d.db.Slave().Model(&Player{})
+	query := db.Model(&Player{})
 	if gameID != "" {
 		query = query.Where("game_id = ?", gameID)
 	}
@@ -160,8 +199,11 @@ func (d *daoImpl) ListPlayers(gameID string, offset, limit int) ([]*Player, int6
 }
 
 // CreateSession 创建会话
-func (d *daoImpl) CreateSession(session *PlayerSession) error {
-	result := d.db.Master().Create(session)
+func (d *daoImpl) CreateSession(ctx context.Context, session *PlayerSession) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Create(session)
 	if result.Error != nil {
 		d.logger.Error("创建会话失败", "session_id", session.SessionID, "error", result.Error)
 		return result.Error
@@ -171,9 +213,12 @@ func (d *daoImpl) CreateSession(session *PlayerSession) error {
 }
 
 // GetSessionByID 根据ID获取会话
-func (d *daoImpl) GetSessionByID(sessionID string) (*PlayerSession, error) {
+func (d *daoImpl) GetSessionByID(ctx context.Context, sessionID string) (*PlayerSession, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var session PlayerSession
-	result := d.db.Slave().Where("session_id = ?", sessionID).First(&session)
+	result := db.Where("session_id = ?", sessionID).First(&session)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -185,9 +230,12 @@ func (d *daoImpl) GetSessionByID(sessionID string) (*PlayerSession, error) {
 }
 
 // GetActiveSessions 获取用户活跃会话
-func (d *daoImpl) GetActiveSessions(userID string) ([]*PlayerSession, error) {
+func (d *daoImpl) GetActiveSessions(ctx context.Context, userID string) ([]*PlayerSession, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var sessions []*PlayerSession
-	result := d.db.Slave().Where("user_id = ? AND is_active = ?", userID, true).Find(&sessions)
+	result := db.Where("user_id = ? AND is_active = ?", userID, true).Find(&sessions)
 	if result.Error != nil {
 		d.logger.Error("获取活跃会话失败", "user_id", userID, "error", result.Error)
 		return nil, result.Error
@@ -196,8 +244,11 @@ func (d *daoImpl) GetActiveSessions(userID string) ([]*PlayerSession, error) {
 }
 
 // UpdateSession 更新会话
-func (d *daoImpl) UpdateSession(session *PlayerSession) error {
-	result := d.db.Master().Save(session)
+func (d *daoImpl) UpdateSession(ctx context.Context, session *PlayerSession) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Save(session)
 	if result.Error != nil {
 		d.logger.Error("更新会话失败", "session_id", session.SessionID, "error", result.Error)
 		return result.Error
@@ -207,8 +258,11 @@ func (d *daoImpl) UpdateSession(session *PlayerSession) error {
 }
 
 // InvalidateSession 使会话失效
-func (d *daoImpl) InvalidateSession(sessionID string) error {
-	result := d.db.Master().Model(&PlayerSession{}).Where("session_id = ?", sessionID).Update("is_active", false)
+func (d *daoImpl) InvalidateSession(ctx context.Context, sessionID string) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Model(&PlayerSession{}).Where("session_id = ?", sessionID).Update("is_active", false)
 	if result.Error != nil {
 		d.logger.Error("使会话失效失败", "session_id", sessionID, "error", result.Error)
 		return result.Error
@@ -218,8 +272,11 @@ func (d *daoImpl) InvalidateSession(sessionID string) error {
 }
 
 // CleanupExpiredSessions 清理过期会话
-func (d *daoImpl) CleanupExpiredSessions() error {
-	result := d.db.Master().Where("expire_at < ? OR is_active = ?", time.Now(), false).Delete(&PlayerSession{})
+func (d *daoImpl) CleanupExpiredSessions(ctx context.Context) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Where("expire_at < ? OR is_active = ?", time.Now(), false).Delete(&PlayerSession{})
 	if result.Error != nil {
 		d.logger.Error("清理过期会话失败", "error", result.Error)
 		return result.Error
@@ -229,8 +286,11 @@ func (d *daoImpl) CleanupExpiredSessions() error {
 }
 
 // CreateItem 创建道具
-func (d *daoImpl) CreateItem(item *Item) error {
-	result := d.db.Master().Create(item)
+func (d *daoImpl) CreateItem(ctx context.Context, item *Item) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Create(item)
 	if result.Error != nil {
 		d.logger.Error("创建道具失败", "item_id", item.ItemID, "error", result.Error)
 		return result.Error
@@ -240,9 +300,12 @@ func (d *daoImpl) CreateItem(item *Item) error {
 }
 
 // GetItemByID 根据ID获取道具
-func (d *daoImpl) GetItemByID(itemID string) (*Item, error) {
+func (d *daoImpl) GetItemByID(ctx context.Context, itemID string) (*Item, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var item Item
-	result := d.db.Slave().Where("item_id = ?", itemID).First(&item)
+	result := db.Where("item_id = ?", itemID).First(&item)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -254,9 +317,12 @@ func (d *daoImpl) GetItemByID(itemID string) (*Item, error) {
 }
 
 // GetUserItems 获取用户道具
-func (d *daoImpl) GetUserItems(userID string, gameID string) ([]*Item, error) {
+func (d *daoImpl) GetUserItems(ctx context.Context, userID string, gameID string) ([]*Item, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var items []*Item
-	query := d.db.Slave().Where("user_id = ?", userID)
+	query := db.Where("user_id = ?", userID)
 	if gameID != "" {
 		query = query.Where("game_id = ?", gameID)
 	}
@@ -270,8 +336,11 @@ func (d *daoImpl) GetUserItems(userID string, gameID string) ([]*Item, error) {
 }
 
 // UpdateItem 更新道具
-func (d *daoImpl) UpdateItem(item *Item) error {
-	result := d.db.Master().Save(item)
+func (d *daoImpl) UpdateItem(ctx context.Context, item *Item) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Save(item)
 	if result.Error != nil {
 		d.logger.Error("更新道具失败", "item_id", item.ItemID, "error", result.Error)
 		return result.Error
@@ -281,8 +350,11 @@ func (d *daoImpl) UpdateItem(item *Item) error {
 }
 
 // DeleteItem 删除道具
-func (d *daoImpl) DeleteItem(itemID string) error {
-	result := d.db.Master().Where("item_id = ?", itemID).Delete(&Item{})
+func (d *daoImpl) DeleteItem(ctx context.Context, itemID string) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Where("item_id = ?", itemID).Delete(&Item{})
 	if result.Error != nil {
 		d.logger.Error("删除道具失败", "item_id", itemID, "error", result.Error)
 		return result.Error
@@ -292,8 +364,11 @@ func (d *daoImpl) DeleteItem(itemID string) error {
 }
 
 // ConsumeItem 消耗道具
-func (d *daoImpl) ConsumeItem(itemID string, quantity int64) error {
-	result := d.db.Master().Model(&Item{}).Where("item_id = ? AND quantity >= ?", itemID, quantity).Update("quantity", gorm.Expr("quantity - ?", quantity))
+func (d *daoImpl) ConsumeItem(ctx context.Context, itemID string, quantity int64) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Model(&Item{}).Where("item_id = ? AND quantity >= ?", itemID, quantity).Update("quantity", gorm.Expr("quantity - ?", quantity))
 	if result.Error != nil {
 		d.logger.Error("消耗道具失败", "item_id", itemID, "quantity", quantity, "error", result.Error)
 		return result.Error
@@ -306,8 +381,11 @@ func (d *daoImpl) ConsumeItem(itemID string, quantity int64) error {
 }
 
 // AddItemQuantity 增加道具数量
-func (d *daoImpl) AddItemQuantity(itemID string, quantity int64) error {
-	result := d.db.Master().Model(&Item{}).Where("item_id = ?", itemID).Update("quantity", gorm.Expr("quantity + ?", quantity))
+func (d *daoImpl) AddItemQuantity(ctx context.Context, itemID string, quantity int64) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Model(&Item{}).Where("item_id = ?", itemID).Update("quantity", gorm.Expr("quantity + ?", quantity))
 	if result.Error != nil {
 		d.logger.Error("增加道具数量失败", "item_id", itemID, "quantity", quantity, "error", result.Error)
 		return result.Error
@@ -317,8 +395,11 @@ func (d *daoImpl) AddItemQuantity(itemID string, quantity int64) error {
 }
 
 // CreateOrder 创建订单
-func (d *daoImpl) CreateOrder(order *Order) error {
-	result := d.db.Master().Create(order)
+func (d *daoImpl) CreateOrder(ctx context.Context, order *Order) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Create(order)
 	if result.Error != nil {
 		d.logger.Error("创建订单失败", "order_id", order.OrderID, "error", result.Error)
 		return result.Error
@@ -328,9 +409,12 @@ func (d *daoImpl) CreateOrder(order *Order) error {
 }
 
 // GetOrderByID 根据ID获取订单
-func (d *daoImpl) GetOrderByID(orderID string) (*Order, error) {
+func (d *daoImpl) GetOrderByID(ctx context.Context, orderID string) (*Order, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var order Order
-	result := d.db.Slave().Where("order_id = ?", orderID).First(&order)
+	result := db.Where("order_id = ?", orderID).First(&order)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -342,11 +426,14 @@ func (d *daoImpl) GetOrderByID(orderID string) (*Order, error) {
 }
 
 // GetUserOrders 获取用户订单
-func (d *daoImpl) GetUserOrders(userID string, status string, offset, limit int) ([]*Order, int64, error) {
+func (d *daoImpl) GetUserOrders(ctx context.Context, userID string, status string, offset, limit int) ([]*Order, int64, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var orders []*Order
 	var total int64
 
-	query := d.db.Slave().Model(&Order{}).Where("user_id = ?", userID)
+	query := db.Model(&Order{}).Where("user_id = ?", userID)
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
@@ -365,7 +452,10 @@ func (d *daoImpl) GetUserOrders(userID string, status string, offset, limit int)
 }
 
 // UpdateOrderStatus 更新订单状态
-func (d *daoImpl) UpdateOrderStatus(orderID string, status string) error {
+func (d *daoImpl) UpdateOrderStatus(ctx context.Context, orderID string, status string) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
 	updates := map[string]interface{}{
 		"status": status,
 	}
@@ -374,7 +464,7 @@ func (d *daoImpl) UpdateOrderStatus(orderID string, status string) error {
 		updates["payment_at"] = time.Now()
 	}
 
-	result := d.db.Master().Model(&Order{}).Where("order_id = ?", orderID).Updates(updates)
+	result := db.Model(&Order{}).Where("order_id = ?", orderID).Updates(updates)
 	if result.Error != nil {
 		d.logger.Error("更新订单状态失败", "order_id", orderID, "status", status, "error", result.Error)
 		return result.Error
@@ -384,11 +474,14 @@ func (d *daoImpl) UpdateOrderStatus(orderID string, status string) error {
 }
 
 // GetOrdersByStatus 根据状态获取订单
-func (d *daoImpl) GetOrdersByStatus(status string, offset, limit int) ([]*Order, int64, error) {
+func (d *daoImpl) GetOrdersByStatus(ctx context.Context, status string, offset, limit int) ([]*Order, int64, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var orders []*Order
 	var total int64
 
-	query := d.db.Slave().Model(&Order{}).Where("status = ?", status)
+	query := db.Model(&Order{}).Where("status = ?", status)
 
 	if err := query.Count(&total).Error; err != nil {
 		d.logger.Error("获取订单总数失败", "status", status, "error", err)
@@ -404,8 +497,11 @@ func (d *daoImpl) GetOrdersByStatus(status string, offset, limit int) ([]*Order,
 }
 
 // CreateGame 创建游戏
-func (d *daoImpl) CreateGame(game *Game) error {
-	result := d.db.Master().Create(game)
+func (d *daoImpl) CreateGame(ctx context.Context, game *Game) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Create(game)
 	if result.Error != nil {
 		d.logger.Error("创建游戏失败", "game_id", game.GameID, "error", result.Error)
 		return result.Error
@@ -415,9 +511,12 @@ func (d *daoImpl) CreateGame(game *Game) error {
 }
 
 // GetGameByID 根据ID获取游戏
-func (d *daoImpl) GetGameByID(gameID string) (*Game, error) {
+func (d *daoImpl) GetGameByID(ctx context.Context, gameID string) (*Game, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var game Game
-	result := d.db.Slave().Where("game_id = ?", gameID).First(&game)
+	result := db.Where("game_id = ?", gameID).First(&game)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -429,16 +528,19 @@ func (d *daoImpl) GetGameByID(gameID string) (*Game, error) {
 }
 
 // ListGames 列出游戏
-func (d *daoImpl) ListGames(offset, limit int) ([]*Game, int64, error) {
+func (d *daoImpl) ListGames(ctx context.Context, offset, limit int) ([]*Game, int64, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var games []*Game
 	var total int64
 
-	if err := d.db.Slave().Model(&Game{}).Count(&total).Error; err != nil {
+	if err := db.Model(&Game{}).Count(&total).Error; err != nil {
 		d.logger.Error("获取游戏总数失败", "error", err)
 		return nil, 0, err
 	}
 
-	if err := d.db.Slave().Offset(offset).Limit(limit).Order("sort_order ASC, created_at DESC").Find(&games).Error; err != nil {
+	if err := db.Offset(offset).Limit(limit).Order("sort_order ASC, created_at DESC").Find(&games).Error; err != nil {
 		d.logger.Error("获取游戏列表失败", "error", err)
 		return nil, 0, err
 	}
@@ -447,8 +549,11 @@ func (d *daoImpl) ListGames(offset, limit int) ([]*Game, int64, error) {
 }
 
 // UpdateGame 更新游戏
-func (d *daoImpl) UpdateGame(game *Game) error {
-	result := d.db.Master().Save(game)
+func (d *daoImpl) UpdateGame(ctx context.Context, game *Game) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Save(game)
 	if result.Error != nil {
 		d.logger.Error("更新游戏失败", "game_id", game.GameID, "error", result.Error)
 		return result.Error
@@ -458,8 +563,11 @@ func (d *daoImpl) UpdateGame(game *Game) error {
 }
 
 // DeleteGame 删除游戏
-func (d *daoImpl) DeleteGame(gameID string) error {
-	result := d.db.Master().Where("game_id = ?", gameID).Delete(&Game{})
+func (d *daoImpl) DeleteGame(ctx context.Context, gameID string) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Where("game_id = ?", gameID).Delete(&Game{})
 	if result.Error != nil {
 		d.logger.Error("删除游戏失败", "game_id", gameID, "error", result.Error)
 		return result.Error
@@ -469,8 +577,11 @@ func (d *daoImpl) DeleteGame(gameID string) error {
 }
 
 // CreateGameStats 创建游戏统计
-func (d *daoImpl) CreateGameStats(stats *GameStats) error {
-	result := d.db.Master().Create(stats)
+func (d *daoImpl) CreateGameStats(ctx context.Context, stats *GameStats) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Create(stats)
 	if result.Error != nil {
 		d.logger.Error("创建游戏统计失败", "game_id", stats.GameID, "error", result.Error)
 		return result.Error
@@ -480,9 +591,12 @@ func (d *daoImpl) CreateGameStats(stats *GameStats) error {
 }
 
 // GetGameStats 获取游戏统计
-func (d *daoImpl) GetGameStats(gameID string, date time.Time) (*GameStats, error) {
+func (d *daoImpl) GetGameStats(ctx context.Context, gameID string, date time.Time) (*GameStats, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var stats GameStats
-	result := d.db.Slave().Where("game_id = ? AND date = ?", gameID, date).First(&stats)
+	result := db.Where("game_id = ? AND date = ?", gameID, date).First(&stats)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -494,8 +608,11 @@ func (d *daoImpl) GetGameStats(gameID string, date time.Time) (*GameStats, error
 }
 
 // UpdateGameStats 更新游戏统计
-func (d *daoImpl) UpdateGameStats(gameID string, date time.Time, updates map[string]interface{}) error {
-	result := d.db.Master().Model(&GameStats{}).Where("game_id = ? AND date = ?", gameID, date).Updates(updates)
+func (d *daoImpl) UpdateGameStats(ctx context.Context, gameID string, date time.Time, updates map[string]interface{}) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Model(&GameStats{}).Where("game_id = ? AND date = ?", gameID, date).Updates(updates)
 	if result.Error != nil {
 		d.logger.Error("更新游戏统计失败", "game_id", gameID, "date", date, "error", result.Error)
 		return result.Error
@@ -505,8 +622,11 @@ func (d *daoImpl) UpdateGameStats(gameID string, date time.Time, updates map[str
 }
 
 // CreateSystemLog 创建系统日志
-func (d *daoImpl) CreateSystemLog(logEntry *SystemLog) error {
-	result := d.db.Master().Create(logEntry)
+func (d *daoImpl) CreateSystemLog(ctx context.Context, logEntry *SystemLog) error {
+	db, cancel := d.master(ctx)
+	defer cancel()
+
+	result := db.Create(logEntry)
 	if result.Error != nil {
 		d.logger.Error("创建系统日志失败", "error", result.Error)
 		return result.Error
@@ -515,11 +635,14 @@ func (d *daoImpl) CreateSystemLog(logEntry *SystemLog) error {
 }
 
 // ListSystemLogs 列出系统日志
-func (d *daoImpl) ListSystemLogs(filters map[string]interface{}, offset, limit int) ([]*SystemLog, int64, error) {
+func (d *daoImpl) ListSystemLogs(ctx context.Context, filters map[string]interface{}, offset, limit int) ([]*SystemLog, int64, error) {
+	db, cancel := d.slave(ctx)
+	defer cancel()
+
 	var logs []*SystemLog
 	var total int64
 
-	query := d.db.Slave().Model(&SystemLog{})
+	query := db.Model(&SystemLog{})
 
 	// 应用过滤条件
 	for key, value := range filters {
@@ -1,9 +1,12 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -13,17 +16,34 @@ import (
 
 // JWTClaims JWT声明
 type JWTClaims struct {
-	UserID   string `json:"user_id"`
-	GameID   string `json:"game_id"`
-	Username string `json:"username"`
+	UserID    string `json:"user_id"`
+	GameID    string `json:"game_id"`
+	Username  string `json:"username"`
+	TokenType string `json:"token_type"` // access 访问令牌, refresh 刷新令牌
 	jwt.RegisteredClaims
 }
 
+// 令牌类型
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// Errors
+var (
+	ErrTokenRevoked = errors.New("令牌已撤销")
+	ErrTokenType    = errors.New("令牌类型不正确")
+	ErrTokenExpired = jwt.ErrTokenExpired // ValidateToken返回的错误可用errors.Is判断是否过期
+)
+
 // JWTService JWT认证服务
 type JWTService struct {
 	secretKey     []byte
 	expireTime    time.Duration
 	refreshExpire time.Duration
+	revoked       map[string]time.Time // 已撤销的令牌ID及可以从黑名单移除的时间
+	revokedMu     sync.RWMutex
+	store         RevocationStore // 多节点共享的黑名单，为nil时只使用本节点的黑名单
 	logger        logger.Logger
 }
 
@@ -33,19 +53,26 @@ func NewJWTService(config types.JWTConfig, log logger.Logger) *JWTService {
 		secretKey:     []byte(config.Secret),
 		expireTime:    time.Duration(config.Expire) * time.Second,
 		refreshExpire: 7 * 24 * time.Hour, // 7天刷新过期时间
+		revoked:       make(map[string]time.Time),
 		logger:        log,
 	}
 }
 
+// SetRevocationStore 设置多节点共享的令牌黑名单，撤销的令牌在所有节点上失效
+func (s *JWTService) SetRevocationStore(store RevocationStore) {
+	s.store = store
+}
+
 // GenerateToken 生成JWT令牌
 func (s *JWTService) GenerateToken(userID, gameID, username string) (*types.TokenPair, error) {
 	now := time.Now()
 
 	// 生成访问令牌
 	accessClaims := JWTClaims{
-		UserID:   userID,
-		GameID:   gameID,
-		Username: username,
+		UserID:    userID,
+		GameID:    gameID,
+		Username:  username,
+		TokenType: TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "datamiddleware",
 			Subject:   userID,
@@ -65,9 +92,10 @@ func (s *JWTService) GenerateToken(userID, gameID, username string) (*types.Toke
 
 	// 生成刷新令牌
 	refreshClaims := JWTClaims{
-		UserID:   userID,
-		GameID:   gameID,
-		Username: username,
+		UserID:    userID,
+		GameID:    gameID,
+		Username:  username,
+		TokenType: TokenTypeRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "datamiddleware",
 			Subject:   userID,
@@ -97,8 +125,8 @@ func (s *JWTService) GenerateToken(userID, gameID, username string) (*types.Toke
 	return tokenPair, nil
 }
 
-// ValidateToken 验证JWT令牌
-func (s *JWTService) ValidateToken(tokenString string) (*types.TokenClaims, error) {
+// ValidateToken 验证JWT令牌，不区分令牌类型
+func (s *JWTService) ValidateToken(ctx context.Context, tokenString string) (*types.TokenClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		// 验证签名算法
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -113,10 +141,16 @@ func (s *JWTService) ValidateToken(tokenString string) (*types.TokenClaims, erro
 	}
 
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		if s.IsTokenRevoked(ctx, claims.ID) {
+			s.logger.Warn("JWT令牌已撤销", "user_id", claims.UserID, "token_id", claims.ID)
+			return nil, fmt.Errorf("令牌无效: %w", ErrTokenRevoked)
+		}
+
 		tokenClaims := &types.TokenClaims{
-			UserID:   claims.UserID,
-			GameID:   claims.GameID,
-			Username: claims.Username,
+			UserID:    claims.UserID,
+			GameID:    claims.GameID,
+			Username:  claims.Username,
+			TokenType: claims.TokenType,
 			ExpiresAt: claims.ExpiresAt.Time.Unix(),
 			IssuedAt:  claims.IssuedAt.Time.Unix(),
 			TokenID:   claims.ID,
@@ -130,13 +164,29 @@ func (s *JWTService) ValidateToken(tokenString string) (*types.TokenClaims, erro
 	return nil, fmt.Errorf("令牌声明无效")
 }
 
+// ValidateAccessToken 验证访问令牌，拒绝刷新令牌
+func (s *JWTService) ValidateAccessToken(ctx context.Context, tokenString string) (*types.TokenClaims, error) {
+	claims, err := s.ValidateToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != TokenTypeAccess {
+		s.logger.Warn("令牌不是访问令牌", "user_id", claims.UserID, "token_type", claims.TokenType)
+		return nil, fmt.Errorf("令牌无效: %w", ErrTokenType)
+	}
+	return claims, nil
+}
+
 // RefreshToken 刷新访问令牌
-func (s *JWTService) RefreshToken(refreshTokenString string) (*types.TokenPair, error) {
+func (s *JWTService) RefreshToken(ctx context.Context, refreshTokenString string) (*types.TokenPair, error) {
 	// 验证刷新令牌
-	claims, err := s.ValidateToken(refreshTokenString)
+	claims, err := s.ValidateToken(ctx, refreshTokenString)
 	if err != nil {
 		return nil, fmt.Errorf("刷新令牌无效: %w", err)
 	}
+	if claims.TokenType != TokenTypeRefresh {
+		return nil, fmt.Errorf("刷新令牌无效: %w", ErrTokenType)
+	}
 
 	// 生成新的令牌对
 	tokenPair, err := s.GenerateToken(claims.UserID, claims.GameID, claims.Username)
@@ -149,17 +199,43 @@ func (s *JWTService) RefreshToken(refreshTokenString string) (*types.TokenPair,
 }
 
 // RevokeToken 撤销令牌（添加到黑名单）
-func (s *JWTService) RevokeToken(tokenID string) error {
-	// TODO: 实现令牌黑名单机制
-	// 这里可以添加到Redis或数据库中
+// 条目保留到该服务签发的令牌最长有效期之后；设置了共享黑名单时同时写入，对其他节点生效
+func (s *JWTService) RevokeToken(ctx context.Context, tokenID string) error {
+	if tokenID == "" {
+		return fmt.Errorf("令牌ID不能为空")
+	}
+
+	now := time.Now()
+	s.revokedMu.Lock()
+	for id, until := range s.revoked {
+		if now.After(until) {
+			delete(s.revoked, id)
+		}
+	}
+	ttl := max(s.expireTime, s.refreshExpire)
+	s.revoked[tokenID] = now.Add(ttl)
+	s.revokedMu.Unlock()
+
+	if s.store != nil {
+		if err := s.store.Revoke(ctx, tokenID, ttl); err != nil {
+			s.logger.Error("写入共享令牌黑名单失败", "token_id", tokenID, "error", err)
+			return fmt.Errorf("撤销令牌失败: %w", err)
+		}
+	}
+
 	s.logger.Info("JWT令牌已撤销", "token_id", tokenID)
 	return nil
 }
 
-// IsTokenRevoked 检查令牌是否已撤销
-func (s *JWTService) IsTokenRevoked(tokenID string) bool {
-	// TODO: 检查令牌黑名单
-	return false
+// IsTokenRevoked 检查令牌是否已撤销，先查本节点的黑名单再查共享黑名单
+func (s *JWTService) IsTokenRevoked(ctx context.Context, tokenID string) bool {
+	s.revokedMu.RLock()
+	_, ok := s.revoked[tokenID]
+	s.revokedMu.RUnlock()
+	if ok {
+		return true
+	}
+	return s.store != nil && s.store.IsRevoked(ctx, tokenID)
 }
 
 // ExtractTokenFromHeader 从Authorization头提取令牌
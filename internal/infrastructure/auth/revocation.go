@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// revokedKeyPrefix 共享黑名单中已撤销令牌的键前缀
+const revokedKeyPrefix = "auth:revoked:"
+
+// RevocationStore 多个服务节点共享的令牌黑名单
+type RevocationStore interface {
+	// Revoke 将令牌ID加入黑名单，ttl后自动移除
+	Revoke(ctx context.Context, tokenID string, ttl time.Duration) error
+	// IsRevoked 检查令牌ID是否在黑名单中
+	IsRevoked(ctx context.Context, tokenID string) bool
+}
+
+// RevocationCache 黑名单使用的缓存操作，缓存管理器实现该接口
+type RevocationCache interface {
+	SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Exists(ctx context.Context, key string) bool
+}
+
+// cacheRevocationStore 基于缓存（L2为Redis）的共享黑名单
+type cacheRevocationStore struct {
+	cache RevocationCache
+}
+
+// NewCacheRevocationStore 创建基于缓存的令牌黑名单，各节点使用同一Redis时撤销对所有节点生效
+func NewCacheRevocationStore(cache RevocationCache) RevocationStore {
+	return &cacheRevocationStore{cache: cache}
+}
+
+// Revoke 将令牌ID写入缓存
+func (s *cacheRevocationStore) Revoke(ctx context.Context, tokenID string, ttl time.Duration) error {
+	if err := s.cache.SetWithTTL(ctx, revokedKeyPrefix+tokenID, []byte("1"), ttl); err != nil {
+		return fmt.Errorf("写入令牌黑名单失败: %w", err)
+	}
+	return nil
+}
+
+// IsRevoked 检查缓存中是否存在令牌ID
+func (s *cacheRevocationStore) IsRevoked(ctx context.Context, tokenID string) bool {
+	return s.cache.Exists(ctx, revokedKeyPrefix+tokenID)
+}
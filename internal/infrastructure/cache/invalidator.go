@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"time"
 
 	"datamiddleware/internal/infrastructure/logging"
@@ -45,11 +46,11 @@ func (i *Invalidator) InvalidateByPrefix(prefix string) error {
 	return nil
 }
 
-// ScheduleInvalidation 定时缓存失效
+// ScheduleInvalidation 定时缓存失效，延迟执行不受调用方ctx影响
 func (i *Invalidator) ScheduleInvalidation(key string, delay time.Duration) {
 	go func() {
 		time.Sleep(delay)
-		if err := i.manager.Delete(key); err != nil {
+		if err := i.manager.Delete(context.Background(), key); err != nil {
 			i.logger.Warn("定时缓存失效失败", "key", key, "error", err)
 		} else {
 			i.logger.Debug("定时缓存失效成功", "key", key)
@@ -58,12 +59,12 @@ func (i *Invalidator) ScheduleInvalidation(key string, delay time.Duration) {
 }
 
 // BatchInvalidate 批量缓存失效
-func (i *Invalidator) BatchInvalidate(keys []string) error {
+func (i *Invalidator) BatchInvalidate(ctx context.Context, keys []string) error {
 	i.logger.Info("开始批量缓存失效", "count", len(keys))
 
 	successCount := 0
 	for _, key := range keys {
-		if err := i.manager.Delete(key); err != nil {
+		if err := i.manager.Delete(ctx, key); err != nil {
 			i.logger.Warn("批量缓存失效失败", "key", key, "error", err)
 			continue
 		}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"time"
 
 	"datamiddleware/internal/infrastructure/logging"
@@ -43,7 +44,7 @@ func NewLocalCache(config types.CacheConfigL1, logger logger.Logger) (*LocalCach
 }
 
 // Get 获取缓存值
-func (c *LocalCache) Get(key string) ([]byte, error) {
+func (c *LocalCache) Get(_ context.Context, key string) ([]byte, error) {
 	if c.cache == nil {
 		return nil, types.ErrCacheDisabled
 	}
@@ -60,7 +61,7 @@ func (c *LocalCache) Get(key string) ([]byte, error) {
 }
 
 // Set 设置缓存值
-func (c *LocalCache) Set(key string, value []byte) error {
+func (c *LocalCache) Set(_ context.Context, key string, value []byte) error {
 	if c.cache == nil {
 		return types.ErrCacheDisabled
 	}
@@ -69,7 +70,7 @@ func (c *LocalCache) Set(key string, value []byte) error {
 }
 
 // SetWithTTL 设置缓存值并指定TTL
-func (c *LocalCache) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+func (c *LocalCache) SetWithTTL(_ context.Context, key string, value []byte, ttl time.Duration) error {
 	if c.cache == nil {
 		return types.ErrCacheDisabled
 	}
@@ -78,7 +79,7 @@ func (c *LocalCache) SetWithTTL(key string, value []byte, ttl time.Duration) err
 }
 
 // Delete 删除缓存值
-func (c *LocalCache) Delete(key string) error {
+func (c *LocalCache) Delete(_ context.Context, key string) error {
 	if c.cache == nil {
 		return types.ErrCacheDisabled
 	}
@@ -87,7 +88,7 @@ func (c *LocalCache) Delete(key string) error {
 }
 
 // Exists 检查键是否存在
-func (c *LocalCache) Exists(key string) bool {
+func (c *LocalCache) Exists(_ context.Context, key string) bool {
 	if c.cache == nil {
 		return false
 	}
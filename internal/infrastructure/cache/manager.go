@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -49,10 +50,10 @@ func NewManager(config types.CacheConfig, logger logger.Logger) (*Manager, error
 }
 
 // Get 获取缓存值
-func (m *Manager) Get(key string) ([]byte, error) {
+func (m *Manager) Get(ctx context.Context, key string) ([]byte, error) {
 	// 先查L1缓存
 	if m.l1 != nil {
-		if value, err := m.l1.Get(key); err == nil {
+		if value, err := m.l1.Get(ctx, key); err == nil {
 			m.logger.Debug("L1缓存命中", "key", key)
 			return value, nil
 		} else if err != types.ErrCacheMiss {
@@ -62,11 +63,11 @@ func (m *Manager) Get(key string) ([]byte, error) {
 
 	// L1未命中，查L2缓存
 	if m.l2 != nil {
-		if value, err := m.l2.Get(key); err == nil {
+		if value, err := m.l2.Get(ctx, key); err == nil {
 			m.logger.Debug("L2缓存命中", "key", key)
 			// 同步到L1缓存
 			if m.l1 != nil {
-				if err := m.l1.Set(key, value); err != nil {
+				if err := m.l1.Set(ctx, key, value); err != nil {
 					m.logger.Warn("同步到L1缓存失败", "key", key, "error", err)
 				}
 			}
@@ -80,17 +81,17 @@ func (m *Manager) Get(key string) ([]byte, error) {
 }
 
 // Set 设置缓存值
-func (m *Manager) Set(key string, value []byte) error {
+func (m *Manager) Set(ctx context.Context, key string, value []byte) error {
 	// 设置L1缓存
 	if m.l1 != nil {
-		if err := m.l1.Set(key, value); err != nil && err != types.ErrCacheDisabled {
+		if err := m.l1.Set(ctx, key, value); err != nil && err != types.ErrCacheDisabled {
 			m.logger.Warn("L1缓存设置失败", "key", key, "error", err)
 		}
 	}
 
 	// 设置L2缓存
 	if m.l2 != nil {
-		if err := m.l2.Set(key, value); err != nil && err != types.ErrCacheDisabled {
+		if err := m.l2.Set(ctx, key, value); err != nil && err != types.ErrCacheDisabled {
 			m.logger.Warn("L2缓存设置失败", "key", key, "error", err)
 		}
 	}
@@ -99,17 +100,17 @@ func (m *Manager) Set(key string, value []byte) error {
 }
 
 // SetWithTTL 设置缓存值并指定TTL
-func (m *Manager) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+func (m *Manager) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
 	// 设置L1缓存
 	if m.l1 != nil {
-		if err := m.l1.SetWithTTL(key, value, ttl); err != nil && err != types.ErrCacheDisabled {
+		if err := m.l1.SetWithTTL(ctx, key, value, ttl); err != nil && err != types.ErrCacheDisabled {
 			m.logger.Warn("L1缓存设置失败", "key", key, "error", err)
 		}
 	}
 
 	// 设置L2缓存
 	if m.l2 != nil {
-		if err := m.l2.SetWithTTL(key, value, ttl); err != nil && err != types.ErrCacheDisabled {
+		if err := m.l2.SetWithTTL(ctx, key, value, ttl); err != nil && err != types.ErrCacheDisabled {
 			m.logger.Warn("L2缓存设置失败", "key", key, "error", err)
 		}
 	}
@@ -118,17 +119,17 @@ func (m *Manager) SetWithTTL(key string, value []byte, ttl time.Duration) error
 }
 
 // Delete 删除缓存值
-func (m *Manager) Delete(key string) error {
+func (m *Manager) Delete(ctx context.Context, key string) error {
 	// 删除L1缓存
 	if m.l1 != nil {
-		if err := m.l1.Delete(key); err != nil && err != types.ErrCacheDisabled {
+		if err := m.l1.Delete(ctx, key); err != nil && err != types.ErrCacheDisabled {
 			m.logger.Warn("L1缓存删除失败", "key", key, "error", err)
 		}
 	}
 
 	// 删除L2缓存
 	if m.l2 != nil {
-		if err := m.l2.Delete(key); err != nil && err != types.ErrCacheDisabled {
+		if err := m.l2.Delete(ctx, key); err != nil && err != types.ErrCacheDisabled {
 			m.logger.Warn("L2缓存删除失败", "key", key, "error", err)
 		}
 	}
@@ -137,14 +138,14 @@ func (m *Manager) Delete(key string) error {
 }
 
 // Exists 检查键是否存在
-func (m *Manager) Exists(key string) bool {
+func (m *Manager) Exists(ctx context.Context, key string) bool {
 	// 先查L1缓存
-	if m.l1 != nil && m.l1.Exists(key) {
+	if m.l1 != nil && m.l1.Exists(ctx, key) {
 		return true
 	}
 
 	// 再查L2缓存
-	if m.l2 != nil && m.l2.Exists(key) {
+	if m.l2 != nil && m.l2.Exists(ctx, key) {
 		return true
 	}
 
@@ -216,26 +217,26 @@ type CacheStats struct {
 }
 
 // SetJSON 设置JSON对象到缓存
-func (m *Manager) SetJSON(key string, value interface{}) error {
+func (m *Manager) SetJSON(ctx context.Context, key string, value interface{}) error {
 	data, err := json.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("JSON序列化失败: %w", err)
 	}
-	return m.Set(key, data)
+	return m.Set(ctx, key, data)
 }
 
 // SetJSONWithTTL 设置JSON对象到缓存并指定TTL
-func (m *Manager) SetJSONWithTTL(key string, value interface{}, ttl time.Duration) error {
+func (m *Manager) SetJSONWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
 	data, err := json.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("JSON序列化失败: %w", err)
 	}
-	return m.SetWithTTL(key, data, ttl)
+	return m.SetWithTTL(ctx, key, data, ttl)
 }
 
 // GetJSON 从缓存获取并反序列化为JSON对象
-func (m *Manager) GetJSON(key string, value interface{}) error {
-	data, err := m.Get(key)
+func (m *Manager) GetJSON(ctx context.Context, key string, value interface{}) error {
+	data, err := m.Get(ctx, key)
 	if err != nil {
 		return err
 	}
@@ -244,13 +245,13 @@ func (m *Manager) GetJSON(key string, value interface{}) error {
 }
 
 // GetWithProtection 带防护的缓存获取
-func (m *Manager) GetWithProtection(key string) ([]byte, error) {
-	return m.protection.GetWithProtection(key)
+func (m *Manager) GetWithProtection(ctx context.Context, key string) ([]byte, error) {
+	return m.protection.GetWithProtection(ctx, key)
 }
 
 // SetWithProtection 带防护的缓存设置
-func (m *Manager) SetWithProtection(key string, value []byte) error {
-	return m.protection.SetWithProtection(key, value)
+func (m *Manager) SetWithProtection(ctx context.Context, key string, value []byte) error {
+	return m.protection.SetWithProtection(ctx, key, value)
 }
 
 // GetProtectionStats 获取防护统计信息
@@ -259,12 +260,12 @@ func (m *Manager) GetProtectionStats() ProtectionStats {
 }
 
 // Preload 预加载热点数据到缓存
-func (m *Manager) Preload(data map[string][]byte) error {
+func (m *Manager) Preload(ctx context.Context, data map[string][]byte) error {
 	m.logger.Info("开始缓存预热", "count", len(data))
 
 	successCount := 0
 	for key, value := range data {
-		if err := m.Set(key, value); err != nil {
+		if err := m.Set(ctx, key, value); err != nil {
 			m.logger.Warn("缓存预热失败", "key", key, "error", err)
 			continue
 		}
@@ -299,7 +300,7 @@ type Warmup interface {
 }
 
 // WarmupCache 缓存预热
-func (m *Manager) WarmupCache(warmer Warmup) error {
+func (m *Manager) WarmupCache(ctx context.Context, warmer Warmup) error {
 	m.logger.Info("开始缓存预热流程")
 
 	// 获取热点键
@@ -317,7 +318,7 @@ func (m *Manager) WarmupCache(warmer Warmup) error {
 	}
 
 	// 预加载到缓存
-	return m.Preload(data)
+	return m.Preload(ctx, data)
 }
 
 // InvalidateByPattern 按模式使缓存失效
@@ -336,6 +337,6 @@ func (m *Manager) ScheduleInvalidation(key string, delay time.Duration) {
 }
 
 // BatchInvalidate 批量缓存失效
-func (m *Manager) BatchInvalidate(keys []string) error {
-	return m.invalidator.BatchInvalidate(keys)
+func (m *Manager) BatchInvalidate(ctx context.Context, keys []string) error {
+	return m.invalidator.BatchInvalidate(ctx, keys)
 }
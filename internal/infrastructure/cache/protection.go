@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -36,7 +37,7 @@ func NewProtection(manager *Manager, logger logger.Logger) *Protection {
 }
 
 // GetWithProtection 带防护的缓存获取
-func (p *Protection) GetWithProtection(key string) ([]byte, error) {
+func (p *Protection) GetWithProtection(ctx context.Context, key string) ([]byte, error) {
 	// 记录监控数据
 	start := time.Now()
 	defer func() {
@@ -57,7 +58,7 @@ func (p *Protection) GetWithProtection(key string) ([]byte, error) {
 	}
 
 	// 正常获取缓存
-	value, err := p.manager.Get(key)
+	value, err := p.manager.Get(ctx, key)
 	if err != nil {
 		if err == types.ErrCacheMiss {
 			// 记录空值，防止缓存穿透
@@ -73,7 +74,7 @@ func (p *Protection) GetWithProtection(key string) ([]byte, error) {
 }
 
 // SetWithProtection 带防护的缓存设置
-func (p *Protection) SetWithProtection(key string, value []byte) error {
+func (p *Protection) SetWithProtection(ctx context.Context, key string, value []byte) error {
 	// 记录监控数据
 	start := time.Now()
 	defer func() {
@@ -85,7 +86,7 @@ func (p *Protection) SetWithProtection(key string, value []byte) error {
 	p.penetrationProtection.ClearBlockedKey(key)
 
 	// 正常设置缓存
-	return p.manager.Set(key, value)
+	return p.manager.Set(ctx, key, value)
 }
 
 // GetStats 获取防护统计信息
@@ -50,12 +50,11 @@ func NewRedisCache(config types.CacheConfigL2, logger logger.Logger) (*RedisCach
 }
 
 // Get 获取缓存值
-func (c *RedisCache) Get(key string) ([]byte, error) {
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
 	if c.client == nil {
 		return nil, types.ErrCacheDisabled
 	}
 
-	ctx := context.Background()
 	value, err := c.client.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -68,42 +67,38 @@ func (c *RedisCache) Get(key string) ([]byte, error) {
 }
 
 // Set 设置缓存值
-func (c *RedisCache) Set(key string, value []byte) error {
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte) error {
 	if c.client == nil {
 		return types.ErrCacheDisabled
 	}
 
-	ctx := context.Background()
 	return c.client.Set(ctx, key, value, 0).Err()
 }
 
 // SetWithTTL 设置缓存值并指定TTL
-func (c *RedisCache) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+func (c *RedisCache) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
 	if c.client == nil {
 		return types.ErrCacheDisabled
 	}
 
-	ctx := context.Background()
 	return c.client.Set(ctx, key, value, ttl).Err()
 }
 
 // Delete 删除缓存值
-func (c *RedisCache) Delete(key string) error {
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
 	if c.client == nil {
 		return types.ErrCacheDisabled
 	}
 
-	ctx := context.Background()
 	return c.client.Del(ctx, key).Err()
 }
 
 // Exists 检查键是否存在
-func (c *RedisCache) Exists(key string) bool {
+func (c *RedisCache) Exists(ctx context.Context, key string) bool {
 	if c.client == nil {
 		return false
 	}
 
-	ctx := context.Background()
 	count, err := c.client.Exists(ctx, key).Result()
 	return err == nil && count > 0
 }
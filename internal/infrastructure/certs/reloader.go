@@ -0,0 +1,231 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay 文件变化后等待的时间，证书和私钥通常分两次写入，合并为一次重新加载
+const reloadDelay = 200 * time.Millisecond
+
+// tlsVersions 支持配置的最低TLS版本，未配置时为1.2
+var tlsVersions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// clientAuthTypes 客户端证书认证方式，未配置时不要求客户端证书
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                   tls.NoClientCert,
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+// ErrInvalidConfig TLS配置无效
+var ErrInvalidConfig = errors.New("无效的TLS配置")
+
+// Reloader 从磁盘加载TLS证书，文件变化后重新加载
+// 新的握手使用最新的证书和客户端CA，已建立的连接不受影响；重新加载失败时继续使用之前的证书
+type Reloader struct {
+	config     types.TLSConfig
+	minVersion uint16
+	clientAuth tls.ClientAuthType
+	current    atomic.Pointer[tls.Config] // 最近一次成功加载的配置
+	loaded     []byte                     // 最近一次成功加载的文件内容，用于忽略内容未变化的文件事件
+	loadMu     sync.Mutex                 // 保护loaded，串行化重新加载
+	watcher    *fsnotify.Watcher
+	stopChan   chan struct{}
+	wg         sync.WaitGroup
+	logger     logger.Logger
+}
+
+// NewReloader 校验配置并加载证书，证书无法加载时返回错误
+func NewReloader(config types.TLSConfig, log logger.Logger) (*Reloader, error) {
+	if err := Validate(config); err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{
+		config:     config,
+		minVersion: tlsVersions[config.MinVersion],
+		clientAuth: clientAuthTypes[config.ClientAuth],
+		logger:     log,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Validate 检查TLS配置，未启用时不检查
+func Validate(config types.TLSConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+	if config.CertFile == "" || config.KeyFile == "" {
+		return fmt.Errorf("%w: 必须配置cert_file和key_file", ErrInvalidConfig)
+	}
+	if _, ok := tlsVersions[config.MinVersion]; !ok {
+		return fmt.Errorf("%w: 不支持的最低版本 %s", ErrInvalidConfig, config.MinVersion)
+	}
+	clientAuth, ok := clientAuthTypes[config.ClientAuth]
+	if !ok {
+		return fmt.Errorf("%w: 不支持的客户端证书认证方式 %s", ErrInvalidConfig, config.ClientAuth)
+	}
+	if clientAuth >= tls.VerifyClientCertIfGiven && config.ClientCAFile == "" {
+		return fmt.Errorf("%w: 校验客户端证书必须配置client_ca_file", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// TLSConfig 返回用于监听器的TLS配置，每次握手使用最近一次成功加载的证书
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: r.minVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}
+}
+
+// Reload 从磁盘重新加载证书、私钥和客户端CA证书
+func (r *Reloader) Reload() error {
+	r.loadMu.Lock()
+	defer r.loadMu.Unlock()
+
+	certPEM, err := os.ReadFile(r.config.CertFile)
+	if err != nil {
+		return fmt.Errorf("读取证书文件失败: %w", err)
+	}
+	keyPEM, err := os.ReadFile(r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("读取私钥文件失败: %w", err)
+	}
+	var caPEM []byte
+	if r.config.ClientCAFile != "" {
+		if caPEM, err = os.ReadFile(r.config.ClientCAFile); err != nil {
+			return fmt.Errorf("读取客户端CA证书文件失败: %w", err)
+		}
+	}
+
+	loaded := bytes.Join([][]byte{certPEM, keyPEM, caPEM}, nil)
+	if bytes.Equal(loaded, r.loaded) {
+		return nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("解析证书和私钥失败: %w", err)
+	}
+	config := &tls.Config{
+		MinVersion:   r.minVersion,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   r.clientAuth,
+	}
+	if caPEM != nil {
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(caPEM) {
+			return fmt.Errorf("客户端CA证书文件中没有有效的证书: %s", r.config.ClientCAFile)
+		}
+	}
+
+	r.current.Store(config)
+	r.loaded = loaded
+	r.logger.Info("TLS证书已加载",
+		"cert_file", r.config.CertFile,
+		"subject", cert.Leaf.Subject.String(),
+		"not_after", cert.Leaf.NotAfter)
+	return nil
+}
+
+// Start 监听证书文件所在目录，文件变化后重新加载
+// 监听目录而不是文件，以便处理通过重命名替换文件（包括Kubernetes挂载的Secret）的情况
+func (r *Reloader) Start() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建证书文件监听器失败: %w", err)
+	}
+
+	dirs := make(map[string]bool)
+	for _, file := range []string{r.config.CertFile, r.config.KeyFile, r.config.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+		dir := filepath.Dir(file)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("监听证书目录失败: %w", err)
+		}
+	}
+
+	r.watcher = watcher
+	r.stopChan = make(chan struct{})
+	r.wg.Add(1)
+	go r.watchLoop()
+	return nil
+}
+
+// Stop 停止监听证书文件
+func (r *Reloader) Stop() {
+	if r.watcher == nil {
+		return
+	}
+	close(r.stopChan)
+	r.watcher.Close()
+	r.wg.Wait()
+	r.watcher = nil
+}
+
+// watchLoop 处理文件事件，事件停止reloadDelay后重新加载一次
+func (r *Reloader) watchLoop() {
+	defer r.wg.Done()
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-r.stopChan:
+			return
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(reloadDelay)
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			r.logger.Warn("证书文件监听出错", "error", err)
+		case <-timer.C:
+			if err := r.Reload(); err != nil {
+				r.logger.Error("重新加载TLS证书失败，继续使用之前的证书", "cert_file", r.config.CertFile, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+
+	"go.uber.org/zap"
+)
+
+// writeCertificate 生成自签名证书并写入dir下的server.crt和server.key
+func writeCertificate(t *testing.T, dir, commonName string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("生成私钥失败: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("生成证书失败: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("编码私钥失败: %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "server.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	writeFile(t, filepath.Join(dir, "server.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// writeFile 先写入临时文件再重命名，与证书管理工具替换文件的方式相同
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("替换文件失败: %v", err)
+	}
+}
+
+// servedCommonName 返回握手时使用的证书的CN
+func servedCommonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+	config, err := r.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("获取TLS配置失败: %v", err)
+	}
+	return config.Certificates[0].Leaf.Subject.CommonName
+}
+
+func newTestReloader(t *testing.T, config types.TLSConfig) *Reloader {
+	t.Helper()
+	r, err := NewReloader(config, &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()})
+	if err != nil {
+		t.Fatalf("创建证书加载器失败: %v", err)
+	}
+	return r
+}
+
+func TestReloaderWatchesCertificateFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeCertificate(t, dir, "v1")
+	r := newTestReloader(t, types.TLSConfig{
+		Enabled:  true,
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	})
+	if err := r.Start(); err != nil {
+		t.Fatalf("监听证书文件失败: %v", err)
+	}
+	defer r.Stop()
+
+	if cn := servedCommonName(t, r); cn != "v1" {
+		t.Fatalf("证书CN = %s, 期望 v1", cn)
+	}
+
+	writeCertificate(t, dir, "v2")
+	deadline := time.Now().Add(5 * time.Second)
+	for servedCommonName(t, r) != "v2" && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if cn := servedCommonName(t, r); cn != "v2" {
+		t.Fatalf("文件更新后证书CN = %s, 期望 v2", cn)
+	}
+
+	// 文件内容无效时继续使用之前的证书
+	writeFile(t, filepath.Join(dir, "server.crt"), []byte("invalid"))
+	if err := r.Reload(); err == nil {
+		t.Error("无效的证书文件应返回错误")
+	}
+	if cn := servedCommonName(t, r); cn != "v2" {
+		t.Errorf("重新加载失败后证书CN = %s, 期望 v2", cn)
+	}
+}
+
+func TestReloaderClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	writeCertificate(t, dir, "backend")
+	certFile := filepath.Join(dir, "server.crt")
+	r := newTestReloader(t, types.TLSConfig{
+		Enabled:      true,
+		CertFile:     certFile,
+		KeyFile:      filepath.Join(dir, "server.key"),
+		MinVersion:   "1.3",
+		ClientAuth:   "require_and_verify",
+		ClientCAFile: certFile,
+	})
+
+	config, _ := r.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	if config.ClientAuth != tls.RequireAndVerifyClientCert || config.ClientCAs == nil || config.MinVersion != tls.VersionTLS13 {
+		t.Errorf("TLS配置 = client_auth %v, client_cas %v, min_version %x", config.ClientAuth, config.ClientCAs != nil, config.MinVersion)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config types.TLSConfig
+		valid  bool
+	}{
+		{"未启用", types.TLSConfig{ClientAuth: "invalid"}, true},
+		{"有效配置", types.TLSConfig{Enabled: true, CertFile: "a.crt", KeyFile: "a.key", MinVersion: "1.3", ClientAuth: "request"}, true},
+		{"缺少私钥", types.TLSConfig{Enabled: true, CertFile: "a.crt"}, false},
+		{"不支持的版本", types.TLSConfig{Enabled: true, CertFile: "a.crt", KeyFile: "a.key", MinVersion: "1.1"}, false},
+		{"不支持的认证方式", types.TLSConfig{Enabled: true, CertFile: "a.crt", KeyFile: "a.key", ClientAuth: "always"}, false},
+		{"校验客户端证书缺少CA", types.TLSConfig{Enabled: true, CertFile: "a.crt", KeyFile: "a.key", ClientAuth: "verify_if_given"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.config)
+			if tt.valid && err != nil {
+				t.Errorf("Validate() = %v, 期望通过", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Validate() = %v, 期望 ErrInvalidConfig", err)
+			}
+		})
+	}
+}
@@ -167,6 +167,15 @@ func getLogLevel(level string) zapcore.Level {
 	}
 }
 
+// DebugEnabled 是否输出调试级别日志，调用方可据此跳过开销较大的日志参数构造
+// 无法判断级别的Logger实现视为已启用
+func DebugEnabled(l Logger) bool {
+	if z, ok := l.(*ZapLogger); ok {
+		return z.Level().Enabled(zapcore.DebugLevel)
+	}
+	return true
+}
+
 // Sync 同步日志缓冲区
 func (l *ZapLogger) Sync() error {
 	return l.SugaredLogger.Sync()
@@ -88,7 +88,7 @@ func (c *CacheHealthChecker) Check(ctx context.Context) HealthStatus {
 	testValue := []byte(fmt.Sprintf("health_check_%d", time.Now().Unix()))
 
 	// 尝试写入
-	err := c.cache.Set(testKey, testValue)
+	err := c.cache.Set(ctx, testKey, testValue)
 	if err != nil {
 		response := time.Since(start).Milliseconds()
 		return HealthStatus{
@@ -100,7 +100,7 @@ func (c *CacheHealthChecker) Check(ctx context.Context) HealthStatus {
 	}
 
 	// 尝试读取
-	readValue, err := c.cache.Get(testKey)
+	readValue, err := c.cache.Get(ctx, testKey)
 	if err != nil {
 		response := time.Since(start).Milliseconds()
 		return HealthStatus{
@@ -123,7 +123,7 @@ func (c *CacheHealthChecker) Check(ctx context.Context) HealthStatus {
 	}
 
 	// 清理测试数据
-	c.cache.Delete(testKey)
+	c.cache.Delete(ctx, testKey)
 
 	response := time.Since(start).Milliseconds()
 	return HealthStatus{
@@ -0,0 +1,113 @@
+package protocol
+
+import (
+	"sync"
+	"sync/atomic"
+
+	utils "datamiddleware/internal/common"
+	"datamiddleware/internal/common/types"
+)
+
+// bufferClasses 池化缓冲区的大小等级，超过最大等级两倍的缓冲区不回收
+var bufferClasses = [...]int{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10}
+
+// bufferPools 每个大小等级对应一个缓冲区池
+var bufferPools = func() []*utils.BufferPool {
+	pools := make([]*utils.BufferPool, len(bufferClasses))
+	for i, size := range bufferClasses {
+		pools[i] = utils.NewSizedBufferPool(size)
+	}
+	return pools
+}()
+
+// getBuffer 获取容量不小于size的缓冲区，长度为0
+func getBuffer(size int) []byte {
+	for _, pool := range bufferPools {
+		if size <= pool.Size() {
+			return pool.Get()
+		}
+	}
+	return make([]byte, 0, size)
+}
+
+// putBuffer 将缓冲区归还到容量所属的大小等级
+func putBuffer(buf []byte) {
+	size := cap(buf)
+	for i := len(bufferPools) - 1; i >= 0; i-- {
+		if size >= bufferPools[i].Size() {
+			if size < 2*bufferPools[i].Size() {
+				bufferPools[i].Put(buf)
+			}
+			return
+		}
+	}
+}
+
+// messagePool 复用读路径上的消息对象
+var messagePool = sync.Pool{
+	New: func() interface{} {
+		return new(types.Message)
+	},
+}
+
+// getMessage 从对象池获取消息对象，保留上次使用的游戏ID和用户ID供解码时复用
+func getMessage() *types.Message {
+	return messagePool.Get().(*types.Message)
+}
+
+// putMessage 归还消息对象
+func putMessage(msg *types.Message) {
+	msg.Header = types.MessageHeader{GameID: msg.Header.GameID, UserID: msg.Header.UserID}
+	msg.Body = nil
+	messagePool.Put(msg)
+}
+
+// readBuffer 连接读缓冲区，解码出的消息体直接引用其中的数据
+// 连接和每条未释放的消息各持有一个引用，引用全部释放后缓冲区归还对象池
+type readBuffer struct {
+	buf     []byte
+	refs    atomic.Int32
+	release func(*types.Message) // 消息释放函数，随缓冲区对象复用，避免每条消息创建闭包
+}
+
+// readBufferPool 复用读缓冲区对象
+var readBufferPool sync.Pool
+
+// newReadBuffer 创建容量不小于size的读缓冲区，调用方持有一个引用
+func newReadBuffer(size int) *readBuffer {
+	rb, _ := readBufferPool.Get().(*readBuffer)
+	if rb == nil {
+		rb = &readBuffer{}
+		rb.release = rb.releaseMessage
+	}
+	rb.buf = getBuffer(size)
+	rb.buf = rb.buf[:cap(rb.buf)]
+	rb.refs.Store(1)
+	return rb
+}
+
+// retain 增加一个引用
+func (rb *readBuffer) retain() {
+	rb.refs.Add(1)
+}
+
+// unref 释放一个引用，最后一个引用释放时归还缓冲区
+func (rb *readBuffer) unref() {
+	if rb.refs.Add(-1) == 0 {
+		putBuffer(rb.buf)
+		rb.buf = nil
+		readBufferPool.Put(rb)
+	}
+}
+
+// exclusive 检查缓冲区是否只被连接引用，此时可以安全地覆盖已解析的数据
+// 只有读协程会增加引用，因此读协程看到的结果不会被其他协程改为false
+func (rb *readBuffer) exclusive() bool {
+	return rb.refs.Load() == 1
+}
+
+// releaseMessage 释放借用读缓冲区的消息
+func (rb *readBuffer) releaseMessage(msg *types.Message) {
+	putMessage(msg)
+	rb.unref()
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"hash/crc32"
+	"slices"
 	"time"
 
 	"datamiddleware/internal/common/types"
@@ -16,6 +17,44 @@ type Codec interface {
 	Decode(data []byte) (msg *types.Message, consumed int, err error)
 }
 
+// CompressibleCodec 支持消息体压缩的编解码器
+type CompressibleCodec interface {
+	Codec
+	// WithCompression 返回使用指定压缩选项的编解码器副本
+	WithCompression(opts *CompressionOptions) Codec
+}
+
+// MessageEncoder 支持追加编码的编解码器，用于复用发送缓冲区
+type MessageEncoder interface {
+	// AppendEncode 将消息编码后追加到dst并返回扩展后的切片
+	AppendEncode(dst []byte, msg *types.Message) ([]byte, error)
+}
+
+// MessageDecoder 支持解码到已有消息对象的编解码器，用于复用消息对象
+type MessageDecoder interface {
+	// DecodeInto 解码data中的第一条消息到msg，返回消耗的字节数
+	DecodeInto(msg *types.Message, data []byte) (consumed int, err error)
+}
+
+// 编解码器名称
+const (
+	CodecBinary   = "binary"
+	CodecJSON     = "json"
+	CodecProtobuf = "protobuf"
+)
+
+// NewCodec 根据名称从默认注册表创建编解码器，名称为空时使用二进制编解码器
+func NewCodec(name string) (Codec, error) {
+	if name == "" {
+		name = CodecBinary
+	}
+	codec, ok := DefaultCodecRegistry.NewByName(name)
+	if !ok {
+		return nil, fmt.Errorf("不支持的编解码器: %s", name)
+	}
+	return codec, nil
+}
+
 // DecodeResult 解码结果
 type DecodeResult struct {
 	Message  *types.Message // 解析出的消息，如果为nil表示数据不足
@@ -23,32 +62,63 @@ type DecodeResult struct {
 }
 
 // JSONCodec JSON编解码器
-type JSONCodec struct{}
+type JSONCodec struct {
+	compression *CompressionOptions // 压缩选项，nil表示不压缩
+	limits      FrameLimits         // 帧大小限制
+}
 
 // NewJSONCodec 创建JSON编解码器
 func NewJSONCodec() *JSONCodec {
 	return &JSONCodec{}
 }
 
+// WithCompression 返回使用指定压缩选项的编解码器副本
+func (c *JSONCodec) WithCompression(opts *CompressionOptions) Codec {
+	cp := *c
+	cp.compression = opts
+	return &cp
+}
+
+// WithLimits 返回使用指定帧大小限制的编解码器副本
+func (c *JSONCodec) WithLimits(limits FrameLimits) Codec {
+	cp := *c
+	cp.limits = limits
+	return &cp
+}
+
 // Encode 编码消息
 func (c *JSONCodec) Encode(msg *types.Message) ([]byte, error) {
-	// 序列化消息头
-	headerData, err := json.Marshal(msg.Header)
+	// 按需压缩消息体
+	body, flags, err := compressBody(c.compression, msg.Header.Flags, msg.Body)
 	if err != nil {
-		return nil, fmt.Errorf("序列化消息头失败: %w", err)
+		return nil, err
 	}
 
-	// 计算校验和
-	checksum := crc32.ChecksumIEEE(headerData)
-	checksum = crc32.Update(checksum, crc32.IEEETable, msg.Body)
+	header := msg.Header
+	header.Flags = flags
+	header.BodyLength = uint32(len(body))
+	// 加密消息的时间戳已计入附加认证数据，不能改写
+	if header.Flags&types.FlagEncrypted == 0 {
+		header.Timestamp = time.Now().Unix()
+	}
+	if header.Version < types.ProtocolVersionV2 {
+		stripHeaderExtensions(&header)
+	}
+
+	// 计算校验和（校验和字段置0后的消息头 + 消息体）
+	checksum, err := jsonChecksum(header, body)
+	if err != nil {
+		return nil, err
+	}
+	header.Checksum = checksum
 
 	// 更新校验和
 	msg.Header.Checksum = checksum
-	msg.Header.BodyLength = uint32(len(msg.Body))
-	msg.Header.Timestamp = time.Now().Unix()
+	msg.Header.BodyLength = header.BodyLength
+	msg.Header.Timestamp = header.Timestamp
 
-	// 重新序列化消息头（包含校验和）
-	headerData, err = json.Marshal(msg.Header)
+	// 序列化消息头（包含校验和）
+	headerData, err := json.Marshal(header)
 	if err != nil {
 		return nil, fmt.Errorf("序列化消息头失败: %w", err)
 	}
@@ -56,7 +126,7 @@ func (c *JSONCodec) Encode(msg *types.Message) ([]byte, error) {
 	// 构造完整消息
 	// 格式: [消息头长度(4字节)] + [消息头数据] + [消息体数据]
 	headerLen := uint32(len(headerData))
-	buffer := make([]byte, 4+headerLen+uint32(len(msg.Body)))
+	buffer := make([]byte, 4+headerLen+uint32(len(body)))
 
 	// 写入消息头长度
 	binary.BigEndian.PutUint32(buffer[0:4], headerLen)
@@ -65,154 +135,278 @@ func (c *JSONCodec) Encode(msg *types.Message) ([]byte, error) {
 	copy(buffer[4:4+headerLen], headerData)
 
 	// 写入消息体数据
-	copy(buffer[4+headerLen:], msg.Body)
+	copy(buffer[4+headerLen:], body)
 
 	return buffer, nil
 }
 
-// Decode 解码消息
-func (c *JSONCodec) Decode(data []byte) (msg *types.Message, consumed int, err error) {
-	if len(data) < 4 {
-		return nil, 0, fmt.Errorf("数据长度不足，无法解析消息头长度")
+// jsonChecksum 计算JSON格式消息的校验和
+func jsonChecksum(header types.MessageHeader, body []byte) (uint32, error) {
+	header.Checksum = 0
+	headerData, err := json.Marshal(header)
+	if err != nil {
+		return 0, fmt.Errorf("序列化消息头失败: %w", err)
 	}
+	checksum := crc32.ChecksumIEEE(headerData)
+	return crc32.Update(checksum, crc32.IEEETable, body), nil
+}
 
-	// 读取消息头长度
-	headerLen := binary.BigEndian.Uint32(data[0:4])
-	if len(data) < int(4+headerLen) {
-		return nil, 4, fmt.Errorf("数据长度不足，无法解析完整消息头")
+// Decode 解码消息
+// 以'{'开头的帧是省略消息头长度的文本帧（WebSocket文本消息），消息头在JSON对象结束处截止；
+// 长度前缀的首字节为'{'意味着消息头超过2GB，因此不会与带长度前缀的帧混淆
+func (c *JSONCodec) Decode(data []byte) (msg *types.Message, consumed int, err error) {
+	var headerStart, headerEnd int
+	if len(data) > 0 && data[0] == '{' {
+		headerEnd = textHeaderEnd(data)
+		if headerEnd == 0 {
+			// 消息头超过限制仍未结束时不等待后续数据直接拒绝
+			if err := c.limits.checkHeaderSize(len(data)); err != nil {
+				return nil, 0, err
+			}
+			return nil, 0, fmt.Errorf("%w，无法解析完整消息头", ErrInsufficientData)
+		}
+		if err := c.limits.checkHeaderSize(headerEnd); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		if len(data) < 4 {
+			return nil, 0, fmt.Errorf("%w，无法解析消息头长度", ErrInsufficientData)
+		}
+
+		// 读取消息头长度，超过限制时不等待后续数据直接拒绝
+		headerLen := int(binary.BigEndian.Uint32(data[0:4]))
+		if err := c.limits.checkHeaderSize(4 + headerLen); err != nil {
+			return nil, 0, err
+		}
+		headerStart, headerEnd = 4, 4+headerLen
+		if len(data) < headerEnd {
+			return nil, 4, fmt.Errorf("%w，无法解析完整消息头", ErrInsufficientData)
+		}
 	}
 
 	// 读取消息头数据
-	headerData := data[4 : 4+headerLen]
+	headerData := data[headerStart:headerEnd]
 
 	// 反序列化消息头
 	var header types.MessageHeader
 	if err := json.Unmarshal(headerData, &header); err != nil {
-		return nil, int(4 + headerLen), fmt.Errorf("反序列化消息头失败: %w", err)
+		return nil, headerEnd, fmt.Errorf("%w: 反序列化消息头失败: %v", ErrMalformedFrame, err)
+	}
+	if err := c.limits.checkBodySize(int(header.BodyLength)); err != nil {
+		return nil, headerEnd, err
 	}
 
 	// 验证消息头长度
-	expectedTotalLen := 4 + headerLen + header.BodyLength
-	if uint32(len(data)) < expectedTotalLen {
-		return nil, int(4 + headerLen), fmt.Errorf("消息长度不足，期望%d，实际%d", expectedTotalLen, len(data))
+	expectedTotalLen := headerEnd + int(header.BodyLength)
+	if len(data) < expectedTotalLen {
+		return nil, headerEnd, fmt.Errorf("%w，消息期望%d字节，实际%d字节", ErrInsufficientData, expectedTotalLen, len(data))
 	}
 
 	// 读取消息体数据
-	bodyData := data[4+headerLen : expectedTotalLen]
+	bodyData := data[headerEnd:expectedTotalLen]
 
 	// 验证校验和
-	checksum := crc32.ChecksumIEEE(headerData)
-	checksum = crc32.Update(checksum, crc32.IEEETable, bodyData)
+	checksum, err := jsonChecksum(header, bodyData)
+	if err != nil {
+		return nil, expectedTotalLen, err
+	}
 	if checksum != header.Checksum {
-		return nil, int(expectedTotalLen), fmt.Errorf("校验和验证失败，期望0x%x，实际0x%x", header.Checksum, checksum)
+		return nil, expectedTotalLen, fmt.Errorf("%w，期望0x%x，实际0x%x", ErrChecksumMismatch, header.Checksum, checksum)
+	}
+
+	// 解压消息体
+	bodyData, err = decompressBody(&header, bodyData, c.limits)
+	if err != nil {
+		return nil, expectedTotalLen, err
 	}
 
 	return &types.Message{
 		Header: header,
 		Body:   bodyData,
-	}, int(expectedTotalLen), nil
+	}, expectedTotalLen, nil
+}
+
+// textHeaderEnd 返回文本帧开头消息头JSON对象的结束位置，对象尚未结束时返回0
+// 只跟踪字符串和括号嵌套，消息头内容由随后的反序列化校验
+func textHeaderEnd(data []byte) int {
+	depth := 0
+	inString, escaped := false, false
+	for i, b := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if b == '\\' {
+				escaped = true
+			} else if b == '"' {
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+		case b == '}' || b == ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return 0
 }
 
 // BinaryCodec 二进制编解码器（性能优化版本）
-type BinaryCodec struct{}
+type BinaryCodec struct {
+	compression *CompressionOptions // 压缩选项，nil表示不压缩
+	limits      FrameLimits         // 帧大小限制
+}
 
 // NewBinaryCodec 创建二进制编解码器
 func NewBinaryCodec() *BinaryCodec {
 	return &BinaryCodec{}
 }
 
-// Encode 编码消息（二进制格式）
-// 格式: [版本(1)] [类型(2)] [标志(1)] [序列号(4)] [时间戳(8)] [体长度(4)] [校验和(4)] [游戏ID长度(2)] [游戏ID] [用户ID长度(2)] [用户ID] [消息体]
-func (c *BinaryCodec) Encode(msg *types.Message) ([]byte, error) {
-	// 准备字符串数据
-	gameIDBytes := []byte(msg.Header.GameID)
-	userIDBytes := []byte(msg.Header.UserID)
+// WithCompression 返回使用指定压缩选项的编解码器副本
+func (c *BinaryCodec) WithCompression(opts *CompressionOptions) Codec {
+	cp := *c
+	cp.compression = opts
+	return &cp
+}
 
-	// 计算消息总长度
-	gameIDLen := uint16(len(gameIDBytes))
-	userIDLen := uint16(len(userIDBytes))
-	bodyLen := uint32(len(msg.Body))
+// WithLimits 返回使用指定帧大小限制的编解码器副本
+func (c *BinaryCodec) WithLimits(limits FrameLimits) Codec {
+	cp := *c
+	cp.limits = limits
+	return &cp
+}
 
-	// 固定头部长度: 版本(1) + 类型(2) + 标志(1) + 序列号(4) + 时间戳(8) + 体长度(4) + 校验和(4) + 游戏ID长度(2) + 用户ID长度(2)
-	fixedHeaderLen := 1 + 2 + 1 + 4 + 8 + 4 + 4 + 2 + 2
-	totalLen := fixedHeaderLen + int(gameIDLen) + int(userIDLen) + int(bodyLen)
+// 二进制消息头固定部分长度
+const (
+	// 版本(1) + 类型(2) + 标志(1) + 序列号(4) + 时间戳(8) + 体长度(4) + 校验和(4) + 游戏ID长度(2) + 用户ID长度(2)
+	binaryHeaderLenV1 = 28
+	// v1固定部分 + 扩展字段长度(2)
+	binaryHeaderLenV2 = binaryHeaderLenV1 + 2
+	// 校验和字段的起始位置
+	binaryChecksumOffset = 20
+	// 所有扩展字段的TLV头部加上定长值（截止时间8字节、错误码4字节）的长度
+	maxFixedExtensionsSize = 4*3 + 8 + 4
+)
 
-	buffer := make([]byte, totalLen)
-	offset := 0
+// Encode 编码消息（二进制格式）
+// v1格式: [版本(1)] [类型(2)] [标志(1)] [序列号(4)] [时间戳(8)] [体长度(4)] [校验和(4)] [游戏ID长度(2)] [用户ID长度(2)] [游戏ID] [用户ID] [消息体]
+// v2格式: [v1固定部分(28)] [扩展字段长度(2)] [游戏ID] [用户ID] [扩展字段(TLV)] [消息体]
+// 扩展字段为若干 [类型(1)] [长度(2)] [值] ，解码时忽略未知类型
+func (c *BinaryCodec) Encode(msg *types.Message) ([]byte, error) {
+	return c.AppendEncode(nil, msg)
+}
 
-	// 版本
-	buffer[offset] = msg.Header.Version
-	offset++
+// AppendEncode 将消息编码后追加到dst，dst容量足够时不分配内存
+func (c *BinaryCodec) AppendEncode(dst []byte, msg *types.Message) ([]byte, error) {
+	// 按需压缩消息体
+	body, flags, err := compressBody(c.compression, msg.Header.Flags, msg.Body)
+	if err != nil {
+		return dst, err
+	}
 
-	// 类型
-	binary.BigEndian.PutUint16(buffer[offset:offset+2], uint16(msg.Header.Type))
-	offset += 2
+	gameID, userID := msg.Header.GameID, msg.Header.UserID
+	if len(gameID) > 0xFFFF || len(userID) > 0xFFFF {
+		return dst, fmt.Errorf("游戏ID或用户ID过长")
+	}
 
-	// 标志
-	buffer[offset] = byte(msg.Header.Flags)
-	offset++
+	// v2消息附带扩展字段，预留全部扩展字段的空间
+	headerLen, extSize := binaryHeaderLenV1, 0
+	if msg.Header.Version >= types.ProtocolVersionV2 {
+		headerLen = binaryHeaderLenV2
+		extSize = maxFixedExtensionsSize + len(msg.Header.TraceID) + len(msg.Header.ClientBuild)
+	}
 
-	// 序列号
-	binary.BigEndian.PutUint32(buffer[offset:offset+4], msg.Header.SequenceID)
-	offset += 4
+	start := len(dst)
+	dst = slices.Grow(dst, headerLen+len(gameID)+len(userID)+extSize+len(body))
+
+	// 版本、类型、标志、序列号
+	dst = append(dst, msg.Header.Version)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Type))
+	dst = append(dst, byte(flags))
+	dst = binary.BigEndian.AppendUint32(dst, msg.Header.SequenceID)
 
 	// 时间戳
 	// 注意：这里不重新设置时间戳，使用消息头中的时间戳
-	binary.BigEndian.PutUint64(buffer[offset:offset+8], uint64(msg.Header.Timestamp))
-	offset += 8
+	dst = binary.BigEndian.AppendUint64(dst, uint64(msg.Header.Timestamp))
 
 	// 消息体长度
-	binary.BigEndian.PutUint32(buffer[offset:offset+4], bodyLen)
-	offset += 4
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(body)))
 
-	// 跳过校验和字段（4字节），稍后填充
-	checksumOffset := offset
-	offset += 4
+	// 校验和字段（4字节），稍后填充
+	dst = append(dst, 0, 0, 0, 0)
 
-	// 游戏ID长度
-	binary.BigEndian.PutUint16(buffer[offset:offset+2], gameIDLen)
-	offset += 2
+	// 游戏ID长度、用户ID长度
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(gameID)))
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(userID)))
 
-	// 用户ID长度
-	binary.BigEndian.PutUint16(buffer[offset:offset+2], userIDLen)
-	offset += 2
-
-	// 游戏ID
-	copy(buffer[offset:offset+int(gameIDLen)], gameIDBytes)
-	offset += int(gameIDLen)
+	// 扩展字段长度，写入扩展字段后回填
+	if headerLen == binaryHeaderLenV2 {
+		dst = append(dst, 0, 0)
+	}
 
-	// 用户ID
-	copy(buffer[offset:offset+int(userIDLen)], userIDBytes)
-	offset += int(userIDLen)
+	// 游戏ID、用户ID
+	dst = append(dst, gameID...)
+	dst = append(dst, userID...)
+
+	// 扩展字段
+	if headerLen == binaryHeaderLenV2 {
+		extStart := len(dst)
+		dst, err = appendHeaderExtensions(dst, &msg.Header)
+		if err != nil {
+			return dst[:start], err
+		}
+		binary.BigEndian.PutUint16(dst[start+binaryHeaderLenV1:], uint16(len(dst)-extStart))
+	}
 
 	// 消息体
-	copy(buffer[offset:], msg.Body)
+	dst = append(dst, body...)
 
-	// 计算校验和（所有数据，除了校验和字段）
-	checksumData := make([]byte, 0, len(buffer)-4)
-	checksumData = append(checksumData, buffer[:checksumOffset]...)   // 校验和字段之前的所有数据
-	checksumData = append(checksumData, buffer[checksumOffset+4:]...) // 校验和字段之后的所有数据
-	checksum := crc32.ChecksumIEEE(checksumData)
-
-	// 写入校验和
-	binary.BigEndian.PutUint32(buffer[checksumOffset:checksumOffset+4], checksum)
+	// 计算并写入校验和（所有数据，除了校验和字段）
+	frame := dst[start:]
+	checksum := binaryChecksum(frame)
+	binary.BigEndian.PutUint32(frame[binaryChecksumOffset:binaryChecksumOffset+4], checksum)
 	msg.Header.Checksum = checksum
 
-	return buffer, nil
+	return dst, nil
 }
 
-// Decode 解码消息（二进制格式）
+// Decode 解码消息（二进制格式），根据版本字节选择v1或v2格式
+// 未压缩的消息体直接引用data，调用方需要在消息处理完成前保持data不被覆盖
 func (c *BinaryCodec) Decode(data []byte) (msg *types.Message, consumed int, err error) {
-	if len(data) < 28 { // 最小消息长度
-		return nil, 0, fmt.Errorf("数据长度不足，无法解析消息")
+	msg = &types.Message{}
+	consumed, err = c.DecodeInto(msg, data)
+	if err != nil {
+		return nil, consumed, err
 	}
+	return msg, consumed, nil
+}
 
-	offset := 0
-	header := types.MessageHeader{}
+// DecodeInto 解码消息到已有的消息对象，用于复用池化的消息对象
+// msg中原有的游戏ID和用户ID与帧中相同时直接复用，避免每条消息分配字符串
+func (c *BinaryCodec) DecodeInto(msg *types.Message, data []byte) (consumed int, err error) {
+	if len(data) < 1 {
+		return 0, fmt.Errorf("%w，无法解析消息", ErrInsufficientData)
+	}
 
-	// 版本
-	header.Version = data[offset]
-	offset++
+	header := types.MessageHeader{Version: data[0]}
+	var headerLen int
+	switch header.Version {
+	case types.ProtocolVersionV1:
+		headerLen = binaryHeaderLenV1
+	case types.ProtocolVersionV2:
+		headerLen = binaryHeaderLenV2
+	default:
+		return 0, fmt.Errorf("%w: 不支持的协议版本 %d", ErrMalformedFrame, header.Version)
+	}
+	if len(data) < headerLen { // 最小消息长度
+		return 0, fmt.Errorf("%w，无法解析消息", ErrInsufficientData)
+	}
+
+	offset := 1
 
 	// 类型
 	header.Type = types.MessageType(binary.BigEndian.Uint16(data[offset : offset+2]))
@@ -239,48 +433,159 @@ func (c *BinaryCodec) Decode(data []byte) (msg *types.Message, consumed int, err
 	offset += 4
 
 	// 游戏ID长度
-	gameIDLen := binary.BigEndian.Uint16(data[offset : offset+2])
+	gameIDLen := int(binary.BigEndian.Uint16(data[offset : offset+2]))
 	offset += 2
 
 	// 用户ID长度
-	userIDLen := binary.BigEndian.Uint16(data[offset : offset+2])
+	userIDLen := int(binary.BigEndian.Uint16(data[offset : offset+2]))
 	offset += 2
 
-	// 游戏ID
-	if len(data) < offset+int(gameIDLen) {
-		return nil, 28, fmt.Errorf("数据长度不足，无法解析游戏ID")
+	// 扩展字段长度
+	extLen := 0
+	if headerLen == binaryHeaderLenV2 {
+		extLen = int(binary.BigEndian.Uint16(data[offset : offset+2]))
+		offset += 2
+	}
+
+	// 长度字段超过限制时不等待后续数据直接拒绝
+	if err := c.limits.checkHeaderSize(offset + gameIDLen + userIDLen + extLen); err != nil {
+		return 0, err
+	}
+	if err := c.limits.checkBodySize(int(header.BodyLength)); err != nil {
+		return 0, err
+	}
+
+	// 整帧长度
+	totalConsumed := offset + gameIDLen + userIDLen + extLen + int(header.BodyLength)
+	if len(data) < totalConsumed {
+		return 0, fmt.Errorf("%w，无法解析消息体", ErrInsufficientData)
+	}
+	frame := data[:totalConsumed]
+
+	// 验证校验和（只覆盖当前帧，缓冲区中可能还有后续消息）
+	if expectedChecksum := binaryChecksum(frame); expectedChecksum != header.Checksum {
+		return totalConsumed, fmt.Errorf("%w，期望0x%x，实际0x%x", ErrChecksumMismatch, header.Checksum, expectedChecksum)
 	}
-	header.GameID = string(data[offset : offset+int(gameIDLen)])
-	offset += int(gameIDLen)
+
+	// 游戏ID
+	header.GameID = reuseString(msg.Header.GameID, frame[offset:offset+gameIDLen])
+	offset += gameIDLen
 
 	// 用户ID
-	if len(data) < offset+int(userIDLen) {
-		return nil, offset, fmt.Errorf("数据长度不足，无法解析用户ID")
+	header.UserID = reuseString(msg.Header.UserID, frame[offset:offset+userIDLen])
+	offset += userIDLen
+
+	// 扩展字段
+	if err := decodeHeaderExtensions(&header, frame[offset:offset+extLen]); err != nil {
+		return totalConsumed, err
 	}
-	header.UserID = string(data[offset : offset+int(userIDLen)])
-	offset += int(userIDLen)
+	offset += extLen
 
 	// 消息体
-	if len(data) < offset+int(header.BodyLength) {
-		return nil, offset, fmt.Errorf("数据长度不足，无法解析消息体")
+	body := frame[offset:]
+
+	// 解压消息体
+	body, err = decompressBody(&header, body, c.limits)
+	if err != nil {
+		return totalConsumed, err
 	}
-	body := data[offset : offset+int(header.BodyLength)]
+	msg.Header = header
+	msg.Body = body
+	return totalConsumed, nil
+}
 
-	// 验证校验和（所有数据，除了校验和字段）
-	checksumData := make([]byte, 0, len(data)-4)
-	checksumOffset := 20                                            // 校验和字段的起始位置
-	checksumData = append(checksumData, data[:checksumOffset]...)   // 校验和字段之前的所有数据
-	checksumData = append(checksumData, data[checksumOffset+4:]...) // 校验和字段之后的所有数据
-	expectedChecksum := crc32.ChecksumIEEE(checksumData)
-	if expectedChecksum != header.Checksum {
-		return nil, offset + int(header.BodyLength), fmt.Errorf("校验和验证失败，期望0x%x，实际0x%x", header.Checksum, expectedChecksum)
+// reuseString 内容相同时复用已有字符串，否则分配新字符串
+func reuseString(prev string, b []byte) string {
+	if prev == string(b) {
+		return prev
 	}
+	return string(b)
+}
 
-	totalConsumed := offset + int(header.BodyLength)
-	return &types.Message{
-		Header: header,
-		Body:   body,
-	}, totalConsumed, nil
+// binaryChecksum 计算二进制帧的校验和（跳过校验和字段）
+func binaryChecksum(frame []byte) uint32 {
+	checksum := crc32.ChecksumIEEE(frame[:binaryChecksumOffset])
+	return crc32.Update(checksum, crc32.IEEETable, frame[binaryChecksumOffset+4:])
+}
+
+// appendHeaderExtensions 将消息头的扩展字段编码为TLV后追加到dst，零值字段不编码
+func appendHeaderExtensions(dst []byte, header *types.MessageHeader) ([]byte, error) {
+	start := len(dst)
+	putString := func(t types.HeaderExtensionType, value string) error {
+		if len(value) > 0xFFFF {
+			return fmt.Errorf("扩展字段过长: 类型0x%02x，长度%d", t, len(value))
+		}
+		dst = append(dst, byte(t))
+		dst = binary.BigEndian.AppendUint16(dst, uint16(len(value)))
+		dst = append(dst, value...)
+		return nil
+	}
+
+	if header.TraceID != "" {
+		if err := putString(types.ExtTraceID, header.TraceID); err != nil {
+			return dst[:start], err
+		}
+	}
+	if header.Deadline != 0 {
+		dst = append(dst, byte(types.ExtDeadline), 0, 8)
+		dst = binary.BigEndian.AppendUint64(dst, uint64(header.Deadline))
+	}
+	if header.ClientBuild != "" {
+		if err := putString(types.ExtClientBuild, header.ClientBuild); err != nil {
+			return dst[:start], err
+		}
+	}
+	if header.ErrorCode != 0 {
+		dst = append(dst, byte(types.ExtErrorCode), 0, 4)
+		dst = binary.BigEndian.AppendUint32(dst, uint32(header.ErrorCode))
+	}
+
+	if len(dst)-start > 0xFFFF {
+		return dst[:start], fmt.Errorf("扩展字段总长度过长: %d", len(dst)-start)
+	}
+	return dst, nil
+}
+
+// stripHeaderExtensions 清除v1协议不支持的扩展字段
+func stripHeaderExtensions(header *types.MessageHeader) {
+	header.TraceID = ""
+	header.Deadline = 0
+	header.ClientBuild = ""
+	header.ErrorCode = 0
+}
+
+// decodeHeaderExtensions 解析TLV扩展字段到消息头，未知类型直接跳过以兼容更新的客户端
+func decodeHeaderExtensions(header *types.MessageHeader, data []byte) error {
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return fmt.Errorf("%w: 扩展字段不完整", ErrMalformedFrame)
+		}
+		t := types.HeaderExtensionType(data[0])
+		length := int(binary.BigEndian.Uint16(data[1:3]))
+		if len(data) < 3+length {
+			return fmt.Errorf("%w: 扩展字段越界，类型0x%02x，长度%d", ErrMalformedFrame, t, length)
+		}
+		value := data[3 : 3+length]
+		data = data[3+length:]
+
+		switch t {
+		case types.ExtTraceID:
+			header.TraceID = string(value)
+		case types.ExtDeadline:
+			if length != 8 {
+				return fmt.Errorf("%w: 截止时间扩展字段长度%d", ErrMalformedFrame, length)
+			}
+			header.Deadline = int64(binary.BigEndian.Uint64(value))
+		case types.ExtClientBuild:
+			header.ClientBuild = string(value)
+		case types.ExtErrorCode:
+			if length != 4 {
+				return fmt.Errorf("%w: 错误码扩展字段长度%d", ErrMalformedFrame, length)
+			}
+			header.ErrorCode = int32(binary.BigEndian.Uint32(value))
+		}
+	}
+	return nil
 }
 
 // CreateHeartbeatMessage 创建心跳消息
@@ -297,13 +602,31 @@ func CreateHeartbeatMessage(sequenceID uint32) *types.Message {
 	}
 }
 
-// CreateHandshakeMessage 创建握手消息
-func CreateHandshakeMessage(gameID, userID string, sequenceID uint32) *types.Message {
-	body := map[string]interface{}{
-		"game_id": gameID,
-		"user_id": userID,
+// CreatePingMessage 创建服务端Ping消息，客户端需回复相同序列号的Pong
+func CreatePingMessage(sequenceID uint32) *types.Message {
+	return &types.Message{
+		Header: types.MessageHeader{
+			Version:    types.ProtocolVersion,
+			Type:       types.MessageTypePing,
+			Flags:      types.FlagNone,
+			SequenceID: sequenceID,
+			Timestamp:  time.Now().Unix(),
+		},
+		Body: []byte{},
 	}
-	bodyData, _ := json.Marshal(body)
+}
+
+// CreatePongMessage 创建Pong消息，序列号与对应的Ping相同
+func CreatePongMessage(sequenceID uint32) *types.Message {
+	msg := CreatePingMessage(sequenceID)
+	msg.Header.Type = types.MessageTypePong
+	return msg
+}
+
+// CreateHandshakeMessage 创建握手消息
+func CreateHandshakeMessage(resp *types.HandshakeResponse, sequenceID uint32) *types.Message {
+	gameID, userID := resp.GameID, resp.UserID
+	bodyData, _ := json.Marshal(resp)
 
 	return &types.Message{
 		Header: types.MessageHeader{
@@ -320,6 +643,39 @@ func CreateHandshakeMessage(gameID, userID string, sequenceID uint32) *types.Mes
 	}
 }
 
+// CreateNoticeMessage 创建服务端通知消息
+func CreateNoticeMessage(notice *types.Notice) *types.Message {
+	bodyData, _ := json.Marshal(notice)
+
+	return &types.Message{
+		Header: types.MessageHeader{
+			Version:    types.ProtocolVersion,
+			Type:       types.MessageTypeNotice,
+			Flags:      types.FlagNone,
+			GameID:     notice.GameID,
+			Timestamp:  time.Now().Unix(),
+			BodyLength: uint32(len(bodyData)),
+		},
+		Body: bodyData,
+	}
+}
+
+// CreateGoAwayMessage 创建排空连接消息
+func CreateGoAwayMessage(goAway *types.GoAway) *types.Message {
+	bodyData, _ := json.Marshal(goAway)
+
+	return &types.Message{
+		Header: types.MessageHeader{
+			Version:    types.ProtocolVersion,
+			Type:       types.MessageTypeGoAway,
+			Flags:      types.FlagNone,
+			Timestamp:  time.Now().Unix(),
+			BodyLength: uint32(len(bodyData)),
+		},
+		Body: bodyData,
+	}
+}
+
 // CreateErrorMessage 创建错误消息
 func CreateErrorMessage(code int, message string, sequenceID uint32) *types.Message {
 	body := map[string]interface{}{
@@ -336,6 +692,7 @@ func CreateErrorMessage(code int, message string, sequenceID uint32) *types.Mess
 			SequenceID: sequenceID,
 			Timestamp:  time.Now().Unix(),
 			BodyLength: uint32(len(bodyData)),
+			ErrorCode:  int32(code),
 		},
 		Body: bodyData,
 	}
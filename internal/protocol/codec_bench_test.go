@@ -0,0 +1,164 @@
+package protocol
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+)
+
+var benchBody = []byte(`{"item_id":"sword","quantity":1,"price":100}`)
+
+func newBenchMessage() *types.Message {
+	msg := newTestMessage(benchBody)
+	msg.Header.Version = types.ProtocolVersionV2
+	msg.Header.TraceID = "trace-bench"
+	return msg
+}
+
+func BenchmarkBinaryCodecEncode(b *testing.B) {
+	codec := NewBinaryCodec()
+	msg := newBenchMessage()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := codec.Encode(msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBinaryCodecAppendEncode(b *testing.B) {
+	codec := NewBinaryCodec()
+	msg := newBenchMessage()
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if buf, err = codec.AppendEncode(buf[:0], msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBinaryCodecDecode(b *testing.B) {
+	codec := NewBinaryCodec()
+	frame, _ := codec.Encode(newBenchMessage())
+	b.ReportAllocs()
+	b.SetBytes(int64(len(frame)))
+	for i := 0; i < b.N; i++ {
+		if _, _, err := codec.Decode(frame); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBinaryCodecDecodeInto(b *testing.B) {
+	codec := NewBinaryCodec()
+	msg := newTestMessage(benchBody)
+	frame, _ := codec.Encode(msg)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(frame)))
+	for i := 0; i < b.N; i++ {
+		if _, err := codec.DecodeInto(msg, frame); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// replayConn 循环返回同一段数据的连接，用于测量读路径的分配
+type replayConn struct {
+	net.Conn
+	data []byte
+	pos  int
+}
+
+func (c *replayConn) Read(p []byte) (int, error) {
+	n := copy(p, c.data[c.pos:])
+	c.pos = (c.pos + n) % len(c.data)
+	return n, nil
+}
+
+func (c *replayConn) Close() error                     { return nil }
+func (c *replayConn) LocalAddr() net.Addr              { return &net.TCPAddr{} }
+func (c *replayConn) RemoteAddr() net.Addr             { return &net.TCPAddr{} }
+func (c *replayConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *replayConn) SetWriteDeadline(time.Time) error { return nil }
+
+func BenchmarkConnectionReadMessage(b *testing.B) {
+	frame, _ := NewBinaryCodec().Encode(newTestMessage(benchBody))
+	var data []byte
+	for len(data) < 64<<10 {
+		data = append(data, frame...)
+	}
+
+	conn := NewConnection(&replayConn{data: data}, types.ConnectionConfig{BufferSize: 4096}, NewBinaryCodec(), newTestLogger())
+	conn.setState(types.StateConnected)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(frame)))
+	for i := 0; i < b.N; i++ {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			b.Fatal(err)
+		}
+		msg.Release()
+	}
+}
+
+// signalConn 每次写入后发出通知的连接，用于等待写协程发送完成
+type signalConn struct {
+	net.Conn
+	written chan struct{}
+}
+
+func (c *signalConn) Write(p []byte) (int, error) {
+	c.written <- struct{}{}
+	return len(p), nil
+}
+
+func (c *signalConn) Close() error                     { return nil }
+func (c *signalConn) LocalAddr() net.Addr              { return &net.TCPAddr{} }
+func (c *signalConn) RemoteAddr() net.Addr             { return &net.TCPAddr{} }
+func (c *signalConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *signalConn) SetWriteDeadline(time.Time) error { return nil }
+
+// BenchmarkConnectionSendQueue 测量经发送队列和写协程发送消息的分配，发送后的帧应归还缓冲区池
+func BenchmarkConnectionSendQueue(b *testing.B) {
+	target := &signalConn{written: make(chan struct{}, 1)}
+	conn := NewConnection(target, types.ConnectionConfig{SendQueueSize: 16}, NewBinaryCodec(), newTestLogger())
+	conn.Start()
+	defer conn.Close()
+	msg := newBenchMessage()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := conn.SendMessage(msg); err != nil {
+			b.Fatal(err)
+		}
+		<-target.written
+	}
+}
+
+// BenchmarkCompressionRoundTrip 测量压缩和解压的分配，压缩器和解压器应从池中复用
+func BenchmarkCompressionRoundTrip(b *testing.B) {
+	body := bytes.Repeat(benchBody, 32)
+	for _, name := range []string{"deflate", "gzip"} {
+		b.Run(name, func(b *testing.B) {
+			c, _ := GetCompressorByName(name)
+			dst := make([]byte, 0, len(body))
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				compressed, err := c.Compress(dst[:0], body)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := c.Decompress(compressed, 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"datamiddleware/internal/common/types"
+)
+
+// 编解码器ID
+const (
+	CodecIDBinary   uint8 = 1
+	CodecIDJSON     uint8 = 2
+	CodecIDProtobuf uint8 = 3
+)
+
+// CodecMagic 连接前导魔数，新客户端建立连接后先发送 [魔数(3)] [编解码器ID(1)] 声明使用的编解码器
+var CodecMagic = []byte("DMW")
+
+// CodecPreamble 生成指定编解码器的连接前导字节
+func CodecPreamble(id uint8) []byte {
+	preamble := make([]byte, 0, len(CodecMagic)+1)
+	preamble = append(preamble, CodecMagic...)
+	return append(preamble, id)
+}
+
+// codecEntry 编解码器注册项
+type codecEntry struct {
+	id      uint8
+	name    string
+	factory func() Codec
+}
+
+// CodecRegistry 编解码器注册表
+type CodecRegistry struct {
+	byID   map[uint8]*codecEntry
+	byName map[string]*codecEntry
+	mu     sync.RWMutex
+}
+
+// NewCodecRegistry 创建编解码器注册表
+func NewCodecRegistry() *CodecRegistry {
+	return &CodecRegistry{
+		byID:   make(map[uint8]*codecEntry),
+		byName: make(map[string]*codecEntry),
+	}
+}
+
+// DefaultCodecRegistry 默认编解码器注册表
+var DefaultCodecRegistry = NewCodecRegistry()
+
+func init() {
+	DefaultCodecRegistry.Register(CodecIDBinary, CodecBinary, func() Codec { return NewBinaryCodec() })
+	DefaultCodecRegistry.Register(CodecIDJSON, CodecJSON, func() Codec { return NewJSONCodec() })
+	DefaultCodecRegistry.Register(CodecIDProtobuf, CodecProtobuf, func() Codec { return NewProtobufCodec() })
+}
+
+// Register 注册编解码器
+func (r *CodecRegistry) Register(id uint8, name string, factory func() Codec) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byID[id]; exists {
+		return fmt.Errorf("编解码器ID已存在: %d", id)
+	}
+	if _, exists := r.byName[name]; exists {
+		return fmt.Errorf("编解码器名称已存在: %s", name)
+	}
+
+	entry := &codecEntry{id: id, name: name, factory: factory}
+	r.byID[id] = entry
+	r.byName[name] = entry
+	return nil
+}
+
+// New 根据ID创建编解码器
+func (r *CodecRegistry) New(id uint8) (Codec, string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.byID[id]
+	if !ok {
+		return nil, "", false
+	}
+	return entry.factory(), entry.name, true
+}
+
+// NewByName 根据名称创建编解码器
+func (r *CodecRegistry) NewByName(name string) (Codec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.byName[name]
+	if !ok {
+		return nil, false
+	}
+	return entry.factory(), true
+}
+
+// ID 根据名称获取编解码器ID
+func (r *CodecRegistry) ID(name string) (uint8, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.byName[name]
+	if !ok {
+		return 0, false
+	}
+	return entry.id, true
+}
+
+// Names 获取已注册的编解码器名称
+func (r *CodecRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.byName))
+	for name := range r.byName {
+		names = append(names, name)
+	}
+	return names
+}
+
+// DetectCodec 根据连接的首部字节选择编解码器
+// 优先识别前导魔数；没有前导的旧客户端按帧格式识别：
+// 二进制帧首字节为协议版本（v1或v2），JSON帧和Protobuf帧以4字节长度开头，之后分别是'{'和消息头字段标签
+// 返回ready=false表示数据不足需要继续读取，codec为nil表示无法识别，应使用默认编解码器
+func (r *CodecRegistry) DetectCodec(data []byte) (codec Codec, name string, consumed int, ready bool, err error) {
+	preambleLen := len(CodecMagic) + 1
+	if len(data) < preambleLen && bytes.HasPrefix(CodecMagic, data) {
+		return nil, "", 0, false, nil
+	}
+	if len(data) >= preambleLen && bytes.HasPrefix(data, CodecMagic) {
+		codec, name, ok := r.New(data[len(CodecMagic)])
+		if !ok {
+			return nil, "", 0, true, fmt.Errorf("%w: 不支持的编解码器ID %d", ErrMalformedFrame, data[len(CodecMagic)])
+		}
+		return codec, name, preambleLen, true, nil
+	}
+
+	if len(data) > 0 && data[0] >= types.ProtocolVersionV1 && data[0] <= types.MaxProtocolVersion {
+		codec, name, _ := r.New(CodecIDBinary)
+		return codec, name, 0, true, nil
+	}
+	if len(data) < 5 {
+		return nil, "", 0, false, nil
+	}
+	switch data[4] {
+	case '{':
+		codec, name, _ := r.New(CodecIDJSON)
+		return codec, name, 0, true, nil
+	case 0x0A: // Frame.header 字段标签
+		codec, name, _ := r.New(CodecIDProtobuf)
+		return codec, name, 0, true, nil
+	}
+	return nil, "", 0, true, nil
+}
@@ -0,0 +1,351 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"datamiddleware/internal/common/types"
+	"datamiddleware/internal/protocol/pb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestMessage(body []byte) *types.Message {
+	return &types.Message{
+		Header: types.MessageHeader{
+			Version:    types.ProtocolVersion,
+			Type:       types.MessageTypeItemOperation,
+			SequenceID: 42,
+			GameID:     "game1",
+			UserID:     "user1",
+			Timestamp:  1700000000,
+		},
+		Body: body,
+	}
+}
+
+func TestCodecCompressionRoundTrip(t *testing.T) {
+	large := bytes.Repeat([]byte(`{"item_id":"sword","quantity":1},`), 200)
+	small := []byte(`{"op":"ping"}`)
+	deflate, _ := GetCompressorByName("deflate")
+	gzip, _ := GetCompressorByName("gzip")
+
+	tests := []struct {
+		name           string
+		codec          Codec
+		body           []byte
+		wantCompressed bool
+	}{
+		{"binary deflate large", NewBinaryCodec().WithCompression(&CompressionOptions{Compressor: deflate, Threshold: 256}), large, true},
+		{"binary gzip large", NewBinaryCodec().WithCompression(&CompressionOptions{Compressor: gzip, Threshold: 256}), large, true},
+		{"binary below threshold", NewBinaryCodec().WithCompression(&CompressionOptions{Compressor: deflate, Threshold: 256}), small, false},
+		{"binary no compression", NewBinaryCodec(), large, false},
+		{"json deflate large", NewJSONCodec().WithCompression(&CompressionOptions{Compressor: deflate, Threshold: 256}), large, true},
+		{"json no compression", NewJSONCodec(), small, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.codec.Encode(newTestMessage(tt.body))
+			if err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+
+			if tt.wantCompressed && len(data) >= len(tt.body) {
+				t.Errorf("期望消息被压缩，编码长度%d，原始消息体长度%d", len(data), len(tt.body))
+			}
+
+			// 解码端不需要压缩选项，根据标志和算法ID自动解压
+			var decoder Codec = NewBinaryCodec()
+			if _, ok := tt.codec.(*JSONCodec); ok {
+				decoder = NewJSONCodec()
+			}
+			msg, consumed, err := decoder.Decode(data)
+			if err != nil {
+				t.Fatalf("解码失败: %v", err)
+			}
+			if consumed != len(data) {
+				t.Errorf("消耗字节数 = %d, 期望 %d", consumed, len(data))
+			}
+			if !bytes.Equal(msg.Body, tt.body) {
+				t.Errorf("消息体不一致")
+			}
+			if msg.Header.Flags&types.FlagCompressed != 0 {
+				t.Errorf("解码后不应保留压缩标志")
+			}
+			if msg.Header.SequenceID != 42 || msg.Header.GameID != "game1" || msg.Header.UserID != "user1" {
+				t.Errorf("消息头不一致: %+v", msg.Header)
+			}
+		})
+	}
+}
+
+func TestNegotiateCompression(t *testing.T) {
+	cfg := types.CompressionConfig{Enabled: true, Threshold: 1024, Algorithms: []string{"deflate", "gzip"}}
+
+	tests := []struct {
+		name    string
+		cfg     types.CompressionConfig
+		offered []string
+		want    string
+	}{
+		{"server preference wins", cfg, []string{"gzip", "deflate"}, "deflate"},
+		{"only gzip offered", cfg, []string{"gzip"}, "gzip"},
+		{"unsupported algorithm", cfg, []string{"zstd"}, ""},
+		{"nothing offered", cfg, nil, ""},
+		{"disabled", types.CompressionConfig{Algorithms: []string{"deflate"}}, []string{"deflate"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if c := NegotiateCompression(tt.cfg, tt.offered); c != nil {
+				got = c.Name()
+			}
+			if got != tt.want {
+				t.Errorf("NegotiateCompression() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtobufCodecPayloads(t *testing.T) {
+	codec := NewProtobufCodec()
+
+	// 客户端发送Protobuf格式的道具操作请求
+	reqBody, err := proto.Marshal(&pb.ItemOperationRequest{UserId: "user1", Operation: "create", Name: "sword", Quantity: 3})
+	if err != nil {
+		t.Fatalf("序列化请求失败: %v", err)
+	}
+	data, err := codec.Encode(newTestMessage(reqBody))
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+
+	msg, consumed, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if consumed != len(data) {
+		t.Errorf("消耗字节数 = %d, 期望 %d", consumed, len(data))
+	}
+	if err := codec.DecodePayload(msg); err != nil {
+		t.Fatalf("转换请求消息体失败: %v", err)
+	}
+
+	// 业务处理器看到的是JSON
+	var itemReq struct {
+		UserID    string `json:"user_id"`
+		Operation string `json:"operation"`
+		Name      string `json:"name"`
+		Quantity  int64  `json:"quantity"`
+	}
+	if err := json.Unmarshal(msg.Body, &itemReq); err != nil {
+		t.Fatalf("请求消息体不是JSON: %v", err)
+	}
+	if itemReq.UserID != "user1" || itemReq.Operation != "create" || itemReq.Name != "sword" || itemReq.Quantity != 3 {
+		t.Errorf("请求内容不一致: %+v", itemReq)
+	}
+
+	// 服务端的JSON响应转换为pb.Response
+	respMsg := newTestMessage([]byte(`{"id":"r1","code":0,"message":"创建道具成功","data":{"item_id":"i1"},"timestamp":1}`))
+	encoded, err := codec.EncodePayload(respMsg)
+	if err != nil {
+		t.Fatalf("转换响应消息体失败: %v", err)
+	}
+	var resp pb.Response
+	if err := proto.Unmarshal(encoded.Body, &resp); err != nil {
+		t.Fatalf("响应消息体不是pb.Response: %v", err)
+	}
+	if resp.Id != "r1" || resp.Message != "创建道具成功" || string(resp.Data) != `{"item_id":"i1"}` {
+		t.Errorf("响应内容不一致: %v", &resp)
+	}
+	if !bytes.HasPrefix(respMsg.Body, []byte("{")) {
+		t.Errorf("不应修改原消息")
+	}
+}
+
+func TestBinaryCodecVersions(t *testing.T) {
+	codec := NewBinaryCodec()
+
+	v2 := newTestMessage([]byte(`{"op":"get"}`))
+	v2.Header.Version = types.ProtocolVersionV2
+	v2.Header.TraceID = "trace-123"
+	v2.Header.Deadline = 1700000000500
+	v2.Header.ClientBuild = "1.2.3+45"
+	v2.Header.ErrorCode = 1004
+
+	v1 := newTestMessage([]byte(`{"op":"get"}`))
+	v1.Header.TraceID = "dropped"
+
+	// 两条消息写入同一缓冲区，校验和只覆盖各自的帧
+	v2Data, err := codec.Encode(v2)
+	if err != nil {
+		t.Fatalf("编码v2消息失败: %v", err)
+	}
+	v1Data, err := codec.Encode(v1)
+	if err != nil {
+		t.Fatalf("编码v1消息失败: %v", err)
+	}
+	buffer := append(append([]byte(nil), v2Data...), v1Data...)
+
+	msg, consumed, err := codec.Decode(buffer)
+	if err != nil {
+		t.Fatalf("解码v2消息失败: %v", err)
+	}
+	if consumed != len(v2Data) {
+		t.Errorf("消耗字节数 = %d, 期望 %d", consumed, len(v2Data))
+	}
+	h := msg.Header
+	if h.Version != types.ProtocolVersionV2 || h.TraceID != "trace-123" || h.Deadline != 1700000000500 ||
+		h.ClientBuild != "1.2.3+45" || h.ErrorCode != 1004 || h.GameID != "game1" || h.UserID != "user1" {
+		t.Errorf("v2消息头不一致: %+v", h)
+	}
+
+	msg, _, err = codec.Decode(buffer[consumed:])
+	if err != nil {
+		t.Fatalf("解码v1消息失败: %v", err)
+	}
+	if msg.Header.Version != types.ProtocolVersionV1 || msg.Header.TraceID != "" {
+		t.Errorf("v1消息不应携带扩展字段: %+v", msg.Header)
+	}
+
+	// 未知扩展字段被忽略
+	header := types.MessageHeader{}
+	if err := decodeHeaderExtensions(&header, []byte{0x7F, 0x00, 0x02, 'h', 'i', 0x01, 0x00, 0x01, 'x'}); err != nil {
+		t.Fatalf("解析扩展字段失败: %v", err)
+	}
+	if header.TraceID != "x" {
+		t.Errorf("TraceID = %q, 期望 %q", header.TraceID, "x")
+	}
+
+	// 不支持的版本
+	bad := append([]byte(nil), v1Data...)
+	bad[0] = 9
+	if _, _, err := codec.Decode(bad); err == nil {
+		t.Error("不支持的协议版本应返回错误")
+	}
+}
+
+func TestFrameLimits(t *testing.T) {
+	limits := FrameLimits{MaxHeaderSize: 64, MaxBodySize: 128}
+	big := newTestMessage(bytes.Repeat([]byte("x"), 256))
+	longID := newTestMessage(nil)
+	longID.Header.UserID = string(bytes.Repeat([]byte("u"), 100))
+
+	tests := []struct {
+		name   string
+		codec  Codec
+		msg    *types.Message
+		prefix int // 只提供前prefix字节即可拒绝
+		reason string
+	}{
+		{"binary body", NewBinaryCodec(), big, binaryHeaderLenV1, RejectBodyTooLarge},
+		{"binary header", NewBinaryCodec(), longID, binaryHeaderLenV1, RejectHeaderTooLarge},
+		{"json header", NewJSONCodec(), longID, 4, RejectHeaderTooLarge},
+		{"protobuf frame", NewProtobufCodec(), big, 4, RejectFrameTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.codec.Encode(tt.msg)
+			if err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			limited := tt.codec.(LimitedCodec).WithLimits(limits)
+			_, _, err = limited.Decode(data[:tt.prefix])
+			if got := FrameRejectReason(err); got != tt.reason {
+				t.Errorf("拒绝原因 = %q, 期望 %q (err=%v)", got, tt.reason, err)
+			}
+		})
+	}
+
+	// 校验和错误不是数据不足
+	data, _ := NewBinaryCodec().Encode(newTestMessage([]byte("hello")))
+	data[len(data)-1] ^= 0xFF
+	_, _, err := NewBinaryCodec().Decode(data)
+	if FrameRejectReason(err) != RejectChecksum {
+		t.Errorf("期望校验和错误，实际 %v", err)
+	}
+}
+
+func TestDecompressedBodyLimit(t *testing.T) {
+	limits := FrameLimits{MaxHeaderSize: 256, MaxBodySize: 1024}
+	deflate, _ := GetCompressorByName("deflate")
+	opts := &CompressionOptions{Compressor: deflate, Threshold: 1}
+	// 线上长度远小于上限，解压后超过上限
+	bomb := newTestMessage(bytes.Repeat([]byte("x"), 64*1024))
+
+	codecs := []struct {
+		name  string
+		codec CompressibleCodec
+	}{
+		{"binary", NewBinaryCodec()},
+		{"json", NewJSONCodec()},
+		{"protobuf", NewProtobufCodec()},
+	}
+
+	for _, tt := range codecs {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.codec.WithCompression(opts).Encode(bomb)
+			if err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			if len(data) > limits.MaxBodySize {
+				t.Fatalf("压缩后长度%d超过上限，无法验证解压上限", len(data))
+			}
+
+			limited := tt.codec.(LimitedCodec).WithLimits(limits)
+			_, _, err = limited.Decode(data)
+			if got := FrameRejectReason(err); got != RejectBodyTooLarge {
+				t.Errorf("拒绝原因 = %q, 期望 %q (err=%v)", got, RejectBodyTooLarge, err)
+			}
+
+			// 未配置上限时按默认上限解压
+			if _, _, err := Codec(tt.codec).Decode(data); err != nil {
+				t.Errorf("未配置上限时解码失败: %v", err)
+			}
+		})
+	}
+}
+
+func TestJSONCodecTextFrames(t *testing.T) {
+	codec := NewJSONCodec()
+	msg := newTestMessage([]byte(`{"item_id":"sword"}`))
+	msg.Header.GameID = `g}"{[` // 字符串中的括号和转义不影响消息头边界
+	frame, err := codec.Encode(msg)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	text := frame[4:]
+	second, _ := codec.Encode(newTestMessage([]byte("next")))
+	stream := append(append([]byte{}, text...), second...)
+
+	got, consumed, err := codec.Decode(stream)
+	if err != nil {
+		t.Fatalf("解码文本帧失败: %v", err)
+	}
+	if consumed != len(text) {
+		t.Errorf("消耗字节数 = %d, 期望 %d", consumed, len(text))
+	}
+	if got.Header.GameID != msg.Header.GameID || string(got.Body) != `{"item_id":"sword"}` {
+		t.Errorf("消息 = %+v %s", got.Header, got.Body)
+	}
+	if got, _, err := codec.Decode(stream[consumed:]); err != nil || string(got.Body) != "next" {
+		t.Errorf("文本帧之后的带长度前缀帧解码失败: %v", err)
+	}
+
+	// 消息头不完整时等待后续数据，超过消息头上限时直接拒绝
+	headerEnd := textHeaderEnd(text)
+	if _, _, err := codec.Decode(text[:headerEnd-1]); !IsInsufficientDataError(err) {
+		t.Errorf("不完整的消息头应返回数据不足: %v", err)
+	}
+	limited := codec.WithLimits(FrameLimits{MaxHeaderSize: headerEnd - 2, MaxBodySize: 1024})
+	if _, _, err := limited.Decode(text[:headerEnd-1]); FrameRejectReason(err) != RejectHeaderTooLarge {
+		t.Errorf("超过上限的消息头应被拒绝: %v", err)
+	}
+	if _, _, err := codec.Decode([]byte(`{"type":}body`)); FrameRejectReason(err) != RejectMalformed {
+		t.Errorf("格式错误的消息头应被拒绝: %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"datamiddleware/internal/common/types"
+)
+
+// 压缩算法ID，压缩后的消息体首字节为算法ID，解码端据此选择解压算法
+const (
+	CompressionDeflate uint8 = 1 // deflate
+	CompressionGzip    uint8 = 2 // gzip
+)
+
+// defaultMaxDecompressedSize 未配置消息体上限时解压后消息体的最大长度，防止压缩炸弹
+const defaultMaxDecompressedSize = 16 * 1024 * 1024
+
+// Compressor 压缩器接口
+type Compressor interface {
+	ID() uint8
+	Name() string
+	// Compress 压缩src并追加到dst，返回扩展后的切片
+	Compress(dst, src []byte) ([]byte, error)
+	// Decompress 解压，解压后超过limit字节返回ErrBodyTooLarge，limit<=0时使用默认上限
+	Decompress(src []byte, limit int) ([]byte, error)
+}
+
+// CompressionOptions 编解码器的压缩选项
+type CompressionOptions struct {
+	Compressor Compressor // 压缩器
+	Threshold  int        // 压缩阈值，小于该长度的消息体不压缩
+}
+
+var (
+	compressorsMu sync.RWMutex
+	compressors   = map[uint8]Compressor{}
+)
+
+func init() {
+	RegisterCompressor(&DeflateCompressor{})
+	RegisterCompressor(&GzipCompressor{})
+}
+
+// RegisterCompressor 注册压缩器
+func RegisterCompressor(c Compressor) {
+	compressorsMu.Lock()
+	defer compressorsMu.Unlock()
+	compressors[c.ID()] = c
+}
+
+// GetCompressor 根据算法ID获取压缩器
+func GetCompressor(id uint8) (Compressor, bool) {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+	c, ok := compressors[id]
+	return c, ok
+}
+
+// GetCompressorByName 根据算法名称获取压缩器
+func GetCompressorByName(name string) (Compressor, bool) {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+	for _, c := range compressors {
+		if c.Name() == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// NegotiateCompression 根据服务端配置和客户端声明的算法协商压缩器
+// 按服务端配置的优先级选择第一个客户端也支持的算法，未协商成功返回nil
+func NegotiateCompression(cfg types.CompressionConfig, offered []string) Compressor {
+	if !cfg.Enabled || len(offered) == 0 {
+		return nil
+	}
+
+	supported := make(map[string]bool, len(offered))
+	for _, name := range offered {
+		supported[name] = true
+	}
+
+	for _, name := range cfg.Algorithms {
+		if !supported[name] {
+			continue
+		}
+		if c, ok := GetCompressorByName(name); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// compressBody 按压缩选项压缩消息体，返回线上消息体和标志
+func compressBody(opts *CompressionOptions, flags types.MessageFlag, body []byte) ([]byte, types.MessageFlag, error) {
+	if opts == nil || opts.Compressor == nil || flags&types.FlagCompressed != 0 || len(body) < opts.Threshold {
+		return body, flags, nil
+	}
+
+	// 压缩后的消息体首字节为算法ID
+	out := make([]byte, 1, len(body)/2+1)
+	out[0] = opts.Compressor.ID()
+	out, err := opts.Compressor.Compress(out, body)
+	if err != nil {
+		return nil, flags, fmt.Errorf("压缩消息体失败: %w", err)
+	}
+
+	// 压缩后没有变小则按原样发送
+	if len(out) >= len(body) {
+		return body, flags, nil
+	}
+	return out, flags | types.FlagCompressed, nil
+}
+
+// decompressBody 解压带FlagCompressed标志的消息体，解压后的长度同样受消息体上限约束
+func decompressBody(header *types.MessageHeader, body []byte, limits FrameLimits) ([]byte, error) {
+	// 加密消息需先由连接解密后再解压
+	if header.Flags&types.FlagCompressed == 0 || header.Flags&types.FlagEncrypted != 0 {
+		return body, nil
+	}
+	if len(body) == 0 {
+		return nil, fmt.Errorf("压缩消息体为空")
+	}
+
+	c, ok := GetCompressor(body[0])
+	if !ok {
+		return nil, fmt.Errorf("不支持的压缩算法: %d", body[0])
+	}
+
+	data, err := c.Decompress(body[1:], limits.MaxBodySize)
+	if err != nil {
+		return nil, fmt.Errorf("解压消息体失败: %w", err)
+	}
+
+	header.Flags &^= types.FlagCompressed
+	header.BodyLength = uint32(len(data))
+	return data, nil
+}
+
+// readLimited 读取解压数据，超过最大长度返回ErrBodyTooLarge
+func readLimited(r io.Reader, limit int) ([]byte, error) {
+	if limit <= 0 {
+		limit = defaultMaxDecompressedSize
+	}
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		return nil, fmt.Errorf("%w: 解压后超过%d字节", ErrBodyTooLarge, limit)
+	}
+	return data, nil
+}
+
+// appendWriter 将写入的数据追加到切片，压缩器直接输出到调用方的缓冲区
+type appendWriter struct {
+	buf []byte
+}
+
+// Write 追加数据
+func (w *appendWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+// 压缩器和解压器的状态有数百KB，按算法池化复用，避免每条消息（广播时每个连接）重新分配
+var (
+	deflateWriterPool = sync.Pool{
+		New: func() interface{} {
+			d := &deflateWriter{}
+			d.w, _ = flate.NewWriter(&d.out, flate.DefaultCompression)
+			return d
+		},
+	}
+	deflateReaderPool sync.Pool // *deflateReader
+	gzipWriterPool    = sync.Pool{
+		New: func() interface{} {
+			g := &gzipWriter{}
+			g.w = gzip.NewWriter(&g.out)
+			return g
+		},
+	}
+	gzipReaderPool = sync.Pool{
+		New: func() interface{} { return new(gzipReader) },
+	}
+)
+
+// deflateWriter 池化的deflate压缩器及其输出
+type deflateWriter struct {
+	out appendWriter
+	w   *flate.Writer
+}
+
+// deflateReader 池化的deflate解压器及其输入
+type deflateReader struct {
+	src bytes.Reader
+	r   io.ReadCloser
+}
+
+// gzipWriter 池化的gzip压缩器及其输出
+type gzipWriter struct {
+	out appendWriter
+	w   *gzip.Writer
+}
+
+// gzipReader 池化的gzip解压器及其输入
+type gzipReader struct {
+	src bytes.Reader
+	r   gzip.Reader
+}
+
+// DeflateCompressor deflate压缩器
+type DeflateCompressor struct{}
+
+// ID 算法ID
+func (c *DeflateCompressor) ID() uint8 { return CompressionDeflate }
+
+// Name 算法名称
+func (c *DeflateCompressor) Name() string { return "deflate" }
+
+// Compress 压缩
+func (c *DeflateCompressor) Compress(dst, src []byte) ([]byte, error) {
+	d := deflateWriterPool.Get().(*deflateWriter)
+	defer deflateWriterPool.Put(d)
+
+	d.out.buf = dst
+	defer func() { d.out.buf = nil }()
+	d.w.Reset(&d.out)
+	if _, err := d.w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := d.w.Close(); err != nil {
+		return nil, err
+	}
+	return d.out.buf, nil
+}
+
+// Decompress 解压
+func (c *DeflateCompressor) Decompress(src []byte, limit int) ([]byte, error) {
+	d, _ := deflateReaderPool.Get().(*deflateReader)
+	if d == nil {
+		d = &deflateReader{}
+		d.src.Reset(src)
+		d.r = flate.NewReader(&d.src)
+	} else {
+		d.src.Reset(src)
+		d.r.(flate.Resetter).Reset(&d.src, nil)
+	}
+	defer func() {
+		d.src.Reset(nil)
+		deflateReaderPool.Put(d)
+	}()
+	return readLimited(d.r, limit)
+}
+
+// GzipCompressor gzip压缩器
+type GzipCompressor struct{}
+
+// ID 算法ID
+func (c *GzipCompressor) ID() uint8 { return CompressionGzip }
+
+// Name 算法名称
+func (c *GzipCompressor) Name() string { return "gzip" }
+
+// Compress 压缩
+func (c *GzipCompressor) Compress(dst, src []byte) ([]byte, error) {
+	g := gzipWriterPool.Get().(*gzipWriter)
+	defer gzipWriterPool.Put(g)
+
+	g.out.buf = dst
+	defer func() { g.out.buf = nil }()
+	g.w.Reset(&g.out)
+	if _, err := g.w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := g.w.Close(); err != nil {
+		return nil, err
+	}
+	return g.out.buf, nil
+}
+
+// Decompress 解压
+func (c *GzipCompressor) Decompress(src []byte, limit int) ([]byte, error) {
+	g := gzipReaderPool.Get().(*gzipReader)
+	defer func() {
+		g.src.Reset(nil)
+		gzipReaderPool.Put(g)
+	}()
+
+	g.src.Reset(src)
+	if err := g.r.Reset(&g.src); err != nil {
+		return nil, err
+	}
+	return readLimited(&g.r, limit)
+}
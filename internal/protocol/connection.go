@@ -1,8 +1,11 @@
 package protocol
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strings"
 	"sync"
@@ -10,6 +13,7 @@ import (
 	"time"
 
 	"datamiddleware/internal/infrastructure/logging"
+	utils "datamiddleware/internal/common"
 	"datamiddleware/internal/common/types"
 )
 
@@ -26,6 +30,9 @@ func isConnectionClosedError(err error) bool {
 		strings.Contains(errStr, "use of closed network connection")
 }
 
+// OutboundFilter 在消息编码发送前调用，可以替换消息；返回nil消息表示丢弃，返回错误时SendMessage返回该错误
+type OutboundFilter func(conn *Connection, msg *types.Message) (*types.Message, error)
+
 // Connection TCP连接包装器
 type Connection struct {
 	ID               string                 `json:"id"`                // 连接ID
@@ -35,18 +42,53 @@ type Connection struct {
 	Config           types.ConnectionConfig `json:"-"`                 // 连接配置
 	Codec            Codec                  `json:"-"`                 // 编解码器
 	Logger           logger.Logger          `json:"-"`                 // 日志器
-	heartbeatTimer   *time.Timer            `json:"-"`                 // 心跳定时器
+	timers           []*utils.Timer         `json:"-"`                 // 连接管理器时间轮上的超时定时器，关闭时取消
+	clock            utils.Clock            `json:"-"`                 // 记录心跳和活动时间使用的时钟
 	closeChan        chan struct{}          `json:"-"`                 // 关闭通道
+	ctx              context.Context        `json:"-"`                 // 连接上下文，关闭时取消
+	cancel           context.CancelFunc     `json:"-"`                 // 取消连接上下文
 	lastHeartbeat    time.Time              `json:"last_heartbeat"`    // 最后心跳时间
 	missedHeartbeats int64                  `json:"missed_heartbeats"` // 连续丢失心跳次数
-	readBuffer       []byte                 `json:"-"`                 // 读缓冲区，用于处理TCP粘包分包
+	lastActivity     atomic.Int64           `json:"-"`                 // 最后收发消息的时间（UnixNano），读写协程并发更新，GetStats时写入Info
+	lastReceived     atomic.Int64           `json:"-"`                 // 最后收到完整消息的时间（UnixNano），由读协程更新
+	pingSeq          uint32                 `json:"-"`                 // 最近一次服务端Ping的序列号
+	pingSentAt       time.Time              `json:"-"`                 // 等待中的Ping的发送时间，零值表示没有等待中的Ping
+	rbuf             *readBuffer            `json:"-"`                 // 读缓冲区，用于处理TCP粘包分包，消息体直接引用其中的数据
+	rstart           int                    `json:"-"`                 // 读缓冲区中未解析数据的起始位置
+	rend             int                    `json:"-"`                 // 读缓冲区中未解析数据的结束位置
+	lastGameID       string                 `json:"-"`                 // 上一条消息的游戏ID，解码时复用字符串
+	lastUserID       string                 `json:"-"`                 // 上一条消息的用户ID，解码时复用字符串
+	writeBuffer      []byte                 `json:"-"`                 // 同步写入时复用的编码缓冲区，由writeMu保护
+	compression      *CompressionOptions    `json:"-"`                 // 协商出的压缩选项
+	codecDetected    bool                   `json:"-"`                 // 是否已根据首部字节选择编解码器
+	cipher           *SessionCipher         `json:"-"`                 // 会话加密器，nil表示未加密
+	writeMu          sync.Mutex             `json:"-"`                 // 保证加密计数器顺序与写入顺序一致
+	sendQueue        chan []byte            `json:"-"`                 // 待发送的已编码帧，nil表示同步写入
+	closeHooks       []func()               `json:"-"`                 // 连接关闭后执行的回调
+	authHook         func(*Connection)      `json:"-"`                 // 认证信息变化后执行的回调，连接管理器用其更新索引
+	outbound         OutboundFilter         `json:"-"`                 // 发送前的消息过滤器，连接启动前设置
+	boundGame        string                 `json:"-"`                 // 通过游戏专属端口接入时绑定的游戏ID，握手只能进入该游戏
 	mu               sync.RWMutex           `json:"-"`                 // 保护并发访问
 }
 
 // NewConnection 创建新连接
 func NewConnection(conn net.Conn, config types.ConnectionConfig, codec Codec, log logger.Logger) *Connection {
+	return newConnection(conn, config, codec, log, utils.SystemClock())
+}
+
+// newConnection 使用指定时钟创建连接，连接管理器用其时间轮的时钟驱动超时检测
+func newConnection(conn net.Conn, config types.ConnectionConfig, codec Codec, log logger.Logger, clock utils.Clock) *Connection {
 	id := generateConnectionID()
-	now := time.Now()
+	now := clock.Now()
+
+	// WebSocket连接总是按第一个消息识别编解码器（文本消息带有JSON编解码器前导）
+	transport := TransportTCP
+	switch conn.(type) {
+	case *WebSocketConn:
+		transport = TransportWebSocket
+	case *tls.Conn:
+		transport = TransportTLS
+	}
 
 	c := &Connection{
 		ID:    id,
@@ -59,13 +101,23 @@ func NewConnection(conn net.Conn, config types.ConnectionConfig, codec Codec, lo
 			State:        types.StateConnecting,
 			ConnectedAt:  now,
 			LastActivity: now,
+			Codec:        config.Codec,
+			Transport:    transport,
 		},
 		Config:           config,
-		Codec:            codec,
+		Codec:            withFrameLimits(codec, config),
 		Logger:           log,
 		closeChan:        make(chan struct{}),
 		lastHeartbeat:    now,
 		missedHeartbeats: 0,
+		codecDetected:    !config.CodecNegotiation && transport != TransportWebSocket,
+		clock:            clock,
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.lastActivity.Store(now.UnixNano())
+	c.lastReceived.Store(now.UnixNano())
+	if config.SendQueueSize > 0 {
+		c.sendQueue = make(chan []byte, config.SendQueueSize)
 	}
 
 	// 设置连接超时
@@ -81,9 +133,25 @@ func NewConnection(conn net.Conn, config types.ConnectionConfig, codec Codec, lo
 
 // Start 启动连接
 func (c *Connection) Start() {
+	// 连接加入管理器后其他协程（如广播和排空通知）可能同时发送消息
+	c.mu.Lock()
 	c.setState(types.StateConnected)
+	c.mu.Unlock()
 	c.Logger.Info("TCP连接已建立", "conn_id", c.ID, "remote_addr", c.Info.RemoteAddr)
 
+	// 启动写协程
+	if c.sendQueue != nil {
+		go c.writeLoop()
+	}
+
+	// 由连接管理器的时间轮驱动超时检测时不启动独立的检测协程
+	c.mu.RLock()
+	managed := c.timers != nil
+	c.mu.RUnlock()
+	if managed {
+		return
+	}
+
 	// 启动心跳检测
 	if c.Config.Heartbeat.Enabled {
 		go c.heartbeatLoop()
@@ -98,17 +166,16 @@ func (c *Connection) Start() {
 // Close 关闭连接
 func (c *Connection) Close() error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if c.State == types.StateClosed {
+		c.mu.Unlock()
 		return nil
 	}
 
 	c.setState(types.StateClosing)
 
-	// 停止心跳定时器
-	if c.heartbeatTimer != nil {
-		c.heartbeatTimer.Stop()
+	// 取消超时定时器
+	for _, t := range c.timers {
+		t.Stop()
 	}
 
 	// 关闭底层连接
@@ -116,18 +183,36 @@ func (c *Connection) Close() error {
 		c.Logger.Error("关闭TCP连接失败", "conn_id", c.ID, "error", err)
 	}
 
-	// 发送关闭信号
-	select {
-	case c.closeChan <- struct{}{}:
-	default:
-	}
+	// 发送关闭信号（心跳、空闲检测和写协程都需要收到），并取消进行中的请求
+	close(c.closeChan)
+	c.cancel()
 
 	c.setState(types.StateClosed)
 	c.Logger.Info("TCP连接已关闭", "conn_id", c.ID)
 
+	hooks := c.closeHooks
+	c.closeHooks = nil
+	c.mu.Unlock()
+
+	// 在锁外执行关闭回调，回调中可以继续访问连接
+	for _, fn := range hooks {
+		fn()
+	}
 	return nil
 }
 
+// OnClose 注册连接关闭后执行的回调，连接已关闭时立即执行
+func (c *Connection) OnClose(fn func()) {
+	c.mu.Lock()
+	if c.State != types.StateClosed {
+		c.closeHooks = append(c.closeHooks, fn)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+	fn()
+}
+
 // SendMessage 发送消息
 func (c *Connection) SendMessage(msg *types.Message) error {
 	c.mu.RLock()
@@ -135,15 +220,78 @@ func (c *Connection) SendMessage(msg *types.Message) error {
 		c.mu.RUnlock()
 		return ErrConnectionClosed
 	}
+	codec := c.Codec
+	cipher := c.cipher
+	compression := c.compression
+	version := c.Info.ProtocolVersion
 	c.mu.RUnlock()
 
-	// 编码消息
-	data, err := c.Codec.Encode(msg)
+	// 出站中间件可以改写或丢弃消息
+	if c.outbound != nil {
+		filtered, err := c.outbound(c, msg)
+		if err != nil || filtered == nil {
+			return err
+		}
+		msg = filtered
+	}
+
+	// 服务端主动创建的消息使用客户端的协议版本，保证v2客户端收到扩展字段
+	if msg.Header.Version < version {
+		upgraded := &types.Message{Header: msg.Header, Body: msg.Body}
+		upgraded.Header.Version = version
+		msg = upgraded
+	}
+
+	// 转换消息体格式（如Protobuf）
+	if pc, ok := codec.(PayloadCodec); ok {
+		encoded, err := pc.EncodePayload(msg)
+		if err != nil {
+			c.Logger.Error("转换消息体失败", "conn_id", c.ID, "error", err)
+			return err
+		}
+		msg = encoded
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	// 加密消息体（先压缩后加密）
+	if cipher != nil {
+		sealed, err := SealMessage(cipher, compression, msg)
+		if err != nil {
+			c.Logger.Error("加密消息失败", "conn_id", c.ID, "error", err)
+			return err
+		}
+		msg = sealed
+	}
+
+	// 编码消息：异步写入时使用池化缓冲区，由写协程发送后归还；同步写入时复用连接的编码缓冲区
+	var dst []byte
+	if _, ok := codec.(MessageEncoder); ok {
+		if c.sendQueue != nil {
+			dst = getBuffer(frameSizeHint(msg))
+		} else {
+			dst = c.writeBuffer[:0]
+		}
+	}
+	data, err := encodeFrame(codec, dst, msg)
 	if err != nil {
 		c.Logger.Error("编码消息失败", "conn_id", c.ID, "error", err)
 		return err
 	}
 
+	// 异步写入：加入发送队列后立即返回，写入错误由写协程处理
+	if c.sendQueue != nil {
+		if err := c.enqueue(data); err != nil {
+			putBuffer(data)
+			return err
+		}
+		return nil
+	}
+	if cap(data) <= maxWriteBufferSize {
+		c.writeBuffer = data[:0]
+	}
+
 	// 发送数据
 	if c.Config.WriteTimeout > 0 {
 		c.Conn.SetWriteDeadline(time.Now().Add(c.Config.WriteTimeout))
@@ -157,9 +305,8 @@ func (c *Connection) SendMessage(msg *types.Message) error {
 		} else {
 			c.Logger.Error("发送消息失败", "conn_id", c.ID, "error", err)
 		}
+		// 部分写入的字节计入发送字节数，发送失败的消息不计数也不刷新活动时间
 		atomic.AddInt64(&c.Info.BytesSent, int64(n))
-		atomic.AddInt64(&c.Info.MessagesSent, 1)
-		c.updateActivity()
 		return err
 	}
 
@@ -171,6 +318,111 @@ func (c *Connection) SendMessage(msg *types.Message) error {
 	return nil
 }
 
+// enqueue 将已编码的帧加入发送队列，队列已满时按慢消费者策略处理
+// 调用方需持有writeMu，保证入队顺序与加密计数器顺序一致
+func (c *Connection) enqueue(frame []byte) error {
+	select {
+	case c.sendQueue <- frame:
+		return nil
+	case <-c.closeChan:
+		return ErrConnectionClosed
+	default:
+	}
+
+	if c.Config.SlowConsumerPolicy == SlowConsumerDropOldest {
+		// 丢弃最旧的帧后重试，写协程可能同时取走帧，因此丢弃失败也可以继续
+		select {
+		case dropped := <-c.sendQueue:
+			putBuffer(dropped)
+			atomic.AddInt64(&c.Info.MessagesDropped, 1)
+		default:
+		}
+		select {
+		case c.sendQueue <- frame:
+			return nil
+		default:
+			atomic.AddInt64(&c.Info.MessagesDropped, 1)
+			return ErrSlowConsumer
+		}
+	}
+
+	c.Logger.Warn("发送队列已满，断开慢消费者连接", "conn_id", c.ID, "queue_size", cap(c.sendQueue))
+	atomic.AddInt64(&c.Info.MessagesDropped, 1)
+	go c.Close()
+	return ErrSlowConsumer
+}
+
+// writeLoop 写协程，将队列中的帧合并为一次向量写入
+func (c *Connection) writeLoop() {
+	batchSize := c.Config.WriteBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultWriteBatchSize
+	}
+	batch := make(net.Buffers, 0, batchSize)
+	// WriteTo会将写出的元素置为nil并前移切片，向量写入使用scratch中的副本，batch保留帧以便归还
+	scratch := make(net.Buffers, 0, batchSize)
+
+	for {
+		select {
+		case frame := <-c.sendQueue:
+			batch = append(batch[:0], frame)
+		case <-c.closeChan:
+			return
+		}
+
+		// 合并队列中已有的帧
+	drain:
+		for len(batch) < batchSize {
+			select {
+			case frame := <-c.sendQueue:
+				batch = append(batch, frame)
+			default:
+				break drain
+			}
+		}
+
+		frames := len(batch)
+		if c.Config.WriteTimeout > 0 {
+			c.Conn.SetWriteDeadline(time.Now().Add(c.Config.WriteTimeout))
+		}
+		pending := append(scratch[:0], batch...)
+		n, err := pending.WriteTo(c.Conn)
+		atomic.AddInt64(&c.Info.BytesSent, n)
+		if err != nil {
+			if isConnectionClosedError(err) {
+				c.Logger.Debug("连接已关闭，发送消息失败", "conn_id", c.ID, "error", err)
+			} else {
+				c.Logger.Error("发送消息失败", "conn_id", c.ID, "error", err, "frames", frames)
+			}
+			c.Close()
+			return
+		}
+
+		atomic.AddInt64(&c.Info.MessagesSent, int64(frames))
+		c.updateActivity()
+		c.Logger.Debug("批量发送消息成功", "conn_id", c.ID, "frames", frames, "size", n)
+
+		// 归还已发送的帧
+		for i := range batch {
+			putBuffer(batch[i])
+			batch[i] = nil
+		}
+	}
+}
+
+// encodeFrame 编码消息，编解码器支持追加编码时输出到dst
+func encodeFrame(codec Codec, dst []byte, msg *types.Message) ([]byte, error) {
+	if encoder, ok := codec.(MessageEncoder); ok {
+		return encoder.AppendEncode(dst, msg)
+	}
+	return codec.Encode(msg)
+}
+
+// frameSizeHint 估算消息编码后的长度，用于选择发送缓冲区大小
+func frameSizeHint(msg *types.Message) int {
+	return binaryHeaderLenV2 + len(msg.Header.GameID) + len(msg.Header.UserID) + len(msg.Header.TraceID) + len(msg.Body) + 32
+}
+
 // ReadMessage 读取消息，支持TCP粘包分包处理
 func (c *Connection) ReadMessage() (*types.Message, error) {
 	c.mu.RLock()
@@ -182,21 +434,9 @@ func (c *Connection) ReadMessage() (*types.Message, error) {
 
 	// 循环读取直到解析出完整消息
 	for {
-		// 如果缓冲区有足够数据，先尝试解析
-		if len(c.readBuffer) >= 28 { // 最小消息长度
-			msg, _, err := c.tryDecodeMessage()
-			if err == nil {
-				// 成功解析消息
-				atomic.AddInt64(&c.Info.MessagesReceived, 1)
-				c.updateActivity()
-				return msg, nil
-			}
-			// 如果不是数据不足的错误，返回错误
-			if !isInsufficientDataError(err) {
-				c.Logger.Error("解码消息失败", "conn_id", c.ID, "error", err, "buffer_size", len(c.readBuffer))
-				return nil, err
-			}
-			// 数据不足或校验和错误，继续读取更多数据
+		msg, err := c.NextMessage()
+		if err != nil || msg != nil {
+			return msg, err
 		}
 
 		// 设置读取超时
@@ -205,61 +445,425 @@ func (c *Connection) ReadMessage() (*types.Message, error) {
 		}
 
 		// 读取更多数据
-		buffer := make([]byte, c.Config.BufferSize)
-		n, err := c.Conn.Read(buffer)
-		if err != nil {
+		if _, err := c.Fill(c.Conn.Read); err != nil {
 			return nil, err
 		}
+	}
+}
+
+// NextMessage 从已读取的数据中解析下一条完整消息，数据不足时返回nil
+// 事件循环引擎在套接字可读时先调用Fill再循环调用NextMessage，两者必须在同一个协程中调用
+func (c *Connection) NextMessage() (*types.Message, error) {
+	// 新连接先根据首部字节选择编解码器
+	if !c.codecDetected {
+		if err := c.detectCodec(); err != nil {
+			c.Logger.Warn("识别编解码器失败", "conn_id", c.ID, "error", err)
+			return nil, err
+		}
+	}
 
-		// 累积到缓冲区
-		c.readBuffer = append(c.readBuffer, buffer[:n]...)
+	// 如果缓冲区有数据，先尝试解析（数据不足时编解码器返回数据不足错误）
+	if c.codecDetected && c.rend > c.rstart {
+		msg, _, err := c.tryDecodeMessage()
+		if err == nil {
+			if _, err = c.openMessage(msg); err != nil {
+				c.Logger.Warn("解密消息失败", "conn_id", c.ID, "error", err)
+				msg.Release()
+				return nil, err
+			}
+			if err := c.decodePayload(msg); err != nil {
+				c.Logger.Warn("转换消息体失败", "conn_id", c.ID, "error", err)
+				msg.Release()
+				return nil, err
+			}
+			c.observeHeader(&msg.Header)
+
+			// 成功解析消息
+			atomic.AddInt64(&c.Info.MessagesReceived, 1)
+			c.updateActivity()
+			c.lastReceived.Store(c.clock.Now().UnixNano())
+			return msg, nil
+		}
+		// 帧被拒绝时无法可靠定位下一帧的起始位置，丢弃缓冲区，由调用方关闭连接
+		if reason := FrameRejectReason(err); reason != "" {
+			c.Logger.Warn("拒绝消息帧", "conn_id", c.ID, "reason", reason, "error", err, "buffer_size", c.rend-c.rstart)
+			c.releaseReadBuffer()
+			return nil, err
+		}
+		// 如果不是数据不足的错误，返回错误
+		if !IsInsufficientDataError(err) {
+			c.Logger.Error("解码消息失败", "conn_id", c.ID, "error", err, "buffer_size", c.rend-c.rstart)
+			return nil, err
+		}
+		// 数据不足，需要读取更多数据
+	}
+
+	// 编解码器无法从长度字段判断大小时（如尚未识别编解码器），限制缓冲区总长度
+	if limit := c.frameLimits().maxBuffered(); limit > 0 && c.rend-c.rstart > limit+len(CodecMagic)+1 {
+		err := fmt.Errorf("%w: 缓冲区%d字节", ErrFrameTooLarge, c.rend-c.rstart)
+		c.Logger.Warn("拒绝消息帧", "conn_id", c.ID, "reason", RejectFrameTooLarge, "error", err)
+		c.releaseReadBuffer()
+		return nil, err
+	}
+	return nil, nil
+}
+
+// Fill 调用read读取一次数据到读缓冲区的空闲部分
+// read可以是底层连接的Read，也可以是事件循环引擎中的非阻塞系统调用
+func (c *Connection) Fill(read func(p []byte) (int, error)) (int, error) {
+	n, err := read(c.readSpace())
+	if n > 0 {
+		c.rend += n
 		atomic.AddInt64(&c.Info.BytesReceived, int64(n))
 	}
+	if err != nil {
+		// 连接已断开时归还读缓冲区，读取超时等错误保留已读取的数据
+		if errors.Is(err, io.EOF) || isConnectionClosedError(err) {
+			c.releaseReadBuffer()
+		}
+		return n, err
+	}
+	return n, nil
+}
+
+// readSpace 返回读缓冲区的空闲部分，必要时整理或更换缓冲区
+// 缓冲区仍被未释放的消息引用时不能覆盖已解析的数据，此时将未解析数据复制到新缓冲区
+func (c *Connection) readSpace() []byte {
+	size := c.Config.BufferSize
+	if size <= 0 {
+		size = defaultReadBufferSize
+	}
+	if c.rbuf == nil {
+		c.rbuf = newReadBuffer(size)
+		c.rstart, c.rend = 0, 0
+	}
+
+	rb := c.rbuf
+	if c.rend < len(rb.buf) {
+		return rb.buf[c.rend:]
+	}
+
+	pending := c.rend - c.rstart
+	switch {
+	case pending <= len(rb.buf)/2 && rb.exclusive():
+		// 未解析数据较少，在原缓冲区内前移
+		copy(rb.buf, rb.buf[c.rstart:c.rend])
+	default:
+		// 未解析数据超过一半时扩容，缓冲区被借用时更换同等大小的缓冲区
+		capacity := len(rb.buf)
+		if pending > capacity/2 {
+			capacity *= 2
+		}
+		next := newReadBuffer(capacity)
+		copy(next.buf, rb.buf[c.rstart:c.rend])
+		rb.unref()
+		c.rbuf = next
+	}
+	c.rstart, c.rend = 0, pending
+	return c.rbuf.buf[c.rend:]
+}
+
+// consumeRead 从读缓冲区移除已解析的字节
+func (c *Connection) consumeRead(n int) {
+	c.rstart += n
+	if c.rstart == c.rend && c.rbuf.exclusive() {
+		c.rstart, c.rend = 0, 0
+	}
+}
+
+// releaseReadBuffer 丢弃未解析的数据并释放连接对读缓冲区的引用
+func (c *Connection) releaseReadBuffer() {
+	if c.rbuf != nil {
+		c.rbuf.unref()
+		c.rbuf = nil
+	}
+	c.rstart, c.rend = 0, 0
+}
+
+// detectCodec 根据连接首部字节选择编解码器，无法识别时保留默认编解码器
+func (c *Connection) detectCodec() error {
+	var buffered []byte
+	if c.rbuf != nil {
+		buffered = c.rbuf.buf[c.rstart:c.rend]
+	}
+	codec, name, consumed, ready, err := DefaultCodecRegistry.DetectCodec(buffered)
+	if err != nil {
+		return err
+	}
+	if !ready {
+		return nil
+	}
+
+	c.codecDetected = true
+	c.rstart += consumed
+	if codec == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.Codec = withFrameLimits(codec, c.Config)
+	c.Info.Codec = name
+	c.mu.Unlock()
+
+	c.Logger.Debug("连接编解码器已识别", "conn_id", c.ID, "codec", name, "preamble", consumed > 0)
+	return nil
+}
+
+// SwitchCodec 切换连接使用的编解码器（握手时由客户端指定），保留已协商的压缩选项
+func (c *Connection) SwitchCodec(name string) error {
+	codec, ok := DefaultCodecRegistry.NewByName(name)
+	if !ok {
+		return fmt.Errorf("不支持的编解码器: %s", name)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	codec = withFrameLimits(codec, c.Config)
+	if cc, ok := codec.(CompressibleCodec); ok && c.compression != nil {
+		codec = cc.WithCompression(c.compression)
+	}
+	c.Codec = codec
+	c.Info.Codec = name
+	c.Logger.Debug("连接已切换编解码器", "conn_id", c.ID, "codec", name)
+	return nil
+}
+
+// frameLimits 获取连接的帧大小限制
+func (c *Connection) frameLimits() FrameLimits {
+	return FrameLimits{
+		MaxHeaderSize: c.Config.MaxHeaderSize,
+		MaxBodySize:   c.Config.MaxBodySize,
+	}
+}
+
+// withFrameLimits 为编解码器设置连接配置中的帧大小限制
+func withFrameLimits(codec Codec, config types.ConnectionConfig) Codec {
+	lc, ok := codec.(LimitedCodec)
+	if !ok || (config.MaxHeaderSize <= 0 && config.MaxBodySize <= 0) {
+		return codec
+	}
+	return lc.WithLimits(FrameLimits{
+		MaxHeaderSize: config.MaxHeaderSize,
+		MaxBodySize:   config.MaxBodySize,
+	})
 }
 
 // tryDecodeMessage 尝试从缓冲区解码消息
+// 消息借用读缓冲区并持有一个引用，调用方处理完成后需要调用Release归还
 func (c *Connection) tryDecodeMessage() (*types.Message, int, error) {
-	if len(c.readBuffer) == 0 {
+	if c.rbuf == nil || c.rend == c.rstart {
 		return nil, 0, fmt.Errorf("缓冲区为空")
 	}
+	data := c.rbuf.buf[c.rstart:c.rend]
 
-	// 尝试解码消息
-	msg, consumed, err := c.Codec.Decode(c.readBuffer)
-	if err != nil {
+	c.mu.RLock()
+	codec := c.Codec
+	c.mu.RUnlock()
+
+	// 尝试解码消息，支持时复用池化的消息对象
+	var msg *types.Message
+	var consumed int
+	var err error
+	if decoder, ok := codec.(MessageDecoder); ok {
+		msg = getMessage()
+		msg.Header.GameID, msg.Header.UserID = c.lastGameID, c.lastUserID
+		if consumed, err = decoder.DecodeInto(msg, data); err != nil {
+			putMessage(msg)
+			return nil, consumed, err
+		}
+		c.lastGameID, c.lastUserID = msg.Header.GameID, msg.Header.UserID
+	} else if msg, consumed, err = codec.Decode(data); err != nil {
 		return nil, consumed, err
 	}
 
+	// 消息体可能引用读缓冲区，消息释放前缓冲区不能被覆盖或归还
+	c.rbuf.retain()
+	msg.SetRelease(c.rbuf.release)
+
 	// 从缓冲区移除已处理的字节
-	if consumed < len(c.readBuffer) {
-		c.readBuffer = c.readBuffer[consumed:]
-	} else {
-		c.readBuffer = nil
-	}
+	c.consumeRead(consumed)
 
 	return msg, consumed, nil
 }
 
-// isInsufficientDataError 检查是否是数据不足的错误
-func isInsufficientDataError(err error) bool {
-	if err == nil {
+// openMessage 解密收到的消息，已建立加密会话的连接拒绝明文消息
+func (c *Connection) openMessage(msg *types.Message) (*types.Message, error) {
+	c.mu.RLock()
+	cipher := c.cipher
+	c.mu.RUnlock()
+
+	return OpenMessage(cipher, msg, c.frameLimits())
+}
+
+// OpenMessage 解密并解压消息体，cipher为nil时拒绝加密消息，不为nil时拒绝明文消息
+// limits限制解压后的消息体长度
+func OpenMessage(cipher *SessionCipher, msg *types.Message, limits FrameLimits) (*types.Message, error) {
+	encrypted := msg.Header.Flags&types.FlagEncrypted != 0
+	if cipher == nil {
+		if encrypted {
+			return nil, ErrNotEncrypted
+		}
+		return msg, nil
+	}
+	if !encrypted {
+		return nil, ErrNotEncrypted
+	}
+
+	body, err := cipher.Open(&msg.Header, msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	msg.Header.Flags &^= types.FlagEncrypted
+
+	// 解密后再解压
+	body, err = decompressBody(&msg.Header, body, limits)
+	if err != nil {
+		return nil, err
+	}
+	msg.Body = body
+	msg.Header.BodyLength = uint32(len(body))
+	return msg, nil
+}
+
+// observeHeader 记录客户端使用的最高协议版本和客户端构建版本
+func (c *Connection) observeHeader(header *types.MessageHeader) {
+	c.mu.RLock()
+	changed := header.Version > c.Info.ProtocolVersion ||
+		(header.ClientBuild != "" && header.ClientBuild != c.Info.ClientBuild)
+	c.mu.RUnlock()
+	if !changed {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if header.Version > c.Info.ProtocolVersion {
+		c.Info.ProtocolVersion = header.Version
+	}
+	if header.ClientBuild != "" {
+		c.Info.ClientBuild = header.ClientBuild
+	}
+}
+
+// decodePayload 将消息体转换为业务处理器使用的格式
+func (c *Connection) decodePayload(msg *types.Message) error {
+	c.mu.RLock()
+	codec := c.Codec
+	c.mu.RUnlock()
+
+	if pc, ok := codec.(PayloadCodec); ok {
+		return pc.DecodePayload(msg)
+	}
+	return nil
+}
+
+// SealMessage 压缩并加密消息体，返回新的消息，不修改原消息（广播时原消息会被多个连接共享）
+func SealMessage(cipher *SessionCipher, compression *CompressionOptions, msg *types.Message) (*types.Message, error) {
+	body, flags, err := compressBody(compression, msg.Header.Flags, msg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := &types.Message{Header: msg.Header}
+	sealed.Header.Flags = flags | types.FlagEncrypted
+	// 时间戳计入附加认证数据，编码时不再设置
+	if sealed.Header.Timestamp == 0 {
+		sealed.Header.Timestamp = time.Now().Unix()
+	}
+	sealed.Body = cipher.Seal(&sealed.Header, body)
+	sealed.Header.BodyLength = uint32(len(sealed.Body))
+	return sealed, nil
+}
+
+// IsInsufficientDataError 检查是否是数据不足的错误
+func IsInsufficientDataError(err error) bool {
+	return errors.Is(err, ErrInsufficientData)
+}
+
+// EnableCompression 启用发送方向的消息体压缩
+func (c *Connection) EnableCompression(compressor Compressor, threshold int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	codec, ok := c.Codec.(CompressibleCodec)
+	if !ok {
 		return false
 	}
-	errStr := err.Error()
-	return strings.Contains(errStr, "数据长度不足") ||
-		strings.Contains(errStr, "insufficient data") ||
-		strings.Contains(errStr, "无法解析")
+
+	c.compression = &CompressionOptions{
+		Compressor: compressor,
+		Threshold:  threshold,
+	}
+	c.Codec = codec.WithCompression(c.compression)
+	c.Logger.Debug("连接已启用压缩", "conn_id", c.ID, "algorithm", compressor.Name(), "threshold", threshold)
+	return true
 }
 
-// Authenticate 认证连接
-func (c *Connection) Authenticate(gameID, userID string) {
+// EnableEncryption 启用会话加密，之后收发的所有消息体都必须加密
+func (c *Connection) EnableEncryption(cipher *SessionCipher) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.cipher = cipher
+	c.Logger.Debug("连接已启用会话加密", "conn_id", c.ID)
+}
+
+// IsEncrypted 检查是否已建立加密会话
+func (c *Connection) IsEncrypted() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cipher != nil
+}
+
+// Authenticate 认证连接
+func (c *Connection) Authenticate(gameID, userID string) {
+	c.mu.Lock()
 	c.Info.GameID = gameID
 	c.Info.UserID = userID
 	c.setState(types.StateAuthenticated)
+	hook := c.authHook
+	c.mu.Unlock()
 
 	c.Logger.Info("连接已认证", "conn_id", c.ID, "game_id", gameID, "user_id", userID)
+	if hook != nil {
+		hook(c)
+	}
+}
+
+// Identity 返回连接认证的游戏ID和用户ID，未认证时为空
+func (c *Connection) Identity() (gameID, userID string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Info.GameID, c.Info.UserID
+}
+
+// BindGame 将连接绑定到游戏，在连接开始读取消息前调用
+func (c *Connection) BindGame(gameID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.boundGame = gameID
+}
+
+// Context 返回连接上下文，连接关闭时取消，处理该连接的请求应基于它派生上下文
+func (c *Connection) Context() context.Context {
+	return c.ctx
+}
+
+// ClientIP 返回客户端IP，经过PROXY协议或可信反向代理接入时为客户端的真实IP
+func (c *Connection) ClientIP() string {
+	host, _, err := net.SplitHostPort(c.Info.RemoteAddr)
+	if err != nil {
+		return c.Info.RemoteAddr
+	}
+	return host
+}
+
+// BoundGame 返回连接绑定的游戏ID，通过公共端口接入时为空
+func (c *Connection) BoundGame() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.boundGame
 }
 
 // IsAuthenticated 检查是否已认证
@@ -274,7 +878,7 @@ func (c *Connection) UpdateHeartbeat() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.lastHeartbeat = time.Now()
+	c.lastHeartbeat = c.clock.Now()
 	atomic.StoreInt64(&c.missedHeartbeats, 0)
 	c.updateActivity()
 }
@@ -284,11 +888,32 @@ func (c *Connection) GetStats() types.ConnectionInfo {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	info := c.Info
-	info.BytesReceived = atomic.LoadInt64(&c.Info.BytesReceived)
-	info.BytesSent = atomic.LoadInt64(&c.Info.BytesSent)
-	info.MessagesReceived = atomic.LoadInt64(&c.Info.MessagesReceived)
-	info.MessagesSent = atomic.LoadInt64(&c.Info.MessagesSent)
+	// 计数器由读写协程原子更新，不能直接复制整个结构体
+	info := types.ConnectionInfo{
+		ID:               c.Info.ID,
+		RemoteAddr:       c.Info.RemoteAddr,
+		LocalAddr:        c.Info.LocalAddr,
+		State:            c.Info.State,
+		ConnectedAt:      c.Info.ConnectedAt,
+		LastActivity:     time.Unix(0, c.lastActivity.Load()),
+		GameID:           c.Info.GameID,
+		UserID:           c.Info.UserID,
+		Codec:            c.Info.Codec,
+		Transport:        c.Info.Transport,
+		ProtocolVersion:  c.Info.ProtocolVersion,
+		ClientBuild:      c.Info.ClientBuild,
+		BytesReceived:    atomic.LoadInt64(&c.Info.BytesReceived),
+		BytesSent:        atomic.LoadInt64(&c.Info.BytesSent),
+		MessagesReceived: atomic.LoadInt64(&c.Info.MessagesReceived),
+		MessagesSent:     atomic.LoadInt64(&c.Info.MessagesSent),
+		MessagesDropped:  atomic.LoadInt64(&c.Info.MessagesDropped),
+		RTT:              c.Info.RTT,
+		RTTJitter:        c.Info.RTTJitter,
+		MissedPings:      atomic.LoadInt64(&c.missedHeartbeats),
+	}
+	if c.sendQueue != nil {
+		info.SendQueueLength = len(c.sendQueue)
+	}
 
 	return info
 }
@@ -301,45 +926,120 @@ func (c *Connection) setState(state types.ConnectionState) {
 }
 
 func (c *Connection) updateActivity() {
-	now := time.Now()
-	c.Info.LastActivity = now
+	c.lastActivity.Store(c.clock.Now().UnixNano())
 }
 
 func (c *Connection) heartbeatLoop() {
 	ticker := time.NewTicker(c.Config.Heartbeat.Interval)
 	defer ticker.Stop()
 
+	var pingTimeout <-chan time.Time
 	for {
 		select {
 		case <-ticker.C:
-			c.checkHeartbeat()
+		case <-pingTimeout:
 		case <-c.closeChan:
 			return
 		}
+		if c.checkHeartbeat() && c.Config.Heartbeat.Timeout > 0 {
+			pingTimeout = time.After(c.Config.Heartbeat.Timeout)
+		}
 	}
 }
 
-func (c *Connection) checkHeartbeat() {
+// checkHeartbeat 按心跳间隔和Ping超时时间调用：连接空闲超过心跳间隔时主动发送Ping，
+// 上一个Ping超时后期间没有收到任何数据计为一次丢失并立即重新发送Ping，连续丢失达到上限时关闭连接
+// 返回是否发送了新的Ping，调用方据此在Ping超时时间后再次检查
+func (c *Connection) checkHeartbeat() bool {
 	c.mu.Lock()
-	defer c.mu.Unlock()
 
 	if c.State != types.StateConnected && c.State != types.StateAuthenticated {
-		return
+		c.mu.Unlock()
+		return false
 	}
 
-	// 检查是否超时
-	if time.Since(c.lastHeartbeat) > c.Config.Heartbeat.Timeout {
-		atomic.AddInt64(&c.missedHeartbeats, 1)
+	now := c.clock.Now()
+	lastReceived := time.Unix(0, c.lastReceived.Load())
 
-		if atomic.LoadInt64(&c.missedHeartbeats) >= int64(c.Config.Heartbeat.MaxMissed) {
-			c.Logger.Warn("心跳超时，关闭连接", "conn_id", c.ID, "missed", atomic.LoadInt64(&c.missedHeartbeats))
-			go c.Close()
-			return
+	// 上一个Ping仍在等待回复
+	if !c.pingSentAt.IsZero() {
+		if now.Sub(c.pingSentAt) < c.Config.Heartbeat.Timeout {
+			c.mu.Unlock()
+			return false
+		}
+		// 等待期间收到其他消息说明连接仍然可用，只是Pong丢失或被延后
+		if lastReceived.Before(c.pingSentAt) {
+			missed := atomic.AddInt64(&c.missedHeartbeats, 1)
+			if missed >= int64(max(c.Config.Heartbeat.MaxMissed, 1)) {
+				c.mu.Unlock()
+				c.Logger.Warn("心跳超时，关闭连接", "conn_id", c.ID, "missed", missed)
+				go c.Close()
+				return false
+			}
 		}
+		c.pingSentAt = time.Time{}
 	}
 
-	// 重置missedHeartbeats计数（如果有活动）
+	// 最近收到过数据时不需要探测
+	if now.Sub(lastReceived) < c.Config.Heartbeat.Interval {
+		atomic.StoreInt64(&c.missedHeartbeats, 0)
+		c.mu.Unlock()
+		return false
+	}
+
+	c.pingSeq++
+	c.pingSentAt = now
+	seq := c.pingSeq
+	c.mu.Unlock()
+
+	c.sendPing(seq)
+	return true
+}
+
+// sendPing 发送服务端Ping，同步写入可能阻塞，放到单独的协程中执行
+func (c *Connection) sendPing(seq uint32) {
+	ping := CreatePingMessage(seq)
+	if c.sendQueue != nil {
+		if err := c.SendMessage(ping); err != nil {
+			c.Logger.Debug("发送Ping失败", "conn_id", c.ID, "seq", seq, "error", err)
+		}
+		return
+	}
+	go func() {
+		if err := c.SendMessage(ping); err != nil {
+			c.Logger.Debug("发送Ping失败", "conn_id", c.ID, "seq", seq, "error", err)
+		}
+	}()
+}
+
+// HandlePong 处理客户端对服务端Ping的回复，序列号与等待中的Ping不匹配时返回false
+// 往返时延按RFC 6298的方式平滑：srtt = 7/8·srtt + 1/8·sample，抖动 = 3/4·抖动 + 1/4·|srtt - sample|
+func (c *Connection) HandlePong(seq uint32) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pingSentAt.IsZero() || seq != c.pingSeq {
+		return false
+	}
+
+	now := c.clock.Now()
+	sample := now.Sub(c.pingSentAt)
+	c.pingSentAt = time.Time{}
+	c.lastHeartbeat = now
 	atomic.StoreInt64(&c.missedHeartbeats, 0)
+
+	if c.Info.RTT == 0 {
+		c.Info.RTT = sample
+		c.Info.RTTJitter = sample / 2
+		return true
+	}
+	diff := c.Info.RTT - sample
+	if diff < 0 {
+		diff = -diff
+	}
+	c.Info.RTTJitter = (3*c.Info.RTTJitter + diff) / 4
+	c.Info.RTT = (7*c.Info.RTT + sample) / 8
+	return true
 }
 
 func (c *Connection) idleCheckLoop() {
@@ -356,20 +1056,40 @@ func (c *Connection) idleCheckLoop() {
 	}
 }
 
-func (c *Connection) checkIdle() {
+// checkIdle 检查空闲超时，超时时关闭连接，否则返回距离超时的剩余时间
+func (c *Connection) checkIdle() time.Duration {
 	c.mu.RLock()
 	if c.State != types.StateConnected && c.State != types.StateAuthenticated {
 		c.mu.RUnlock()
-		return
+		return 0
 	}
 
-	if time.Since(c.Info.LastActivity) > c.Config.IdleTimeout {
-		c.mu.RUnlock()
+	remaining := c.Config.IdleTimeout - c.clock.Now().Sub(time.Unix(0, c.lastActivity.Load()))
+	c.mu.RUnlock()
+	if remaining < 0 {
 		c.Logger.Warn("连接空闲超时，关闭连接", "conn_id", c.ID)
 		go c.Close()
-		return
+		return 0
 	}
+	return remaining
+}
+
+// checkHandshake 检查握手超时，超时仍未认证时关闭连接
+func (c *Connection) checkHandshake() {
+	c.mu.RLock()
+	pending := c.State == types.StateConnected
 	c.mu.RUnlock()
+	if pending {
+		c.Logger.Warn("握手超时，关闭连接", "conn_id", c.ID, "timeout", c.Config.HandshakeTimeout)
+		go c.Close()
+	}
+}
+
+// isActive 检查连接是否处于可收发状态
+func (c *Connection) isActive() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.State == types.StateConnected || c.State == types.StateAuthenticated
 }
 
 // generateConnectionID 生成连接ID
@@ -377,7 +1097,30 @@ func generateConnectionID() string {
 	return fmt.Sprintf("conn_%d_%d", time.Now().Unix(), time.Now().UnixNano()%1000000)
 }
 
+// 慢消费者策略
+const (
+	SlowConsumerDisconnect = "disconnect"  // 发送队列已满时断开连接
+	SlowConsumerDropOldest = "drop_oldest" // 发送队列已满时丢弃最旧的帧
+)
+
+// 连接的传输方式
+const (
+	TransportTCP       = "tcp"       // TCP端口接入
+	TransportTLS       = "tls"       // 启用TLS的TCP端口接入
+	TransportWebSocket = "websocket" // WebSocket接入
+)
+
+// defaultWriteBatchSize 单次向量写入合并的最大帧数
+const defaultWriteBatchSize = 64
+
+// defaultReadBufferSize 未配置缓冲区大小时读缓冲区的初始大小
+const defaultReadBufferSize = 4096
+
+// maxWriteBufferSize 同步写入时保留的编码缓冲区最大容量，发送大消息后不长期占用内存
+const maxWriteBufferSize = 64 << 10
+
 // Errors
 var (
 	ErrConnectionClosed = errors.New("连接已关闭")
+	ErrSlowConsumer     = errors.New("发送队列已满")
 )
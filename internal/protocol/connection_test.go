@@ -0,0 +1,265 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+
+	"go.uber.org/zap"
+)
+
+func newTestLogger() logger.Logger {
+	return &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+}
+
+// newTestConnection 创建基于内存管道的服务端连接，返回连接和客户端一端
+func newTestConnection(t *testing.T, config types.ConnectionConfig) (*Connection, net.Conn) {
+	t.Helper()
+
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+
+	if config.BufferSize == 0 {
+		config.BufferSize = 4096
+	}
+	conn := NewConnection(server, config, NewBinaryCodec(), newTestLogger())
+	conn.setState(types.StateConnected)
+	return conn, client
+}
+
+func TestConnectionCodecNegotiation(t *testing.T) {
+	tests := []struct {
+		name      string
+		preamble  []byte
+		codec     Codec
+		wantCodec string
+	}{
+		{"legacy binary", nil, NewBinaryCodec(), CodecBinary},
+		{"legacy json", nil, NewJSONCodec(), CodecJSON},
+		{"preamble protobuf", CodecPreamble(CodecIDProtobuf), NewProtobufCodec(), CodecProtobuf},
+		{"preamble json", CodecPreamble(CodecIDJSON), NewJSONCodec(), CodecJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, client := newTestConnection(t, types.ConnectionConfig{Codec: CodecBinary, CodecNegotiation: true})
+
+			data, err := tt.codec.Encode(CreateHeartbeatMessage(9))
+			if err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			go func() {
+				client.Write(append(append([]byte(nil), tt.preamble...), data...))
+			}()
+
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("读取消息失败: %v", err)
+			}
+			if msg.Header.Type != types.MessageTypeHeartbeat || msg.Header.SequenceID != 9 {
+				t.Errorf("消息不一致: %+v", msg.Header)
+			}
+			if got := conn.GetStats().Codec; got != tt.wantCodec {
+				t.Errorf("编解码器 = %s, 期望 %s", got, tt.wantCodec)
+			}
+		})
+	}
+}
+
+func TestConnectionRejectsUnknownCodecID(t *testing.T) {
+	conn, client := newTestConnection(t, types.ConnectionConfig{Codec: CodecBinary, CodecNegotiation: true})
+
+	go client.Write(CodecPreamble(0xFF))
+
+	if _, err := conn.ReadMessage(); err == nil {
+		t.Error("未知编解码器ID应返回错误")
+	}
+}
+
+func TestConnectionRejectsOversizedFrame(t *testing.T) {
+	conn, client := newTestConnection(t, types.ConnectionConfig{Codec: CodecBinary, MaxHeaderSize: 256, MaxBodySize: 1024})
+
+	data, err := NewBinaryCodec().Encode(newTestMessage(bytes.Repeat([]byte("x"), 4096)))
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	// 只发送消息头，服务端不应等待消息体
+	go client.Write(data[:binaryHeaderLenV1+len("game1")+len("user1")])
+
+	_, err = conn.ReadMessage()
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("期望消息体过大错误，实际 %v", err)
+	}
+}
+
+func TestConnectionContextCancelledOnClose(t *testing.T) {
+	conn, _ := newTestConnection(t, types.ConnectionConfig{Codec: CodecBinary})
+
+	ctx := conn.Context()
+	if ctx.Err() != nil {
+		t.Fatal("连接未关闭时上下文不应取消")
+	}
+	conn.Close()
+	if ctx.Err() == nil {
+		t.Error("连接关闭后上下文应取消")
+	}
+}
+
+func TestConnectionSendQueue(t *testing.T) {
+	conn, client := newTestConnection(t, types.ConnectionConfig{Codec: CodecBinary, SendQueueSize: 16})
+	go conn.writeLoop()
+
+	for i := uint32(1); i <= 10; i++ {
+		if err := conn.SendMessage(CreateHeartbeatMessage(i)); err != nil {
+			t.Fatalf("发送消息失败: %v", err)
+		}
+	}
+
+	// 批量写出的帧保持发送顺序
+	codec := NewBinaryCodec()
+	var buffer []byte
+	for i := uint32(1); i <= 10; {
+		msg, consumed, err := codec.Decode(buffer)
+		if err == nil {
+			if msg.Header.SequenceID != i {
+				t.Fatalf("序列号 = %d, 期望 %d", msg.Header.SequenceID, i)
+			}
+			buffer = buffer[consumed:]
+			i++
+			continue
+		}
+		chunk := make([]byte, 1024)
+		n, err := client.Read(chunk)
+		if err != nil {
+			t.Fatalf("读取失败: %v", err)
+		}
+		buffer = append(buffer, chunk[:n]...)
+	}
+}
+
+func TestConnectionSendFailureNotCounted(t *testing.T) {
+	conn, client := newTestConnection(t, types.ConnectionConfig{Codec: CodecBinary})
+	client.Close()
+
+	before := conn.lastActivity.Load()
+	if err := conn.SendMessage(CreateHeartbeatMessage(1)); err == nil {
+		t.Fatal("对端关闭后发送应失败")
+	}
+	if stats := conn.GetStats(); stats.MessagesSent != 0 {
+		t.Errorf("发送失败的消息计数 = %d, 期望 0", stats.MessagesSent)
+	}
+	if conn.lastActivity.Load() != before {
+		t.Error("发送失败不应刷新活动时间")
+	}
+}
+
+func TestConnectionSlowConsumer(t *testing.T) {
+	tests := []struct {
+		policy     string
+		wantClosed bool
+	}{
+		{SlowConsumerDisconnect, true},
+		{SlowConsumerDropOldest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			// 客户端不读取数据，写协程阻塞在第一次写入上
+			conn, _ := newTestConnection(t, types.ConnectionConfig{Codec: CodecBinary, SendQueueSize: 2, SlowConsumerPolicy: tt.policy})
+			go conn.writeLoop()
+
+			var sendErr error
+			for i := uint32(1); i <= 10 && sendErr == nil; i++ {
+				sendErr = conn.SendMessage(CreateHeartbeatMessage(i))
+			}
+
+			stats := conn.GetStats()
+			if tt.wantClosed {
+				if !errors.Is(sendErr, ErrSlowConsumer) {
+					t.Errorf("期望发送队列已满错误，实际 %v", sendErr)
+				}
+			} else if sendErr != nil {
+				t.Errorf("丢弃最旧消息策略不应返回错误: %v", sendErr)
+			}
+			if stats.MessagesDropped == 0 {
+				t.Error("应记录丢弃的消息数")
+			}
+		})
+	}
+}
+
+func TestConnectionBorrowedBody(t *testing.T) {
+	// 缓冲区很小，读取后续消息时必须整理或更换缓冲区
+	conn, client := newTestConnection(t, types.ConnectionConfig{Codec: CodecBinary, BufferSize: 64})
+
+	codec := NewBinaryCodec()
+	go func() {
+		for i := 0; i < 20; i++ {
+			data, _ := codec.Encode(newTestMessage([]byte(fmt.Sprintf(`{"index":%02d}`, i))))
+			client.Write(data)
+		}
+	}()
+
+	// 第一条消息不释放，其消息体不能被后续读取覆盖
+	first, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("读取消息失败: %v", err)
+	}
+	for i := 1; i < 20; i++ {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("读取消息失败: %v", err)
+		}
+		if want := fmt.Sprintf(`{"index":%02d}`, i); string(msg.Body) != want {
+			t.Fatalf("消息体 = %s, 期望 %s", msg.Body, want)
+		}
+		msg.Release()
+	}
+	if string(first.Body) != `{"index":00}` {
+		t.Errorf("借用的消息体被覆盖: %s", first.Body)
+	}
+	first.Release()
+}
+
+func TestConnectionSplitFrame(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 1000)
+	for _, codec := range []Codec{NewJSONCodec(), NewBinaryCodec()} {
+		t.Run(fmt.Sprintf("%T", codec), func(t *testing.T) {
+			server, client := net.Pipe()
+			t.Cleanup(func() {
+				server.Close()
+				client.Close()
+			})
+			conn := NewConnection(server, types.ConnectionConfig{BufferSize: 4096}, codec, newTestLogger())
+			conn.setState(types.StateConnected)
+
+			// 消息体分两次到达，第一次读取时数据不足应继续等待
+			data, err := codec.Encode(newTestMessage(body))
+			if err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			go func() {
+				half := len(data) - len(body)/2
+				client.Write(data[:half])
+				client.Write(data[half:])
+			}()
+
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("读取分段到达的消息失败: %v", err)
+			}
+			if !bytes.Equal(msg.Body, body) {
+				t.Errorf("消息体长度 = %d, 期望 %d", len(msg.Body), len(body))
+			}
+			msg.Release()
+		})
+	}
+}
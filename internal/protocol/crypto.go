@@ -0,0 +1,298 @@
+package protocol
+
+import (
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"datamiddleware/internal/common/types"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// 会话加密流程：
+// 1. 客户端在握手请求中携带临时X25519公钥
+// 2. 服务端生成临时X25519密钥对，计算共享密钥，并用Ed25519身份密钥对握手记录签名
+// 3. 双方通过HKDF-SHA256派生两个方向的ChaCha20-Poly1305密钥
+// 4. 之后带FlagEncrypted标志的消息体格式为 [计数器(8)] [密文+认证标签]，计数器必须严格递增
+// 5. 除消息体长度和校验和之外的消息头（含标志和v2扩展字段）作为附加认证数据，篡改后解密失败
+
+// KeyExchangeX25519 密钥交换方案
+const KeyExchangeX25519 = "x25519-chacha20poly1305"
+
+const (
+	handshakeSignContext = "datamiddleware-handshake-v1"
+	sessionKeyInfo       = "datamiddleware-session-keys-v1"
+	counterSize          = 8
+)
+
+// Errors
+var (
+	ErrDecryptFailed    = errors.New("消息解密失败")
+	ErrReplayedMessage  = errors.New("检测到重放消息")
+	ErrNotEncrypted     = errors.New("连接未建立加密会话")
+	ErrHandshakeForged  = errors.New("握手签名验证失败")
+	ErrUnsupportedKeyEx = errors.New("不支持的密钥交换方案")
+)
+
+// SessionCipher 连接会话加密器，发送和接收方向使用独立的密钥和计数器
+type SessionCipher struct {
+	sendAEAD    cipher.AEAD
+	recvAEAD    cipher.AEAD
+	sendCounter uint64
+	recvCounter uint64
+	mu          sync.Mutex
+}
+
+// newSessionCipher 创建会话加密器
+func newSessionCipher(sendKey, recvKey []byte) (*SessionCipher, error) {
+	sendAEAD, err := chacha20poly1305.New(sendKey)
+	if err != nil {
+		return nil, err
+	}
+	recvAEAD, err := chacha20poly1305.New(recvKey)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionCipher{sendAEAD: sendAEAD, recvAEAD: recvAEAD}, nil
+}
+
+// Seal 加密消息体
+func (s *SessionCipher) Seal(header *types.MessageHeader, plaintext []byte) []byte {
+	s.mu.Lock()
+	s.sendCounter++
+	counter := s.sendCounter
+	s.mu.Unlock()
+
+	out := make([]byte, counterSize, counterSize+len(plaintext)+s.sendAEAD.Overhead())
+	binary.BigEndian.PutUint64(out, counter)
+	return s.sendAEAD.Seal(out, sessionNonce(counter), plaintext, sessionAAD(header))
+}
+
+// Open 解密消息体，拒绝被篡改或计数器未递增（重放）的消息
+func (s *SessionCipher) Open(header *types.MessageHeader, body []byte) ([]byte, error) {
+	if len(body) < counterSize+s.recvAEAD.Overhead() {
+		return nil, ErrDecryptFailed
+	}
+	counter := binary.BigEndian.Uint64(body[:counterSize])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if counter <= s.recvCounter {
+		return nil, ErrReplayedMessage
+	}
+
+	plaintext, err := s.recvAEAD.Open(nil, sessionNonce(counter), body[counterSize:], sessionAAD(header))
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+
+	s.recvCounter = counter
+	return plaintext, nil
+}
+
+// sessionNonce 根据计数器生成nonce
+func sessionNonce(counter uint64) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[chacha20poly1305.NonceSize-8:], counter)
+	return nonce
+}
+
+// sessionAAD 将消息头作为附加认证数据，防止消息头被篡改
+// 格式与二进制编码的消息头相同，但不包含加密后才确定的消息体长度和校验和；v1消息不携带扩展字段，同样不计入
+func sessionAAD(header *types.MessageHeader) []byte {
+	aad := make([]byte, 0, 20+len(header.GameID)+len(header.UserID)+maxFixedExtensionsSize+len(header.TraceID)+len(header.ClientBuild))
+	aad = append(aad, header.Version)
+	aad = binary.BigEndian.AppendUint16(aad, uint16(header.Type))
+	aad = append(aad, byte(header.Flags))
+	aad = binary.BigEndian.AppendUint32(aad, header.SequenceID)
+	aad = binary.BigEndian.AppendUint64(aad, uint64(header.Timestamp))
+	aad = binary.BigEndian.AppendUint16(aad, uint16(len(header.GameID)))
+	aad = binary.BigEndian.AppendUint16(aad, uint16(len(header.UserID)))
+	aad = append(aad, header.GameID...)
+	aad = append(aad, header.UserID...)
+	if header.Version >= types.ProtocolVersionV2 {
+		// 扩展字段过长时编码消息同样失败，这里不需要处理错误
+		aad, _ = appendHeaderExtensions(aad, header)
+	}
+	return aad
+}
+
+// deriveSessionKeys 从共享密钥派生客户端->服务端、服务端->客户端两个方向的密钥
+func deriveSessionKeys(shared, clientPub, serverPub []byte) (c2s, s2c []byte, err error) {
+	salt := make([]byte, 0, len(clientPub)+len(serverPub))
+	salt = append(salt, clientPub...)
+	salt = append(salt, serverPub...)
+
+	r := hkdf.New(sha256.New, shared, salt, []byte(sessionKeyInfo))
+	c2s = make([]byte, chacha20poly1305.KeySize)
+	s2c = make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(r, c2s); err != nil {
+		return nil, nil, err
+	}
+	if _, err := io.ReadFull(r, s2c); err != nil {
+		return nil, nil, err
+	}
+	return c2s, s2c, nil
+}
+
+// handshakeTranscript 生成需要签名的握手记录
+func handshakeTranscript(clientPub, serverPub []byte, gameID, userID string) []byte {
+	transcript := make([]byte, 0, len(handshakeSignContext)+len(clientPub)+len(serverPub)+len(gameID)+len(userID)+2)
+	transcript = append(transcript, handshakeSignContext...)
+	transcript = append(transcript, clientPub...)
+	transcript = append(transcript, serverPub...)
+	transcript = append(transcript, gameID...)
+	transcript = append(transcript, 0)
+	transcript = append(transcript, userID...)
+	transcript = append(transcript, 0)
+	return transcript
+}
+
+// ServerIdentity 服务端身份密钥，用于对握手记录签名
+type ServerIdentity struct {
+	key ed25519.PrivateKey
+}
+
+// NewServerIdentity 创建服务端身份，seed为base64编码的32字节Ed25519种子，为空时随机生成
+func NewServerIdentity(seed string) (*ServerIdentity, error) {
+	if seed == "" {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("生成身份密钥失败: %w", err)
+		}
+		return &ServerIdentity{key: key}, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil {
+		return nil, fmt.Errorf("身份密钥不是有效的base64: %w", err)
+	}
+	if len(raw) != ed25519.SeedSize {
+		return nil, fmt.Errorf("身份密钥长度应为%d字节，实际%d字节", ed25519.SeedSize, len(raw))
+	}
+	return &ServerIdentity{key: ed25519.NewKeyFromSeed(raw)}, nil
+}
+
+// PublicKey 获取base64编码的身份公钥，客户端应预置该公钥用于校验握手
+func (id *ServerIdentity) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(id.key.Public().(ed25519.PublicKey))
+}
+
+// KeyExchange 服务端处理密钥交换请求，返回握手响应中的密钥交换数据和会话加密器
+func (id *ServerIdentity) KeyExchange(req *types.KeyExchangeRequest, gameID, userID string) (*types.KeyExchangeResponse, *SessionCipher, error) {
+	if req.Scheme != KeyExchangeX25519 {
+		return nil, nil, ErrUnsupportedKeyEx
+	}
+
+	clientPubBytes, err := base64.StdEncoding.DecodeString(req.PublicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("客户端公钥格式错误: %w", err)
+	}
+	clientPub, err := ecdh.X25519().NewPublicKey(clientPubBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("客户端公钥无效: %w", err)
+	}
+
+	serverPriv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("生成临时密钥失败: %w", err)
+	}
+	shared, err := serverPriv.ECDH(clientPub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("计算共享密钥失败: %w", err)
+	}
+
+	serverPubBytes := serverPriv.PublicKey().Bytes()
+	c2s, s2c, err := deriveSessionKeys(shared, clientPubBytes, serverPubBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("派生会话密钥失败: %w", err)
+	}
+	sc, err := newSessionCipher(s2c, c2s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signature := ed25519.Sign(id.key, handshakeTranscript(clientPubBytes, serverPubBytes, gameID, userID))
+	return &types.KeyExchangeResponse{
+		Scheme:      KeyExchangeX25519,
+		PublicKey:   base64.StdEncoding.EncodeToString(serverPubBytes),
+		IdentityKey: id.PublicKey(),
+		Signature:   base64.StdEncoding.EncodeToString(signature),
+	}, sc, nil
+}
+
+// ClientKeyExchange 客户端密钥交换状态
+type ClientKeyExchange struct {
+	priv *ecdh.PrivateKey
+}
+
+// NewClientKeyExchange 创建客户端密钥交换
+func NewClientKeyExchange() (*ClientKeyExchange, error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成临时密钥失败: %w", err)
+	}
+	return &ClientKeyExchange{priv: priv}, nil
+}
+
+// Request 生成握手请求中的密钥交换数据
+func (k *ClientKeyExchange) Request() *types.KeyExchangeRequest {
+	return &types.KeyExchangeRequest{
+		Scheme:    KeyExchangeX25519,
+		PublicKey: base64.StdEncoding.EncodeToString(k.priv.PublicKey().Bytes()),
+	}
+}
+
+// Complete 校验服务端握手响应并创建会话加密器，trustedKey为预置的服务端身份公钥（base64）
+func (k *ClientKeyExchange) Complete(resp *types.KeyExchangeResponse, gameID, userID, trustedKey string) (*SessionCipher, error) {
+	if resp == nil || resp.Scheme != KeyExchangeX25519 {
+		return nil, ErrUnsupportedKeyEx
+	}
+	if trustedKey == "" || resp.IdentityKey != trustedKey {
+		return nil, ErrHandshakeForged
+	}
+
+	identity, err := base64.StdEncoding.DecodeString(resp.IdentityKey)
+	if err != nil || len(identity) != ed25519.PublicKeySize {
+		return nil, ErrHandshakeForged
+	}
+	serverPubBytes, err := base64.StdEncoding.DecodeString(resp.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("服务端公钥格式错误: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, ErrHandshakeForged
+	}
+
+	clientPubBytes := k.priv.PublicKey().Bytes()
+	if !ed25519.Verify(identity, handshakeTranscript(clientPubBytes, serverPubBytes, gameID, userID), signature) {
+		return nil, ErrHandshakeForged
+	}
+
+	serverPub, err := ecdh.X25519().NewPublicKey(serverPubBytes)
+	if err != nil {
+		return nil, fmt.Errorf("服务端公钥无效: %w", err)
+	}
+	shared, err := k.priv.ECDH(serverPub)
+	if err != nil {
+		return nil, fmt.Errorf("计算共享密钥失败: %w", err)
+	}
+
+	c2s, s2c, err := deriveSessionKeys(shared, clientPubBytes, serverPubBytes)
+	if err != nil {
+		return nil, fmt.Errorf("派生会话密钥失败: %w", err)
+	}
+	return newSessionCipher(c2s, s2c)
+}
@@ -0,0 +1,155 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"datamiddleware/internal/common/types"
+)
+
+// establishSession 完成一次握手，返回服务端和客户端的会话加密器
+func establishSession(t *testing.T) (server, client *SessionCipher) {
+	t.Helper()
+
+	identity, err := NewServerIdentity("")
+	if err != nil {
+		t.Fatalf("创建服务端身份失败: %v", err)
+	}
+	kx, err := NewClientKeyExchange()
+	if err != nil {
+		t.Fatalf("创建客户端密钥交换失败: %v", err)
+	}
+
+	resp, server, err := identity.KeyExchange(kx.Request(), "game1", "user1")
+	if err != nil {
+		t.Fatalf("服务端密钥交换失败: %v", err)
+	}
+	client, err = kx.Complete(resp, "game1", "user1", identity.PublicKey())
+	if err != nil {
+		t.Fatalf("客户端完成密钥交换失败: %v", err)
+	}
+	return server, client
+}
+
+func TestSessionCipherRoundTrip(t *testing.T) {
+	server, client := establishSession(t)
+	header := &types.MessageHeader{Type: types.MessageTypeItemOperation, SequenceID: 7}
+
+	for i := 0; i < 3; i++ {
+		plaintext := []byte(`{"operation":"create","name":"sword"}`)
+		sealed := client.Seal(header, plaintext)
+		opened, err := server.Open(header, sealed)
+		if err != nil {
+			t.Fatalf("第%d条消息解密失败: %v", i, err)
+		}
+		if !bytes.Equal(opened, plaintext) {
+			t.Fatalf("第%d条消息解密结果不一致", i)
+		}
+	}
+
+	// 反方向
+	reply := server.Seal(header, []byte("ok"))
+	if opened, err := client.Open(header, reply); err != nil || string(opened) != "ok" {
+		t.Fatalf("服务端消息解密失败: %v", err)
+	}
+}
+
+func TestSessionCipherRejectsTamperAndReplay(t *testing.T) {
+	server, client := establishSession(t)
+	header := &types.MessageHeader{Type: types.MessageTypeOrderOperation, SequenceID: 1}
+
+	sealed := client.Seal(header, []byte("pay"))
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 0xFF
+	if _, err := server.Open(header, tampered); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("篡改消息体应解密失败，实际: %v", err)
+	}
+
+	otherHeader := &types.MessageHeader{Type: types.MessageTypeItemOperation, SequenceID: 1}
+	if _, err := server.Open(otherHeader, sealed); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("篡改消息头应解密失败，实际: %v", err)
+	}
+
+	if _, err := server.Open(header, sealed); err != nil {
+		t.Fatalf("原始消息应解密成功: %v", err)
+	}
+	if _, err := server.Open(header, sealed); !errors.Is(err, ErrReplayedMessage) {
+		t.Errorf("重放消息应被拒绝，实际: %v", err)
+	}
+}
+
+func TestClientRejectsUntrustedIdentity(t *testing.T) {
+	identity, _ := NewServerIdentity("")
+	impostor, _ := NewServerIdentity("")
+	kx, _ := NewClientKeyExchange()
+
+	resp, _, err := impostor.KeyExchange(kx.Request(), "game1", "user1")
+	if err != nil {
+		t.Fatalf("服务端密钥交换失败: %v", err)
+	}
+	if _, err := kx.Complete(resp, "game1", "user1", identity.PublicKey()); !errors.Is(err, ErrHandshakeForged) {
+		t.Errorf("非预置身份应被拒绝，实际: %v", err)
+	}
+
+	// 握手记录中的用户被替换
+	resp, _, _ = identity.KeyExchange(kx.Request(), "game1", "user1")
+	if _, err := kx.Complete(resp, "game1", "user2", identity.PublicKey()); !errors.Is(err, ErrHandshakeForged) {
+		t.Errorf("握手记录不一致应被拒绝，实际: %v", err)
+	}
+}
+
+func TestSealedMessageRejectsHeaderTamper(t *testing.T) {
+	server, client := establishSession(t)
+	codec := NewBinaryCodec()
+
+	msg := &types.Message{
+		Header: types.MessageHeader{Version: types.ProtocolVersionV2, Type: types.MessageTypeItemOperation, SequenceID: 3, GameID: "game1", UserID: "user1", TraceID: "trace-1", Deadline: 1700000000000},
+		Body:   []byte(`{"operation":"use"}`),
+	}
+	sealed, err := SealMessage(client, nil, msg)
+	if err != nil {
+		t.Fatalf("加密消息失败: %v", err)
+	}
+	frame, err := codec.Encode(sealed)
+	if err != nil {
+		t.Fatalf("编码消息失败: %v", err)
+	}
+
+	// 修改帧中的一个字节后重新计算校验和，模拟中间人改写消息头
+	tamper := func(offset int, mask byte) *types.Message {
+		f := append([]byte(nil), frame...)
+		f[offset] ^= mask
+		binary.BigEndian.PutUint32(f[binaryChecksumOffset:], binaryChecksum(f))
+		decoded, _, err := codec.Decode(f)
+		if err != nil {
+			t.Fatalf("解码篡改后的帧失败: %v", err)
+		}
+		return decoded
+	}
+
+	traceOffset := binaryHeaderLenV2 + len("game1") + len("user1") + 3
+	tests := []struct {
+		name   string
+		offset int
+		mask   byte
+	}{
+		{"标志位", 3, byte(types.FlagCompressed)},
+		{"扩展字段", traceOffset, 0x01},
+	}
+	for _, tt := range tests {
+		if _, err := OpenMessage(server, tamper(tt.offset, tt.mask), FrameLimits{}); !errors.Is(err, ErrDecryptFailed) {
+			t.Errorf("篡改%s应解密失败，实际: %v", tt.name, err)
+		}
+	}
+
+	opened, err := OpenMessage(server, tamper(0, 0), FrameLimits{})
+	if err != nil {
+		t.Fatalf("原始消息应解密成功: %v", err)
+	}
+	if string(opened.Body) != `{"operation":"use"}` || opened.Header.TraceID != "trace-1" {
+		t.Errorf("解密结果 = %+v %s", opened.Header, opened.Body)
+	}
+}
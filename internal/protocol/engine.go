@@ -0,0 +1,104 @@
+package protocol
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+
+	"datamiddleware/internal/common/types"
+)
+
+// 连接引擎名称
+const (
+	EngineGoroutine = "goroutine" // 每个连接一个读协程
+	EngineEpoll     = "epoll"     // 少量事件循环协程等待套接字可读事件，仅支持Linux
+)
+
+// EventHandler 事件循环引擎的回调
+type EventHandler interface {
+	// HandleMessage 在工作协程中处理消息，同一连接的消息按接收顺序串行处理，返回后由引擎释放消息
+	HandleMessage(conn *Connection, msg *types.Message)
+	// HandleClose 连接读取失败或已被关闭时调用，每个连接只调用一次
+	HandleClose(conn *Connection, err error)
+}
+
+// EventEngineConfig 事件循环引擎配置
+type EventEngineConfig struct {
+	Loops           int // 事件循环协程数，0表示CPU核数
+	Workers         int // 处理消息的工作协程数，0表示CPU核数的8倍
+	WorkerQueueSize int // 每个工作协程的消息队列容量，队列满时事件循环等待
+}
+
+// withDefaults 补全未设置的配置项
+func (c EventEngineConfig) withDefaults() EventEngineConfig {
+	if c.Loops <= 0 {
+		c.Loops = runtime.NumCPU()
+	}
+	if c.Workers <= 0 {
+		c.Workers = runtime.NumCPU() * 8
+	}
+	if c.WorkerQueueSize <= 0 {
+		c.WorkerQueueSize = 256
+	}
+	return c
+}
+
+// workItem 交给工作协程的任务，msg为nil表示连接已断开
+type workItem struct {
+	conn *Connection
+	msg  *types.Message
+	err  error
+}
+
+// workerPool 消息处理协程池，每个连接固定分配到一个工作协程以保证消息顺序
+type workerPool struct {
+	queues  []chan workItem
+	handler EventHandler
+	wg      sync.WaitGroup
+}
+
+// newWorkerPool 创建并启动工作协程池
+func newWorkerPool(workers, queueSize int, handler EventHandler) *workerPool {
+	p := &workerPool{
+		queues:  make([]chan workItem, workers),
+		handler: handler,
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan workItem, queueSize)
+		p.wg.Add(1)
+		go p.run(p.queues[i])
+	}
+	return p
+}
+
+// run 工作协程，依次处理队列中的任务
+func (p *workerPool) run(queue chan workItem) {
+	defer p.wg.Done()
+	for item := range queue {
+		if item.msg == nil {
+			p.handler.HandleClose(item.conn, item.err)
+			continue
+		}
+		p.handler.HandleMessage(item.conn, item.msg)
+		item.msg.Release()
+	}
+}
+
+// dispatch 将任务加入指定工作协程的队列
+func (p *workerPool) dispatch(worker int, item workItem) {
+	p.queues[worker%len(p.queues)] <- item
+}
+
+// stop 处理完队列中剩余的任务后停止所有工作协程，调用前必须已停止分发
+func (p *workerPool) stop() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
+
+// Errors
+var (
+	ErrEngineUnsupported = errors.New("当前平台不支持事件循环引擎")
+	ErrEngineStopped     = errors.New("事件循环引擎已停止")
+)
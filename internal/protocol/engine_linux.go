@@ -0,0 +1,243 @@
+//go:build linux
+
+package protocol
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"datamiddleware/internal/infrastructure/logging"
+)
+
+// epollWaitTimeout 等待事件的超时时间（毫秒），用于及时响应停止信号
+const epollWaitTimeout = 100
+
+// EventEngine 基于epoll的连接引擎，少量事件循环协程读取就绪的套接字并解码消息，完整消息交给工作协程池处理
+type EventEngine struct {
+	config   EventEngineConfig
+	handler  EventHandler
+	loops    []*eventLoop
+	workers  *workerPool
+	next     atomic.Uint32 // 轮询分配事件循环和工作协程
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	logger   logger.Logger
+	stopped  atomic.Bool
+}
+
+// eventLoop 单个epoll实例及其负责的连接
+type eventLoop struct {
+	epfd  int
+	conns map[int]*pollEntry
+	mu    sync.Mutex
+}
+
+// pollEntry 注册到事件循环的连接
+type pollEntry struct {
+	conn   *Connection
+	raw    syscall.RawConn
+	fd     int
+	worker int
+	closed atomic.Bool
+}
+
+// NewEventEngine 创建并启动事件循环引擎
+func NewEventEngine(config EventEngineConfig, handler EventHandler, log logger.Logger) (*EventEngine, error) {
+	config = config.withDefaults()
+	e := &EventEngine{
+		config:   config,
+		handler:  handler,
+		stopChan: make(chan struct{}),
+		logger:   log,
+	}
+
+	for i := 0; i < config.Loops; i++ {
+		epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+		if err != nil {
+			for _, l := range e.loops {
+				syscall.Close(l.epfd)
+			}
+			return nil, fmt.Errorf("创建epoll实例失败: %w", err)
+		}
+		e.loops = append(e.loops, &eventLoop{epfd: epfd, conns: make(map[int]*pollEntry)})
+	}
+
+	e.workers = newWorkerPool(config.Workers, config.WorkerQueueSize, handler)
+	for _, l := range e.loops {
+		e.wg.Add(1)
+		go e.run(l)
+	}
+
+	log.Info("事件循环引擎已启动", "loops", config.Loops, "workers", config.Workers)
+	return e, nil
+}
+
+// Register 将连接注册到事件循环，之后由引擎读取消息，调用方不能再调用ReadMessage
+func (e *EventEngine) Register(conn *Connection) error {
+	if e.stopped.Load() {
+		return ErrEngineStopped
+	}
+	sc, ok := conn.Conn.(syscall.Conn)
+	if !ok {
+		return fmt.Errorf("%w: 连接类型%T不支持获取文件描述符", ErrEngineUnsupported, conn.Conn)
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("获取原始连接失败: %w", err)
+	}
+
+	n := e.next.Add(1)
+	l := e.loops[int(n)%len(e.loops)]
+	entry := &pollEntry{conn: conn, raw: raw, worker: int(n)}
+
+	// 在Control回调中操作文件描述符，保证期间不会被并发关闭
+	var ctlErr error
+	err = raw.Control(func(fd uintptr) {
+		entry.fd = int(fd)
+		l.mu.Lock()
+		l.conns[entry.fd] = entry
+		l.mu.Unlock()
+
+		event := syscall.EpollEvent{Events: syscall.EPOLLIN | syscall.EPOLLRDHUP, Fd: int32(fd)}
+		if ctlErr = syscall.EpollCtl(l.epfd, syscall.EPOLL_CTL_ADD, int(fd), &event); ctlErr != nil {
+			l.mu.Lock()
+			delete(l.conns, entry.fd)
+			l.mu.Unlock()
+		}
+	})
+	if err == nil {
+		err = ctlErr
+	}
+	if err != nil {
+		return fmt.Errorf("注册连接到epoll失败: %w", err)
+	}
+
+	// 连接被其他协程关闭（如心跳超时）时文件描述符已从epoll中移除，需要单独通知
+	conn.OnClose(func() {
+		e.closeEntry(l, entry, ErrConnectionClosed, false)
+	})
+	return nil
+}
+
+// Stop 停止事件循环，处理完已解码的消息后返回
+func (e *EventEngine) Stop() {
+	if !e.stopped.CompareAndSwap(false, true) {
+		return
+	}
+	close(e.stopChan)
+	e.wg.Wait()
+	for _, l := range e.loops {
+		syscall.Close(l.epfd)
+	}
+	e.workers.stop()
+	e.logger.Info("事件循环引擎已停止")
+}
+
+// run 事件循环
+func (e *EventEngine) run(l *eventLoop) {
+	defer e.wg.Done()
+
+	events := make([]syscall.EpollEvent, 256)
+	for {
+		select {
+		case <-e.stopChan:
+			return
+		default:
+		}
+
+		n, err := syscall.EpollWait(l.epfd, events, epollWaitTimeout)
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			e.logger.Error("等待epoll事件失败", "error", err)
+			return
+		}
+
+		for i := 0; i < n; i++ {
+			l.mu.Lock()
+			entry := l.conns[int(events[i].Fd)]
+			l.mu.Unlock()
+			if entry != nil {
+				e.handleReadable(l, entry)
+			}
+		}
+	}
+}
+
+// handleReadable 读取一次就绪的数据并分发其中所有完整消息，剩余数据等待下一次可读事件（水平触发）
+func (e *EventEngine) handleReadable(l *eventLoop, entry *pollEntry) {
+	if entry.closed.Load() {
+		return
+	}
+	conn := entry.conn
+
+	_, readErr := conn.Fill(entry.read)
+	if readErr == syscall.EAGAIN {
+		readErr = nil
+	}
+
+	for {
+		msg, err := conn.NextMessage()
+		if err != nil {
+			e.closeEntry(l, entry, err, true)
+			return
+		}
+		if msg == nil {
+			break
+		}
+		e.workers.dispatch(entry.worker, workItem{conn: conn, msg: msg})
+	}
+
+	if readErr != nil {
+		e.closeEntry(l, entry, readErr, true)
+	}
+}
+
+// read 非阻塞读取套接字，对端关闭时返回io.EOF
+func (entry *pollEntry) read(p []byte) (int, error) {
+	var n int
+	var readErr error
+	err := entry.raw.Read(func(fd uintptr) bool {
+		n, readErr = syscall.Read(int(fd), p)
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if readErr != nil {
+		return 0, readErr
+	}
+	if n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// closeEntry 注销连接并通知处理器，每个连接只执行一次
+// 事件循环中发现的错误通过连接所属的工作协程通知，保证排在已分发的消息之后
+func (e *EventEngine) closeEntry(l *eventLoop, entry *pollEntry, err error, viaWorker bool) {
+	if !entry.closed.CompareAndSwap(false, true) {
+		return
+	}
+
+	// 连接已关闭时Control直接返回错误，此时文件描述符已自动从epoll中移除
+	entry.raw.Control(func(fd uintptr) {
+		syscall.EpollCtl(l.epfd, syscall.EPOLL_CTL_DEL, int(fd), nil)
+	})
+	l.mu.Lock()
+	if l.conns[entry.fd] == entry {
+		delete(l.conns, entry.fd)
+	}
+	l.mu.Unlock()
+
+	if viaWorker {
+		e.workers.dispatch(entry.worker, workItem{conn: entry.conn, err: err})
+		return
+	}
+	// 关闭回调可能在持有其他锁的协程中执行，异步通知避免死锁
+	go e.handler.HandleClose(entry.conn, err)
+}
@@ -0,0 +1,122 @@
+//go:build linux
+
+package protocol
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+)
+
+// recordingHandler 记录每个连接收到的序列号和断开通知
+type recordingHandler struct {
+	mu     sync.Mutex
+	seqs   map[string][]uint32
+	closed chan *Connection
+}
+
+func (h *recordingHandler) HandleMessage(conn *Connection, msg *types.Message) {
+	h.mu.Lock()
+	h.seqs[conn.ID] = append(h.seqs[conn.ID], msg.Header.SequenceID)
+	h.mu.Unlock()
+}
+
+func (h *recordingHandler) HandleClose(conn *Connection, err error) {
+	h.closed <- conn
+}
+
+func (h *recordingHandler) count(id string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.seqs[id])
+}
+
+func TestEventEngine(t *testing.T) {
+	handler := &recordingHandler{seqs: make(map[string][]uint32), closed: make(chan *Connection, 8)}
+	engine, err := NewEventEngine(EventEngineConfig{Loops: 2, Workers: 4, WorkerQueueSize: 8}, handler, newTestLogger())
+	if err != nil {
+		t.Fatalf("创建事件循环引擎失败: %v", err)
+	}
+	defer engine.Stop()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	defer listener.Close()
+
+	const clients, messages = 3, 50
+	conns := make([]*Connection, clients)
+	peers := make([]net.Conn, clients)
+	for i := range conns {
+		peer, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("连接失败: %v", err)
+		}
+		defer peer.Close()
+		raw, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("接受连接失败: %v", err)
+		}
+		conns[i] = NewConnection(raw, types.ConnectionConfig{BufferSize: 256}, NewBinaryCodec(), newTestLogger())
+		conns[i].setState(types.StateConnected)
+		if err := engine.Register(conns[i]); err != nil {
+			t.Fatalf("注册连接失败: %v", err)
+		}
+		peers[i] = peer
+	}
+
+	// 每个客户端发送的帧被拆成小块写入，验证分包处理和同一连接内的消息顺序
+	codec := NewBinaryCodec()
+	for _, peer := range peers {
+		go func(peer net.Conn) {
+			var data []byte
+			for seq := uint32(1); seq <= messages; seq++ {
+				frame, _ := codec.Encode(CreateHeartbeatMessage(seq))
+				data = append(data, frame...)
+			}
+			for len(data) > 0 {
+				n := min(len(data), 37)
+				peer.Write(data[:n])
+				data = data[n:]
+			}
+		}(peer)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for _, conn := range conns {
+		for handler.count(conn.ID) < messages && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		handler.mu.Lock()
+		seqs := handler.seqs[conn.ID]
+		handler.mu.Unlock()
+		if len(seqs) != messages {
+			t.Fatalf("连接%s收到%d条消息，期望%d", conn.ID, len(seqs), messages)
+		}
+		for i, seq := range seqs {
+			if seq != uint32(i+1) {
+				t.Fatalf("连接%s第%d条消息序列号为%d", conn.ID, i, seq)
+			}
+		}
+	}
+
+	// 客户端断开和服务端主动关闭都会通知处理器
+	peers[0].Close()
+	conns[1].Close()
+	closed := map[*Connection]bool{}
+	for len(closed) < 2 {
+		select {
+		case conn := <-handler.closed:
+			closed[conn] = true
+		case <-time.After(2 * time.Second):
+			t.Fatalf("未收到断开通知: %v", closed)
+		}
+	}
+	if !closed[conns[0]] || !closed[conns[1]] {
+		t.Errorf("断开通知的连接不一致")
+	}
+}
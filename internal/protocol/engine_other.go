@@ -0,0 +1,23 @@
+//go:build !linux
+
+package protocol
+
+import (
+	"datamiddleware/internal/infrastructure/logging"
+)
+
+// EventEngine 事件循环引擎，非Linux平台不支持，调用方应回退到每个连接一个读协程
+type EventEngine struct{}
+
+// NewEventEngine 非Linux平台始终返回ErrEngineUnsupported
+func NewEventEngine(config EventEngineConfig, handler EventHandler, log logger.Logger) (*EventEngine, error) {
+	return nil, ErrEngineUnsupported
+}
+
+// Register 非Linux平台不支持
+func (e *EventEngine) Register(conn *Connection) error {
+	return ErrEngineUnsupported
+}
+
+// Stop 非Linux平台为空操作
+func (e *EventEngine) Stop() {}
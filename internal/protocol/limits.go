@@ -0,0 +1,89 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FrameLimits 帧大小限制，0表示不限制
+type FrameLimits struct {
+	MaxHeaderSize int // 消息头最大字节数（含长度前缀、游戏ID、用户ID和扩展字段，不含消息体）
+	MaxBodySize   int // 消息体最大字节数（线上长度，压缩和加密之后）
+}
+
+// LimitedCodec 支持帧大小限制的编解码器
+type LimitedCodec interface {
+	Codec
+	// WithLimits 返回使用指定帧大小限制的编解码器副本
+	WithLimits(limits FrameLimits) Codec
+}
+
+// checkHeaderSize 检查消息头长度
+func (l FrameLimits) checkHeaderSize(size int) error {
+	if l.MaxHeaderSize > 0 && size > l.MaxHeaderSize {
+		return fmt.Errorf("%w: %d字节，上限%d字节", ErrHeaderTooLarge, size, l.MaxHeaderSize)
+	}
+	return nil
+}
+
+// checkBodySize 检查消息体长度
+func (l FrameLimits) checkBodySize(size int) error {
+	if l.MaxBodySize > 0 && size > l.MaxBodySize {
+		return fmt.Errorf("%w: %d字节，上限%d字节", ErrBodyTooLarge, size, l.MaxBodySize)
+	}
+	return nil
+}
+
+// checkFrameSize 检查无法区分消息头和消息体的整帧长度（如Protobuf帧）
+func (l FrameLimits) checkFrameSize(size int) error {
+	if l.MaxHeaderSize > 0 && l.MaxBodySize > 0 && size > l.MaxHeaderSize+l.MaxBodySize {
+		return fmt.Errorf("%w: %d字节，上限%d字节", ErrFrameTooLarge, size, l.MaxHeaderSize+l.MaxBodySize)
+	}
+	return nil
+}
+
+// maxBuffered 读缓冲区允许积累的最大字节数，0表示不限制
+func (l FrameLimits) maxBuffered() int {
+	if l.MaxHeaderSize <= 0 || l.MaxBodySize <= 0 {
+		return 0
+	}
+	return l.MaxHeaderSize + l.MaxBodySize
+}
+
+// Errors
+var (
+	ErrFrameTooLarge    = errors.New("消息帧过大")
+	ErrHeaderTooLarge   = fmt.Errorf("%w: 消息头过大", ErrFrameTooLarge)
+	ErrBodyTooLarge     = fmt.Errorf("%w: 消息体过大", ErrFrameTooLarge)
+	ErrChecksumMismatch = errors.New("校验和验证失败")
+	ErrMalformedFrame   = errors.New("消息帧格式错误")
+	ErrInsufficientData = errors.New("数据长度不足") // 帧尚未完整到达，需要继续读取
+)
+
+// 帧被拒绝的原因
+const (
+	RejectHeaderTooLarge = "header_too_large"
+	RejectBodyTooLarge   = "body_too_large"
+	RejectFrameTooLarge  = "frame_too_large"
+	RejectChecksum       = "checksum_mismatch"
+	RejectMalformed      = "malformed"
+)
+
+// FrameRejectReason 获取帧被拒绝的原因，不是帧错误时返回空字符串
+func FrameRejectReason(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrHeaderTooLarge):
+		return RejectHeaderTooLarge
+	case errors.Is(err, ErrBodyTooLarge):
+		return RejectBodyTooLarge
+	case errors.Is(err, ErrFrameTooLarge):
+		return RejectFrameTooLarge
+	case errors.Is(err, ErrChecksumMismatch):
+		return RejectChecksum
+	case errors.Is(err, ErrMalformedFrame):
+		return RejectMalformed
+	}
+	return ""
+}
@@ -3,32 +3,52 @@ package protocol
 import (
 	"fmt"
 	"net"
-	"sync"
+	"slices"
 	"time"
 
 	"datamiddleware/internal/infrastructure/logging"
+	utils "datamiddleware/internal/common"
 	"datamiddleware/internal/common/types"
 )
 
 // ConnectionManager 连接管理器
 type ConnectionManager struct {
 	config        types.ConnectionConfig `json:"config"`         // 连接配置
-	connections   map[string]*Connection `json:"-"`              // 连接映射
-	mu            sync.RWMutex           `json:"-"`              // 保护并发访问
+	registry      *connectionRegistry    `json:"-"`              // 分片的连接注册表，维护游戏和用户索引
 	logger        logger.Logger          `json:"-"`              // 日志器
 	codec         Codec                  `json:"-"`              // 编解码器
 	stopChan      chan struct{}          `json:"-"`              // 停止通道
 	cleanupTicker *time.Ticker           `json:"-"`              // 清理定时器
+	clock         utils.Clock            `json:"-"`              // 超时检测使用的时钟
+	timeWheel     *utils.TimeWheel       `json:"-"`              // 驱动所有连接心跳、空闲和握手超时的时间轮
+	outbound      OutboundFilter         `json:"-"`              // 新连接发送消息前的过滤器
+	pusher        PushFunc               `json:"-"`              // 广播使用的推送投递函数，nil表示直接发送到连接
 }
 
+// PushFunc 投递服务端推送，gameID或userID为空时不按其筛选，返回接收推送的连接或会话数
+type PushFunc func(gameID, userID string, msg *types.Message) int
+
+// 超时检测时间轮的刻度和每层槽位数，第0层覆盖51.2秒
+const (
+	timeWheelTick = 100 * time.Millisecond
+	timeWheelSize = 512
+)
+
 // NewConnectionManager 创建连接管理器
 func NewConnectionManager(config types.ConnectionConfig, codec Codec, log logger.Logger) *ConnectionManager {
+	return NewConnectionManagerWithClock(config, codec, log, utils.SystemClock())
+}
+
+// NewConnectionManagerWithClock 使用指定时钟创建连接管理器，测试时通过推进时钟和时间轮验证超时行为
+func NewConnectionManagerWithClock(config types.ConnectionConfig, codec Codec, log logger.Logger, clock utils.Clock) *ConnectionManager {
 	return &ConnectionManager{
 		config:      config,
-		connections: make(map[string]*Connection),
+		registry:    newConnectionRegistry(),
 		logger:      log,
 		codec:       codec,
 		stopChan:    make(chan struct{}),
+		clock:       clock,
+		timeWheel:   utils.NewTimeWheelWithClock(timeWheelTick, timeWheelSize, clock),
 	}
 }
 
@@ -36,6 +56,9 @@ func NewConnectionManager(config types.ConnectionConfig, codec Codec, log logger
 func (cm *ConnectionManager) Start() {
 	cm.logger.Info("连接管理器启动", "max_connections", cm.config.MaxConnections)
 
+	// 启动超时检测时间轮
+	cm.timeWheel.Start()
+
 	// 启动清理协程
 	if cm.config.CleanupInterval > 0 {
 		cm.cleanupTicker = time.NewTicker(cm.config.CleanupInterval)
@@ -47,10 +70,11 @@ func (cm *ConnectionManager) Start() {
 func (cm *ConnectionManager) Stop() {
 	cm.logger.Info("连接管理器停止中...")
 
-	// 停止清理协程
+	// 停止清理协程和时间轮
 	if cm.cleanupTicker != nil {
 		cm.cleanupTicker.Stop()
 	}
+	cm.timeWheel.Stop()
 
 	// 关闭停止通道
 	select {
@@ -58,116 +82,148 @@ func (cm *ConnectionManager) Stop() {
 	default:
 	}
 
-	// 关闭所有连接
-	cm.mu.Lock()
-	defer cm.mu.Unlock()
-
-	for id, conn := range cm.connections {
+	// 关闭所有连接（在锁外关闭，连接的关闭回调可能会移除连接）
+	for _, conn := range cm.registry.drain() {
 		if err := conn.Close(); err != nil {
-			cm.logger.Error("关闭连接失败", "conn_id", id, "error", err)
+			cm.logger.Error("关闭连接失败", "conn_id", conn.ID, "error", err)
 		}
 	}
 
-	cm.connections = make(map[string]*Connection)
 	cm.logger.Info("连接管理器已停止")
 }
 
 // AddConnection 添加连接
 func (cm *ConnectionManager) AddConnection(conn net.Conn) (*Connection, error) {
-	cm.mu.Lock()
-	defer cm.mu.Unlock()
-
 	// 检查连接数量限制
-	if cm.config.MaxConnections > 0 && len(cm.connections) >= cm.config.MaxConnections {
+	if !cm.registry.reserve(cm.config.MaxConnections) {
 		conn.Close()
 		return nil, fmt.Errorf("连接数量已达到上限: %d", cm.config.MaxConnections)
 	}
 
-	// 创建连接包装器
-	connection := NewConnection(conn, cm.config, cm.codec, cm.logger)
+	// 创建连接包装器，认证后更新游戏和用户索引
+	connection := newConnection(conn, cm.config, cm.codec, cm.logger, cm.clock)
+	connection.authHook = cm.registry.reindex
+	connection.outbound = cm.outbound
+	cm.scheduleTimeouts(connection)
 
-	// 添加到连接映射
-	cm.connections[connection.ID] = connection
+	// 添加到注册表
+	cm.registry.add(connection)
 
 	// 启动连接
 	connection.Start()
 
-	cm.logger.Info("连接已添加", "conn_id", connection.ID, "total", len(cm.connections))
+	cm.logger.Info("连接已添加", "conn_id", connection.ID, "total", cm.registry.count.Load())
 	return connection, nil
 }
 
+// SetOutboundFilter 设置新连接发送消息前的过滤器，必须在添加连接前调用
+func (cm *ConnectionManager) SetOutboundFilter(filter OutboundFilter) {
+	cm.outbound = filter
+}
+
+// SetPushFunc 设置广播使用的推送投递函数，设置后BroadcastToGame和BroadcastToUser经由它投递（如保留推送供断线重连后补发）
+func (cm *ConnectionManager) SetPushFunc(fn PushFunc) {
+	cm.pusher = fn
+}
+
+// scheduleTimeouts 在时间轮上注册连接的心跳、空闲和握手超时检测，代替每个连接的检测协程
+// 定时器在连接关闭时取消，必须在连接启动前调用
+func (cm *ConnectionManager) scheduleTimeouts(conn *Connection) {
+	timers := []*utils.Timer{}
+
+	// 心跳检测：按心跳间隔周期检查，发送Ping后在Ping超时时间到达时单独检查一次，不必等到下一个心跳间隔
+	if interval := cm.config.Heartbeat.Interval; cm.config.Heartbeat.Enabled && interval > 0 {
+		timeout := cm.config.Heartbeat.Timeout
+		var pingTimer *utils.Timer
+		pingTimer = cm.timeWheel.NewTimer(func() {
+			if conn.isActive() && conn.checkHeartbeat() {
+				pingTimer.Reset(timeout)
+			}
+		})
+
+		var t *utils.Timer
+		t = cm.timeWheel.NewTimer(func() {
+			if !conn.isActive() {
+				return
+			}
+			if conn.checkHeartbeat() && timeout > 0 {
+				pingTimer.Reset(timeout)
+			}
+			t.Reset(interval)
+		})
+		t.Reset(interval)
+		timers = append(timers, t, pingTimer)
+	}
+
+	// 空闲检测：到期时按最后活动时间重新计算剩余时间，收发消息时不需要重置定时器
+	if timeout := cm.config.IdleTimeout; timeout > 0 {
+		var t *utils.Timer
+		t = cm.timeWheel.NewTimer(func() {
+			if remaining := conn.checkIdle(); remaining > 0 {
+				t.Reset(remaining)
+			}
+		})
+		t.Reset(timeout)
+		timers = append(timers, t)
+	}
+
+	// 握手超时：只检查一次
+	if timeout := cm.config.HandshakeTimeout; timeout > 0 {
+		timers = append(timers, cm.timeWheel.AfterFunc(timeout, conn.checkHandshake))
+	}
+
+	conn.timers = timers
+}
+
+// AdvanceTimers 将超时检测时间轮推进到时钟的当前时间，用于配合注入的时钟测试超时行为
+func (cm *ConnectionManager) AdvanceTimers() {
+	cm.timeWheel.Advance()
+}
+
 // RemoveConnection 移除连接
 func (cm *ConnectionManager) RemoveConnection(connID string) {
-	cm.mu.Lock()
-	defer cm.mu.Unlock()
-
-	_, exists := cm.connections[connID]
-	if !exists {
+	if !cm.registry.remove(connID) {
 		return
 	}
-
-	delete(cm.connections, connID)
-	cm.logger.Info("连接已移除", "conn_id", connID, "remaining", len(cm.connections))
+	cm.logger.Info("连接已移除", "conn_id", connID, "remaining", cm.registry.count.Load())
 }
 
 // GetConnection 获取连接
 func (cm *ConnectionManager) GetConnection(connID string) (*Connection, bool) {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-
-	conn, exists := cm.connections[connID]
-	return conn, exists
+	return cm.registry.get(connID)
 }
 
 // GetAllConnections 获取所有连接
 func (cm *ConnectionManager) GetAllConnections() map[string]*Connection {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-
-	connections := make(map[string]*Connection)
-	for id, conn := range cm.connections {
-		connections[id] = conn
+	snapshot := cm.registry.snapshot()
+	connections := make(map[string]*Connection, len(snapshot))
+	for _, conn := range snapshot {
+		connections[conn.ID] = conn
 	}
 	return connections
 }
 
 // GetConnectionCount 获取连接数量
 func (cm *ConnectionManager) GetConnectionCount() int {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-	return len(cm.connections)
+	return int(cm.registry.count.Load())
 }
 
-// GetConnectionsByGame 获取指定游戏的所有连接
+// GetConnectionsByGame 获取指定游戏的所有已认证连接，返回的切片为只读快照，调用方不能修改
 func (cm *ConnectionManager) GetConnectionsByGame(gameID string) []*Connection {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-
-	var connections []*Connection
-	for _, conn := range cm.connections {
-		if conn.Info.GameID == gameID {
-			connections = append(connections, conn)
-		}
-	}
-	return connections
+	return cm.registry.byGame.get(gameID)
 }
 
-// GetConnectionsByUser 获取指定用户的所有连接
+// GetConnectionsByUser 获取指定用户的所有已认证连接，返回的切片为只读快照，调用方不能修改
 func (cm *ConnectionManager) GetConnectionsByUser(userID string) []*Connection {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-
-	var connections []*Connection
-	for _, conn := range cm.connections {
-		if conn.Info.UserID == userID {
-			connections = append(connections, conn)
-		}
-	}
-	return connections
+	return cm.registry.byUser.get(userID)
 }
 
 // BroadcastToGame 广播消息到指定游戏的所有连接
 func (cm *ConnectionManager) BroadcastToGame(gameID string, msg *types.Message) {
+	if cm.pusher != nil {
+		cm.pusher(gameID, "", msg)
+		return
+	}
 	connections := cm.GetConnectionsByGame(gameID)
 	for _, conn := range connections {
 		if err := conn.SendMessage(msg); err != nil {
@@ -178,6 +234,10 @@ func (cm *ConnectionManager) BroadcastToGame(gameID string, msg *types.Message)
 
 // BroadcastToUser 广播消息到指定用户的所有连接
 func (cm *ConnectionManager) BroadcastToUser(userID string, msg *types.Message) {
+	if cm.pusher != nil {
+		cm.pusher("", userID, msg)
+		return
+	}
 	connections := cm.GetConnectionsByUser(userID)
 	for _, conn := range connections {
 		if err := conn.SendMessage(msg); err != nil {
@@ -188,40 +248,80 @@ func (cm *ConnectionManager) BroadcastToUser(userID string, msg *types.Message)
 
 // GetStats 获取统计信息
 func (cm *ConnectionManager) GetStats() ConnectionManagerStats {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-
+	// 按游戏和用户统计直接读取索引
 	stats := ConnectionManagerStats{
-		TotalConnections: len(cm.connections),
-		GameStats:        make(map[string]int),
-		UserStats:        make(map[string]int),
+		TotalConnections: cm.GetConnectionCount(),
+		GameStats:        cm.registry.byGame.counts(),
+		UserStats:        cm.registry.byUser.counts(),
 		StateStats:       make(map[types.ConnectionState]int),
+		GameRTT:          make(map[string]RTTStats),
 	}
 
-	for _, conn := range cm.connections {
-		// 按游戏统计
-		if conn.Info.GameID != "" {
-			stats.GameStats[conn.Info.GameID]++
-		}
+	samples := make(map[string][]types.ConnectionInfo)
+	for _, conn := range cm.registry.snapshot() {
+		info := conn.GetStats()
 
-		// 按用户统计
-		if conn.Info.UserID != "" {
-			stats.UserStats[conn.Info.UserID]++
+		// 按游戏收集已测得的往返时延
+		if info.GameID != "" && info.RTT > 0 {
+			samples[info.GameID] = append(samples[info.GameID], info)
 		}
 
 		// 按状态统计
-		stats.StateStats[conn.State]++
+		stats.StateStats[info.State]++
+	}
+
+	for gameID, infos := range samples {
+		stats.GameRTT[gameID] = newRTTStats(infos)
 	}
 
 	return stats
 }
 
+// RTTStats 一组连接的往返时延分布，单位为毫秒
+type RTTStats struct {
+	Connections int     `json:"connections"`   // 已测得往返时延的连接数
+	P50         float64 `json:"p50_ms"`        // 中位数
+	P90         float64 `json:"p90_ms"`        // 90分位
+	P99         float64 `json:"p99_ms"`        // 99分位
+	Max         float64 `json:"max_ms"`        // 最大值
+	AvgJitter   float64 `json:"avg_jitter_ms"` // 平均抖动
+}
+
+// newRTTStats 按连接的平滑往返时延计算分布
+func newRTTStats(infos []types.ConnectionInfo) RTTStats {
+	rtts := make([]time.Duration, len(infos))
+	var jitter time.Duration
+	for i, info := range infos {
+		rtts[i] = info.RTT
+		jitter += info.RTTJitter
+	}
+	slices.Sort(rtts)
+
+	percentile := func(p int) float64 {
+		return durationMillis(rtts[(len(rtts)-1)*p/100])
+	}
+	return RTTStats{
+		Connections: len(rtts),
+		P50:         percentile(50),
+		P90:         percentile(90),
+		P99:         percentile(99),
+		Max:         durationMillis(rtts[len(rtts)-1]),
+		AvgJitter:   durationMillis(jitter / time.Duration(len(rtts))),
+	}
+}
+
+// durationMillis 将时长转换为毫秒
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
 // ConnectionManagerStats 连接管理器统计信息
 type ConnectionManagerStats struct {
 	TotalConnections int                              `json:"total_connections"` // 总连接数
 	GameStats        map[string]int                   `json:"game_stats"`        // 按游戏统计
 	UserStats        map[string]int                   `json:"user_stats"`        // 按用户统计
 	StateStats       map[types.ConnectionState]int    `json:"state_stats"`      // 按状态统计
+	GameRTT          map[string]RTTStats              `json:"game_rtt"`         // 按游戏统计的往返时延分布
 }
 
 // cleanupLoop 清理循环
@@ -238,24 +338,21 @@ func (cm *ConnectionManager) cleanupLoop() {
 
 // cleanup 清理无效连接
 func (cm *ConnectionManager) cleanup() {
-	cm.mu.Lock()
-	defer cm.mu.Unlock()
-
-	var toRemove []string
-	for id, conn := range cm.connections {
+	removed := 0
+	for _, conn := range cm.registry.snapshot() {
 		// 检查连接状态
-		if conn.State == types.StateClosed {
-			toRemove = append(toRemove, id)
+		if conn.GetStats().State != types.StateClosed {
+			continue
 		}
-	}
 
-	// 移除无效连接
-	for _, id := range toRemove {
-		delete(cm.connections, id)
-		cm.logger.Debug("清理无效连接", "conn_id", id)
+		// 移除无效连接
+		if cm.registry.remove(conn.ID) {
+			removed++
+			cm.logger.Debug("清理无效连接", "conn_id", conn.ID)
+		}
 	}
 
-	if len(toRemove) > 0 {
-		cm.logger.Info("连接清理完成", "removed", len(toRemove), "remaining", len(cm.connections))
+	if removed > 0 {
+		cm.logger.Info("连接清理完成", "removed", removed, "remaining", cm.registry.count.Load())
 	}
 }
@@ -0,0 +1,309 @@
+package protocol
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+)
+
+// fakeClock 测试用时钟，由测试手动推进
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// waitClosed 等待连接关闭（超时检测通过异步Close关闭连接）
+func waitClosed(conn *Connection) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if !conn.isActive() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
+
+func TestConnectionManagerTimeouts(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	config := types.ConnectionConfig{
+		BufferSize:       4096,
+		IdleTimeout:      60 * time.Second,
+		HandshakeTimeout: 10 * time.Second,
+	}
+	cm := NewConnectionManagerWithClock(config, NewBinaryCodec(), newTestLogger(), clock)
+
+	newConn := func() *Connection {
+		server, client := net.Pipe()
+		t.Cleanup(func() { client.Close() })
+		conn, err := cm.AddConnection(server)
+		if err != nil {
+			t.Fatalf("添加连接失败: %v", err)
+		}
+		return conn
+	}
+	advance := func(d time.Duration) {
+		clock.Advance(d)
+		cm.AdvanceTimers()
+	}
+
+	pending := newConn()
+	authed := newConn()
+	authed.Authenticate("game1", "user1")
+
+	// 握手超时只关闭未认证的连接
+	advance(11 * time.Second)
+	if !waitClosed(pending) {
+		t.Fatal("未认证的连接应在握手超时后关闭")
+	}
+	if !authed.isActive() {
+		t.Fatal("已认证的连接不应因握手超时关闭")
+	}
+
+	// 有活动时空闲超时顺延
+	advance(40 * time.Second)
+	authed.mu.Lock()
+	authed.updateActivity()
+	authed.mu.Unlock()
+	advance(30 * time.Second)
+	if !authed.isActive() {
+		t.Fatal("有活动的连接不应空闲超时")
+	}
+
+	advance(31 * time.Second)
+	if !waitClosed(authed) {
+		t.Fatal("空闲连接应在超时后关闭")
+	}
+	if n := cm.timeWheel.Len(); n != 0 {
+		t.Errorf("关闭的连接仍有%d个定时器", n)
+	}
+}
+
+// readPings 持续读取客户端一端收到的帧，返回其中的Ping序列号
+func readPings(t *testing.T, client net.Conn) <-chan uint32 {
+	pings := make(chan uint32, 8)
+	go func() {
+		codec := NewBinaryCodec()
+		var buffer []byte
+		chunk := make([]byte, 1024)
+		for {
+			msg, consumed, err := codec.Decode(buffer)
+			if err == nil {
+				if msg.Header.Type == types.MessageTypePing {
+					pings <- msg.Header.SequenceID
+				}
+				buffer = buffer[consumed:]
+				continue
+			}
+			n, err := client.Read(chunk)
+			if err != nil {
+				return
+			}
+			buffer = append(buffer, chunk[:n]...)
+		}
+	}()
+	return pings
+}
+
+func TestConnectionManagerPing(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	config := types.ConnectionConfig{
+		BufferSize: 4096,
+		Heartbeat: types.HeartbeatConfig{
+			Enabled:   true,
+			Interval:  10 * time.Second,
+			Timeout:   5 * time.Second,
+			MaxMissed: 2,
+		},
+	}
+	cm := NewConnectionManagerWithClock(config, NewBinaryCodec(), newTestLogger(), clock)
+
+	server, client := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	conn, err := cm.AddConnection(server)
+	if err != nil {
+		t.Fatalf("添加连接失败: %v", err)
+	}
+	conn.Authenticate("game1", "user1")
+	pings := readPings(t, client)
+
+	advance := func(d time.Duration) {
+		clock.Advance(d)
+		cm.AdvanceTimers()
+	}
+	nextPing := func() uint32 {
+		select {
+		case seq := <-pings:
+			return seq
+		case <-time.After(time.Second):
+			t.Fatal("未收到服务端Ping")
+			return 0
+		}
+	}
+
+	// 空闲超过心跳间隔时发送Ping，按序列号匹配Pong并计算往返时延
+	advance(10 * time.Second)
+	seq := nextPing()
+	clock.Advance(40 * time.Millisecond)
+	if conn.HandlePong(seq + 1) {
+		t.Error("序列号不匹配的Pong不应被接受")
+	}
+	if !conn.HandlePong(seq) {
+		t.Fatal("匹配的Pong应被接受")
+	}
+	if info := conn.GetStats(); info.RTT != 40*time.Millisecond || info.RTTJitter != 20*time.Millisecond {
+		t.Errorf("RTT = %v, 抖动 = %v", info.RTT, info.RTTJitter)
+	}
+	rtt := cm.GetStats().GameRTT["game1"]
+	if rtt.Connections != 1 || rtt.P50 != 40 || rtt.Max != 40 || rtt.AvgJitter != 20 {
+		t.Errorf("游戏往返时延统计 = %+v", rtt)
+	}
+
+	// 第二次采样按平滑公式更新
+	advance(10 * time.Second)
+	seq = nextPing()
+	clock.Advance(80 * time.Millisecond)
+	conn.HandlePong(seq)
+	if info := conn.GetStats(); info.RTT != 45*time.Millisecond || info.RTTJitter != 25*time.Millisecond {
+		t.Errorf("平滑后RTT = %v, 抖动 = %v", info.RTT, info.RTTJitter)
+	}
+
+	// Ping超时后立即计为丢失并重新发送，不等待下一个心跳间隔；连续未回复Pong达到上限后关闭连接
+	advance(10 * time.Second)
+	nextPing()
+	advance(5 * time.Second)
+	nextPing()
+	if !conn.isActive() {
+		t.Fatal("丢失次数未达到上限时不应关闭连接")
+	}
+	if missed := conn.GetStats().MissedPings; missed != 1 {
+		t.Errorf("连续丢失次数 = %d, 期望 1", missed)
+	}
+	advance(5 * time.Second)
+	if !waitClosed(conn) {
+		t.Fatal("连续丢失Pong后应关闭连接")
+	}
+}
+
+func TestConnectionManagerIndexes(t *testing.T) {
+	cm := NewConnectionManager(types.ConnectionConfig{BufferSize: 4096, MaxConnections: 3}, NewBinaryCodec(), newTestLogger())
+	defer cm.Stop()
+
+	newConn := func() *Connection {
+		server, client := net.Pipe()
+		t.Cleanup(func() { client.Close() })
+		conn, err := cm.AddConnection(server)
+		if err != nil {
+			t.Fatalf("添加连接失败: %v", err)
+		}
+		return conn
+	}
+	ids := func(conns []*Connection) map[string]bool {
+		set := make(map[string]bool)
+		for _, conn := range conns {
+			set[conn.ID] = true
+		}
+		return set
+	}
+
+	a, b, c := newConn(), newConn(), newConn()
+	server, client := net.Pipe()
+	defer client.Close()
+	if _, err := cm.AddConnection(server); err == nil {
+		t.Fatal("超过连接数上限时应拒绝连接")
+	}
+
+	// 未认证的连接不在索引中
+	if n := len(cm.GetConnectionsByGame("")); n != 0 {
+		t.Errorf("未认证连接被索引: %d", n)
+	}
+
+	a.Authenticate("game1", "user1")
+	b.Authenticate("game1", "user2")
+	c.Authenticate("game2", "user1")
+	if got := ids(cm.GetConnectionsByGame("game1")); len(got) != 2 || !got[a.ID] || !got[b.ID] {
+		t.Errorf("game1的连接 = %v", got)
+	}
+	if got := ids(cm.GetConnectionsByUser("user1")); len(got) != 2 || !got[a.ID] || !got[c.ID] {
+		t.Errorf("user1的连接 = %v", got)
+	}
+
+	// 重新认证后从旧索引移到新索引
+	b.Authenticate("game2", "user2")
+	if got := ids(cm.GetConnectionsByGame("game1")); len(got) != 1 || !got[a.ID] {
+		t.Errorf("重新认证后game1的连接 = %v", got)
+	}
+
+	// 移除后从索引中删除并释放名额
+	cm.RemoveConnection(a.ID)
+	if n := len(cm.GetConnectionsByUser("user1")); n != 1 {
+		t.Errorf("移除后user1的连接数 = %d", n)
+	}
+	if n := len(cm.GetConnectionsByGame("game1")); n != 0 {
+		t.Errorf("移除后game1的连接数 = %d", n)
+	}
+	stats := cm.GetStats()
+	if stats.TotalConnections != 2 || stats.GameStats["game2"] != 2 || stats.UserStats["user1"] != 1 {
+		t.Errorf("统计信息 = %+v", stats)
+	}
+	newConn()
+
+	// 移除后的连接重新认证不会再加入索引
+	a.Authenticate("game3", "user3")
+	if n := len(cm.GetConnectionsByGame("game3")); n != 0 {
+		t.Errorf("已移除的连接被重新索引: %d", n)
+	}
+}
+
+func TestConnectionManagerConcurrentIndexes(t *testing.T) {
+	cm := NewConnectionManager(types.ConnectionConfig{BufferSize: 4096}, NewBinaryCodec(), newTestLogger())
+	defer cm.Stop()
+
+	const workers, rounds = 8, 50
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				server, client := net.Pipe()
+				conn, err := cm.AddConnection(server)
+				if err != nil {
+					t.Errorf("添加连接失败: %v", err)
+					client.Close()
+					return
+				}
+				conn.Authenticate("game1", fmt.Sprintf("user%d", w))
+				for _, other := range cm.GetConnectionsByGame("game1") {
+					_ = other.ID
+				}
+				cm.RemoveConnection(conn.ID)
+				client.Close()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if n := cm.GetConnectionCount(); n != 0 {
+		t.Errorf("连接数 = %d, 期望 0", n)
+	}
+	if n := len(cm.GetConnectionsByGame("game1")); n != 0 {
+		t.Errorf("game1仍有%d个连接", n)
+	}
+}
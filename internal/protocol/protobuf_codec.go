@@ -0,0 +1,225 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+
+	"datamiddleware/internal/common/types"
+	"datamiddleware/internal/protocol/pb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// PayloadCodec 需要转换消息体格式的编解码器
+// 连接在加密/压缩之前调用EncodePayload，在解密/解压之后调用DecodePayload，
+// 使业务处理器始终面对JSON格式的消息体
+type PayloadCodec interface {
+	// EncodePayload 将服务端发出的消息体转换为线上格式，返回新消息，不修改原消息
+	EncodePayload(msg *types.Message) (*types.Message, error)
+	// DecodePayload 将收到的线上格式消息体转换为业务处理器使用的格式
+	DecodePayload(msg *types.Message) error
+}
+
+// protobufRequestPayloads 使用Protobuf消息体的请求类型
+var protobufRequestPayloads = map[types.MessageType]func() proto.Message{
+	types.MessageTypePlayerLogin:    func() proto.Message { return &pb.LoginRequest{} },
+	types.MessageTypePlayerLogout:   func() proto.Message { return &pb.LogoutRequest{} },
+	types.MessageTypeItemOperation:  func() proto.Message { return &pb.ItemOperationRequest{} },
+	types.MessageTypeOrderOperation: func() proto.Message { return &pb.OrderOperationRequest{} },
+}
+
+// ProtobufCodec Protobuf编解码器
+// 格式: [帧长度(4字节)] [pb.Frame]
+type ProtobufCodec struct {
+	compression *CompressionOptions // 压缩选项，nil表示不压缩
+	limits      FrameLimits         // 帧大小限制
+}
+
+// NewProtobufCodec 创建Protobuf编解码器
+func NewProtobufCodec() *ProtobufCodec {
+	return &ProtobufCodec{}
+}
+
+// WithCompression 返回使用指定压缩选项的编解码器副本
+func (c *ProtobufCodec) WithCompression(opts *CompressionOptions) Codec {
+	cp := *c
+	cp.compression = opts
+	return &cp
+}
+
+// WithLimits 返回使用指定帧大小限制的编解码器副本
+func (c *ProtobufCodec) WithLimits(limits FrameLimits) Codec {
+	cp := *c
+	cp.limits = limits
+	return &cp
+}
+
+// Encode 编码消息
+func (c *ProtobufCodec) Encode(msg *types.Message) ([]byte, error) {
+	// 按需压缩消息体
+	body, flags, err := compressBody(c.compression, msg.Header.Flags, msg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := crc32.ChecksumIEEE(body)
+	extHeader := msg.Header
+	if extHeader.Version < types.ProtocolVersionV2 {
+		stripHeaderExtensions(&extHeader)
+	}
+	frame := &pb.Frame{
+		Header: &pb.Header{
+			Version:    uint32(msg.Header.Version),
+			Type:       uint32(msg.Header.Type),
+			Flags:      uint32(flags),
+			SequenceId: msg.Header.SequenceID,
+			GameId:     msg.Header.GameID,
+			UserId:     msg.Header.UserID,
+			Timestamp:  msg.Header.Timestamp,
+			BodyLength: uint32(len(body)),
+			Checksum:   checksum,
+
+			TraceId:     extHeader.TraceID,
+			Deadline:    extHeader.Deadline,
+			ClientBuild: extHeader.ClientBuild,
+			ErrorCode:   extHeader.ErrorCode,
+		},
+		Body: body,
+	}
+
+	frameData, err := proto.Marshal(frame)
+	if err != nil {
+		return nil, fmt.Errorf("序列化消息失败: %w", err)
+	}
+	msg.Header.Checksum = checksum
+
+	buffer := make([]byte, 4+len(frameData))
+	binary.BigEndian.PutUint32(buffer[0:4], uint32(len(frameData)))
+	copy(buffer[4:], frameData)
+	return buffer, nil
+}
+
+// Decode 解码消息
+func (c *ProtobufCodec) Decode(data []byte) (msg *types.Message, consumed int, err error) {
+	if len(data) < 4 {
+		return nil, 0, fmt.Errorf("%w，无法解析帧长度", ErrInsufficientData)
+	}
+
+	// 帧长度超过限制时不等待后续数据直接拒绝
+	frameLen := int(binary.BigEndian.Uint32(data[0:4]))
+	if err := c.limits.checkFrameSize(4 + frameLen); err != nil {
+		return nil, 0, err
+	}
+	if len(data) < 4+frameLen {
+		return nil, 4, fmt.Errorf("%w，无法解析完整消息", ErrInsufficientData)
+	}
+	consumed = 4 + frameLen
+
+	var frame pb.Frame
+	if err := proto.Unmarshal(data[4:consumed], &frame); err != nil {
+		return nil, consumed, fmt.Errorf("%w: 反序列化消息失败: %v", ErrMalformedFrame, err)
+	}
+	if frame.Header == nil {
+		return nil, consumed, fmt.Errorf("%w: 消息缺少消息头", ErrMalformedFrame)
+	}
+
+	h := frame.Header
+	header := types.MessageHeader{
+		Version:    uint8(h.Version),
+		Type:       types.MessageType(h.Type),
+		Flags:      types.MessageFlag(h.Flags),
+		SequenceID: h.SequenceId,
+		GameID:     h.GameId,
+		UserID:     h.UserId,
+		Timestamp:  h.Timestamp,
+		BodyLength: h.BodyLength,
+		Checksum:   h.Checksum,
+
+		TraceID:     h.TraceId,
+		Deadline:    h.Deadline,
+		ClientBuild: h.ClientBuild,
+		ErrorCode:   h.ErrorCode,
+	}
+
+	// 验证消息体
+	if int(header.BodyLength) != len(frame.Body) {
+		return nil, consumed, fmt.Errorf("%w: 消息体长度不一致，期望%d，实际%d", ErrMalformedFrame, header.BodyLength, len(frame.Body))
+	}
+	if checksum := crc32.ChecksumIEEE(frame.Body); checksum != header.Checksum {
+		return nil, consumed, fmt.Errorf("%w，期望0x%x，实际0x%x", ErrChecksumMismatch, header.Checksum, checksum)
+	}
+
+	// 解压消息体
+	body, err := decompressBody(&header, frame.Body, c.limits)
+	if err != nil {
+		return nil, consumed, err
+	}
+
+	return &types.Message{
+		Header: header,
+		Body:   body,
+	}, consumed, nil
+}
+
+// EncodePayload 将JSON格式的业务响应和错误消息转换为pb.Response
+func (c *ProtobufCodec) EncodePayload(msg *types.Message) (*types.Message, error) {
+	if _, ok := protobufRequestPayloads[msg.Header.Type]; !ok && msg.Header.Type != types.MessageTypeError {
+		return msg, nil
+	}
+
+	var resp struct {
+		ID        string          `json:"id"`
+		Code      int32           `json:"code"`
+		Message   string          `json:"message"`
+		Data      json.RawMessage `json:"data"`
+		Timestamp int64           `json:"timestamp"`
+	}
+	if err := json.Unmarshal(msg.Body, &resp); err != nil {
+		// 非JSON响应（例如业务直接推送的二进制数据）原样发送
+		return msg, nil
+	}
+
+	data := []byte(resp.Data)
+	if string(data) == "null" {
+		data = nil
+	}
+	body, err := proto.Marshal(&pb.Response{
+		Id:        resp.ID,
+		Code:      resp.Code,
+		Message:   resp.Message,
+		Data:      data,
+		Timestamp: resp.Timestamp,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("序列化响应失败: %w", err)
+	}
+
+	encoded := &types.Message{Header: msg.Header, Body: body}
+	encoded.Header.BodyLength = uint32(len(body))
+	return encoded, nil
+}
+
+// DecodePayload 将Protobuf格式的请求消息体转换为JSON
+func (c *ProtobufCodec) DecodePayload(msg *types.Message) error {
+	newPayload, ok := protobufRequestPayloads[msg.Header.Type]
+	if !ok {
+		return nil
+	}
+
+	payload := newPayload()
+	if err := proto.Unmarshal(msg.Body, payload); err != nil {
+		return fmt.Errorf("反序列化请求消息体失败: %w", err)
+	}
+
+	// 生成代码的json标签与业务处理器使用的字段名一致
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("转换请求消息体失败: %w", err)
+	}
+
+	msg.Body = body
+	msg.Header.BodyLength = uint32(len(body))
+	return nil
+}
@@ -0,0 +1,200 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// PROXY协议（HAProxy定义，nginx、云负载均衡均支持）在连接开头携带客户端的真实地址
+const (
+	proxyV1MaxLength = 107 // v1头部的最大长度（包含结尾的CRLF）
+	proxyV2MaxLength = 4096
+)
+
+// proxyV2Signature v2头部的固定签名
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ErrInvalidProxyHeader PROXY协议头格式错误
+var ErrInvalidProxyHeader = errors.New("无效的PROXY协议头")
+
+// ProxyTrust 允许发送PROXY协议头的上游地址段
+type ProxyTrust []netip.Prefix
+
+// ParseProxyTrust 解析CIDR列表，单个IP视为只包含该地址的地址段
+func ParseProxyTrust(cidrs []string) (ProxyTrust, error) {
+	trust := make(ProxyTrust, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("无效的上游地址 %s: %w", cidr, err)
+			}
+			trust = append(trust, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的上游地址段 %s: %w", cidr, err)
+		}
+		trust = append(trust, prefix.Masked())
+	}
+	return trust, nil
+}
+
+// Contains 检查地址是否属于可信的上游
+func (t ProxyTrust) Contains(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	ip, ok := netip.AddrFromSlice(tcpAddr.IP)
+	if !ok {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range t {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProxyConn 远程地址为PROXY协议头中客户端地址的连接
+type ProxyConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+// RemoteAddr 返回客户端的真实地址
+func (c *ProxyConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// ProxyAddr 返回发送PROXY协议头的上游地址
+func (c *ProxyConn) ProxyAddr() net.Addr {
+	return c.Conn.RemoteAddr()
+}
+
+// SyscallConn 返回底层连接的原始连接，事件循环引擎通过它获取文件描述符
+func (c *ProxyConn) SyscallConn() (syscall.RawConn, error) {
+	sc, ok := c.Conn.(syscall.Conn)
+	if !ok {
+		return nil, fmt.Errorf("连接类型%T不支持获取文件描述符", c.Conn)
+	}
+	return sc.SyscallConn()
+}
+
+// ReadProxyHeader 读取连接开头的PROXY协议头（v1或v2），返回远程地址为客户端真实地址的连接
+// 只读取头部本身，之后的数据仍由连接读取；头部不携带地址（LOCAL命令、UNKNOWN协议，如负载均衡的健康检查）时返回原连接
+// 调用方需要在调用前设置读取超时
+func ReadProxyHeader(conn net.Conn) (net.Conn, error) {
+	// v2签名为12字节，v1头部最短为15字节，先读取12字节区分版本
+	prefix := make([]byte, len(proxyV2Signature))
+	if _, err := io.ReadFull(conn, prefix); err != nil {
+		return nil, fmt.Errorf("读取PROXY协议头失败: %w", err)
+	}
+
+	var addr net.Addr
+	var err error
+	switch {
+	case bytes.Equal(prefix, proxyV2Signature):
+		addr, err = readProxyV2(conn)
+	case bytes.HasPrefix(prefix, []byte("PROXY ")):
+		addr, err = readProxyV1(conn, prefix)
+	default:
+		return nil, fmt.Errorf("%w: 连接未以PROXY协议头开始", ErrInvalidProxyHeader)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if addr == nil {
+		return conn, nil
+	}
+	return &ProxyConn{Conn: conn, remoteAddr: addr}, nil
+}
+
+// readProxyV1 读取文本格式的头部: PROXY TCP4 源地址 目的地址 源端口 目的端口\r\n
+func readProxyV1(conn net.Conn, prefix []byte) (net.Addr, error) {
+	// 逐字节读取到换行，避免读取头部之后的数据
+	line := append(make([]byte, 0, proxyV1MaxLength), prefix...)
+	b := make([]byte, 1)
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= proxyV1MaxLength {
+			return nil, fmt.Errorf("%w: v1头部超过%d字节", ErrInvalidProxyHeader, proxyV1MaxLength)
+		}
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return nil, fmt.Errorf("读取PROXY协议头失败: %w", err)
+		}
+		line = append(line, b[0])
+	}
+
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidProxyHeader, line)
+	}
+	ip, err := netip.ParseAddr(fields[2])
+	if err != nil || ip.Is4() != (fields[1] == "TCP4") {
+		return nil, fmt.Errorf("%w: 无效的源地址 %s", ErrInvalidProxyHeader, fields[2])
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 无效的源端口 %s", ErrInvalidProxyHeader, fields[4])
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, uint16(port))), nil
+}
+
+// readProxyV2 读取二进制格式的头部（签名之后的部分）
+func readProxyV2(conn net.Conn) (net.Addr, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, fmt.Errorf("读取PROXY协议头失败: %w", err)
+	}
+	if header[0]>>4 != 2 {
+		return nil, fmt.Errorf("%w: 不支持的版本 %d", ErrInvalidProxyHeader, header[0]>>4)
+	}
+	length := int(binary.BigEndian.Uint16(header[2:]))
+	if length > proxyV2MaxLength {
+		return nil, fmt.Errorf("%w: v2头部超过%d字节", ErrInvalidProxyHeader, proxyV2MaxLength)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return nil, fmt.Errorf("读取PROXY协议头失败: %w", err)
+	}
+
+	switch command := header[0] & 0x0F; command {
+	case 0x0: // LOCAL: 上游自身发起的连接
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, fmt.Errorf("%w: 不支持的命令 %d", ErrInvalidProxyHeader, command)
+	}
+
+	// 只使用TCP地址，其余地址族（UNIX、UDP）和之后的TLV扩展忽略
+	var size int
+	switch header[1] {
+	case 0x11: // TCP over IPv4
+		size = 4
+	case 0x21: // TCP over IPv6
+		size = 16
+	default:
+		return nil, nil
+	}
+	if length < 2*size+4 {
+		return nil, fmt.Errorf("%w: 地址长度不足", ErrInvalidProxyHeader)
+	}
+	ip, _ := netip.AddrFromSlice(payload[:size])
+	port := binary.BigEndian.Uint16(payload[2*size:])
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, port)), nil
+}
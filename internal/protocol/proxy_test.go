@@ -0,0 +1,124 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// readProxyHeaderFrom 通过内存连接发送data，返回解析后的连接和头部之后剩余的数据
+func readProxyHeaderFrom(t *testing.T, data []byte) (net.Conn, string, error) {
+	t.Helper()
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		client.Write(data)
+		client.Close()
+	}()
+
+	conn, err := ReadProxyHeader(server)
+	if err != nil {
+		return nil, "", err
+	}
+	rest, _ := io.ReadAll(conn)
+	return conn, string(rest), nil
+}
+
+// proxyV2Header 构造v2头部，addr为地址部分
+func proxyV2Header(command, family byte, addr []byte) []byte {
+	header := append([]byte(nil), proxyV2Signature...)
+	header = append(header, 0x20|command, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(addr)))
+	return append(header, addr...)
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	ipv4 := []byte{203, 0, 113, 7, 10, 0, 0, 1, 0x1F, 0x90, 0x23, 0x82} // 203.0.113.7:8080 -> 10.0.0.1:9090
+	ipv6 := make([]byte, 36)
+	copy(ipv6, net.ParseIP("2001:db8::1"))
+	binary.BigEndian.PutUint16(ipv6[32:], 443)
+
+	tests := []struct {
+		name   string
+		data   string
+		remote string // 为空表示保留原连接
+	}{
+		{"v1 IPv4", "PROXY TCP4 203.0.113.7 10.0.0.1 8080 9090\r\n", "203.0.113.7:8080"},
+		{"v1 IPv6", "PROXY TCP6 2001:db8::1 2001:db8::2 443 9090\r\n", "[2001:db8::1]:443"},
+		{"v1 UNKNOWN", "PROXY UNKNOWN\r\n", ""},
+		{"v2 IPv4", string(proxyV2Header(0x1, 0x11, ipv4)), "203.0.113.7:8080"},
+		{"v2 IPv6 带TLV", string(proxyV2Header(0x1, 0x21, append(ipv6, 0x04, 0x00, 0x01, 0xFF))), "[2001:db8::1]:443"},
+		{"v2 LOCAL", string(proxyV2Header(0x0, 0x00, nil)), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, rest, err := readProxyHeaderFrom(t, []byte(tt.data+"frame"))
+			if err != nil {
+				t.Fatalf("ReadProxyHeader() error = %v", err)
+			}
+			if rest != "frame" {
+				t.Errorf("头部之后的数据 = %q, 期望 frame", rest)
+			}
+			proxied, ok := conn.(*ProxyConn)
+			if tt.remote == "" {
+				if ok {
+					t.Errorf("不携带地址的头部不应替换远程地址: %s", proxied.RemoteAddr())
+				}
+				return
+			}
+			if !ok || conn.RemoteAddr().String() != tt.remote {
+				t.Errorf("远程地址 = %s, 期望 %s", conn.RemoteAddr(), tt.remote)
+			}
+		})
+	}
+}
+
+func TestReadProxyHeaderInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"没有头部", "\x00\x00\x00\x10binary frame"},
+		{"v1 缺少字段", "PROXY TCP4 203.0.113.7\r\n"},
+		{"v1 地址族不匹配", "PROXY TCP4 2001:db8::1 10.0.0.1 8080 9090\r\n"},
+		{"v1 没有换行", "PROXY TCP4 203.0.113.7 10.0.0.1 8080 9090 " + string(make([]byte, 100))},
+		{"v2 地址长度不足", string(proxyV2Header(0x1, 0x11, []byte{1, 2, 3}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := readProxyHeaderFrom(t, []byte(tt.data)); !errors.Is(err, ErrInvalidProxyHeader) {
+				t.Errorf("ReadProxyHeader() error = %v, 期望 ErrInvalidProxyHeader", err)
+			}
+		})
+	}
+}
+
+func TestProxyTrust(t *testing.T) {
+	trust, err := ParseProxyTrust([]string{"10.0.0.0/8", "192.168.1.5", "::1"})
+	if err != nil {
+		t.Fatalf("ParseProxyTrust() error = %v", err)
+	}
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.1.2.3", true},
+		{"::ffff:10.1.2.3", true},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+		{"::1", true},
+		{"203.0.113.7", false},
+	}
+	for _, tt := range tests {
+		if got := trust.Contains(&net.TCPAddr{IP: net.ParseIP(tt.ip), Port: 1234}); got != tt.want {
+			t.Errorf("Contains(%s) = %v, 期望 %v", tt.ip, got, tt.want)
+		}
+	}
+
+	if _, err := ParseProxyTrust([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("无效的地址段应返回错误")
+	}
+}
@@ -0,0 +1,257 @@
+package protocol
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+// registryShards 连接注册表和索引的分片数，必须是2的幂
+const registryShards = 32
+
+// shardIndex 计算键所属的分片
+func shardIndex(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() & (registryShards - 1))
+}
+
+// connectionRegistry 分片的连接注册表，按游戏ID和用户ID维护二级索引
+// 加锁顺序：连接所在分片 -> 索引分片 -> 连接自身的锁
+type connectionRegistry struct {
+	shards [registryShards]registryShard
+	byGame connIndex
+	byUser connIndex
+	count  atomic.Int64
+}
+
+// registryShard 注册表分片
+type registryShard struct {
+	mu    sync.RWMutex
+	conns map[string]*registryEntry
+}
+
+// registryEntry 注册的连接及其当前所在的索引键
+type registryEntry struct {
+	conn   *Connection
+	gameID string
+	userID string
+}
+
+// newConnectionRegistry 创建连接注册表
+func newConnectionRegistry() *connectionRegistry {
+	r := &connectionRegistry{}
+	for i := range r.shards {
+		r.shards[i].conns = make(map[string]*registryEntry)
+	}
+	r.byGame.init()
+	r.byUser.init()
+	return r
+}
+
+// reserve 占用一个连接名额，超过上限时返回false
+func (r *connectionRegistry) reserve(limit int) bool {
+	if n := r.count.Add(1); limit > 0 && n > int64(limit) {
+		r.count.Add(-1)
+		return false
+	}
+	return true
+}
+
+// add 注册已占用名额的连接
+func (r *connectionRegistry) add(conn *Connection) {
+	shard := &r.shards[shardIndex(conn.ID)]
+	shard.mu.Lock()
+	shard.conns[conn.ID] = &registryEntry{conn: conn}
+	shard.mu.Unlock()
+}
+
+// reindex 按连接当前的游戏ID和用户ID更新索引，连接已移除时忽略
+func (r *connectionRegistry) reindex(conn *Connection) {
+	shard := &r.shards[shardIndex(conn.ID)]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	entry, ok := shard.conns[conn.ID]
+	if !ok || entry.conn != conn {
+		return
+	}
+	gameID, userID := conn.Identity()
+	if gameID != entry.gameID {
+		r.byGame.remove(entry.gameID, conn)
+		r.byGame.add(gameID, conn)
+		entry.gameID = gameID
+	}
+	if userID != entry.userID {
+		r.byUser.remove(entry.userID, conn)
+		r.byUser.add(userID, conn)
+		entry.userID = userID
+	}
+}
+
+// remove 注销连接并释放名额，返回连接是否存在
+func (r *connectionRegistry) remove(connID string) bool {
+	shard := &r.shards[shardIndex(connID)]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	entry, ok := shard.conns[connID]
+	if !ok {
+		return false
+	}
+	delete(shard.conns, connID)
+	r.byGame.remove(entry.gameID, entry.conn)
+	r.byUser.remove(entry.userID, entry.conn)
+	r.count.Add(-1)
+	return true
+}
+
+// get 按连接ID查找连接
+func (r *connectionRegistry) get(connID string) (*Connection, bool) {
+	shard := &r.shards[shardIndex(connID)]
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	entry, ok := shard.conns[connID]
+	if !ok {
+		return nil, false
+	}
+	return entry.conn, true
+}
+
+// snapshot 返回所有连接的快照，每次只锁定一个分片
+func (r *connectionRegistry) snapshot() []*Connection {
+	connections := make([]*Connection, 0, r.count.Load())
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mu.RLock()
+		for _, entry := range shard.conns {
+			connections = append(connections, entry.conn)
+		}
+		shard.mu.RUnlock()
+	}
+	return connections
+}
+
+// drain 移除并返回所有连接
+func (r *connectionRegistry) drain() []*Connection {
+	var connections []*Connection
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mu.Lock()
+		for id, entry := range shard.conns {
+			delete(shard.conns, id)
+			r.byGame.remove(entry.gameID, entry.conn)
+			r.byUser.remove(entry.userID, entry.conn)
+			r.count.Add(-1)
+			connections = append(connections, entry.conn)
+		}
+		shard.mu.Unlock()
+	}
+	return connections
+}
+
+// connIndex 分片的二级索引，键为空字符串的连接不建立索引
+type connIndex struct {
+	shards [registryShards]indexShard
+}
+
+// indexShard 索引分片
+type indexShard struct {
+	mu   sync.RWMutex
+	sets map[string]*indexSet
+}
+
+// indexSet 同一个键下的连接集合
+// snapshot缓存只读的连接列表，集合变化时置空并在下次读取时重建，调用方遍历快照时不持有锁
+type indexSet struct {
+	conns    map[*Connection]struct{}
+	snapshot []*Connection
+}
+
+func (idx *connIndex) init() {
+	for i := range idx.shards {
+		idx.shards[i].sets = make(map[string]*indexSet)
+	}
+}
+
+func (idx *connIndex) add(key string, conn *Connection) {
+	if key == "" {
+		return
+	}
+	shard := &idx.shards[shardIndex(key)]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	set, ok := shard.sets[key]
+	if !ok {
+		set = &indexSet{conns: make(map[*Connection]struct{})}
+		shard.sets[key] = set
+	}
+	set.conns[conn] = struct{}{}
+	set.snapshot = nil
+}
+
+func (idx *connIndex) remove(key string, conn *Connection) {
+	if key == "" {
+		return
+	}
+	shard := &idx.shards[shardIndex(key)]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	set, ok := shard.sets[key]
+	if !ok {
+		return
+	}
+	delete(set.conns, conn)
+	set.snapshot = nil
+	if len(set.conns) == 0 {
+		delete(shard.sets, key)
+	}
+}
+
+// get 返回键下所有连接的只读快照
+func (idx *connIndex) get(key string) []*Connection {
+	shard := &idx.shards[shardIndex(key)]
+	shard.mu.RLock()
+	set, ok := shard.sets[key]
+	if !ok {
+		shard.mu.RUnlock()
+		return nil
+	}
+	if snapshot := set.snapshot; snapshot != nil {
+		shard.mu.RUnlock()
+		return snapshot
+	}
+	shard.mu.RUnlock()
+
+	// 缓存失效时在写锁下重建
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	set, ok = shard.sets[key]
+	if !ok {
+		return nil
+	}
+	if set.snapshot == nil {
+		set.snapshot = make([]*Connection, 0, len(set.conns))
+		for conn := range set.conns {
+			set.snapshot = append(set.snapshot, conn)
+		}
+	}
+	return set.snapshot
+}
+
+// counts 返回每个键下的连接数
+func (idx *connIndex) counts() map[string]int {
+	counts := make(map[string]int)
+	for i := range idx.shards {
+		shard := &idx.shards[i]
+		shard.mu.RLock()
+		for key, set := range shard.sets {
+			counts[key] = len(set.conns)
+		}
+		shard.mu.RUnlock()
+	}
+	return counts
+}
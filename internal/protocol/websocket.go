@@ -0,0 +1,183 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// WebSocketConn 将WebSocket连接适配为net.Conn，WebSocket客户端与TCP客户端共用连接管理和消息处理
+// 每个WebSocket消息承载一个完整的消息帧：
+// 二进制消息为编解码器编码的帧（默认二进制编解码器，也可以在第一个消息开头发送编解码器前导）；
+// 文本消息为JSON编解码器的帧去掉开头4字节的消息头长度，即消息头JSON之后紧跟消息体，由JSON编解码器直接解码。
+// 连接使用第一个消息的类型，之后收到其他类型的消息按帧格式错误处理
+type WebSocketConn struct {
+	ws          *websocket.Conn
+	remoteAddr  net.Addr
+	localAddr   net.Addr
+	payloadType atomic.Uint32 // 连接使用的WebSocket消息类型，0表示尚未收到消息
+	preamble    []byte        // 第一个文本消息之前尚未被读取的编解码器前导，只由读协程访问
+	pending     []byte        // 已收到尚未被读取的数据，只由读协程访问
+}
+
+// jsonPreamble 文本消息连接的JSON编解码器前导
+var jsonPreamble = CodecPreamble(CodecIDJSON)
+
+// NewWebSocketConn 包装服务端WebSocket连接，limits限制单个WebSocket消息的大小
+func NewWebSocketConn(ws *websocket.Conn, limits FrameLimits) *WebSocketConn {
+	if size := limits.maxBuffered(); size > 0 {
+		ws.MaxPayloadBytes = size
+	}
+
+	// websocket.Conn的地址是Origin和URL，这里使用HTTP请求的网络地址
+	c := &WebSocketConn{ws: ws}
+	if req := ws.Request(); req != nil {
+		c.remoteAddr = webSocketAddr(req.RemoteAddr)
+		if addr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+			c.localAddr = addr
+		} else {
+			c.localAddr = webSocketAddr(req.Host)
+		}
+	} else {
+		c.remoteAddr, c.localAddr = ws.RemoteAddr(), ws.LocalAddr()
+	}
+	return c
+}
+
+// SetClientIP 使用可信反向代理转发的客户端IP作为远程地址，必须在创建Connection之前调用
+func (c *WebSocketConn) SetClientIP(ip string) {
+	if ip == "" {
+		return
+	}
+	_, port, err := net.SplitHostPort(c.remoteAddr.String())
+	if err != nil {
+		port = "0"
+	}
+	c.remoteAddr = webSocketAddr(net.JoinHostPort(ip, port))
+}
+
+// Read 读取收到的消息帧数据，每次缓冲区读完后接收下一个WebSocket消息
+// 消息数据直接复制到调用方（连接的池化读缓冲区），不经过中间缓冲区
+func (c *WebSocketConn) Read(p []byte) (int, error) {
+	for len(c.preamble) == 0 && len(c.pending) == 0 {
+		if err := c.receive(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.preamble)
+	c.preamble = c.preamble[n:]
+	m := copy(p[n:], c.pending)
+	c.pending = c.pending[m:]
+	return n + m, nil
+}
+
+// receive 接收一个WebSocket消息，第一个文本消息之前补充JSON编解码器前导
+func (c *WebSocketConn) receive() error {
+	var frame webSocketFrame
+	if err := webSocketFrameCodec.Receive(c.ws, &frame); err != nil {
+		if errors.Is(err, websocket.ErrFrameTooLarge) {
+			return fmt.Errorf("%w: WebSocket消息超过%d字节", ErrFrameTooLarge, c.ws.MaxPayloadBytes)
+		}
+		return err
+	}
+
+	first := c.payloadType.CompareAndSwap(0, uint32(frame.payloadType))
+	if !first && c.payloadType.Load() != uint32(frame.payloadType) {
+		return fmt.Errorf("%w: 连接已使用%s消息，收到%s消息", ErrMalformedFrame,
+			webSocketPayloadName(byte(c.payloadType.Load())), webSocketPayloadName(frame.payloadType))
+	}
+
+	// 文本消息连接总是使用JSON编解码器
+	if first && frame.payloadType == websocket.TextFrame {
+		c.preamble = jsonPreamble
+	}
+	c.pending = frame.data
+	return nil
+}
+
+// Write 将一个完整的消息帧作为一个WebSocket消息发送
+// 连接每次写入一帧（同步写入和写协程的向量写入都逐帧调用Write），因此不需要再拆分帧
+func (c *WebSocketConn) Write(p []byte) (int, error) {
+	frame := webSocketFrame{payloadType: websocket.BinaryFrame, data: p}
+	if c.payloadType.Load() == websocket.TextFrame {
+		if len(p) < 4 {
+			return 0, fmt.Errorf("%w: JSON帧长度不足", ErrMalformedFrame)
+		}
+		frame = webSocketFrame{payloadType: websocket.TextFrame, data: p[4:]}
+	}
+	if err := webSocketFrameCodec.Send(c.ws, frame); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close 发送WebSocket关闭帧并关闭底层连接
+func (c *WebSocketConn) Close() error {
+	return c.ws.Close()
+}
+
+// LocalAddr 返回本地网络地址
+func (c *WebSocketConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+// RemoteAddr 返回客户端网络地址
+func (c *WebSocketConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// SetDeadline 设置读写超时
+func (c *WebSocketConn) SetDeadline(t time.Time) error {
+	return c.ws.SetDeadline(t)
+}
+
+// SetReadDeadline 设置读取超时
+func (c *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return c.ws.SetReadDeadline(t)
+}
+
+// SetWriteDeadline 设置写入超时
+func (c *WebSocketConn) SetWriteDeadline(t time.Time) error {
+	return c.ws.SetWriteDeadline(t)
+}
+
+// webSocketFrame 一个WebSocket消息及其类型
+type webSocketFrame struct {
+	payloadType byte
+	data        []byte
+}
+
+// webSocketFrameCodec 按消息类型收发WebSocket消息
+var webSocketFrameCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		frame := v.(webSocketFrame)
+		return frame.data, frame.payloadType, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		frame := v.(*webSocketFrame)
+		frame.payloadType, frame.data = payloadType, data
+		return nil
+	},
+}
+
+// webSocketPayloadName 返回WebSocket消息类型的名称
+func webSocketPayloadName(payloadType byte) string {
+	if payloadType == websocket.TextFrame {
+		return "文本"
+	}
+	return "二进制"
+}
+
+// webSocketAddr WebSocket连接的网络地址
+type webSocketAddr string
+
+// Network 返回网络类型
+func (a webSocketAddr) Network() string { return "websocket" }
+
+// String 返回地址
+func (a webSocketAddr) String() string { return string(a) }
@@ -0,0 +1,116 @@
+package protocol
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+
+	"golang.org/x/net/websocket"
+)
+
+// startWebSocketEcho 启动WebSocket服务端，原样回复收到的第一条消息，之后读取的结果写入errs
+func startWebSocketEcho(t *testing.T, config types.ConnectionConfig) (*websocket.Conn, <-chan error, <-chan string) {
+	t.Helper()
+
+	errs := make(chan error, 1)
+	codecs := make(chan string, 1)
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		conn := NewConnection(NewWebSocketConn(ws, FrameLimits{MaxHeaderSize: 256, MaxBodySize: 1024}), config, NewBinaryCodec(), newTestLogger())
+		conn.setState(types.StateConnected)
+		defer conn.Close()
+
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			errs <- err
+			return
+		}
+		codecs <- conn.GetStats().Codec
+		reply := &types.Message{Header: msg.Header, Body: append([]byte(nil), msg.Body...)}
+		msg.Release()
+		if err := conn.SendMessage(reply); err != nil {
+			errs <- err
+			return
+		}
+		_, err = conn.ReadMessage()
+		errs <- err
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	if err != nil {
+		t.Fatalf("建立WebSocket连接失败: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	return client, errs, codecs
+}
+
+func TestWebSocketConnBinaryFrames(t *testing.T) {
+	client, errs, codecs := startWebSocketEcho(t, types.ConnectionConfig{Codec: CodecBinary})
+
+	codec := NewBinaryCodec()
+	frame, err := codec.Encode(newTestMessage([]byte(`{"item_id":"sword"}`)))
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if err := websocket.Message.Send(client, frame); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	var reply []byte
+	if err := websocket.Message.Receive(client, &reply); err != nil {
+		t.Fatalf("接收失败: %v", err)
+	}
+	msg, consumed, err := codec.Decode(reply)
+	if err != nil || consumed != len(reply) {
+		t.Fatalf("一个WebSocket消息应恰好是一帧: consumed=%d/%d, err=%v", consumed, len(reply), err)
+	}
+	if string(msg.Body) != `{"item_id":"sword"}` || msg.Header.SequenceID != 42 {
+		t.Errorf("回复消息 = %+v %s", msg.Header, msg.Body)
+	}
+	if name := <-codecs; name != CodecBinary {
+		t.Errorf("编解码器 = %s, 期望 %s", name, CodecBinary)
+	}
+
+	// 同一连接中改用文本消息按帧格式错误处理
+	websocket.Message.Send(client, `{"type":1}`)
+	if err := <-errs; !errors.Is(err, ErrMalformedFrame) {
+		t.Errorf("混用消息类型的错误 = %v", err)
+	}
+}
+
+func TestWebSocketConnTextFrames(t *testing.T) {
+	// 即使默认编解码器为二进制，文本消息连接也使用JSON编解码器
+	client, errs, codecs := startWebSocketEcho(t, types.ConnectionConfig{Codec: CodecBinary})
+
+	codec := NewJSONCodec()
+	frame, err := codec.Encode(newTestMessage([]byte(`{"item_id":"sword"}`)))
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	// 文本消息省略开头4字节的消息头长度
+	if err := websocket.Message.Send(client, string(frame[4:])); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	var reply string
+	if err := websocket.Message.Receive(client, &reply); err != nil {
+		t.Fatalf("接收失败: %v", err)
+	}
+	if !strings.HasPrefix(reply, "{") || !strings.HasSuffix(reply, `{"item_id":"sword"}`) {
+		t.Errorf("文本回复应为消息头JSON后紧跟消息体: %s", reply)
+	}
+	if name := <-codecs; name != CodecJSON {
+		t.Errorf("编解码器 = %s, 期望 %s", name, CodecJSON)
+	}
+
+	// 超过帧大小限制的消息被拒绝
+	websocket.Message.Send(client, strings.Repeat("x", 2048))
+	if err := <-errs; !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("超大消息的错误 = %v", err)
+	}
+}
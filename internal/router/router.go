@@ -1,6 +1,7 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"sync"
@@ -9,10 +10,24 @@ import (
 	"datamiddleware/internal/common/types"
 )
 
+// clientIPKey 上下文中客户端IP的键
+type clientIPKey struct{}
+
+// WithClientIP 返回携带客户端IP的上下文，RouteTCPMessage用它填充业务请求的ClientIP
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIPFromContext 返回上下文中的客户端IP，未设置时返回空字符串
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
 // GameHandler 游戏处理器接口
 type GameHandler interface {
-	// Handle 处理游戏请求
-	Handle(gameID string, req *types.Request) (*types.Response, error)
+	// Handle 处理游戏请求，ctx 在请求超时、客户端断开或连接关闭时取消，需要传给服务层和数据访问层
+	Handle(ctx context.Context, gameID string, req *types.Request) (*types.Response, error)
 
 	// GetSupportedMessageTypes 获取支持的消息类型
 	GetSupportedMessageTypes() []types.MessageType
@@ -21,6 +36,12 @@ type GameHandler interface {
 	GetName() string
 }
 
+// OperationLister 可以列出支持的操作的游戏处理器
+type OperationLister interface {
+	// GetOperations 获取支持的操作
+	GetOperations() []types.OperationInfo
+}
+
 // Router 路由器
 type Router struct {
 	handlers map[string]GameHandler // gameID -> handler
@@ -61,8 +82,8 @@ func (r *Router) UnregisterHandler(gameID string) {
 	}
 }
 
-// Route 路由请求
-func (r *Router) Route(req *types.Request) (*types.Response, error) {
+// Route 路由请求，req.Timeout 大于0时在ctx上附加该超时
+func (r *Router) Route(ctx context.Context, req *types.Request) (*types.Response, error) {
 	r.mu.RLock()
 	handler, exists := r.handlers[req.GameID]
 	r.mu.RUnlock()
@@ -92,6 +113,22 @@ func (r *Router) Route(req *types.Request) (*types.Response, error) {
 		}, nil
 	}
 
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+		defer cancel()
+	}
+	// 等待处理期间客户端已断开或已超过截止时间
+	if err := ctx.Err(); err != nil {
+		r.logger.Warn("请求已取消或超时", "game_id", req.GameID, "user_id", req.UserID, "message_type", req.Type, "error", err)
+		return &types.Response{
+			ID:        req.ID,
+			Code:      1004, // 超时
+			Message:   "请求已超时或已取消",
+			Timestamp: req.Timestamp,
+		}, nil
+	}
+
 	// 处理请求
 	r.logger.Debug("路由请求到处理器",
 		"game_id", req.GameID,
@@ -99,7 +136,7 @@ func (r *Router) Route(req *types.Request) (*types.Response, error) {
 		"message_type", req.Type,
 		"user_id", req.UserID)
 
-	return handler.Handle(req.GameID, req)
+	return handler.Handle(ctx, req.GameID, req)
 }
 
 // GetRegisteredGames 获取已注册的游戏列表
@@ -123,6 +160,24 @@ func (r *Router) GetHandler(gameID string) (GameHandler, bool) {
 	return handler, exists
 }
 
+// GetOperations 获取游戏支持的操作，处理器未实现OperationLister时按支持的消息类型列出
+func (r *Router) GetOperations(gameID string) ([]types.OperationInfo, bool) {
+	handler, exists := r.GetHandler(gameID)
+	if !exists {
+		return nil, false
+	}
+
+	if lister, ok := handler.(OperationLister); ok {
+		return lister.GetOperations(), true
+	}
+	msgTypes := handler.GetSupportedMessageTypes()
+	operations := make([]types.OperationInfo, 0, len(msgTypes))
+	for _, msgType := range msgTypes {
+		operations = append(operations, types.OperationInfo{MessageType: msgType})
+	}
+	return operations, true
+}
+
 // supportsMessageType 检查处理器是否支持消息类型
 func (r *Router) supportsMessageType(handler GameHandler, msgType types.MessageType) bool {
 	supportedTypes := handler.GetSupportedMessageTypes()
@@ -136,8 +191,8 @@ func (r *Router) supportsMessageType(handler GameHandler, msgType types.MessageT
 
 // TCPMessageHandler TCP消息处理器接口
 type TCPMessageHandler interface {
-	// HandleTCPMessage 处理TCP消息
-	HandleTCPMessage(connID string, msg *types.Message) (*types.Message, error)
+	// HandleTCPMessage 处理TCP消息，ctx 在连接关闭或超过消息截止时间时取消
+	HandleTCPMessage(ctx context.Context, connID string, msg *types.Message) (*types.Message, error)
 }
 
 // HTTPRequestHandler HTTP请求处理器接口
@@ -183,24 +238,29 @@ func (mr *MessageRouter) RegisterGameHandler(gameID string, handler GameHandler)
 }
 
 // RouteTCPMessage 路由TCP消息
-func (mr *MessageRouter) RouteTCPMessage(connID string, msg *types.Message) (*types.Message, error) {
+func (mr *MessageRouter) RouteTCPMessage(ctx context.Context, connID string, msg *types.Message) (*types.Message, error) {
 	if mr.tcpRouter != nil {
-		return mr.tcpRouter.HandleTCPMessage(connID, msg)
+		return mr.tcpRouter.HandleTCPMessage(ctx, connID, msg)
 	}
 
 	// 默认处理：转换为业务请求并路由
 	req := &types.Request{
-		ID:      fmt.Sprintf("%s_%d", connID, msg.Header.SequenceID),
-		Type:    msg.Header.Type,
-		GameID:  msg.Header.GameID,
-		UserID:  msg.Header.UserID,
-		Data:    msg.Body,
+		ID:        fmt.Sprintf("%s_%d", connID, msg.Header.SequenceID),
+		Type:      msg.Header.Type,
+		GameID:    msg.Header.GameID,
+		UserID:    msg.Header.UserID,
+		ClientIP:  ClientIPFromContext(ctx),
+		Data:      msg.Body,
+		Timestamp: msg.Header.Timestamp,
 	}
 
-	resp, err := mr.gameRouter.Route(req)
+	resp, err := mr.gameRouter.Route(ctx, req)
 	if err != nil {
 		return nil, err
 	}
+	if resp == nil {
+		return nil, fmt.Errorf("游戏处理器未返回响应: %s", req.GameID)
+	}
 
 	// 转换为TCP响应消息
 	return mr.createTCPResponse(msg, resp), nil
@@ -217,6 +277,8 @@ func (mr *MessageRouter) createTCPResponse(reqMsg *types.Message, resp *types.Re
 			GameID:      reqMsg.Header.GameID,
 			UserID:      reqMsg.Header.UserID,
 			Timestamp:   resp.Timestamp,
+			TraceID:     reqMsg.Header.TraceID,
+			ErrorCode:   int32(resp.Code),
 			BodyLength:  0, // 稍后计算
 		},
 	}
@@ -0,0 +1,683 @@
+// Package client 数据中间件TCP协议的Go客户端
+//
+// 客户端负责建立连接、握手（压缩和加密协商）、心跳、按序列号匹配请求和响应，
+// 并在连接断开后按退避策略自动重连。服务端主动推送的消息通过Pushes通道交给调用方。
+// 服务端启用会话恢复时，重连在恢复窗口内会恢复之前的会话并补发断开期间的推送，
+// Session().Resumed为false时表示会话重新开始，调用方需要重新同步状态。
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	"datamiddleware/internal/protocol"
+)
+
+// Errors
+var (
+	ErrClosed       = errors.New("客户端已关闭")
+	ErrNotConnected = errors.New("客户端未连接")
+	ErrDisconnected = errors.New("连接已断开")
+)
+
+// ServerError 服务端返回的错误
+type ServerError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	TraceID string `json:"-"`
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("服务端错误 %d: %s", e.Code, e.Message)
+}
+
+// result 请求结果
+type result struct {
+	msg *types.Message
+	err error
+}
+
+// session 一次成功握手后的连接状态
+type session struct {
+	conn        net.Conn
+	codec       protocol.Codec
+	compression *protocol.CompressionOptions // 发送方向的压缩选项，加密时先压缩后加密
+	cipher      *protocol.SessionCipher
+	info        types.HandshakeResponse
+}
+
+// Client TCP客户端
+type Client struct {
+	config Config
+
+	sequence uint32 // 最近使用的序列号
+
+	mu          sync.RWMutex
+	current     *session // 当前连接，nil表示未连接
+	redirect    string   // 服务端排空连接时建议的重连地址，非空时代替配置的地址
+	resumeToken string   // 最近一次握手返回的恢复令牌，重连时携带以恢复会话
+	lastPushSeq uint32   // 已收到的最后一条推送的序列号
+	writeMu     sync.Mutex
+
+	pendingMu sync.Mutex
+	pending   map[uint32]chan result
+
+	pushes        chan *types.Message
+	droppedPushes int64
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// Dial 连接服务器并完成握手
+func Dial(config Config) (*Client, error) {
+	config = config.withDefaults()
+	if config.Addr == "" || config.GameID == "" || config.UserID == "" {
+		return nil, fmt.Errorf("服务器地址、游戏ID和用户ID不能为空")
+	}
+	if config.Encryption && config.ServerIdentityKey == "" {
+		return nil, fmt.Errorf("启用加密时必须配置服务端身份公钥")
+	}
+
+	c := &Client{
+		config:  config,
+		pending: make(map[uint32]chan result),
+		pushes:  make(chan *types.Message, config.PushBuffer),
+		closed:  make(chan struct{}),
+	}
+
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+
+	if config.HeartbeatInterval > 0 {
+		c.wg.Add(1)
+		go c.heartbeatLoop()
+	}
+	return c, nil
+}
+
+// Pushes 服务端推送消息通道，客户端关闭后通道关闭
+func (c *Client) Pushes() <-chan *types.Message {
+	return c.pushes
+}
+
+// DroppedPushes 因推送通道已满被丢弃的消息数
+func (c *Client) DroppedPushes() int64 {
+	return atomic.LoadInt64(&c.droppedPushes)
+}
+
+// Connected 检查当前是否已连接
+func (c *Client) Connected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current != nil
+}
+
+// Session 获取最近一次握手的响应
+func (c *Client) Session() (types.HandshakeResponse, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.current == nil {
+		return types.HandshakeResponse{}, false
+	}
+	return c.current.info, true
+}
+
+// Call 发送请求并等待响应，ctx没有截止时间时使用配置的默认请求超时
+func (c *Client) Call(ctx context.Context, msgType types.MessageType, body []byte) (*types.Message, error) {
+	return c.CallMessage(ctx, c.newMessage(msgType, body))
+}
+
+// CallJSON 发送JSON请求，解析响应中的data字段到out，响应码非0时返回*ServerError
+func (c *Client) CallJSON(ctx context.Context, msgType types.MessageType, req interface{}, out interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	msg, err := c.Call(ctx, msgType, body)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(msg.Body, &resp); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	if resp.Code != 0 {
+		return &ServerError{Code: resp.Code, Message: resp.Message, TraceID: msg.Header.TraceID}
+	}
+	if out != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return fmt.Errorf("解析响应数据失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// CallMessage 发送自定义消息头的请求并等待响应，序列号为0时自动分配
+// 服务端返回错误消息时返回*ServerError
+func (c *Client) CallMessage(ctx context.Context, msg *types.Message) (*types.Message, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.RequestTimeout)
+		defer cancel()
+	}
+	if msg.Header.SequenceID == 0 {
+		msg.Header.SequenceID = c.nextSequence()
+	}
+	if deadline, ok := ctx.Deadline(); ok && msg.Header.Deadline == 0 {
+		msg.Header.Deadline = deadline.UnixMilli()
+	}
+
+	ch := make(chan result, 1)
+	seq := msg.Header.SequenceID
+	c.pendingMu.Lock()
+	c.pending[seq] = ch
+	c.pendingMu.Unlock()
+
+	if err := c.send(msg); err != nil {
+		c.removePending(seq)
+		return nil, err
+	}
+
+	select {
+	case res := <-ch:
+		if res.err != nil {
+			return nil, res.err
+		}
+		if res.msg.Header.Type == types.MessageTypeError {
+			return res.msg, parseServerError(res.msg)
+		}
+		return res.msg, nil
+	case <-ctx.Done():
+		c.removePending(seq)
+		return nil, fmt.Errorf("等待响应失败(seq=%d): %w", seq, ctx.Err())
+	case <-c.closed:
+		c.removePending(seq)
+		return nil, ErrClosed
+	}
+}
+
+// Send 发送消息，不等待响应
+func (c *Client) Send(msgType types.MessageType, body []byte) error {
+	msg := c.newMessage(msgType, body)
+	msg.Header.SequenceID = c.nextSequence()
+	return c.send(msg)
+}
+
+// Close 关闭客户端，等待中的请求返回ErrClosed
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+
+		c.mu.Lock()
+		if c.current != nil {
+			c.current.conn.Close()
+		}
+		c.mu.Unlock()
+
+		c.wg.Wait()
+		c.failPending(ErrClosed)
+		close(c.pushes)
+	})
+	return nil
+}
+
+// connect 建立连接并握手，成功后启动读循环
+func (c *Client) connect() error {
+	c.mu.RLock()
+	addr := c.config.Addr
+	if c.redirect != "" {
+		addr = c.redirect
+	}
+	c.mu.RUnlock()
+
+	conn, err := net.DialTimeout("tcp", addr, c.config.DialTimeout)
+	if err != nil {
+		return fmt.Errorf("连接服务器失败: %w", err)
+	}
+
+	sess, reader, err := c.handshake(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	select {
+	case <-c.closed:
+		c.mu.Unlock()
+		conn.Close()
+		return ErrClosed
+	default:
+	}
+	c.current = sess
+	c.resumeToken = sess.info.ResumeToken
+	if !sess.info.Resumed {
+		// 新会话的推送序列号从1开始
+		c.lastPushSeq = 0
+	}
+	c.wg.Add(1)
+	c.mu.Unlock()
+
+	go c.readLoop(sess, reader)
+	return nil
+}
+
+// handshake 发送握手请求并根据响应配置编解码器、压缩和加密
+func (c *Client) handshake(conn net.Conn) (*session, *frameReader, error) {
+	conn.SetDeadline(time.Now().Add(c.config.HandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	codec, err := protocol.NewCodec(c.config.Codec)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.config.Codec != protocol.CodecBinary {
+		id, _ := protocol.DefaultCodecRegistry.ID(c.config.Codec)
+		if _, err := conn.Write(protocol.CodecPreamble(id)); err != nil {
+			return nil, nil, fmt.Errorf("发送编解码器前导失败: %w", err)
+		}
+	}
+
+	req := types.HandshakeRequest{
+		Token:       c.config.Token,
+		SessionID:   c.config.SessionID,
+		Compression: c.config.Compression,
+	}
+	c.mu.RLock()
+	req.ResumeToken, req.LastPushSeq = c.resumeToken, c.lastPushSeq
+	c.mu.RUnlock()
+	var kx *protocol.ClientKeyExchange
+	if c.config.Encryption {
+		if kx, err = protocol.NewClientKeyExchange(); err != nil {
+			return nil, nil, err
+		}
+		req.KeyExchange = kx.Request()
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("序列化握手请求失败: %w", err)
+	}
+
+	msg := c.newMessage(types.MessageTypeHandshake, body)
+	msg.Header.SequenceID = c.nextSequence()
+	data, err := codec.Encode(msg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("编码握手请求失败: %w", err)
+	}
+	if _, err := conn.Write(data); err != nil {
+		return nil, nil, fmt.Errorf("发送握手请求失败: %w", err)
+	}
+
+	reader := &frameReader{conn: conn}
+	resp, err := reader.next(codec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取握手响应失败: %w", err)
+	}
+	if resp.Header.Type == types.MessageTypeError {
+		return nil, nil, fmt.Errorf("握手被拒绝: %w", parseServerError(resp))
+	}
+	if resp.Header.Type != types.MessageTypeHandshake {
+		return nil, nil, fmt.Errorf("握手响应类型错误: 0x%04x", resp.Header.Type)
+	}
+
+	sess := &session{conn: conn, codec: codec}
+	if err := json.Unmarshal(resp.Body, &sess.info); err != nil {
+		return nil, nil, fmt.Errorf("解析握手响应失败: %w", err)
+	}
+
+	// 服务端选中的压缩算法用于发送方向，接收方向按消息标志自动解压
+	if sess.info.Compression != "" {
+		compressor, ok := protocol.GetCompressorByName(sess.info.Compression)
+		if !ok {
+			return nil, nil, fmt.Errorf("服务端选择了不支持的压缩算法: %s", sess.info.Compression)
+		}
+		sess.compression = &protocol.CompressionOptions{
+			Compressor: compressor,
+			Threshold:  sess.info.CompressionThreshold,
+		}
+		if cc, ok := codec.(protocol.CompressibleCodec); ok {
+			sess.codec = cc.WithCompression(sess.compression)
+		}
+	}
+
+	if kx != nil {
+		if sess.info.KeyExchange == nil {
+			return nil, nil, fmt.Errorf("服务端未启用会话加密")
+		}
+		sess.cipher, err = kx.Complete(sess.info.KeyExchange, sess.info.GameID, sess.info.UserID, c.config.ServerIdentityKey)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return sess, reader, nil
+}
+
+// readLoop 读取服务端消息，分发给等待中的请求或推送通道
+func (c *Client) readLoop(sess *session, reader *frameReader) {
+	defer c.wg.Done()
+
+	for {
+		msg, err := reader.next(sess.codec)
+		if err == nil {
+			msg, err = protocol.OpenMessage(sess.cipher, msg, protocol.FrameLimits{})
+		}
+		if err != nil {
+			c.handleDisconnect(sess, err)
+			return
+		}
+		c.dispatch(msg)
+	}
+}
+
+// dispatch 分发收到的消息
+func (c *Client) dispatch(msg *types.Message) {
+	// 服务端Ping的序列号由服务端分配，可能与未完成的请求相同，优先回复
+	if msg.Header.Type == types.MessageTypePing {
+		pong := c.newMessage(types.MessageTypePong, nil)
+		pong.Header.SequenceID = msg.Header.SequenceID
+		c.send(pong)
+		return
+	}
+	if msg.Header.Type == types.MessageTypeGoAway {
+		c.handleGoAway(msg)
+	}
+
+	// 推送的序列号为会话内的推送序列号，不与请求匹配；恢复会话后补发的推送中已收到的部分忽略
+	if msg.Header.Flags&types.FlagAsync != 0 {
+		if seq := msg.Header.SequenceID; seq != 0 {
+			c.mu.Lock()
+			duplicate := seq <= c.lastPushSeq
+			if !duplicate {
+				c.lastPushSeq = seq
+			}
+			c.mu.Unlock()
+			if duplicate {
+				return
+			}
+		}
+		c.deliverPush(msg)
+		return
+	}
+
+	c.pendingMu.Lock()
+	ch, ok := c.pending[msg.Header.SequenceID]
+	if ok {
+		delete(c.pending, msg.Header.SequenceID)
+	}
+	c.pendingMu.Unlock()
+
+	if ok {
+		ch <- result{msg: msg}
+		return
+	}
+
+	// 超时请求的迟到心跳回复不作为推送
+	if msg.Header.Type == types.MessageTypeHeartbeat {
+		return
+	}
+
+	c.deliverPush(msg)
+}
+
+// deliverPush 将消息放入推送通道，通道已满时丢弃
+func (c *Client) deliverPush(msg *types.Message) {
+	select {
+	case c.pushes <- msg:
+	default:
+		atomic.AddInt64(&c.droppedPushes, 1)
+	}
+}
+
+// handleGoAway 服务端即将关闭：记录建议的重连地址，启用自动重连时在服务端指定的等待时间后断开并重连
+// 消息同时作为推送交给调用方
+func (c *Client) handleGoAway(msg *types.Message) {
+	var goAway types.GoAway
+	if err := json.Unmarshal(msg.Body, &goAway); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	sess := c.current
+	if goAway.Redirect != "" {
+		c.redirect = goAway.Redirect
+	}
+	c.mu.Unlock()
+
+	if sess == nil || !c.config.Reconnect.Enabled {
+		return
+	}
+	time.AfterFunc(time.Duration(goAway.ReconnectAfter)*time.Millisecond, func() {
+		sess.conn.Close()
+	})
+}
+
+// handleDisconnect 处理连接断开，按配置启动重连
+func (c *Client) handleDisconnect(sess *session, err error) {
+	c.mu.Lock()
+	if c.current == sess {
+		c.current = nil
+	}
+	c.mu.Unlock()
+	sess.conn.Close()
+
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+
+	c.failPending(fmt.Errorf("%w: %v", ErrDisconnected, err))
+	if c.config.OnDisconnect != nil {
+		c.config.OnDisconnect(err)
+	}
+
+	if c.config.Reconnect.Enabled {
+		c.wg.Add(1)
+		go c.reconnectLoop()
+	}
+}
+
+// reconnectLoop 按指数退避重连，超过最大次数后关闭客户端
+func (c *Client) reconnectLoop() {
+	defer c.wg.Done()
+
+	backoff := c.config.Reconnect.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		// 增加随机抖动，避免大量客户端同时重连
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-c.closed:
+			return
+		case <-time.After(wait):
+		}
+
+		if err := c.connect(); err == nil {
+			if c.config.OnReconnect != nil {
+				c.config.OnReconnect()
+			}
+			return
+		} else if errors.Is(err, ErrClosed) {
+			return
+		}
+
+		if limit := c.config.Reconnect.MaxAttempts; limit > 0 && attempt >= limit {
+			go c.Close()
+			return
+		}
+		backoff *= 2
+		if backoff > c.config.Reconnect.MaxBackoff {
+			backoff = c.config.Reconnect.MaxBackoff
+		}
+	}
+}
+
+// heartbeatLoop 定期发送心跳，心跳超时时断开连接触发重连
+func (c *Client) heartbeatLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+		}
+
+		c.mu.RLock()
+		sess := c.current
+		c.mu.RUnlock()
+		if sess == nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.config.HeartbeatInterval)
+		_, err := c.Call(ctx, types.MessageTypeHeartbeat, nil)
+		cancel()
+		if err != nil && !errors.Is(err, ErrClosed) {
+			sess.conn.Close()
+		}
+	}
+}
+
+// send 加密并编码消息后写入当前连接
+func (c *Client) send(msg *types.Message) error {
+	c.mu.RLock()
+	sess := c.current
+	c.mu.RUnlock()
+
+	select {
+	case <-c.closed:
+		return ErrClosed
+	default:
+	}
+	if sess == nil {
+		return ErrNotConnected
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if sess.cipher != nil {
+		sealed, err := protocol.SealMessage(sess.cipher, sess.compression, msg)
+		if err != nil {
+			return err
+		}
+		msg = sealed
+	}
+	data, err := sess.codec.Encode(msg)
+	if err != nil {
+		return fmt.Errorf("编码消息失败: %w", err)
+	}
+
+	sess.conn.SetWriteDeadline(time.Now().Add(c.config.WriteTimeout))
+	if _, err := sess.conn.Write(data); err != nil {
+		sess.conn.Close()
+		return fmt.Errorf("发送消息失败: %w", err)
+	}
+	return nil
+}
+
+// newMessage 创建请求消息
+func (c *Client) newMessage(msgType types.MessageType, body []byte) *types.Message {
+	if body == nil {
+		body = []byte{}
+	}
+	return &types.Message{
+		Header: types.MessageHeader{
+			Version:     c.config.ProtocolVersion,
+			Type:        msgType,
+			GameID:      c.config.GameID,
+			UserID:      c.config.UserID,
+			Timestamp:   time.Now().Unix(),
+			BodyLength:  uint32(len(body)),
+			ClientBuild: c.config.ClientBuild,
+		},
+		Body: body,
+	}
+}
+
+// nextSequence 分配序列号，跳过0
+func (c *Client) nextSequence() uint32 {
+	for {
+		if seq := atomic.AddUint32(&c.sequence, 1); seq != 0 {
+			return seq
+		}
+	}
+}
+
+func (c *Client) removePending(seq uint32) {
+	c.pendingMu.Lock()
+	delete(c.pending, seq)
+	c.pendingMu.Unlock()
+}
+
+// failPending 使所有等待中的请求返回错误
+func (c *Client) failPending(err error) {
+	c.pendingMu.Lock()
+	pending := c.pending
+	c.pending = make(map[uint32]chan result)
+	c.pendingMu.Unlock()
+
+	for _, ch := range pending {
+		ch <- result{err: err}
+	}
+}
+
+// parseServerError 解析错误消息
+func parseServerError(msg *types.Message) error {
+	serverErr := &ServerError{TraceID: msg.Header.TraceID}
+	if err := json.Unmarshal(msg.Body, serverErr); err != nil {
+		serverErr.Code = int(msg.Header.ErrorCode)
+		serverErr.Message = string(msg.Body)
+	}
+	return serverErr
+}
+
+// frameReader 从连接读取完整消息，处理TCP粘包分包
+type frameReader struct {
+	conn   net.Conn
+	buffer []byte
+}
+
+// next 读取下一条消息
+func (r *frameReader) next(codec protocol.Codec) (*types.Message, error) {
+	for {
+		if len(r.buffer) > 0 {
+			msg, consumed, err := codec.Decode(r.buffer)
+			if err == nil {
+				r.buffer = r.buffer[consumed:]
+				return msg, nil
+			}
+			if !protocol.IsInsufficientDataError(err) {
+				return nil, err
+			}
+		}
+
+		buf := make([]byte, 4096)
+		n, err := r.conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		r.buffer = append(r.buffer, buf[:n]...)
+	}
+}
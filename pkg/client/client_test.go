@@ -0,0 +1,340 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"datamiddleware/internal/common/types"
+	logger "datamiddleware/internal/infrastructure/logging"
+	"datamiddleware/internal/protocol"
+
+	"go.uber.org/zap"
+)
+
+// testServer 使用服务端连接实现的最小服务器：完成握手，回显游戏消息，并在回显后推送一条消息
+type testServer struct {
+	listener net.Listener
+	identity *protocol.ServerIdentity
+	conns    chan *protocol.Connection
+	pongs    chan uint32 // 收到的Pong序列号
+
+	handshakes chan types.HandshakeRequest // 收到的握手请求
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	identity, err := protocol.NewServerIdentity("")
+	if err != nil {
+		t.Fatalf("创建身份密钥失败: %v", err)
+	}
+
+	s := &testServer{listener: listener, identity: identity, conns: make(chan *protocol.Connection, 8), pongs: make(chan uint32, 8), handshakes: make(chan types.HandshakeRequest, 8)}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *testServer) serve() {
+	log := &logger.ZapLogger{SugaredLogger: zap.NewNop().Sugar()}
+	for {
+		raw, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		conn := protocol.NewConnection(raw, types.ConnectionConfig{BufferSize: 4096, CodecNegotiation: true}, protocol.NewBinaryCodec(), log)
+		conn.Start()
+		s.conns <- conn
+		go s.handle(conn)
+	}
+}
+
+func (s *testServer) handle(conn *protocol.Connection) {
+	defer conn.Close()
+	for {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		switch msg.Header.Type {
+		case types.MessageTypeHeartbeat:
+			conn.SendMessage(protocol.CreateHeartbeatMessage(msg.Header.SequenceID))
+		case types.MessageTypeHandshake:
+			var req types.HandshakeRequest
+			json.Unmarshal(msg.Body, &req)
+			s.handshakes <- req
+			resp := &types.HandshakeResponse{GameID: msg.Header.GameID, UserID: msg.Header.UserID, ResumeToken: "resume-1", Resumed: req.ResumeToken == "resume-1"}
+
+			compressor := protocol.NegotiateCompression(types.CompressionConfig{Enabled: true, Threshold: 16, Algorithms: []string{"deflate"}}, req.Compression)
+			if compressor != nil {
+				resp.Compression = compressor.Name()
+				resp.CompressionThreshold = 16
+			}
+			var cipher *protocol.SessionCipher
+			if req.KeyExchange != nil {
+				resp.KeyExchange, cipher, _ = s.identity.KeyExchange(req.KeyExchange, msg.Header.GameID, msg.Header.UserID)
+			}
+
+			conn.Authenticate(msg.Header.GameID, msg.Header.UserID)
+			conn.SendMessage(protocol.CreateHandshakeMessage(resp, msg.Header.SequenceID))
+			if compressor != nil {
+				conn.EnableCompression(compressor, 16)
+			}
+			if cipher != nil {
+				conn.EnableEncryption(cipher)
+			}
+		case types.MessageTypePing:
+			// 不回复，用于测试请求超时
+		case types.MessageTypePong:
+			s.pongs <- msg.Header.SequenceID
+		case types.MessageTypeError:
+			// 客户端发送错误类型的消息时回复错误，用于测试错误解析
+			conn.SendMessage(protocol.CreateErrorMessage(1101, "参数无效", msg.Header.SequenceID))
+		default:
+			reply := &types.Message{Header: msg.Header, Body: msg.Body}
+			conn.SendMessage(reply)
+			conn.SendMessage(&types.Message{
+				Header: types.MessageHeader{Version: types.ProtocolVersionV2, Type: types.MessageTypePlayerData, TraceID: msg.Header.TraceID},
+				Body:   []byte(`{"event":"push"}`),
+			})
+		}
+	}
+}
+
+func TestClientCallAndPush(t *testing.T) {
+	server := newTestServer(t)
+
+	config := DefaultConfig(server.listener.Addr().String(), "game1", "user1")
+	config.Compression = []string{"gzip", "deflate"}
+	config.Encryption = true
+	config.ServerIdentityKey = server.identity.PublicKey()
+	config.HeartbeatInterval = 0
+
+	c, err := Dial(config)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer c.Close()
+
+	session, ok := c.Session()
+	if !ok || session.Compression != "deflate" || session.KeyExchange == nil {
+		t.Fatalf("握手结果不一致: %+v", session)
+	}
+
+	body := []byte(`{"item_id":"sword","quantity":1,"comment":"long enough to compress"}`)
+	msg := c.newMessage(types.MessageTypeItemOperation, body)
+	msg.Header.TraceID = "trace-1"
+	resp, err := c.CallMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if string(resp.Body) != string(body) || resp.Header.TraceID != "trace-1" {
+		t.Errorf("响应不一致: %+v %s", resp.Header, resp.Body)
+	}
+
+	select {
+	case push := <-c.Pushes():
+		if push.Header.Type != types.MessageTypePlayerData || push.Header.TraceID != "trace-1" {
+			t.Errorf("推送消息不一致: %+v", push.Header)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("未收到推送消息")
+	}
+
+	if _, err := c.Call(context.Background(), types.MessageTypeHeartbeat, nil); err != nil {
+		t.Errorf("心跳失败: %v", err)
+	}
+
+	_, err = c.Call(context.Background(), types.MessageTypeError, nil)
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) || serverErr.Code != 1101 {
+		t.Errorf("期望服务端错误1101，实际 %v", err)
+	}
+
+	// 服务端Ping由SDK自动回复相同序列号的Pong，不作为推送
+	(<-server.conns).SendMessage(protocol.CreatePingMessage(77))
+	select {
+	case seq := <-server.pongs:
+		if seq != 77 {
+			t.Errorf("Pong序列号 = %d, 期望 77", seq)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("未收到Pong")
+	}
+	select {
+	case push := <-c.Pushes():
+		t.Errorf("Ping不应作为推送: %+v", push.Header)
+	default:
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	server := newTestServer(t)
+
+	reconnected := make(chan struct{}, 1)
+	config := DefaultConfig(server.listener.Addr().String(), "game1", "user1")
+	config.HeartbeatInterval = 0
+	config.Reconnect.InitialBackoff = 10 * time.Millisecond
+	config.OnReconnect = func() { reconnected <- struct{}{} }
+
+	c, err := Dial(config)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer c.Close()
+
+	// 服务端断开第一个连接
+	(<-server.conns).Close()
+
+	select {
+	case <-reconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("未自动重连")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c.Call(ctx, types.MessageTypeHeartbeat, nil); err != nil {
+		t.Errorf("重连后请求失败: %v", err)
+	}
+}
+
+func TestClientCallTimeout(t *testing.T) {
+	server := newTestServer(t)
+
+	config := DefaultConfig(server.listener.Addr().String(), "game1", "user1")
+	config.HeartbeatInterval = 0
+	c, err := Dial(config)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer c.Close()
+
+	// 测试服务器不回复Ping
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = c.Call(ctx, types.MessageTypePing, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("期望超时错误，实际 %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("超时时间过长: %v", time.Since(start))
+	}
+}
+
+func TestClientGoAwayRedirect(t *testing.T) {
+	draining := newTestServer(t)
+	backup := newTestServer(t)
+
+	reconnected := make(chan struct{}, 1)
+	config := DefaultConfig(draining.listener.Addr().String(), "game1", "user1")
+	config.HeartbeatInterval = 0
+	config.Reconnect.InitialBackoff = 10 * time.Millisecond
+	config.OnReconnect = func() { reconnected <- struct{}{} }
+
+	c, err := Dial(config)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer c.Close()
+
+	// 服务端排空连接，建议重连到备用服务器
+	(<-draining.conns).SendMessage(protocol.CreateGoAwayMessage(&types.GoAway{
+		Redirect:       backup.listener.Addr().String(),
+		ReconnectAfter: 20,
+		CloseAfter:     5000,
+	}))
+
+	select {
+	case push := <-c.Pushes():
+		if push.Header.Type != types.MessageTypeGoAway {
+			t.Errorf("推送消息类型 = 0x%04x, 期望GoAway", push.Header.Type)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("未收到GoAway推送")
+	}
+
+	select {
+	case <-reconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("收到GoAway后未重连")
+	}
+	select {
+	case <-backup.conns:
+	case <-time.After(time.Second):
+		t.Fatal("未重连到建议的地址")
+	}
+}
+
+func TestClientResumeSession(t *testing.T) {
+	server := newTestServer(t)
+
+	reconnected := make(chan struct{}, 1)
+	config := DefaultConfig(server.listener.Addr().String(), "game1", "user1")
+	config.HeartbeatInterval = 0
+	config.Reconnect.InitialBackoff = 10 * time.Millisecond
+	config.OnReconnect = func() { reconnected <- struct{}{} }
+
+	c, err := Dial(config)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer c.Close()
+	<-server.handshakes
+
+	push := func(conn *protocol.Connection, seq uint32) {
+		conn.SendMessage(&types.Message{
+			Header: types.MessageHeader{Version: types.ProtocolVersion, Type: types.MessageTypePlayerData, Flags: types.FlagAsync, SequenceID: seq},
+			Body:   []byte(`{"event":"push"}`),
+		})
+	}
+	receive := func() uint32 {
+		select {
+		case msg := <-c.Pushes():
+			return msg.Header.SequenceID
+		case <-time.After(2 * time.Second):
+			t.Fatal("未收到推送")
+			return 0
+		}
+	}
+
+	first := <-server.conns
+	push(first, 1)
+	push(first, 2)
+	if a, b := receive(), receive(); a != 1 || b != 2 {
+		t.Fatalf("推送序列号 = %d %d, 期望 1 2", a, b)
+	}
+
+	// 重连时携带恢复令牌和已收到的最后一条推送的序列号
+	first.Close()
+	select {
+	case <-reconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("未自动重连")
+	}
+	req := <-server.handshakes
+	if req.ResumeToken != "resume-1" || req.LastPushSeq != 2 {
+		t.Errorf("重连握手请求 = %+v", req)
+	}
+	if session, _ := c.Session(); !session.Resumed {
+		t.Error("会话应已恢复")
+	}
+
+	// 补发的推送中已收到的部分被忽略
+	second := <-server.conns
+	push(second, 2)
+	push(second, 3)
+	if seq := receive(); seq != 3 {
+		t.Errorf("补发的推送序列号 = %d, 期望 3", seq)
+	}
+}
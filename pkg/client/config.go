@@ -0,0 +1,102 @@
+package client
+
+import (
+	"time"
+
+	"datamiddleware/internal/common/types"
+	"datamiddleware/internal/protocol"
+)
+
+// Config 客户端配置
+type Config struct {
+	Addr            string // 服务器地址，例如 localhost:9090
+	GameID          string // 游戏ID
+	UserID          string // 用户ID，服务端启用握手认证时以凭证中的用户为准
+	Token           string // 登录获得的访问令牌，与SessionID二选一，服务端启用握手认证时必填
+	SessionID       string // 玩家登录返回的会话ID
+	ClientBuild     string // 客户端构建版本，v2协议下随每条消息发送
+	ProtocolVersion uint8  // 协议版本，默认v2
+	Codec           string // 编解码器: binary, json, protobuf，非二进制时连接后先发送前导字节
+
+	Compression       []string // 支持的压缩算法，为空表示不压缩
+	Encryption        bool     // 是否建立加密会话
+	ServerIdentityKey string   // 服务端Ed25519身份公钥（base64），启用加密时必填
+
+	DialTimeout       time.Duration // 连接超时
+	HandshakeTimeout  time.Duration // 握手超时
+	WriteTimeout      time.Duration // 写入超时
+	RequestTimeout    time.Duration // 默认请求超时，调用方context没有截止时间时使用
+	HeartbeatInterval time.Duration // 心跳间隔，0表示不发送心跳
+
+	Reconnect  ReconnectConfig // 断线重连配置
+	PushBuffer int             // 服务端推送消息通道容量，通道满时丢弃新消息
+
+	OnDisconnect func(err error) // 连接断开回调
+	OnReconnect  func()          // 重连成功回调
+}
+
+// ReconnectConfig 断线重连配置
+type ReconnectConfig struct {
+	Enabled        bool          // 是否自动重连
+	InitialBackoff time.Duration // 首次重连等待时间
+	MaxBackoff     time.Duration // 最大重连等待时间
+	MaxAttempts    int           // 最大连续重连次数，0表示不限制
+}
+
+// DefaultConfig 返回默认客户端配置
+func DefaultConfig(addr, gameID, userID string) Config {
+	return Config{
+		Addr:              addr,
+		GameID:            gameID,
+		UserID:            userID,
+		ProtocolVersion:   types.ProtocolVersionV2,
+		Codec:             protocol.CodecBinary,
+		DialTimeout:       5 * time.Second,
+		HandshakeTimeout:  5 * time.Second,
+		WriteTimeout:      5 * time.Second,
+		RequestTimeout:    10 * time.Second,
+		HeartbeatInterval: 30 * time.Second,
+		Reconnect: ReconnectConfig{
+			Enabled:        true,
+			InitialBackoff: 500 * time.Millisecond,
+			MaxBackoff:     30 * time.Second,
+		},
+		PushBuffer: 256,
+	}
+}
+
+// withDefaults 补全未设置的配置项
+func (c Config) withDefaults() Config {
+	def := DefaultConfig(c.Addr, c.GameID, c.UserID)
+	if c.ProtocolVersion == 0 {
+		c.ProtocolVersion = def.ProtocolVersion
+	}
+	if c.Codec == "" {
+		c.Codec = def.Codec
+	}
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = def.DialTimeout
+	}
+	if c.HandshakeTimeout <= 0 {
+		c.HandshakeTimeout = def.HandshakeTimeout
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = def.WriteTimeout
+	}
+	if c.RequestTimeout <= 0 {
+		c.RequestTimeout = def.RequestTimeout
+	}
+	if c.Reconnect.InitialBackoff <= 0 {
+		c.Reconnect.InitialBackoff = def.Reconnect.InitialBackoff
+	}
+	if c.Reconnect.MaxBackoff <= 0 {
+		c.Reconnect.MaxBackoff = def.Reconnect.MaxBackoff
+	}
+	if c.Reconnect.MaxBackoff < c.Reconnect.InitialBackoff {
+		c.Reconnect.MaxBackoff = c.Reconnect.InitialBackoff
+	}
+	if c.PushBuffer <= 0 {
+		c.PushBuffer = def.PushBuffer
+	}
+	return c
+}
@@ -40,6 +40,7 @@ const (
 	ErrCodeConnectionClosed    = 2004 // 连接已关闭
 	ErrCodeProtocolError       = 2005 // 协议错误
 	ErrCodeMessageTooLarge     = 2006 // 消息过大
+	ErrCodeServerDraining      = 2007 // 服务器正在排空连接
 )
 
 // 数据库错误码 (003XXX)
@@ -70,6 +71,7 @@ const (
 	ErrCodeGameServerFull     = 5003 // 游戏服务器已满
 	ErrCodeGameInProgress     = 5004 // 游戏进行中
 	ErrCodeGameFinished       = 5005 // 游戏已结束
+	ErrCodeGameMaintenance    = 5006 // 游戏维护中
 )
 
 // 道具相关错误码 (006XXX)